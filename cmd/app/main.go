@@ -1,17 +1,86 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	outboxRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/outbox/repository"
+	outboxSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/outbox/service"
+	webhookRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/webhook/repository"
+	webhookSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/webhook/service"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/database"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/env"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/server"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/egress"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/fieldcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/mailer"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/metrics"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
 )
 
 func main() {
-	server := server.NewHttpServer()
+	shutdownTracing, err := tracing.Init(context.Background(), tracing.Config{
+		ServiceName: "gogomanager-api",
+		Environment: env.AppEnv.AppEnv,
+		Exporter:    env.AppEnv.TracingExporter,
+		FilePath:    env.AppEnv.TracingFilePath,
+		SampleRatio: env.AppEnv.TracingSampleRatio,
+	})
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[MAIN] failed to initialize tracing")
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			log.Error(log.LogInfo{
+				"error": err.Error(),
+			}, "[MAIN] failed to flush traces")
+		}
+	}()
+
+	metricsServer := server.NewMetricsServer(metrics.Metrics, env.AppEnv.MetricsToken)
+	server := server.NewHttpServer(server.ProxyConfig{
+		TrustedProxies: env.AppEnv.TrustedProxies,
+		Header:         env.AppEnv.ProxyHeader,
+	})
+	database.EnsureSchema(env.AppEnv.DBAutoMigrate)
 	psqlDB := database.NewPgsqlConn()
 	defer psqlDB.Close()
 
+	// Deliver queued emails in the background for as long as the server runs
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	defer stopRelay()
+	relay := outboxSvc.NewOutboxService(outboxRepo.NewOutboxEmailRepository(psqlDB), mailer.Mailer, env.AppEnv.MailOutboxInterval)
+	go relay.Run(relayCtx)
+
+	// Webhook deliveries go out the same way, retried with backoff until they succeed or die
+	dispatcher := webhookSvc.NewWebhookDispatcher(webhookRepo.NewWebhookRepository(psqlDB, fieldcrypt.FieldCrypt), webhookSvc.DispatcherConfig{
+		Interval: env.AppEnv.WebhookDispatchInterval,
+		Timeout:  env.AppEnv.WebhookTimeout,
+		Guard:    egress.Guard{AllowPrivate: env.AppEnv.WebhookAllowPrivate},
+	})
+	go dispatcher.Run(relayCtx)
+
+	// Domain events committed to the outbox are relayed to the configured sinks, webhooks among them
+	sinks, err := outboxSvc.NewOutboxSinks(outboxSvc.SinkConfig{
+		Sinks:             env.AppEnv.OutboxSinks,
+		NATSURL:           env.AppEnv.NATSURL,
+		NATSSubjectPrefix: env.AppEnv.NATSSubjectPrefix,
+		KafkaBrokers:      env.AppEnv.KafkaBrokers,
+		KafkaTopic:        env.AppEnv.KafkaTopic,
+	}, webhookRepo.NewWebhookRepository(psqlDB, fieldcrypt.FieldCrypt))
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[MAIN] failed to initialize outbox sinks")
+	}
+	eventRelay := outboxSvc.NewOutboxRelay(outboxRepo.NewOutboxEventRepository(psqlDB, fieldcrypt.FieldCrypt), sinks, outboxSvc.RelayConfig{
+		Interval:  env.AppEnv.OutboxRelayInterval,
+		Retention: env.AppEnv.OutboxRetention,
+	})
+	go eventRelay.Run(relayCtx)
+
 	server.MountMiddlewares()
 	server.MountRoutes(psqlDB)
 
@@ -23,5 +92,13 @@ func main() {
 		fmt.Printf("%s -> '%s'\n", route.Method, route.Path)
 	}
 
+	if env.AppEnv.MetricsPort != "" {
+		go metricsServer.Start(env.AppEnv.MetricsPort)
+	}
+
+	if env.AppEnv.GRPCPort != "" {
+		go server.GetGrpcServer().Start(env.AppEnv.GRPCPort)
+	}
+
 	server.Start(env.AppEnv.AppPort)
 }
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/database"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+)
+
+const MigrationsPath = "database/migrations/"
+
+const usage = `Usage: migrate <command> [arguments]
+
+Commands:
+  up [N]         apply all pending migrations, or the next N
+  down [N]       roll back N migrations (default 1)
+  status         show the current and latest schema version
+  force VERSION  set the schema version without running migrations (clears the dirty flag)
+  create NAME    create a new pair of empty migration files in ` + MigrationsPath + `
+`
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	if flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	command := flag.Arg(0)
+	if command == "create" {
+		createMigration(flag.Arg(1))
+		return
+	}
+
+	m, err := database.NewMigrator()
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[migrate] failed to initialize migrator")
+	}
+	defer m.Close()
+
+	switch command {
+	case "up":
+		if flag.NArg() > 1 {
+			err = m.Steps(intArg(1))
+		} else {
+			err = m.Up()
+		}
+	case "down":
+		steps := 1
+		if flag.NArg() > 1 {
+			steps = intArg(1)
+		}
+		err = m.Down(steps)
+	case "force":
+		if flag.NArg() < 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		err = m.Force(intArg(1))
+	case "status":
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[migrate] failed to run "+command)
+	}
+
+	printStatus(m)
+}
+
+func intArg(i int) int {
+	n, err := strconv.Atoi(flag.Arg(i))
+	if err != nil || n < 0 {
+		log.Fatal(log.LogInfo{
+			"argument": flag.Arg(i),
+		}, "[migrate] argument must be a non-negative number")
+	}
+
+	return n
+}
+
+func printStatus(m database.Migrator) {
+	status, err := m.Status()
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[migrate] failed to read status")
+	}
+
+	fmt.Printf("current version: %d\n", status.Current)
+	fmt.Printf("latest version:  %d\n", status.Latest)
+	fmt.Printf("dirty:           %t\n", status.Dirty)
+	fmt.Printf("pending:         %v\n", status.Pending)
+}
+
+var migrationFileName = regexp.MustCompile(`^(\d+)_.*\.(up|down)\.sql$`)
+
+func createMigration(name string) {
+	if name == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	entries, err := os.ReadDir(MigrationsPath)
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[migrate] failed to read migrations directory")
+	}
+
+	next := 1
+	for _, entry := range entries {
+		match := migrationFileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		if version >= next {
+			next = version + 1
+		}
+	}
+
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(MigrationsPath, fmt.Sprintf("%06d_%s.%s.sql", next, name, direction))
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			log.Fatal(log.LogInfo{
+				"error": err.Error(),
+			}, "[migrate] failed to create migration file")
+		}
+
+		fmt.Println(path)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+
+	employeeRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/employee/repository"
+	outboxRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/outbox/repository"
+	webhookRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/webhook/repository"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/database"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/fieldcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+)
+
+const usage = `Usage: rekey [-batch N] [-decrypt]
+
+Encrypts the employee rows, outbox events and webhook deliveries that are still plaintext or
+sealed with another key than FIELD_ENCRYPTION_ACTIVE_KEY, one batch per transaction. Once it finishes, keys that are no
+longer active can be removed from FIELD_ENCRYPTION_KEYS.
+
+  -batch N   rows per transaction (default 500)
+  -decrypt   write every row back in plaintext instead, before rolling back the encryption migration
+`
+
+// rekeyer is what every repository with sealed columns implements
+type rekeyer interface {
+	Reencrypt(ctx context.Context, limit int) (int, error)
+	Decrypt(ctx context.Context, limit int) (int, error)
+}
+
+func main() {
+	batch := flag.Int("batch", 500, "rows per transaction")
+	decrypt := flag.Bool("decrypt", false, "write rows back in plaintext")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+	}
+	flag.Parse()
+
+	if *batch <= 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	db := database.NewPgsqlConn()
+	defer db.Close()
+
+	ctx := context.Background()
+	tables := []struct {
+		name string
+		repo rekeyer
+	}{
+		{"employees", employeeRepo.NewEmployeeRepository(db, fieldcrypt.FieldCrypt)},
+		{"outbox_events", outboxRepo.NewOutboxEventRepository(db, fieldcrypt.FieldCrypt)},
+		{"webhook_deliveries", webhookRepo.NewWebhookRepository(db, fieldcrypt.FieldCrypt)},
+	}
+
+	total := 0
+	for _, table := range tables {
+		step := table.repo.Reencrypt
+		if *decrypt {
+			step = table.repo.Decrypt
+		}
+
+		for {
+			n, err := step(ctx, *batch)
+			if err != nil {
+				log.Fatal(log.LogInfo{
+					"error": err.Error(),
+					"table": table.name,
+					"done":  total,
+				}, "[rekey] failed to process batch")
+			}
+
+			if n == 0 {
+				break
+			}
+
+			total += n
+			log.Info(log.LogInfo{
+				"table": table.name,
+				"done":  total,
+			}, "[rekey] batch committed")
+		}
+	}
+
+	log.Info(log.LogInfo{
+		"rows":      total,
+		"decrypt":   *decrypt,
+		"activeKey": fieldcrypt.FieldCrypt.ActiveKeyID(),
+	}, "[rekey] done")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+type seedDepartmentRequest struct {
+	Name string `json:"name" validate:"required,min=4,max=33"`
+}
+
+// departments.csv columns: manager_email, name
+func seedDepartments(path string, db *sqlx.DB, validator validator.ValidatorInterface) {
+	records := readRecords(path+"departments.csv", 2)
+
+	for _, record := range records {
+		req := seedDepartmentRequest{
+			Name: record[1],
+		}
+
+		valErr := validator.Validate(req)
+		if valErr != nil {
+			log.Fatal(log.LogInfo{
+				"error": valErr,
+			}, "[seed][seedDepartments] Error validating department")
+		}
+
+		managerID, found := findManagerID(db, record[0])
+		if !found {
+			log.Fatal(log.LogInfo{
+				"email": record[0],
+			}, "[seed][seedDepartments] Manager not found, seed managers first")
+		}
+
+		if _, found := findDepartmentID(db, managerID, req.Name); found {
+			log.Info(log.LogInfo{
+				"name": req.Name,
+			}, "[seed][seedDepartments] Department already exists, skipping")
+			continue
+		}
+
+		department := &entity.Department{
+			Name:      req.Name,
+			ManagerID: managerID,
+		}
+
+		_, err := db.NamedExec(
+			`INSERT INTO departments (name, manager_id) VALUES (:name, :manager_id)`,
+			department,
+		)
+		if err != nil {
+			log.Fatal(log.LogInfo{
+				"error": err,
+			}, "[seed][seedDepartments] Error inserting department")
+		}
+
+		log.Info(log.LogInfo{
+			"name": req.Name,
+		}, "[seed][seedDepartments] Inserted department")
+	}
+}
+
+func findDepartmentID(db *sqlx.DB, managerID int, name string) (int, bool) {
+	var ids []int
+	err := db.Select(&ids, `SELECT id FROM departments WHERE manager_id = $1 AND name = $2 ORDER BY id LIMIT 1`, managerID, name)
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err,
+		}, "[seed][findDepartmentID] Error looking up department")
+	}
+
+	if len(ids) == 0 {
+		return 0, false
+	}
+
+	return ids[0], true
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	employeeRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/employee/repository"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/fieldcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+// employees.csv columns: manager_email, department_name, identity_number, name, gender, employee_image_uri
+func seedEmployees(path string, db *sqlx.DB, validator validator.ValidatorInterface) {
+	records := readRecords(path+"employees.csv", 6)
+	repo := employeeRepo.NewEmployeeRepository(db, fieldcrypt.FieldCrypt)
+	ctx := context.Background()
+
+	for i, record := range records {
+		managerID, found := findManagerID(db, record[0])
+		if !found {
+			log.Fatal(log.LogInfo{
+				"email": record[0],
+			}, "[seed][seedEmployees] Manager not found, seed managers first")
+		}
+
+		departmentID, found := findDepartmentID(db, managerID, record[1])
+		if !found {
+			log.Fatal(log.LogInfo{
+				"department": record[1],
+			}, "[seed][seedEmployees] Department not found, seed departments first")
+		}
+
+		req := dto.EmployeeCreateReq{
+			IdentityNumber:   record[2],
+			Name:             record[3],
+			Gender:           record[4],
+			EmployeeImageURI: record[5],
+			DepartmentID:     strconv.Itoa(departmentID),
+		}
+
+		valErr := validator.Validate(req)
+		if valErr != nil {
+			log.Fatal(log.LogInfo{
+				"error": valErr,
+			}, "[seed][seedEmployees] Error validating employee")
+		}
+
+		_, err := repo.FindByIdentityNumber(ctx, req.IdentityNumber)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			log.Fatal(log.LogInfo{
+				"error": err,
+			}, "[seed][seedEmployees] Error looking up employee")
+		}
+
+		if err == nil {
+			log.Info(log.LogInfo{
+				"row": i + 1,
+			}, "[seed][seedEmployees] Employee already exists, skipping")
+			continue
+		}
+
+		_, err = repo.Create(ctx, entity.Employee{
+			IdentityNumber:   req.IdentityNumber,
+			Name:             req.Name,
+			EmployeeImageURI: req.EmployeeImageURI,
+			Gender:           req.Gender,
+			DepartmentID:     departmentID,
+		})
+		if err != nil {
+			log.Fatal(log.LogInfo{
+				"error": err,
+			}, "[seed][seedEmployees] Error inserting employee")
+		}
+
+		log.Info(log.LogInfo{
+			"row": i + 1,
+		}, "[seed][seedEmployees] Inserted employee")
+	}
+}
+
+// encryptEmployees seals employees inserted in plaintext by the bulk generator, the same way the rekey command does
+func encryptEmployees(db *sqlx.DB, batchSize int) {
+	repo := employeeRepo.NewEmployeeRepository(db, fieldcrypt.FieldCrypt)
+
+	for {
+		n, err := repo.Reencrypt(context.Background(), batchSize)
+		if err != nil {
+			log.Fatal(log.LogInfo{
+				"error": err,
+			}, "[seed][encryptEmployees] Error encrypting employees")
+		}
+
+		if n == 0 {
+			return
+		}
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/hasher"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/flag"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+)
+
+// FakePassword is shared by every generated manager so the data can be used right away
+const FakePassword = "password123"
+
+// FakeEmailDomain marks generated managers, their emails embed the seed so runs with
+// different seeds never collide and a repeated seed is detected before anything is inserted
+const FakeEmailDomain = "gogomanager.test"
+
+// MaxBatchSize keeps a batch of employees under the 65535 bind parameters Postgres accepts
+const MaxBatchSize = 10_000
+
+var fakeFirstNames = []string{
+	"Adi", "Bima", "Citra", "Dewi", "Eka", "Fajar", "Gita", "Hadi", "Indah", "Joko",
+	"Kartika", "Lestari", "Made", "Nadia", "Oki", "Putri", "Rizky", "Sari", "Tono", "Wulan",
+}
+
+var fakeLastNames = []string{
+	"Saputra", "Wijaya", "Pratama", "Kusuma", "Santoso", "Hidayat", "Nugroho", "Lestari",
+	"Permata", "Setiawan", "Gunawan", "Halim", "Siregar", "Nasution", "Utama", "Wibowo",
+}
+
+var fakeDepartmentNames = []string{
+	"Engineering", "Finance", "Human Resources", "Marketing", "Operations", "Sales",
+	"Legal", "Procurement", "Customer Support", "Research", "Security", "Logistics",
+}
+
+var fakeCompanySuffixes = []string{"Corp", "Group", "Labs", "Holdings", "Works", "Digital"}
+
+var fakeGenders = []string{"male", "female"}
+
+type fakeGenerator struct {
+	db         *sqlx.DB
+	rng        *rand.Rand
+	flags      *flag.Flag
+	password   string
+	identities int
+}
+
+// generateFakeData creates flags.Managers managers, each with flags.Departments departments holding
+// flags.Employees employees. The same seed and counts always produce the same rows; every manager
+// is written in its own transaction.
+func generateFakeData(db *sqlx.DB, hasher hasher.PasswordHasher, flags *flag.Flag) {
+	if flags.Managers < 0 || flags.Departments < 0 || flags.Employees < 0 || flags.BatchSize <= 0 || flags.BatchSize > MaxBatchSize {
+		log.Fatal(log.LogInfo{
+			"managers":    flags.Managers,
+			"departments": flags.Departments,
+			"employees":   flags.Employees,
+			"batchSize":   flags.BatchSize,
+		}, "[seed][generateFakeData] Counts must not be negative and batch size must be between 1 and 10000")
+	}
+
+	var existing int
+	err := db.Get(&existing, `SELECT COUNT(*) FROM managers WHERE email LIKE $1`, fmt.Sprintf("%%.seed%d@%s", flags.Seed, FakeEmailDomain))
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err,
+		}, "[seed][generateFakeData] Error checking for previous runs")
+	}
+
+	if existing > 0 {
+		log.Fatal(log.LogInfo{
+			"seed": flags.Seed,
+		}, "[seed][generateFakeData] Data for this seed was already generated, use another seed or reset the database")
+	}
+
+	hashedPassword, err := hasher.Hash(FakePassword)
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err,
+		}, "[seed][generateFakeData] Error hashing password")
+	}
+
+	g := &fakeGenerator{
+		db:       db,
+		rng:      rand.New(rand.NewPCG(flags.Seed, flags.Seed)),
+		flags:    flags,
+		password: hashedPassword,
+	}
+
+	for i := 1; i <= flags.Managers; i++ {
+		email := g.generateManager(i)
+
+		log.Info(log.LogInfo{
+			"email":       email,
+			"departments": flags.Departments,
+			"employees":   flags.Departments * flags.Employees,
+		}, "[seed][generateFakeData] Generated manager")
+	}
+
+	encryptEmployees(db, flags.BatchSize)
+
+	log.Info(log.LogInfo{
+		"seed":      flags.Seed,
+		"managers":  flags.Managers,
+		"employees": flags.Managers * flags.Departments * flags.Employees,
+	}, "[seed][generateFakeData] Done, every generated manager logs in with the password "+FakePassword)
+}
+
+func (g *fakeGenerator) generateManager(n int) string {
+	tx, err := g.db.Beginx()
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err,
+		}, "[seed][generateManager] Error starting transaction")
+	}
+	defer tx.Rollback()
+
+	manager := &entity.Manager{
+		Email:       fmt.Sprintf("manager%d.seed%d@%s", n, g.flags.Seed, FakeEmailDomain),
+		Password:    g.password,
+		Name:        g.name(),
+		CompanyName: pick(g.rng, fakeLastNames) + " " + pick(g.rng, fakeCompanySuffixes),
+	}
+
+	rows, err := tx.NamedQuery(
+		`INSERT INTO managers (email, password, name, company_name) VALUES (:email, :password, :name, :company_name) RETURNING id`,
+		manager,
+	)
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err,
+		}, "[seed][generateManager] Error inserting manager")
+	}
+	managerIDs := scanIDs(rows)
+
+	departmentIDs := g.generateDepartments(tx, managerIDs[0])
+	g.generateEmployees(tx, departmentIDs)
+
+	if err := tx.Commit(); err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err,
+		}, "[seed][generateManager] Error committing transaction")
+	}
+
+	return manager.Email
+}
+
+func (g *fakeGenerator) generateDepartments(tx *sqlx.Tx, managerID int) []int {
+	departments := make([]entity.Department, 0, g.flags.Departments)
+	for i := 1; i <= g.flags.Departments; i++ {
+		departments = append(departments, entity.Department{
+			Name:      fmt.Sprintf("%s %d", pick(g.rng, fakeDepartmentNames), i),
+			ManagerID: managerID,
+		})
+	}
+
+	var ids []int
+	for batch := range slices.Chunk(departments, g.flags.BatchSize) {
+		rows, err := tx.NamedQuery(
+			`INSERT INTO departments (name, manager_id) VALUES (:name, :manager_id) RETURNING id`,
+			batch,
+		)
+		if err != nil {
+			log.Fatal(log.LogInfo{
+				"error": err,
+			}, "[seed][generateDepartments] Error inserting departments")
+		}
+
+		ids = append(ids, scanIDs(rows)...)
+	}
+
+	// RETURNING does not promise VALUES order, sorting keeps employees on the same departments across runs
+	slices.Sort(ids)
+
+	return ids
+}
+
+func (g *fakeGenerator) generateEmployees(tx *sqlx.Tx, departmentIDs []int) {
+	batch := make([]entity.Employee, 0, g.flags.BatchSize)
+
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+
+		_, err := tx.NamedExec(
+			`INSERT INTO employees (identity_number, name, employee_image_uri, gender, department_id) VALUES (:identity_number, :name, :employee_image_uri, :gender, :department_id)`,
+			batch,
+		)
+		if err != nil {
+			log.Fatal(log.LogInfo{
+				"error": err,
+			}, "[seed][generateEmployees] Error inserting employees")
+		}
+
+		batch = batch[:0]
+	}
+
+	for _, departmentID := range departmentIDs {
+		for i := 0; i < g.flags.Employees; i++ {
+			g.identities++
+			identityNumber := fmt.Sprintf("%06d%04d%010d", g.flags.Seed%1_000_000, g.rng.IntN(10_000), g.identities)
+
+			batch = append(batch, entity.Employee{
+				IdentityNumber:   identityNumber,
+				Name:             g.name(),
+				EmployeeImageURI: "https://i.pravatar.cc/150?u=" + identityNumber,
+				Gender:           pick(g.rng, fakeGenders),
+				DepartmentID:     departmentID,
+			})
+
+			if len(batch) == g.flags.BatchSize {
+				flush()
+			}
+		}
+	}
+
+	flush()
+}
+
+func (g *fakeGenerator) name() string {
+	return pick(g.rng, fakeFirstNames) + " " + pick(g.rng, fakeLastNames)
+}
+
+func pick(rng *rand.Rand, values []string) string {
+	return values[rng.IntN(len(values))]
+}
+
+func scanIDs(rows *sqlx.Rows) []int {
+	defer rows.Close()
+
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			log.Fatal(log.LogInfo{
+				"error": err,
+			}, "[seed][scanIDs] Error scanning id")
+		}
+		ids = append(ids, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err,
+		}, "[seed][scanIDs] Error reading ids")
+	}
+
+	return ids
+}
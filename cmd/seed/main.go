@@ -10,7 +10,8 @@ import (
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/database"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/env"
-	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/bcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/hasher"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/flag"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/uuid"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
@@ -33,12 +34,59 @@ func main() {
 
 	validator := validator.Validator
 	uuid := uuid.UUID
-	bcrypt := bcrypt.Bcrypt
+	hasher := hasher.Hasher
+	flags := flag.FlagVars
 
-	seedUsers(path, psqlDB, validator, uuid, bcrypt)
+	if flags.Fake {
+		generateFakeData(psqlDB, hasher, flags)
+		return
+	}
+
+	switch flags.Entity {
+	case "users":
+		seedUsers(path, psqlDB, validator, uuid, hasher)
+	case "managers":
+		seedManagers(path, psqlDB, validator, hasher)
+	case "departments":
+		seedDepartments(path, psqlDB, validator)
+	case "employees":
+		seedEmployees(path, psqlDB, validator)
+	case "all":
+		seedUsers(path, psqlDB, validator, uuid, hasher)
+		seedManagers(path, psqlDB, validator, hasher)
+		seedDepartments(path, psqlDB, validator)
+		seedEmployees(path, psqlDB, validator)
+	default:
+		log.Fatal(log.LogInfo{
+			"entity": flags.Entity,
+		}, "[seed] Unknown entity, expected users, managers, departments, employees or all")
+	}
+}
+
+func readRecords(path string, columns int) [][]string {
+	file, err := os.Open(path)
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err,
+			"path":  path,
+		}, "[seed][readRecords] Error opening file")
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = columns
+	records, err := reader.ReadAll()
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err,
+			"path":  path,
+		}, "[seed][readRecords] Error reading file")
+	}
+
+	return records
 }
 
-func seedUsers(path string, db *sqlx.DB, validator validator.ValidatorInterface, uuid uuid.UUIDInterface, bcrypt bcrypt.BcryptInterface) {
+func seedUsers(path string, db *sqlx.DB, validator validator.ValidatorInterface, uuid uuid.UUIDInterface, hasher hasher.PasswordHasher) {
 	path += "users.csv"
 
 	file, err := os.Open(path)
@@ -90,7 +138,7 @@ func seedUsers(path string, db *sqlx.DB, validator validator.ValidatorInterface,
 			}, "[seed][seedUsers] Error generating uuid")
 		}
 
-		hashedPassword, err := bcrypt.Hash(req.Password)
+		hashedPassword, err := hasher.Hash(req.Password)
 		if err != nil {
 			log.Fatal(log.LogInfo{
 				"error": err,
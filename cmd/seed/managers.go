@@ -0,0 +1,81 @@
+package main
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/hasher"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+// managers.csv columns: email, password, name, company_name
+func seedManagers(path string, db *sqlx.DB, validator validator.ValidatorInterface, hasher hasher.PasswordHasher) {
+	records := readRecords(path+"managers.csv", 4)
+
+	for _, record := range records {
+		req := dto.AuthRequest{
+			Email:    record[0],
+			Password: record[1],
+			Action:   "create",
+		}
+
+		valErr := validator.Validate(req)
+		if valErr != nil {
+			log.Fatal(log.LogInfo{
+				"error": valErr,
+			}, "[seed][seedManagers] Error validating manager")
+		}
+
+		if _, found := findManagerID(db, req.Email); found {
+			log.Info(log.LogInfo{
+				"email": req.Email,
+			}, "[seed][seedManagers] Manager already exists, skipping")
+			continue
+		}
+
+		hashedPassword, err := hasher.Hash(req.Password)
+		if err != nil {
+			log.Fatal(log.LogInfo{
+				"error": err,
+			}, "[seed][seedManagers] Error hashing password")
+		}
+
+		manager := &entity.Manager{
+			Email:       req.Email,
+			Password:    hashedPassword,
+			Name:        record[2],
+			CompanyName: record[3],
+		}
+
+		_, err = db.NamedExec(
+			`INSERT INTO managers (email, password, name, company_name) VALUES (:email, :password, :name, :company_name)`,
+			manager,
+		)
+		if err != nil {
+			log.Fatal(log.LogInfo{
+				"error": err,
+			}, "[seed][seedManagers] Error inserting manager")
+		}
+
+		log.Info(log.LogInfo{
+			"email": req.Email,
+		}, "[seed][seedManagers] Inserted manager")
+	}
+}
+
+func findManagerID(db *sqlx.DB, email string) (int, bool) {
+	var ids []int
+	err := db.Select(&ids, `SELECT id FROM managers WHERE email = $1 ORDER BY id LIMIT 1`, email)
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err,
+		}, "[seed][findManagerID] Error looking up manager")
+	}
+
+	if len(ids) == 0 {
+		return 0, false
+	}
+
+	return ids[0], true
+}
@@ -7,4 +7,4 @@ CREATE TABLE managers (
 	company_name VARCHAR(255) DEFAULT '',
 	company_image_uri VARCHAR(4096) DEFAULT '',
 	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-)
+);
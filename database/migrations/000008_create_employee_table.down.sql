@@ -1 +1 @@
-DROP TABLE IF EXISTS "employees";
\ No newline at end of file
+DROP TABLE IF EXISTS "employees";
@@ -0,0 +1,8 @@
+CREATE TABLE login_attempts (
+	scope VARCHAR(16) NOT NULL,
+	subject VARCHAR(320) NOT NULL,
+	failures INT NOT NULL DEFAULT 0,
+	last_failure_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	locked_until TIMESTAMPTZ,
+	PRIMARY KEY (scope, subject)
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS outbox_emails;
+DROP TABLE IF EXISTS auth_tokens;
+
+ALTER TABLE managers
+DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,29 @@
+ALTER TABLE managers
+ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+CREATE TABLE auth_tokens (
+	id SERIAL PRIMARY KEY,
+	manager_id INT NOT NULL REFERENCES managers(id) ON DELETE CASCADE,
+	purpose VARCHAR(32) NOT NULL,
+	token_hash CHAR(64) NOT NULL UNIQUE,
+	expires_at TIMESTAMPTZ NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_auth_tokens_manager_purpose ON auth_tokens (manager_id, purpose);
+
+CREATE TABLE outbox_emails (
+	id BIGSERIAL PRIMARY KEY,
+	recipient VARCHAR(255) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	body TEXT NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sending', 'sent', 'failed')),
+	attempts INT NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	sent_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_outbox_emails_due ON outbox_emails (next_attempt_at) WHERE status IN ('pending', 'sending');
@@ -0,0 +1,20 @@
+CREATE TABLE mfa_factors (
+	subject_kind VARCHAR(16) NOT NULL,
+	subject_id VARCHAR(64) NOT NULL,
+	secret VARCHAR(64) NOT NULL,
+	confirmed_at TIMESTAMPTZ,
+	last_used_step BIGINT,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (subject_kind, subject_id)
+);
+
+CREATE TABLE mfa_recovery_codes (
+	id SERIAL PRIMARY KEY,
+	subject_kind VARCHAR(16) NOT NULL,
+	subject_id VARCHAR(64) NOT NULL,
+	code_hash CHAR(64) NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	FOREIGN KEY (subject_kind, subject_id) REFERENCES mfa_factors (subject_kind, subject_id) ON DELETE CASCADE,
+	UNIQUE (subject_kind, subject_id, code_hash)
+);
@@ -0,0 +1,14 @@
+CREATE TABLE api_keys (
+	id SERIAL PRIMARY KEY,
+	manager_id INT NOT NULL REFERENCES managers(id) ON DELETE CASCADE,
+	name VARCHAR(64) NOT NULL,
+	prefix VARCHAR(16) NOT NULL UNIQUE,
+	key_hash CHAR(64) NOT NULL,
+	scopes VARCHAR(255) NOT NULL,
+	expires_at TIMESTAMPTZ,
+	last_used_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_api_keys_manager_id ON api_keys (manager_id);
@@ -0,0 +1,9 @@
+-- Buckets are cheap to lose, a crash only refills them, so skip the WAL
+CREATE UNLOGGED TABLE rate_limit_buckets (
+	key VARCHAR(255) PRIMARY KEY,
+	tokens DOUBLE PRECISION NOT NULL,
+	capacity DOUBLE PRECISION NOT NULL,
+	rate DOUBLE PRECISION NOT NULL,
+	allowed BOOLEAN NOT NULL,
+	updated_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,35 @@
+-- The column types are JSON, not JSONB, so the stored diffs keep the exact bytes that were hashed
+CREATE TABLE audit_events (
+	id BIGSERIAL PRIMARY KEY,
+	tenant_id INT,
+	actor_kind VARCHAR(16) NOT NULL,
+	actor_id VARCHAR(64) NOT NULL,
+	api_key_id INT,
+	action VARCHAR(16) NOT NULL,
+	entity_type VARCHAR(32) NOT NULL,
+	entity_id VARCHAR(64) NOT NULL,
+	before JSON,
+	after JSON,
+	ip VARCHAR(64) NOT NULL,
+	request_id VARCHAR(128) NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL,
+	prev_hash CHAR(64) NOT NULL,
+	hash CHAR(64) NOT NULL UNIQUE
+);
+
+CREATE INDEX idx_audit_events_tenant_id ON audit_events (tenant_id, id);
+CREATE INDEX idx_audit_events_entity ON audit_events (entity_type, entity_id);
+
+CREATE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_change
+	BEFORE UPDATE OR DELETE ON audit_events
+	FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+
+CREATE TRIGGER audit_events_no_truncate
+	BEFORE TRUNCATE ON audit_events
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,18 @@
+-- Rows still encrypted would lose their identity number and name, refuse until they are decrypted
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM employees WHERE key_id IS NOT NULL) THEN
+		RAISE EXCEPTION 'employees are still encrypted, run `go run ./cmd/rekey -decrypt` first';
+	END IF;
+END $$;
+
+ALTER TABLE employees
+	DROP CONSTRAINT employees_encrypted_or_plain,
+	DROP COLUMN identity_number_enc,
+	DROP COLUMN identity_number_bidx,
+	DROP COLUMN name_enc,
+	DROP COLUMN name_bidx,
+	DROP COLUMN data_key,
+	DROP COLUMN key_id,
+	ALTER COLUMN identity_number SET NOT NULL,
+	ALTER COLUMN name SET NOT NULL;
@@ -0,0 +1,18 @@
+-- Rows keep their plaintext until `go run ./cmd/rekey` encrypts them, key_id IS NULL marks those
+ALTER TABLE employees
+	ADD COLUMN identity_number_enc BYTEA,
+	ADD COLUMN identity_number_bidx CHAR(64),
+	ADD COLUMN name_enc BYTEA,
+	ADD COLUMN name_bidx CHAR(64),
+	ADD COLUMN data_key BYTEA,
+	ADD COLUMN key_id VARCHAR(32),
+	ALTER COLUMN identity_number DROP NOT NULL,
+	ALTER COLUMN name DROP NOT NULL,
+	ADD CONSTRAINT employees_encrypted_or_plain CHECK (
+		(key_id IS NULL AND identity_number IS NOT NULL AND name IS NOT NULL)
+		OR (key_id IS NOT NULL AND identity_number_enc IS NOT NULL AND name_enc IS NOT NULL AND data_key IS NOT NULL)
+	);
+
+CREATE INDEX idx_employees_identity_number_bidx ON employees (identity_number_bidx);
+CREATE INDEX idx_employees_name_bidx ON employees (name_bidx);
+CREATE INDEX idx_employees_key_id ON employees (key_id);
@@ -0,0 +1,16 @@
+-- Erased rows have nothing left to restore, refuse rather than delete them
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM employees WHERE erased_at IS NOT NULL) THEN
+		RAISE EXCEPTION 'employees have been erased, delete them before rolling back';
+	END IF;
+END $$;
+
+ALTER TABLE employees
+	DROP CONSTRAINT employees_encrypted_or_plain,
+	DROP COLUMN erased_at,
+	ALTER COLUMN gender SET NOT NULL,
+	ADD CONSTRAINT employees_encrypted_or_plain CHECK (
+		(key_id IS NULL AND identity_number IS NOT NULL AND name IS NOT NULL)
+		OR (key_id IS NOT NULL AND identity_number_enc IS NOT NULL AND name_enc IS NOT NULL AND data_key IS NOT NULL)
+	);
@@ -0,0 +1,14 @@
+-- Erased employees keep their row, so the audit trail and anything pointing at the id stay valid,
+-- but lose every field that identifies them
+ALTER TABLE employees
+	ADD COLUMN erased_at TIMESTAMPTZ,
+	ALTER COLUMN gender DROP NOT NULL,
+	DROP CONSTRAINT employees_encrypted_or_plain,
+	ADD CONSTRAINT employees_encrypted_or_plain CHECK (
+		(erased_at IS NULL AND key_id IS NULL AND identity_number IS NOT NULL AND name IS NOT NULL AND gender IS NOT NULL)
+		OR (erased_at IS NULL AND key_id IS NOT NULL AND identity_number_enc IS NOT NULL AND name_enc IS NOT NULL
+			AND data_key IS NOT NULL AND gender IS NOT NULL)
+		OR (erased_at IS NOT NULL AND key_id IS NULL AND identity_number IS NULL AND name IS NULL
+			AND identity_number_enc IS NULL AND identity_number_bidx IS NULL AND name_enc IS NULL AND name_bidx IS NULL
+			AND data_key IS NULL AND gender IS NULL AND employee_image_uri IS NULL)
+	);
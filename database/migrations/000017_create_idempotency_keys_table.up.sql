@@ -0,0 +1,15 @@
+-- status_code is NULL while the first request is still running
+CREATE TABLE idempotency_keys (
+	tenant_id INT NOT NULL REFERENCES managers(id) ON DELETE CASCADE,
+	key VARCHAR(255) NOT NULL,
+	fingerprint CHAR(64) NOT NULL,
+	status_code INT,
+	content_type VARCHAR(255),
+	body BYTEA,
+	locked_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	expires_at TIMESTAMPTZ NOT NULL,
+	PRIMARY KEY (tenant_id, key)
+);
+
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys (expires_at);
@@ -0,0 +1,11 @@
+ALTER TABLE managers
+	DROP COLUMN version,
+	DROP COLUMN updated_at;
+
+ALTER TABLE departments
+	DROP COLUMN version,
+	DROP COLUMN updated_at;
+
+ALTER TABLE employees
+	DROP COLUMN version,
+	DROP COLUMN updated_at;
@@ -0,0 +1,12 @@
+-- version is bumped by every update that changes what the API returns, it backs the ETag
+ALTER TABLE employees
+	ADD COLUMN version INT NOT NULL DEFAULT 1,
+	ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+ALTER TABLE departments
+	ADD COLUMN version INT NOT NULL DEFAULT 1,
+	ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+ALTER TABLE managers
+	ADD COLUMN version INT NOT NULL DEFAULT 1,
+	ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
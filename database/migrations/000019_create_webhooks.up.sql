@@ -0,0 +1,46 @@
+-- events is a comma separated list of event types, empty to receive every event
+CREATE TABLE webhook_endpoints (
+	id SERIAL PRIMARY KEY,
+	tenant_id INT NOT NULL REFERENCES managers(id) ON DELETE CASCADE,
+	url VARCHAR(2048) NOT NULL,
+	description VARCHAR(255) NOT NULL DEFAULT '',
+	events TEXT NOT NULL DEFAULT '',
+	secret VARCHAR(64) NOT NULL,
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_endpoints_tenant_id ON webhook_endpoints (tenant_id);
+
+-- payload is kept as sent: the signature covers its exact bytes, which JSONB would not preserve
+CREATE TABLE webhook_deliveries (
+	id BIGSERIAL PRIMARY KEY,
+	endpoint_id INT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+	event_id VARCHAR(64) NOT NULL,
+	event_type VARCHAR(64) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivering', 'delivered', 'dead')),
+	attempts INT NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	last_status_code INT,
+	last_error TEXT NOT NULL DEFAULT '',
+	delivered_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status IN ('pending', 'delivering');
+CREATE INDEX idx_webhook_deliveries_endpoint_id ON webhook_deliveries (endpoint_id, id DESC);
+
+-- One row per request made, status_code is NULL when no response came back
+CREATE TABLE webhook_delivery_attempts (
+	id BIGSERIAL PRIMARY KEY,
+	delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+	status_code INT,
+	error TEXT NOT NULL DEFAULT '',
+	response_body TEXT NOT NULL DEFAULT '',
+	duration_ms INT NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webhook_delivery_attempts_delivery_id ON webhook_delivery_attempts (delivery_id, id);
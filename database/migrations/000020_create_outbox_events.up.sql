@@ -0,0 +1,24 @@
+-- Domain events are written in the transaction of the change they describe and relayed to the
+-- configured sinks afterwards. An aggregate's events go out one at a time in id order.
+CREATE TABLE outbox_events (
+	id BIGSERIAL PRIMARY KEY,
+	tenant_id INT NOT NULL,
+	aggregate_type VARCHAR(32) NOT NULL,
+	aggregate_id VARCHAR(64) NOT NULL,
+	event_id VARCHAR(64) NOT NULL UNIQUE,
+	event_type VARCHAR(64) NOT NULL,
+	payload TEXT NOT NULL,
+	status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'publishing', 'published')),
+	attempts INT NOT NULL DEFAULT 0,
+	last_error TEXT NOT NULL DEFAULT '',
+	next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+	published_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_outbox_events_due ON outbox_events (next_attempt_at) WHERE status IN ('pending', 'publishing');
+CREATE INDEX idx_outbox_events_aggregate ON outbox_events (aggregate_type, aggregate_id, id) WHERE status <> 'published';
+CREATE INDEX idx_outbox_events_published_at ON outbox_events (published_at) WHERE status = 'published';
+
+-- The relay publishes at least once, a repeated event must not be delivered to an endpoint twice
+CREATE UNIQUE INDEX idx_webhook_deliveries_event ON webhook_deliveries (endpoint_id, event_id);
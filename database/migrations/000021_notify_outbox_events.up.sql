@@ -0,0 +1,14 @@
+-- Wakes the API instances streaming the tenant's changes. Notifications are sent on commit and
+-- carry only the tenant, listeners read the events themselves.
+CREATE FUNCTION notify_outbox_event() RETURNS TRIGGER AS $$
+BEGIN
+	PERFORM pg_notify('outbox_events', NEW.tenant_id::text);
+	RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER outbox_events_notify
+AFTER INSERT ON outbox_events
+FOR EACH ROW EXECUTE FUNCTION notify_outbox_event();
+
+CREATE INDEX idx_outbox_events_tenant ON outbox_events (tenant_id, id);
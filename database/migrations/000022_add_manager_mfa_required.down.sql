@@ -0,0 +1,2 @@
+ALTER TABLE managers
+	DROP COLUMN mfa_required;
@@ -0,0 +1,3 @@
+-- Each manager decides whether their own logins need a second factor
+ALTER TABLE managers
+	ADD COLUMN mfa_required BOOLEAN NOT NULL DEFAULT FALSE;
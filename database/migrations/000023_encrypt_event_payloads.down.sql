@@ -0,0 +1,25 @@
+-- Sealed payloads would be lost, refuse until they are decrypted
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM outbox_events WHERE key_id IS NOT NULL)
+		OR EXISTS (SELECT 1 FROM webhook_deliveries WHERE key_id IS NOT NULL) THEN
+		RAISE EXCEPTION 'event payloads are still encrypted, run `go run ./cmd/rekey -decrypt` first';
+	END IF;
+END $$;
+
+DROP INDEX idx_webhook_deliveries_event_id;
+DROP INDEX idx_outbox_events_aggregate_all;
+
+ALTER TABLE webhook_deliveries
+	DROP CONSTRAINT webhook_deliveries_encrypted_or_plain,
+	DROP COLUMN payload_enc,
+	DROP COLUMN data_key,
+	DROP COLUMN key_id,
+	ALTER COLUMN payload SET NOT NULL;
+
+ALTER TABLE outbox_events
+	DROP CONSTRAINT outbox_events_encrypted_or_plain,
+	DROP COLUMN payload_enc,
+	DROP COLUMN data_key,
+	DROP COLUMN key_id,
+	ALTER COLUMN payload SET NOT NULL;
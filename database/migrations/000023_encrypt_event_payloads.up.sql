@@ -0,0 +1,28 @@
+-- Event payloads carry the employee data of employee events. They are sealed like the employees'
+-- fields; rows written before stay plaintext, key_id IS NULL, until `go run ./cmd/rekey` seals them
+ALTER TABLE outbox_events
+	ADD COLUMN payload_enc BYTEA,
+	ADD COLUMN data_key BYTEA,
+	ADD COLUMN key_id VARCHAR(32),
+	ALTER COLUMN payload DROP NOT NULL,
+	ADD CONSTRAINT outbox_events_encrypted_or_plain CHECK (
+		(key_id IS NULL AND payload IS NOT NULL)
+		OR (key_id IS NOT NULL AND payload IS NULL AND payload_enc IS NOT NULL AND data_key IS NOT NULL)
+	);
+
+ALTER TABLE webhook_deliveries
+	ADD COLUMN payload_enc BYTEA,
+	ADD COLUMN data_key BYTEA,
+	ADD COLUMN key_id VARCHAR(32),
+	ALTER COLUMN payload DROP NOT NULL,
+	ADD CONSTRAINT webhook_deliveries_encrypted_or_plain CHECK (
+		(key_id IS NULL AND payload IS NOT NULL)
+		OR (key_id IS NOT NULL AND payload IS NULL AND payload_enc IS NOT NULL AND data_key IS NOT NULL)
+	);
+
+CREATE INDEX idx_outbox_events_key_id ON outbox_events (key_id);
+CREATE INDEX idx_webhook_deliveries_key_id ON webhook_deliveries (key_id);
+
+-- Erasure finds an employee's events and their deliveries by aggregate and event id
+CREATE INDEX idx_outbox_events_aggregate_all ON outbox_events (aggregate_type, aggregate_id);
+CREATE INDEX idx_webhook_deliveries_event_id ON webhook_deliveries (event_id);
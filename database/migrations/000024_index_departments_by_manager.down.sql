@@ -0,0 +1 @@
+DROP INDEX idx_departments_manager_name;
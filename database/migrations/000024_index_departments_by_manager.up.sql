@@ -0,0 +1,2 @@
+-- Departments are listed per manager, and looked up by name per manager by SCIM
+CREATE INDEX idx_departments_manager_name ON departments (manager_id, LOWER(name));
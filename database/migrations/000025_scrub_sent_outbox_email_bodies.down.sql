@@ -0,0 +1,2 @@
+-- Cleared bodies can't be restored, there is nothing to undo
+SELECT 1;
@@ -0,0 +1,3 @@
+-- Bodies carry the raw token of their link, they are cleared once the email is sent or given up on.
+-- Rows finished before are cleared here.
+UPDATE outbox_emails SET body = '' WHERE status IN ('sent', 'failed');
@@ -0,0 +1,7 @@
+// Package migrations embeds the SQL migration files so the binary can apply and verify its own schema.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
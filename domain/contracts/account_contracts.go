@@ -0,0 +1,25 @@
+package contracts
+
+import (
+	"context"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+)
+
+type AccountRepository interface {
+	GetManagerByID(ctx context.Context, id int) (*entity.Manager, error)
+	IssueToken(ctx context.Context, token entity.AuthToken, email entity.OutboxEmail) error
+	// IssueTokenByEmail is IssueToken for the manager registered with the email recipient. It runs
+	// the same statements whether there is one or not, and returns sql.ErrNoRows when there isn't.
+	IssueTokenByEmail(ctx context.Context, token entity.AuthToken, email entity.OutboxEmail) error
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (int, error)
+	VerifyEmail(ctx context.Context, tokenHash string) (int, error)
+}
+
+type AccountService interface {
+	ForgotPassword(ctx context.Context, req dto.ForgotPasswordRequest) error
+	ResetPassword(ctx context.Context, req dto.ResetPasswordRequest) error
+	RequestVerification(ctx context.Context, managerID int) error
+	VerifyEmail(ctx context.Context, req dto.VerifyEmailRequest) error
+}
@@ -0,0 +1,25 @@
+package contracts
+
+import (
+	"context"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+)
+
+type APIKeyRepository interface {
+	Create(ctx context.Context, key entity.APIKey) (entity.APIKey, error)
+	ListByManager(ctx context.Context, managerID int) ([]entity.APIKey, error)
+	Revoke(ctx context.Context, managerID, id int) (bool, error)
+	// GetActiveByPrefix returns the unrevoked, unexpired key with the prefix and its manager's email
+	GetActiveByPrefix(ctx context.Context, prefix string) (*entity.APIKey, error)
+	TouchLastUsed(ctx context.Context, id int) error
+}
+
+type APIKeyService interface {
+	Create(ctx context.Context, managerID int, req dto.CreateAPIKeyRequest) (dto.CreateAPIKeyRes, error)
+	List(ctx context.Context, managerID int) ([]dto.APIKeyRes, error)
+	Revoke(ctx context.Context, managerID, id int) error
+	// Authenticate resolves a raw key sent by a client; every failure is ErrInvalidAPIKey
+	Authenticate(ctx context.Context, rawKey string) (*entity.APIKey, error)
+}
@@ -0,0 +1,25 @@
+package contracts
+
+import (
+	"context"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+)
+
+type AuditRepository interface {
+	// Append links the event to the newest one and stores it, seal computes its hash once PrevHash is set.
+	// Appends are serialized so the chain never forks.
+	Append(ctx context.Context, event entity.AuditEvent, seal func(entity.AuditEvent) string) error
+	Find(ctx context.Context, filter dto.AuditFilter) ([]entity.AuditEvent, error)
+	// ListLinks returns the tenant's events with an id above afterID in id order, for verification
+	ListLinks(ctx context.Context, tenantID int, afterID int64, limit int) ([]entity.AuditLink, error)
+}
+
+type AuditService interface {
+	// Record appends a mutation to the audit log. Called inside dbtx WithinTx it writes through
+	// the transaction, so the mutation and its event commit or roll back together.
+	Record(ctx context.Context, record dto.AuditRecord) error
+	List(ctx context.Context, tenantID int, query dto.AuditQuery) ([]dto.AuditEventRes, error)
+	Verify(ctx context.Context, tenantID int, query dto.AuditVerifyQuery) (dto.AuditVerifyRes, error)
+}
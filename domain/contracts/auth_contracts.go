@@ -11,6 +11,7 @@ import (
 type AuthRepository interface {
 	GetUserByEmail(ctx context.Context, email string) (*entity.User, error)
 	RegisterUser(ctx context.Context, user entity.User) (uuid.UUID, error)
+	RehashPassword(ctx context.Context, id uuid.UUID, oldHash, newHash string) error
 }
 
 type AuthService interface {
@@ -11,16 +11,36 @@ type DepartmentRepository interface {
 	Create(ctx context.Context, data entity.Department) (int, error)
 	FindByName(ctx context.Context, name string, limit, offset int) ([]*entity.Department, error)
 	FindByID(ctx context.Context, id int) (*entity.Department, error)
+	// FindByIDs returns the departments among ids that exist, in no particular order
+	FindByIDs(ctx context.Context, ids []int) ([]*entity.Department, error)
 	FindAll(ctx context.Context) ([]*entity.Department, error)
 	FindAllWithLimitOffset(ctx context.Context, limit, offset int) ([]*entity.Department, error)
-	Update(ctx context.Context, id int, newName string) (int, error)
-	Delete(ctx context.Context, id int) error
+	// FindByManager pages the manager's departments by id, CountByManager counts them
+	FindByManager(ctx context.Context, managerID, limit, offset int) ([]*entity.Department, error)
+	CountByManager(ctx context.Context, managerID int) (int, error)
+	// SearchByManager pages the manager's departments whose name contains name, like FindByName
+	SearchByManager(ctx context.Context, managerID int, name string, limit, offset int) ([]*entity.Department, error)
+	// FindByManagerAndName pages the manager's departments named name, whatever the case, by id.
+	// CountByManagerAndName counts them.
+	FindByManagerAndName(ctx context.Context, managerID int, name string, limit, offset int) ([]*entity.Department, error)
+	CountByManagerAndName(ctx context.Context, managerID int, name string) (int, error)
+	// Update and Delete only apply to the row at version, sql.ErrNoRows otherwise. Update returns the new version.
+	Update(ctx context.Context, id, version int, newName string) (int, error)
+	Delete(ctx context.Context, id, version int) error
 }
 
 type DepartmentService interface {
 	Create(ctx context.Context, managerId int, name string) (*dto.DepartmentRes, error)
-	Update(ctx context.Context, id int, name string) (*dto.DepartmentRes, error)
+	// Update and Delete take the If-Match header of the request, empty when it had none. Like
+	// FindOwned they don't find the departments of other managers.
+	Update(ctx context.Context, managerID, id int, name, ifMatch string) (*dto.DepartmentRes, error)
 	FindAll(ctx context.Context, limit, offset int) ([]*dto.DepartmentRes, error)
 	FindByName(ctx context.Context, limit, offset int, name string) ([]*dto.DepartmentRes, error)
-	Delete(ctx context.Context, id int) error
+	// FindByIDs returns the manager's departments among ids, those of other managers are left out
+	FindByIDs(ctx context.Context, managerID int, ids []int) ([]*dto.DepartmentRes, error)
+	// FindByManager pages the manager's departments, those whose name contains name unless it is empty
+	FindByManager(ctx context.Context, managerID int, name string, limit, offset int) ([]*dto.DepartmentRes, error)
+	// FindOwned looks one of the manager's departments up, the departments of other managers are not found
+	FindOwned(ctx context.Context, managerID, id int) (*dto.DepartmentRes, error)
+	Delete(ctx context.Context, managerID, id int, ifMatch string) error
 }
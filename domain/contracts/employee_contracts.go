@@ -8,16 +8,43 @@ import (
 )
 
 type EmployeeRepository interface {
-	Create(ctx context.Context, data entity.Employee) error
-	Find(ctx context.Context, identityNumber, name, gender string, departmentID, limit, offset int) ([]*entity.Employee, error)
+	Create(ctx context.Context, data entity.Employee) (int, error)
+	// Find pages the employees matching the filters that aren't empty, managerID keeps those of the
+	// manager's departments
+	Find(ctx context.Context, managerID int, identityNumber, name, gender string, departmentID, limit, offset int) ([]*entity.Employee, error)
 	FindByIdentityNumber(ctx context.Context, identityNumber string) (*entity.Employee, error)
-	Update(ctx context.Context, data entity.Employee) error
-	Delete(ctx context.Context, id string) error
+	// FindByDepartmentIDs pages the employees of every department of the manager on its own: limit
+	// and offset apply per department, the result is ordered by department
+	FindByDepartmentIDs(ctx context.Context, managerID int, departmentIDs []int, limit, offset int) ([]*entity.Employee, error)
+	// FindByManager pages the employees of every department of the manager, CountByManager counts them
+	FindByManager(ctx context.Context, managerID, limit, offset int) ([]*entity.Employee, error)
+	CountByManager(ctx context.Context, managerID int) (int, error)
+	// Update and Delete only apply to the row at data.Version / version, sql.ErrNoRows otherwise
+	Update(ctx context.Context, data entity.Employee) (int, error)
+	Delete(ctx context.Context, id, version int) error
+	// Erase anonymises the employee in place, returning sql.ErrNoRows when there is none left to erase
+	Erase(ctx context.Context, id int) error
+	// Reencrypt seals up to limit rows that are plaintext or sealed with a retired key, returning how many it did
+	Reencrypt(ctx context.Context, limit int) (int, error)
+	// Decrypt writes up to limit sealed rows back in plaintext, returning how many it did
+	Decrypt(ctx context.Context, limit int) (int, error)
 }
 
 type EmployeeService interface {
 	Create(ctx context.Context, data dto.EmployeeCreateReq) (*dto.EmployeeDataRes, error)
-	Update(ctx context.Context, data dto.EmployeeUpdateReq, identityNumber string) (*dto.EmployeeDataRes, error)
-	Find(ctx context.Context, identityNumber, name, gender string, departmentID, limit, offset int) ([]*dto.EmployeeDataRes, error)
-	Delete(ctx context.Context, identityNumber string) error
+	// Update and Delete take the If-Match header of the request, empty when it had none. Like
+	// FindByIdentityNumber they don't find the employees of other managers.
+	Update(ctx context.Context, managerID int, data dto.EmployeeUpdateReq, identityNumber, ifMatch string) (*dto.EmployeeDataRes, error)
+	// Find pages the employees matching the filters that aren't empty, managerID keeps those of the
+	// manager's departments
+	Find(ctx context.Context, managerID int, identityNumber, name, gender string, departmentID, limit, offset int) ([]*dto.EmployeeDataRes, error)
+	// FindByIdentityNumber fails with ErrEmployeeNotFound when the employee isn't in a department of
+	// the manager, a managerID of 0 looks across all managers
+	FindByIdentityNumber(ctx context.Context, managerID int, identityNumber string) (*dto.EmployeeDataRes, error)
+	// FindByDepartmentIDs returns a page of employees of each department of the manager, limit and
+	// offset apply per department. Departments of other managers have none.
+	FindByDepartmentIDs(ctx context.Context, managerID int, departmentIDs []int, limit, offset int) ([]*dto.EmployeeDataRes, error)
+	// FindByManager returns a page of the employees of the manager's departments and how many they are in all
+	FindByManager(ctx context.Context, managerID, limit, offset int) ([]*dto.EmployeeDataRes, int, error)
+	Delete(ctx context.Context, managerID int, identityNumber, ifMatch string) error
 }
@@ -0,0 +1,13 @@
+package contracts
+
+import (
+	"context"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+)
+
+// GraphQLService executes GraphQL operations as the principal in ctx. Errors of single fields
+// are reported in the response next to the data that did resolve.
+type GraphQLService interface {
+	Execute(ctx context.Context, req dto.GraphQLRequest) *dto.GraphQLResponse
+}
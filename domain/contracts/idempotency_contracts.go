@@ -0,0 +1,28 @@
+package contracts
+
+import (
+	"context"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+)
+
+type IdempotencyRepository interface {
+	// Reserve claims the key for a new request and returns nil, or returns the row already holding it.
+	// Expired rows and reservations locked before staleBefore are taken over.
+	Reserve(ctx context.Context, key entity.IdempotencyKey, staleBefore time.Time) (*entity.IdempotencyKey, error)
+	Complete(ctx context.Context, tenantID int, key string, statusCode int, contentType string, body []byte) error
+	// Release drops a reservation whose request produced no response worth replaying
+	Release(ctx context.Context, tenantID int, key string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+}
+
+type IdempotencyService interface {
+	// Begin reserves the key for the request, or returns the response stored for it when the
+	// request is a replay. The fingerprint identifies the request the key was first used with.
+	Begin(ctx context.Context, tenantID int, key, fingerprint string) (*dto.IdempotentResponse, error)
+	// Complete stores the response of a request Begin reserved. Server errors are not stored,
+	// the reservation is released so the client can retry with the same key.
+	Complete(ctx context.Context, tenantID int, key string, res dto.IdempotentResponse)
+}
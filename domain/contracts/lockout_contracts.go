@@ -0,0 +1,22 @@
+package contracts
+
+import (
+	"context"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+)
+
+type LoginAttemptRepository interface {
+	RecordFailure(ctx context.Context, scope, subject string, window time.Duration) (int, error)
+	Lock(ctx context.Context, scope, subject string, duration time.Duration) error
+	LockedFor(ctx context.Context, scope, subject string) (time.Duration, error)
+	Reset(ctx context.Context, scope, subject string) error
+}
+
+type LockoutService interface {
+	Check(ctx context.Context, account string) error
+	Fail(ctx context.Context, account string) error
+	Succeed(ctx context.Context, account string) error
+	Unlock(ctx context.Context, req dto.UnlockAccountRequest) error
+}
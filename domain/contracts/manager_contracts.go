@@ -14,5 +14,5 @@ type ManagerRepository interface {
 
 type ManagerService interface {
 	GetManagerById(ctx context.Context, id int) (*dto.GetCurrentManagerResponse, error)
-	UpdateManagerById(ctx context.Context, id int, req dto.UpdateManagerRequest) (*dto.UpdateManagerResponse, error)
+	UpdateManagerById(ctx context.Context, id int, req dto.UpdateManagerRequest, ifMatch string) (*dto.UpdateManagerResponse, error)
 }
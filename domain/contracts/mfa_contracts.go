@@ -0,0 +1,36 @@
+package contracts
+
+import (
+	"context"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+)
+
+type MFARepository interface {
+	GetFactor(ctx context.Context, kind, subjectID string) (*entity.MFAFactor, error)
+	// SaveFactor starts or restarts an enrollment; it never overwrites a confirmed factor
+	SaveFactor(ctx context.Context, factor entity.MFAFactor) error
+	ConfirmFactor(ctx context.Context, kind, subjectID string, codeHashes []string) error
+	DeleteFactor(ctx context.Context, kind, subjectID string) error
+	// UseStep records the time step of an accepted code and reports false if it was used already
+	UseStep(ctx context.Context, kind, subjectID string, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, kind, subjectID, codeHash string) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, kind, subjectID string, codeHashes []string) error
+	// GetManagerRequired reports whether the manager made 2FA mandatory for their logins
+	GetManagerRequired(ctx context.Context, managerID int) (bool, error)
+	SetManagerRequired(ctx context.Context, managerID int, required bool) error
+}
+
+type MFAService interface {
+	// Challenge returns nil when the account can log in with its password alone
+	Challenge(ctx context.Context, subject dto.MFASubject) (*dto.MFAChallengeResponse, error)
+	Verify(ctx context.Context, subject dto.MFASubject, req dto.MFACodeRequest) (dto.MFATokenResponse, error)
+	Enroll(ctx context.Context, subject dto.MFASubject) (dto.MFAEnrollResponse, error)
+	Confirm(ctx context.Context, subject dto.MFASubject, req dto.MFACodeRequest) (dto.MFARecoveryCodesResponse, error)
+	Disable(ctx context.Context, subject dto.MFASubject, req dto.MFACodeRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, subject dto.MFASubject, req dto.MFACodeRequest) (dto.MFARecoveryCodesResponse, error)
+	// GetPolicy and SetPolicy read and change whether a manager's logins need 2FA
+	GetPolicy(ctx context.Context, subject dto.MFASubject) (dto.MFAPolicy, error)
+	SetPolicy(ctx context.Context, subject dto.MFASubject, req dto.MFAPolicyRequest) (dto.MFAPolicy, error)
+}
@@ -0,0 +1,60 @@
+package contracts
+
+import (
+	"context"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+)
+
+type OutboxEmailRepository interface {
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxEmail, error)
+	MarkSent(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, lastError string, retryIn time.Duration, giveUp bool) error
+}
+
+type OutboxService interface {
+	Dispatch(ctx context.Context) (int, error)
+	Run(ctx context.Context)
+}
+
+type OutboxEventRepository interface {
+	// Append writes the event with the transaction ctx carries, if any
+	Append(ctx context.Context, event entity.OutboxEvent) error
+	// Claim leases due events that are the oldest unpublished one of their aggregate
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id int64) error
+	MarkFailed(ctx context.Context, id int64, lastError string, retryIn time.Duration) error
+	// Prune deletes events published before the given time and returns how many
+	Prune(ctx context.Context, before time.Time) (int64, error)
+	// ListAfter returns the tenant's events with an id above afterID in id order, published or not
+	ListAfter(ctx context.Context, tenantID int, afterID int64, limit int) ([]entity.OutboxEvent, error)
+	LastID(ctx context.Context, tenantID int) (int64, error)
+	// Scrub strips the data from every event of the aggregate, with the transaction ctx carries if any,
+	// returning their event ids
+	Scrub(ctx context.Context, aggregateType, aggregateID string) ([]string, error)
+	// Reencrypt seals up to limit payloads that are plaintext or sealed with a retired key, returning how many it did
+	Reencrypt(ctx context.Context, limit int) (int, error)
+	// Decrypt writes up to limit sealed payloads back in plaintext, returning how many it did
+	Decrypt(ctx context.Context, limit int) (int, error)
+}
+
+type OutboxEventService interface {
+	// Record writes an event about one resource for the tenant owning it, whoever made the change.
+	// Called inside dbtx.WithinTx it is committed or rolled back together with the change it describes.
+	Record(ctx context.Context, tenantID int, aggregateID string, event enums.WebhookEventEnum, data, previous any) error
+}
+
+// OutboxSink is somewhere relayed events are published to
+type OutboxSink interface {
+	Name() string
+	// Publish delivers the event. A failed event is published again later, to every sink,
+	// so sinks and their consumers see an event at least once and dedupe on its id.
+	Publish(ctx context.Context, event entity.OutboxEvent) error
+}
+
+type OutboxRelay interface {
+	Relay(ctx context.Context) (int, error)
+	Run(ctx context.Context)
+}
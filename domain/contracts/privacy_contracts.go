@@ -0,0 +1,14 @@
+package contracts
+
+import (
+	"context"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+)
+
+// PrivacyService answers an employee's requests for a copy or the erasure of their data.
+// Both only reach employees in the manager's own departments.
+type PrivacyService interface {
+	Export(ctx context.Context, managerID int, identityNumber string) (*dto.PrivacyExport, error)
+	Erase(ctx context.Context, managerID int, identityNumber string) error
+}
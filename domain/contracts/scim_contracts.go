@@ -0,0 +1,29 @@
+package contracts
+
+import (
+	"context"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+)
+
+// SCIMService provisions employees as SCIM users and departments as SCIM groups. Resources
+// are returned without meta.location, which depends on the URL they were requested at.
+// Changes take the If-Match header of the request, empty when it had none.
+type SCIMService interface {
+	ServiceProviderConfig() dto.SCIMServiceProviderConfig
+
+	CreateUser(ctx context.Context, user dto.SCIMUser) (*dto.SCIMUser, error)
+	GetUser(ctx context.Context, id string) (*dto.SCIMUser, error)
+	ListUsers(ctx context.Context, query dto.SCIMQuery) (*dto.SCIMUserList, error)
+	ReplaceUser(ctx context.Context, id string, user dto.SCIMUser, ifMatch string) (*dto.SCIMUser, error)
+	PatchUser(ctx context.Context, id string, req dto.SCIMPatchRequest, ifMatch string) (*dto.SCIMUser, error)
+	DeleteUser(ctx context.Context, id, ifMatch string) error
+
+	CreateGroup(ctx context.Context, managerID int, group dto.SCIMGroup) (*dto.SCIMGroup, error)
+	// GetGroup and ListGroups leave the members out when excludedAttributes names them
+	GetGroup(ctx context.Context, id, excludedAttributes string) (*dto.SCIMGroup, error)
+	ListGroups(ctx context.Context, query dto.SCIMQuery) (*dto.SCIMGroupList, error)
+	ReplaceGroup(ctx context.Context, id string, group dto.SCIMGroup, ifMatch string) (*dto.SCIMGroup, error)
+	PatchGroup(ctx context.Context, id string, req dto.SCIMPatchRequest, ifMatch string) (*dto.SCIMGroup, error)
+	DeleteGroup(ctx context.Context, id, ifMatch string) error
+}
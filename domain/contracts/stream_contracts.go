@@ -0,0 +1,20 @@
+package contracts
+
+import (
+	"context"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+)
+
+type StreamService interface {
+	// Cursor is where a stream starts: after lastEventID when the client resumes, after the
+	// tenant's latest event when it is empty
+	Cursor(ctx context.Context, tenantID int, lastEventID string) (int64, error)
+	// Next returns the tenant's events after the cursor, in order
+	Next(ctx context.Context, tenantID int, after int64) ([]dto.StreamEvent, error)
+	// Subscribe returns a channel signalled whenever the tenant may have new events, and the
+	// function that unsubscribes it
+	Subscribe(tenantID int) (<-chan struct{}, func())
+	// Listen signals subscribers on the notifications of every instance until ctx is cancelled
+	Listen(ctx context.Context)
+}
@@ -0,0 +1,64 @@
+package contracts
+
+import (
+	"context"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, endpoint entity.WebhookEndpoint) (entity.WebhookEndpoint, error)
+	ListByTenant(ctx context.Context, tenantID int) ([]entity.WebhookEndpoint, error)
+	FindByID(ctx context.Context, tenantID, id int) (*entity.WebhookEndpoint, error)
+	// Update writes the url, description, events, secret and active flag of the tenant's endpoint
+	Update(ctx context.Context, endpoint entity.WebhookEndpoint) (entity.WebhookEndpoint, error)
+	Delete(ctx context.Context, tenantID, id int) (bool, error)
+
+	// Enqueue queues the event for every active endpoint of the tenant subscribed to it, returning how many.
+	// Endpoints that already have the event are skipped, so it can be enqueued again safely.
+	Enqueue(ctx context.Context, tenantID int, event entity.WebhookDelivery) (int, error)
+	// EnqueueTo queues the event for a single endpoint whatever it subscribed to
+	EnqueueTo(ctx context.Context, endpointID int, event entity.WebhookDelivery) (entity.WebhookDelivery, error)
+	// Claim leases due deliveries of active endpoints, loading their url and secret
+	Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.WebhookDelivery, error)
+	MarkDelivered(ctx context.Context, attempt entity.WebhookAttempt) error
+	MarkFailed(ctx context.Context, attempt entity.WebhookAttempt, retryIn time.Duration, giveUp bool) error
+
+	ListDeliveries(ctx context.Context, endpointID int, query dto.WebhookDeliveryQuery) ([]entity.WebhookDelivery, error)
+	FindDelivery(ctx context.Context, endpointID int, id int64) (*entity.WebhookDelivery, error)
+	ListAttempts(ctx context.Context, deliveryID int64) ([]entity.WebhookAttempt, error)
+	// Redeliver queues the delivery again with a fresh set of attempts, false when it is being sent
+	Redeliver(ctx context.Context, endpointID int, id int64) (bool, error)
+	// RedeliverDead queues every dead delivery of the endpoint again, returning how many
+	RedeliverDead(ctx context.Context, endpointID int) (int, error)
+	// ScrubEvents strips the data from every delivery of the events, with the transaction ctx carries
+	// if any, returning how many
+	ScrubEvents(ctx context.Context, eventIDs []string) (int, error)
+	// Reencrypt seals up to limit payloads that are plaintext or sealed with a retired key, returning how many it did
+	Reencrypt(ctx context.Context, limit int) (int, error)
+	// Decrypt writes up to limit sealed payloads back in plaintext, returning how many it did
+	Decrypt(ctx context.Context, limit int) (int, error)
+}
+
+type WebhookService interface {
+	Create(ctx context.Context, managerID int, req dto.CreateWebhookRequest) (dto.WebhookSecretRes, error)
+	List(ctx context.Context, managerID int) ([]dto.WebhookRes, error)
+	Get(ctx context.Context, managerID, id int) (dto.WebhookRes, error)
+	Update(ctx context.Context, managerID, id int, req dto.UpdateWebhookRequest) (dto.WebhookRes, error)
+	RotateSecret(ctx context.Context, managerID, id int) (dto.WebhookSecretRes, error)
+	Delete(ctx context.Context, managerID, id int) error
+	// Ping queues a webhook.ping event for the endpoint
+	Ping(ctx context.Context, managerID, id int) (dto.WebhookDeliveryRes, error)
+
+	ListDeliveries(ctx context.Context, managerID, id int, query dto.WebhookDeliveryQuery) ([]dto.WebhookDeliveryRes, error)
+	GetDelivery(ctx context.Context, managerID, id int, deliveryID int64) (dto.WebhookDeliveryDetailRes, error)
+	Redeliver(ctx context.Context, managerID, id int, deliveryID int64) error
+	RedeliverDead(ctx context.Context, managerID, id int) (dto.WebhookRedeliverRes, error)
+}
+
+type WebhookDispatcher interface {
+	Dispatch(ctx context.Context) (int, error)
+	Run(ctx context.Context)
+}
@@ -0,0 +1,27 @@
+package dto
+
+import "time"
+
+type CreateAPIKeyRequest struct {
+	Name   string   `json:"name" validate:"required,min=1,max=64"`
+	Scopes []string `json:"scopes" validate:"required,min=1,unique,dive,oneof=read department:write employee:write file:write audit:read scim"`
+	// ExpiresInDays leaves the key valid forever when omitted
+	ExpiresInDays int `json:"expiresInDays" validate:"omitempty,min=1,max=365"`
+}
+
+type APIKeyRes struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	RevokedAt  *time.Time `json:"revokedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type CreateAPIKeyRes struct {
+	APIKeyRes
+	// Key is only returned once, at creation
+	Key string `json:"key"`
+}
@@ -0,0 +1,86 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+)
+
+const (
+	AuditEntityEmployee   = "employee"
+	AuditEntityDepartment = "department"
+	AuditEntityManager    = "manager"
+	AuditEntityUser       = "user"
+)
+
+// AuditRecord describes a mutation for the audit log. Before and After are the entity as it
+// was and as it is; only the fields that changed are kept and passwords are never stored.
+type AuditRecord struct {
+	Action     enums.AuditActionEnum
+	EntityType string
+	EntityID   string
+	Before     any
+	After      any
+	// Actor replaces the authenticated principal, for mutations made before there is one,
+	// like a manager registering
+	Actor *reqctx.Principal
+}
+
+type AuditQuery struct {
+	ActorID    string `query:"actorId" validate:"omitempty,max=64"`
+	Action     string `query:"action" validate:"omitempty,oneof=create update delete soft_delete restore erase"`
+	EntityType string `query:"entityType" validate:"omitempty,oneof=employee department manager user"`
+	EntityID   string `query:"entityId" validate:"omitempty,max=64"`
+	From       string `query:"from" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	To         string `query:"to" validate:"omitempty,datetime=2006-01-02T15:04:05Z07:00"`
+	Limit      int    `query:"limit" validate:"min=1,max=100"`
+	Offset     int    `query:"offset" validate:"min=0"`
+}
+
+// AuditFilter is an AuditQuery checked and scoped to the tenant asking
+type AuditFilter struct {
+	TenantID   int
+	ActorID    string
+	Action     string
+	EntityType string
+	EntityID   string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+type AuditEventRes struct {
+	ID         int64          `json:"id"`
+	ActorKind  string         `json:"actorKind"`
+	ActorID    string         `json:"actorId"`
+	APIKeyID   *int           `json:"apiKeyId"`
+	Action     string         `json:"action"`
+	EntityType string         `json:"entityType"`
+	EntityID   string         `json:"entityId"`
+	Before     map[string]any `json:"before"`
+	After      map[string]any `json:"after"`
+	IP         string         `json:"ip"`
+	RequestID  string         `json:"requestId"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	Hash       string         `json:"hash"`
+}
+
+// AuditVerifyQuery pages through the tenant's events, afterId being the next of the previous page
+type AuditVerifyQuery struct {
+	AfterID int64 `query:"afterId" validate:"min=0"`
+	Limit   int   `query:"limit" validate:"min=1,max=1000"`
+}
+
+type AuditVerifyRes struct {
+	Valid   bool `json:"valid"`
+	Checked int  `json:"checked"`
+	// BrokenAt is the first event whose hash or link to its predecessor doesn't match
+	BrokenAt *int64 `json:"brokenAt"`
+	// Head is the hash of the last event checked; keeping a copy of the newest one elsewhere
+	// also catches removals at the end
+	Head string `json:"head"`
+	// Next is the afterId of the following page, null once the newest event was checked
+	Next *int64 `json:"next"`
+}
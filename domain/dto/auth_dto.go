@@ -18,5 +18,7 @@ type LoginRequest struct {
 }
 
 type LoginResponse struct {
-	AccessToken string `json:"access_token"`
+	AccessToken string `json:"access_token,omitempty"`
+	// MFA replaces AccessToken when the login still needs a second factor
+	MFA *MFAChallengeResponse `json:"mfa,omitempty"`
 }
@@ -1,6 +1,17 @@
 package dto
 
 type DepartmentRes struct {
-	ID   string `json:"departmentId"`
-	Name string `json:"name"`
+	ID      string `json:"departmentId"`
+	Name    string `json:"name"`
+	Version int    `json:"version"`
+}
+
+type DepartmentReq struct {
+	Name string `json:"name" validate:"required,min=4,max=33"`
+}
+
+type DepartmentQuery struct {
+	Limit  int    `query:"limit"`
+	Offset int    `query:"offset"`
+	Name   string `query:"name"`
 }
@@ -1,5 +1,7 @@
 package dto
 
+import "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/patch"
+
 type EmployeeCreateReq struct {
 	IdentityNumber   string `json:"identityNumber" validate:"min=5,max=33,required"`
 	Name             string `json:"name" validate:"min=4,max=33,required"`
@@ -14,12 +16,33 @@ type EmployeeDataRes struct {
 	EmployeeImageURI string `json:"employeeImageUri"`
 	Gender           string `json:"gender"`
 	DepartmentID     string `json:"departmentId"`
+	Version          int    `json:"version"`
+}
+
+// EmployeeErasedRes is the data of an employee.erased event, only what consumers need to find their
+// copy. Nothing identifies the person, the employee is known by its aggregate id alone.
+type EmployeeErasedRes struct {
+	EmployeeID   string `json:"employeeId"`
+	DepartmentID string `json:"departmentId"`
 }
 
+// EmployeeUpdateReq is a merge patch (RFC 7396): members left out are kept and only the image
+// can be cleared with null, the others are required whenever present
 type EmployeeUpdateReq struct {
-	IdentityNumber   string `json:"identityNumber,omitempty" validate:"min=5,max=33"`
-	Name             string `json:"name,omitempty" validate:"min=4,max=33"`
-	EmployeeImageURI string `json:"employeeImageUri,omitempty" validate:"url"`
-	Gender           string `json:"gender,omitempty" validate:"oneof=male female"`
-	DepartmentID     string `json:"departmentId" validate:"required"`
+	IdentityNumber   patch.Field[string] `json:"identityNumber" validate:"omitnil,required,min=5,max=33"`
+	Name             patch.Field[string] `json:"name" validate:"omitnil,required,min=4,max=33"`
+	EmployeeImageURI patch.Field[string] `json:"employeeImageUri" validate:"omitempty,url"`
+	Gender           patch.Field[string] `json:"gender" validate:"omitnil,required,oneof=male female"`
+	DepartmentID     patch.Field[string] `json:"departmentId" validate:"omitnil,required,numeric"`
+}
+
+// EmployeeQuery filters employees. Identity numbers and names are stored encrypted, so both
+// only match whole values; names are compared case-insensitively.
+type EmployeeQuery struct {
+	IdentityNumber string `query:"identityNumber"`
+	Name           string `query:"name"`
+	Gender         string `query:"gender"`
+	DepartmentID   int    `query:"departmentId"`
+	Limit          int    `query:"limit"`
+	Offset         int    `query:"offset"`
 }
@@ -0,0 +1,5 @@
+package dto
+
+type FileUploadRes struct {
+	URI string `json:"uri"`
+}
@@ -0,0 +1,28 @@
+package dto
+
+// GraphQLRequest is a GraphQL operation sent over HTTP
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// GraphQLResponse is sent as is, not wrapped in the payload envelope. Data holds whatever
+// resolved, errors the fields that did not.
+type GraphQLResponse struct {
+	Data   interface{}    `json:"data"`
+	Errors []GraphQLError `json:"errors,omitempty"`
+}
+
+type GraphQLError struct {
+	Message    string                 `json:"message"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions *GraphQLErrorExtension `json:"extensions,omitempty"`
+}
+
+// GraphQLErrorExtension carries the status and code the same error gets from the REST API
+type GraphQLErrorExtension struct {
+	Code   string      `json:"code"`
+	Status int         `json:"status"`
+	Errors interface{} `json:"errors,omitempty"`
+}
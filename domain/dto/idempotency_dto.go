@@ -0,0 +1,8 @@
+package dto
+
+// IdempotentResponse is a response as sent, replayed byte for byte
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
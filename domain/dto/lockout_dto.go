@@ -0,0 +1,6 @@
+package dto
+
+type UnlockAccountRequest struct {
+	Kind  string `json:"kind" validate:"required,oneof=manager user"`
+	Email string `json:"email" validate:"required,email"`
+}
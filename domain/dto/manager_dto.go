@@ -1,5 +1,7 @@
 package dto
 
+import "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/patch"
+
 type GetCurrentManagerRequest struct {
 }
 
@@ -9,13 +11,18 @@ type GetCurrentManagerResponse struct {
 	UserImageUri    string `db:"user_image_uri" json:"userImageUri"`
 	CompanyName     string `db:"company_name" json:"companyName"`
 	CompanyImageUri string `db:"company_image_uri" json:"companyImageUri"`
+	EmailVerified   bool   `json:"emailVerified"`
+	Version         int    `json:"version"`
 }
+
+// UpdateManagerRequest is a merge patch (RFC 7396): members left out are kept, null clears
+// everything but the email
 type UpdateManagerRequest struct {
-	Email           *string `json:"email" validate:"email"`
-	Name            *string `json:"name" validate:"min=4,max=52,ascii"`
-	UserImageUri    *string `json:"userImageUri" validate:"url"`
-	CompanyName     *string `json:"companyName" validate:"min=4,max=52,ascii"`
-	CompanyImageUri *string `json:"companyImageUri" validate:"url"`
+	Email           patch.Field[string] `json:"email" validate:"omitnil,required,email"`
+	Name            patch.Field[string] `json:"name" validate:"omitempty,min=4,max=52,ascii"`
+	UserImageUri    patch.Field[string] `json:"userImageUri" validate:"omitempty,url"`
+	CompanyName     patch.Field[string] `json:"companyName" validate:"omitempty,min=4,max=52,ascii"`
+	CompanyImageUri patch.Field[string] `json:"companyImageUri" validate:"omitempty,url"`
 }
 
 type UpdateManagerResponse struct {
@@ -24,4 +31,6 @@ type UpdateManagerResponse struct {
 	UserImageUri    string `json:"userImageUri"`
 	CompanyName     string `json:"companyName"`
 	CompanyImageUri string `json:"companyImageUri"`
+	EmailVerified   bool   `json:"emailVerified"`
+	Version         int    `json:"version"`
 }
@@ -8,7 +8,9 @@ type AuthRequest struct {
 
 type AuthResponse struct {
 	Email string `json:"email"`
-	Token string `json:"token"`
+	Token string `json:"token,omitempty"`
+	// MFA replaces Token when the login still needs a second factor
+	MFA *MFAChallengeResponse `json:"mfa,omitempty"`
 }
 
 type ManagerProfile struct {
@@ -0,0 +1,48 @@
+package dto
+
+// MFASubject identifies the account a two-factor operation applies to. It is built from the
+// access or challenge token and never read from a request body.
+type MFASubject struct {
+	Kind     string
+	ID       string
+	Email    string
+	RoleName string
+	// Challenged is set when the caller holds a login challenge rather than an access token,
+	// completing the challenge then returns an access token
+	Challenged bool
+}
+
+type MFACodeRequest struct {
+	// Code is a 6 digit TOTP code or, where accepted, an unused recovery code
+	Code string `json:"code" validate:"required,min=6,max=32"`
+}
+
+// MFAChallengeResponse is returned by a login whose password was correct but needs a second factor
+type MFAChallengeResponse struct {
+	MFAToken           string `json:"mfaToken"`
+	EnrollmentRequired bool   `json:"enrollmentRequired"`
+}
+
+type MFAEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioningUri"`
+}
+
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recoveryCodes"`
+	// Token is only set when enrollment completed a login challenge
+	Token string `json:"token,omitempty"`
+}
+
+type MFATokenResponse struct {
+	Token string `json:"token"`
+}
+
+type MFAPolicyRequest struct {
+	// Required makes 2FA mandatory for the manager's logins, enrolling at the next one if needed
+	Required *bool `json:"required" validate:"required"`
+}
+
+type MFAPolicy struct {
+	Required bool `json:"required"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "time"
+
+// PrivacyExport is the archive of everything stored about one employee
+type PrivacyExport struct {
+	FileName string
+	Archive  []byte
+}
+
+// EmployeeExport is employee.json in the archive: the row as stored, decrypted
+type EmployeeExport struct {
+	ID               int       `json:"id"`
+	IdentityNumber   string    `json:"identityNumber"`
+	Name             string    `json:"name"`
+	Gender           string    `json:"gender"`
+	DepartmentID     string    `json:"departmentId"`
+	EmployeeImageURI string    `json:"employeeImageUri"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
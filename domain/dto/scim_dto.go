@@ -0,0 +1,150 @@
+package dto
+
+import "encoding/json"
+
+// SCIM 2.0 (RFC 7643, RFC 7644) schema and message URNs
+const (
+	SCIMSchemaUser          = "urn:ietf:params:scim:schemas:core:2.0:User"
+	SCIMSchemaGroup         = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	SCIMSchemaEmployee      = "urn:gogomanager:params:scim:schemas:extension:employee:2.0:User"
+	SCIMSchemaServiceConfig = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	SCIMMessageList         = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	SCIMMessagePatch        = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	SCIMMessageError        = "urn:ietf:params:scim:api:messages:2.0:Error"
+)
+
+// SCIMUser is an employee. Its id and userName are both the identity number, so renaming
+// a user moves it to a new location.
+type SCIMUser struct {
+	Schemas  []string `json:"schemas"`
+	ID       string   `json:"id,omitempty"`
+	UserName string   `json:"userName"`
+	// DisplayName, name.formatted and name.givenName with name.familyName all set the name,
+	// in that order of precedence
+	DisplayName string    `json:"displayName,omitempty"`
+	Name        *SCIMName `json:"name,omitempty"`
+	// Active is always true, employees are deprovisioned by deleting them
+	Active *bool `json:"active,omitempty"`
+	// Photos holds the employee image as the primary photo
+	Photos []SCIMValue `json:"photos,omitempty"`
+	// Groups is the department of the employee, read-only: move employees through the groups
+	Groups   []SCIMValue            `json:"groups,omitempty"`
+	Employee *SCIMEmployeeExtension `json:"urn:gogomanager:params:scim:schemas:extension:employee:2.0:User,omitempty"`
+	Meta     *SCIMMeta              `json:"meta,omitempty"`
+}
+
+type SCIMName struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// SCIMEmployeeExtension holds the employee attributes SCIM has no core attribute for
+type SCIMEmployeeExtension struct {
+	Gender       string `json:"gender,omitempty"`
+	DepartmentID string `json:"departmentId,omitempty"`
+}
+
+// SCIMValue is an element of a multi-valued attribute such as photos, groups or members
+type SCIMValue struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+// SCIMGroup is a department, its members are the employees working in it
+type SCIMGroup struct {
+	Schemas     []string    `json:"schemas"`
+	ID          string      `json:"id,omitempty"`
+	DisplayName string      `json:"displayName"`
+	Members     []SCIMValue `json:"members,omitempty"`
+	Meta        *SCIMMeta   `json:"meta,omitempty"`
+}
+
+type SCIMMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location,omitempty"`
+	Version      string `json:"version,omitempty"`
+}
+
+// SCIMListResponse is the envelope of a page of resources. Nothing is counted, so TotalResults
+// is the index of the last resource returned, plus one while more follow; clients page on
+// until a page comes back short.
+type SCIMListResponse struct {
+	Schemas      []string `json:"schemas"`
+	TotalResults int      `json:"totalResults"`
+	StartIndex   int      `json:"startIndex"`
+	ItemsPerPage int      `json:"itemsPerPage"`
+}
+
+type SCIMUserList struct {
+	SCIMListResponse
+	Resources []SCIMUser `json:"Resources"`
+}
+
+type SCIMGroupList struct {
+	SCIMListResponse
+	Resources []SCIMGroup `json:"Resources"`
+}
+
+// SCIMQuery pages and filters a list. StartIndex is one-based as in SCIM, Count defaults to
+// the largest page.
+type SCIMQuery struct {
+	Filter             string `query:"filter"`
+	StartIndex         int    `query:"startIndex"`
+	Count              *int   `query:"count"`
+	ExcludedAttributes string `query:"excludedAttributes"`
+}
+
+type SCIMPatchRequest struct {
+	Schemas    []string             `json:"schemas"`
+	Operations []SCIMPatchOperation `json:"Operations"`
+}
+
+type SCIMPatchOperation struct {
+	// Op is add, replace or remove, case-insensitively
+	Op    string          `json:"op"`
+	Path  string          `json:"path,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+type SCIMError struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	SCIMType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail"`
+}
+
+type SCIMServiceProviderConfig struct {
+	Schemas               []string                   `json:"schemas"`
+	Patch                 SCIMSupported              `json:"patch"`
+	Bulk                  SCIMBulk                   `json:"bulk"`
+	Filter                SCIMFilter                 `json:"filter"`
+	ChangePassword        SCIMSupported              `json:"changePassword"`
+	Sort                  SCIMSupported              `json:"sort"`
+	ETag                  SCIMSupported              `json:"etag"`
+	AuthenticationSchemes []SCIMAuthenticationScheme `json:"authenticationSchemes"`
+}
+
+type SCIMSupported struct {
+	Supported bool `json:"supported"`
+}
+
+type SCIMBulk struct {
+	Supported      bool `json:"supported"`
+	MaxOperations  int  `json:"maxOperations"`
+	MaxPayloadSize int  `json:"maxPayloadSize"`
+}
+
+type SCIMFilter struct {
+	Supported  bool `json:"supported"`
+	MaxResults int  `json:"maxResults"`
+}
+
+type SCIMAuthenticationScheme struct {
+	Type        string `json:"type"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Primary     bool   `json:"primary"`
+}
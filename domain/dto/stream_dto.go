@@ -0,0 +1,16 @@
+package dto
+
+import "encoding/json"
+
+// StreamEvent is one server-sent event. ID is the cursor a client resumes from with Last-Event-ID,
+// Data is the same envelope webhooks receive.
+type StreamEvent struct {
+	ID   int64           `json:"id"`
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// StreamQuery resumes a stream for clients that cannot send Last-Event-ID, the header wins when both are set
+type StreamQuery struct {
+	LastEventID string `query:"lastEventId"`
+}
@@ -0,0 +1,90 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/patch"
+)
+
+type CreateWebhookRequest struct {
+	URL         string `json:"url" validate:"required,url,max=2048"`
+	Description string `json:"description" validate:"max=255"`
+	// Events subscribes to every event when omitted or empty
+	Events []string `json:"events" validate:"omitempty,unique,dive,oneof=employee.created employee.updated employee.moved employee.deleted employee.erased department.created department.updated department.deleted"`
+}
+
+// UpdateWebhookRequest is a merge patch (RFC 7396); null events subscribe to every event
+// and a null active pauses the endpoint
+type UpdateWebhookRequest struct {
+	URL         patch.Field[string]   `json:"url" validate:"omitnil,required,url,max=2048"`
+	Description patch.Field[string]   `json:"description" validate:"omitempty,max=255"`
+	Events      patch.Field[[]string] `json:"events" validate:"omitempty,unique,dive,oneof=employee.created employee.updated employee.moved employee.deleted employee.erased department.created department.updated department.deleted"`
+	// Active false pauses the endpoint: no new deliveries are queued and queued ones wait
+	Active patch.Field[bool] `json:"active"`
+}
+
+type WebhookRes struct {
+	ID          int       `json:"id"`
+	URL         string    `json:"url"`
+	Description string    `json:"description"`
+	Events      []string  `json:"events"`
+	Active      bool      `json:"active"`
+	CreatedAt   time.Time `json:"createdAt"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+type WebhookSecretRes struct {
+	WebhookRes
+	// Secret signs the deliveries, it is only returned when the webhook is created or the secret rotated
+	Secret string `json:"secret"`
+}
+
+type WebhookDeliveryQuery struct {
+	Status string `query:"status" validate:"omitempty,oneof=pending delivering delivered dead"`
+	Limit  int    `query:"limit" validate:"min=1,max=100"`
+	Offset int    `query:"offset" validate:"min=0"`
+}
+
+type WebhookDeliveryRes struct {
+	ID             int64      `json:"id"`
+	EventID        string     `json:"eventId"`
+	EventType      string     `json:"eventType"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt"`
+	LastStatusCode *int       `json:"lastStatusCode"`
+	LastError      string     `json:"lastError"`
+	DeliveredAt    *time.Time `json:"deliveredAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+type WebhookDeliveryDetailRes struct {
+	WebhookDeliveryRes
+	// Payload is the WebhookEvent exactly as it is sent
+	Payload any `json:"payload"`
+	// History lists every request made for the delivery, oldest first
+	History []WebhookAttemptRes `json:"history"`
+}
+
+type WebhookAttemptRes struct {
+	StatusCode   *int      `json:"statusCode"`
+	Error        string    `json:"error"`
+	ResponseBody string    `json:"responseBody"`
+	DurationMs   int       `json:"durationMs"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type WebhookRedeliverRes struct {
+	Count int `json:"count"`
+}
+
+// WebhookEvent is the body POSTed to webhook endpoints and published to the brokers. Data is
+// the resource as the REST API returns it, after the change or, for deletions, before it;
+// Previous is set on updates.
+type WebhookEvent struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurredAt"`
+	Data       any       `json:"data"`
+	Previous   any       `json:"previous,omitempty"`
+}
@@ -0,0 +1,30 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+type APIKey struct {
+	ID         int        `db:"id"`
+	ManagerID  int        `db:"manager_id"`
+	Name       string     `db:"name"`
+	Prefix     string     `db:"prefix"`
+	KeyHash    string     `db:"key_hash"`
+	Scopes     string     `db:"scopes"` // comma separated
+	ExpiresAt  *time.Time `db:"expires_at"`
+	LastUsedAt *time.Time `db:"last_used_at"`
+	RevokedAt  *time.Time `db:"revoked_at"`
+	CreatedAt  time.Time  `db:"created_at"`
+
+	// ManagerEmail is only loaded when authenticating a request
+	ManagerEmail string `db:"manager_email"`
+}
+
+func (k APIKey) ScopeList() []string {
+	if k.Scopes == "" {
+		return nil
+	}
+
+	return strings.Split(k.Scopes, ",")
+}
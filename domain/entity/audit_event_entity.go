@@ -0,0 +1,34 @@
+package entity
+
+import "time"
+
+// AuditEvent is one entry of the append-only audit log. Every entry carries the hash of the
+// previous one, so removing or editing a row breaks the chain from that point on.
+type AuditEvent struct {
+	ID         int64  `db:"id"`
+	TenantID   *int   `db:"tenant_id"`
+	ActorKind  string `db:"actor_kind"`
+	ActorID    string `db:"actor_id"`
+	APIKeyID   *int   `db:"api_key_id"`
+	Action     string `db:"action"`
+	EntityType string `db:"entity_type"`
+	EntityID   string `db:"entity_id"`
+	// Before and After hold the changed fields as JSON objects, nil on create and delete respectively
+	Before    []byte    `db:"before"`
+	After     []byte    `db:"after"`
+	IP        string    `db:"ip"`
+	RequestID string    `db:"request_id"`
+	CreatedAt time.Time `db:"created_at"`
+	PrevHash  string    `db:"prev_hash"`
+	Hash      string    `db:"hash"`
+}
+
+// AuditLink is an event with the hash of the event before it in the chain, whichever tenant's it
+// is, nil when it is the first
+type AuditLink struct {
+	AuditEvent
+	PredecessorHash *string `db:"predecessor_hash"`
+}
+
+// AuditGenesisHash is the predecessor of the first event
+const AuditGenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
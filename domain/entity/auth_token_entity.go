@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+type AuthToken struct {
+	ID        int        `db:"id"`
+	ManagerID int        `db:"manager_id"`
+	Purpose   string     `db:"purpose"`
+	TokenHash string     `db:"token_hash"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
@@ -6,5 +6,7 @@ type Department struct {
 	ID        int       `db:"id" json:"id"`
 	Name      string    `db:"name" json:"name"`
 	ManagerID int       `db:"manager_id" json:"manager_id"`
+	Version   int       `db:"version" json:"version"`
 	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
 }
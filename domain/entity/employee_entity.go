@@ -9,5 +9,7 @@ type Employee struct {
 	EmployeeImageURI string    `db:"employee_image_uri" json:"employee_image_uri"`
 	Gender           string    `db:"gender" json:"gender"`
 	DepartmentID     int       `db:"department_id" json:"department_id"`
+	Version          int       `db:"version" json:"version"`
 	CreatedAt        time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt        time.Time `db:"updated_at" json:"updated_at"`
 }
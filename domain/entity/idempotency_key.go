@@ -0,0 +1,17 @@
+package entity
+
+import "time"
+
+// IdempotencyKey is the first response to a request sent with an Idempotency-Key header.
+// StatusCode is nil while that request is still being handled.
+type IdempotencyKey struct {
+	TenantID    int       `db:"tenant_id"`
+	Key         string    `db:"key"`
+	Fingerprint string    `db:"fingerprint"`
+	StatusCode  *int      `db:"status_code"`
+	ContentType *string   `db:"content_type"`
+	Body        []byte    `db:"body"`
+	LockedAt    time.Time `db:"locked_at"`
+	CreatedAt   time.Time `db:"created_at"`
+	ExpiresAt   time.Time `db:"expires_at"`
+}
@@ -3,12 +3,16 @@ package entity
 import "time"
 
 type Manager struct {
-	ID              int       `db:"id"`
-	Email           string    `db:"email"`
-	Password        string    `db:"password"`
-	Name            string   `db:"name"`
-	UserImageURI    string   `db:"user_image_uri"`
-	CompanyName     string   `db:"company_name"`
-	CompanyImageURI string   `db:"company_image_uri"`
-	CreatedAt       time.Time `db:"created_at"`
+	ID              int        `db:"id" json:"id"`
+	Email           string     `db:"email" json:"email"`
+	Password        string     `db:"password" json:"-"`
+	Name            string     `db:"name" json:"name"`
+	UserImageURI    string     `db:"user_image_uri" json:"user_image_uri"`
+	CompanyName     string     `db:"company_name" json:"company_name"`
+	CompanyImageURI string     `db:"company_image_uri" json:"company_image_uri"`
+	EmailVerifiedAt *time.Time `db:"email_verified_at" json:"email_verified_at"`
+	MFARequired     bool       `db:"mfa_required" json:"mfa_required"`
+	Version         int        `db:"version" json:"version"`
+	CreatedAt       time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time  `db:"updated_at" json:"updated_at"`
 }
@@ -0,0 +1,13 @@
+package entity
+
+import "time"
+
+// MFAFactor is the TOTP secret of a manager or user, keyed by the kind of account and its id
+type MFAFactor struct {
+	SubjectKind  string     `db:"subject_kind"`
+	SubjectID    string     `db:"subject_id"`
+	Secret       string     `db:"secret"`
+	ConfirmedAt  *time.Time `db:"confirmed_at"`
+	LastUsedStep *int64     `db:"last_used_step"`
+	CreatedAt    time.Time  `db:"created_at"`
+}
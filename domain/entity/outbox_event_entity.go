@@ -0,0 +1,27 @@
+package entity
+
+import "time"
+
+const (
+	OutboxEventPending    = "pending"
+	OutboxEventPublishing = "publishing"
+	OutboxEventPublished  = "published"
+)
+
+// OutboxEvent is a domain event waiting in, or relayed from, the outbox. Payload is the
+// JSON envelope every sink publishes as is.
+type OutboxEvent struct {
+	ID            int64      `db:"id"`
+	TenantID      int        `db:"tenant_id"`
+	AggregateType string     `db:"aggregate_type"`
+	AggregateID   string     `db:"aggregate_id"`
+	EventID       string     `db:"event_id"`
+	EventType     string     `db:"event_type"`
+	Payload       string     `db:"payload"`
+	Status        string     `db:"status"`
+	Attempts      int        `db:"attempts"`
+	LastError     string     `db:"last_error"`
+	NextAttemptAt time.Time  `db:"next_attempt_at"`
+	PublishedAt   *time.Time `db:"published_at"`
+	CreatedAt     time.Time  `db:"created_at"`
+}
@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	WebhookDeliveryPending    = "pending"
+	WebhookDeliveryDelivering = "delivering"
+	WebhookDeliveryDelivered  = "delivered"
+	// WebhookDeliveryDead is a delivery that ran out of attempts, it is only sent again on request
+	WebhookDeliveryDead = "dead"
+)
+
+type WebhookEndpoint struct {
+	ID          int       `db:"id"`
+	TenantID    int       `db:"tenant_id"`
+	URL         string    `db:"url"`
+	Description string    `db:"description"`
+	Events      string    `db:"events"` // comma separated, empty for every event
+	Secret      string    `db:"secret"`
+	Active      bool      `db:"active"`
+	CreatedAt   time.Time `db:"created_at"`
+	UpdatedAt   time.Time `db:"updated_at"`
+}
+
+func (e WebhookEndpoint) EventList() []string {
+	if e.Events == "" {
+		return []string{}
+	}
+
+	return strings.Split(e.Events, ",")
+}
+
+type WebhookDelivery struct {
+	ID             int64      `db:"id"`
+	EndpointID     int        `db:"endpoint_id"`
+	EventID        string     `db:"event_id"`
+	EventType      string     `db:"event_type"`
+	Payload        string     `db:"payload"`
+	Status         string     `db:"status"`
+	Attempts       int        `db:"attempts"`
+	NextAttemptAt  time.Time  `db:"next_attempt_at"`
+	LastStatusCode *int       `db:"last_status_code"`
+	LastError      string     `db:"last_error"`
+	DeliveredAt    *time.Time `db:"delivered_at"`
+	CreatedAt      time.Time  `db:"created_at"`
+
+	// URL and Secret are only loaded when a delivery is claimed for sending
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+type WebhookAttempt struct {
+	ID           int64     `db:"id"`
+	DeliveryID   int64     `db:"delivery_id"`
+	StatusCode   *int      `db:"status_code"`
+	Error        string    `db:"error"`
+	ResponseBody string    `db:"response_body"`
+	DurationMs   int       `db:"duration_ms"`
+	CreatedAt    time.Time `db:"created_at"`
+}
@@ -1,5 +1,7 @@
 package enums
 
+import "strings"
+
 type RoleEnum string
 
 const (
@@ -12,3 +14,80 @@ const (
 func (r RoleEnum) String() string {
 	return string(r)
 }
+
+// rank orders roles from least to most privileged; unknown roles rank lowest
+var rank = map[RoleEnum]int{
+	User:       1,
+	Admin:      2,
+	LeadAdmin:  3,
+	SuperAdmin: 4,
+}
+
+// AtLeast reports whether r is as privileged as other or more
+func (r RoleEnum) AtLeast(other RoleEnum) bool {
+	return rank[r] > 0 && rank[r] >= rank[other]
+}
+
+// ScopeEnum is a permission granted to an API key. Requests made with a manager token are
+// not limited by scopes.
+type ScopeEnum string
+
+const (
+	ScopeRead            ScopeEnum = "read"
+	ScopeDepartmentWrite ScopeEnum = "department:write"
+	ScopeEmployeeWrite   ScopeEnum = "employee:write"
+	ScopeFileWrite       ScopeEnum = "file:write"
+	ScopeAuditRead       ScopeEnum = "audit:read"
+	// ScopeSCIM lets an identity provider provision employees and departments over SCIM
+	ScopeSCIM ScopeEnum = "scim"
+)
+
+func (s ScopeEnum) String() string {
+	return string(s)
+}
+
+// AuditActionEnum is what a mutation recorded in the audit log did to its entity
+type AuditActionEnum string
+
+const (
+	AuditCreate     AuditActionEnum = "create"
+	AuditUpdate     AuditActionEnum = "update"
+	AuditDelete     AuditActionEnum = "delete"
+	AuditSoftDelete AuditActionEnum = "soft_delete"
+	AuditRestore    AuditActionEnum = "restore"
+	AuditErase      AuditActionEnum = "erase"
+)
+
+func (a AuditActionEnum) String() string {
+	return string(a)
+}
+
+// WebhookEventEnum is the type of a domain event, relayed from the outbox to webhook endpoints
+// and the other configured sinks
+type WebhookEventEnum string
+
+const (
+	WebhookEmployeeCreated WebhookEventEnum = "employee.created"
+	WebhookEmployeeUpdated WebhookEventEnum = "employee.updated"
+	// WebhookEmployeeMoved follows employee.updated when the update changed the department
+	WebhookEmployeeMoved   WebhookEventEnum = "employee.moved"
+	WebhookEmployeeDeleted WebhookEventEnum = "employee.deleted"
+	// WebhookEmployeeErased tells consumers to drop what they kept about the employee, the data of
+	// the employee's earlier events is gone by then
+	WebhookEmployeeErased    WebhookEventEnum = "employee.erased"
+	WebhookDepartmentCreated WebhookEventEnum = "department.created"
+	WebhookDepartmentUpdated WebhookEventEnum = "department.updated"
+	WebhookDepartmentDeleted WebhookEventEnum = "department.deleted"
+	// WebhookPing is only sent on request, to a single endpoint, whatever it subscribed to
+	WebhookPing WebhookEventEnum = "webhook.ping"
+)
+
+func (w WebhookEventEnum) String() string {
+	return string(w)
+}
+
+// Aggregate is the kind of resource the event is about, events of one resource are published in order
+func (w WebhookEventEnum) Aggregate() string {
+	aggregate, _, _ := strings.Cut(string(w), ".")
+	return aggregate
+}
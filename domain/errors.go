@@ -3,97 +3,420 @@ package domain
 import (
 	"errors"
 	"net/http"
+	"time"
 )
 
 type SerializableError interface {
 	Serialize() any
 }
 
+// RequestError is an error that maps to an HTTP status. Code is a stable, machine-readable
+// identifier sent to clients in the problem details body; never change it once released.
 type RequestError struct {
 	StatusCode int
+	Code       string
 	Err        error
+	// RetryAfter is sent as the Retry-After header when set
+	RetryAfter time.Duration
 }
 
 func (r *RequestError) Error() string {
 	return r.Err.Error()
 }
 
+// Is matches on Code so copies made by WithRetryAfter still match their sentinel in errors.Is
+func (r *RequestError) Is(target error) bool {
+	t, ok := target.(*RequestError)
+	return ok && t.Code == r.Code
+}
+
+// WithRetryAfter returns a copy of r telling the client how long to wait before retrying
+func (r *RequestError) WithRetryAfter(d time.Duration) *RequestError {
+	c := *r
+	c.RetryAfter = d
+	return &c
+}
+
 var ErrNotFound = &RequestError{
 	StatusCode: http.StatusNotFound,
+	Code:       "not_found",
 	Err:        errors.New("something not found"),
 }
 
 var ErrNoAPIKey = &RequestError{
 	StatusCode: http.StatusUnauthorized,
+	Code:       "no_api_key",
 	Err:        errors.New("no api key provided"),
 }
 
 var ErrInvalidAPIKey = &RequestError{
 	StatusCode: http.StatusUnauthorized,
+	Code:       "invalid_api_key",
 	Err:        errors.New("invalid api key"),
 }
 
 var ErrUserNotFound = &RequestError{
 	StatusCode: http.StatusNotFound,
+	Code:       "user_not_found",
 	Err:        errors.New("user not found"),
 }
 
 var ErrUserEmailAlreadyExists = &RequestError{
 	StatusCode: http.StatusConflict,
+	Code:       "user_email_already_exists",
 	Err:        errors.New("user email already exists"),
 }
 
 var ErrNoBearerToken = &RequestError{
 	StatusCode: http.StatusUnauthorized,
+	Code:       "no_bearer_token",
 	Err:        errors.New("no bearer token provided"),
 }
 
 var ErrInvalidBearerToken = &RequestError{
 	StatusCode: http.StatusUnauthorized,
+	Code:       "invalid_bearer_token",
 	Err:        errors.New("invalid bearer token"),
 }
 
 var ErrExpiredBearerToken = &RequestError{
 	StatusCode: http.StatusUnauthorized,
+	Code:       "expired_bearer_token",
 	Err:        errors.New("expired bearer token"),
 }
 
 var ErrBearerTokenNotActive = &RequestError{
 	StatusCode: http.StatusUnauthorized,
+	Code:       "bearer_token_not_active",
 	Err:        errors.New("bearer token not active"),
 }
 
 var ErrEmailNotFound = &RequestError{
 	StatusCode: http.StatusNotFound,
+	Code:       "email_not_found",
 	Err:        errors.New("email not found"),
 }
 
 var ErrCredentialsNotMatch = &RequestError{
 	StatusCode: http.StatusUnauthorized,
+	Code:       "credentials_not_match",
 	Err:        errors.New("credentials do not match"),
 }
 
 var ErrRoleCantAccessResource = &RequestError{
 	StatusCode: http.StatusForbidden,
+	Code:       "role_cant_access_resource",
 	Err:        errors.New("role can't access resource"),
 }
 
 var ErrFileSizeLimitExceeded = &RequestError{
 	StatusCode: http.StatusBadRequest,
+	Code:       "file_size_limit_exceeded",
 	Err:        errors.New("file size limit exceeded"),
 }
 
 var ErrInvalidFileExtension = &RequestError{
 	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_file_extension",
 	Err:        errors.New("invalid file extension"),
 }
 
 var ErrFileNotFound = &RequestError{
 	StatusCode: http.StatusBadRequest,
+	Code:       "file_not_found",
 	Err:        errors.New("file not found"),
 }
 
 var ErrInvalidMimeType = &RequestError{
 	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_mime_type",
 	Err:        errors.New("invalid mime type"),
 }
+
+var ErrInvalidRequestBody = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_request_body",
+	Err:        errors.New("invalid request body"),
+}
+
+var ErrInvalidQueryParam = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_query_param",
+	Err:        errors.New("invalid query parameter"),
+}
+
+var ErrIdentityNumberRequired = &RequestError{
+	StatusCode: http.StatusNotFound,
+	Code:       "identity_number_required",
+	Err:        errors.New("identity number is required"),
+}
+
+var ErrInvalidDepartmentID = &RequestError{
+	StatusCode: http.StatusNotFound,
+	Code:       "invalid_department_id",
+	Err:        errors.New("invalid department id"),
+}
+
+var ErrDepartmentIDRequired = &RequestError{
+	StatusCode: http.StatusNotFound,
+	Code:       "department_id_required",
+	Err:        errors.New("department id is required"),
+}
+
+var ErrDepartmentNotFound = &RequestError{
+	StatusCode: http.StatusNotFound,
+	Code:       "department_not_found",
+	Err:        errors.New("department not found"),
+}
+
+var ErrEmployeeNotFound = &RequestError{
+	StatusCode: http.StatusNotFound,
+	Code:       "employee_not_found",
+	Err:        errors.New("employee not found"),
+}
+
+var ErrInvalidEmployeeImageURI = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_employee_image_uri",
+	Err:        errors.New("invalid employee image uri"),
+}
+
+var ErrManagerNotFound = &RequestError{
+	StatusCode: http.StatusNotFound,
+	Code:       "manager_not_found",
+	Err:        errors.New("manager not found"),
+}
+
+var ErrManagerEmailAlreadyExists = &RequestError{
+	StatusCode: http.StatusConflict,
+	Code:       "manager_email_already_exists",
+	Err:        errors.New("email already exists"),
+}
+
+var ErrInvalidAuthAction = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_auth_action",
+	Err:        errors.New("invalid action"),
+}
+
+var ErrInvalidUserImageURI = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_user_image_uri",
+	Err:        errors.New("invalid user image uri"),
+}
+
+var ErrInvalidCompanyImageURI = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_company_image_uri",
+	Err:        errors.New("invalid company image uri"),
+}
+
+var ErrTooManyLoginAttempts = &RequestError{
+	StatusCode: http.StatusTooManyRequests,
+	Code:       "too_many_login_attempts",
+	Err:        errors.New("too many login attempts, try again later"),
+}
+
+var ErrInvalidAuthToken = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_auth_token",
+	Err:        errors.New("token is invalid, expired or already used"),
+}
+
+var ErrEmailAlreadyVerified = &RequestError{
+	StatusCode: http.StatusConflict,
+	Code:       "email_already_verified",
+	Err:        errors.New("email is already verified"),
+}
+
+var ErrInvalidMFACode = &RequestError{
+	StatusCode: http.StatusUnauthorized,
+	Code:       "invalid_mfa_code",
+	Err:        errors.New("invalid or already used two-factor code"),
+}
+
+var ErrMFAAlreadyEnabled = &RequestError{
+	StatusCode: http.StatusConflict,
+	Code:       "mfa_already_enabled",
+	Err:        errors.New("two-factor authentication is already enabled"),
+}
+
+var ErrMFANotEnabled = &RequestError{
+	StatusCode: http.StatusConflict,
+	Code:       "mfa_not_enabled",
+	Err:        errors.New("two-factor authentication is not enabled"),
+}
+
+var ErrMFANotEnrolled = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "mfa_not_enrolled",
+	Err:        errors.New("start two-factor enrollment before confirming it"),
+}
+
+var ErrMFARequired = &RequestError{
+	StatusCode: http.StatusForbidden,
+	Code:       "mfa_required",
+	Err:        errors.New("two-factor authentication is mandatory for this account"),
+}
+
+var ErrInvalidMFAToken = &RequestError{
+	StatusCode: http.StatusUnauthorized,
+	Code:       "invalid_mfa_token",
+	Err:        errors.New("two-factor challenge is invalid or expired, log in again"),
+}
+
+var ErrPasswordTooLong = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "password_too_long",
+	Err:        errors.New("password is too long for the configured hashing algorithm"),
+}
+
+var ErrAPIKeyNotFound = &RequestError{
+	StatusCode: http.StatusNotFound,
+	Code:       "api_key_not_found",
+	Err:        errors.New("api key not found"),
+}
+
+var ErrAPIKeyScopeDenied = &RequestError{
+	StatusCode: http.StatusForbidden,
+	Code:       "api_key_scope_denied",
+	Err:        errors.New("api key is missing the scope this endpoint requires"),
+}
+
+var ErrTooManyRequests = &RequestError{
+	StatusCode: http.StatusTooManyRequests,
+	Code:       "rate_limited",
+	Err:        errors.New("rate limit exceeded, try again later"),
+}
+
+var ErrRateLimitUnavailable = &RequestError{
+	StatusCode: http.StatusServiceUnavailable,
+	Code:       "rate_limit_unavailable",
+	Err:        errors.New("rate limits can't be checked right now, try again later"),
+}
+
+var ErrIdempotencyKeyReused = &RequestError{
+	StatusCode: http.StatusUnprocessableEntity,
+	Code:       "idempotency_key_reused",
+	Err:        errors.New("idempotency key was already used with a different request"),
+}
+
+var ErrIdempotencyKeyInProgress = &RequestError{
+	StatusCode: http.StatusConflict,
+	Code:       "idempotency_key_in_progress",
+	Err:        errors.New("a request with this idempotency key is still being processed"),
+}
+
+var ErrInvalidIdempotencyKey = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_idempotency_key",
+	Err:        errors.New("idempotency key must be at most 255 printable ASCII characters"),
+}
+
+var ErrPreconditionFailed = &RequestError{
+	StatusCode: http.StatusPreconditionFailed,
+	Code:       "precondition_failed",
+	Err:        errors.New("resource was modified since it was read, fetch it again"),
+}
+
+var ErrPreconditionRequired = &RequestError{
+	StatusCode: http.StatusPreconditionRequired,
+	Code:       "precondition_required",
+	Err:        errors.New("If-Match header is required, send the ETag of the resource being changed"),
+}
+
+var ErrWebhookNotFound = &RequestError{
+	StatusCode: http.StatusNotFound,
+	Code:       "webhook_not_found",
+	Err:        errors.New("webhook not found"),
+}
+
+var ErrWebhookDeliveryNotFound = &RequestError{
+	StatusCode: http.StatusNotFound,
+	Code:       "webhook_delivery_not_found",
+	Err:        errors.New("webhook delivery not found"),
+}
+
+var ErrWebhookDeliveryInProgress = &RequestError{
+	StatusCode: http.StatusConflict,
+	Code:       "webhook_delivery_in_progress",
+	Err:        errors.New("webhook delivery is being sent, try again once it finished"),
+}
+
+var ErrInvalidWebhookURL = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_webhook_url",
+	Err:        errors.New("webhook url must be an absolute http or https url of a publicly reachable host"),
+}
+
+var ErrInvalidLastEventID = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_last_event_id",
+	Err:        errors.New("last event id must be the id of an event received from the stream"),
+}
+
+var ErrEmployeeAlreadyExists = &RequestError{
+	StatusCode: http.StatusConflict,
+	Code:       "employee_already_exists",
+	Err:        errors.New("an employee with this identity number already exists"),
+}
+
+var ErrInvalidSCIMFilter = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_scim_filter",
+	Err:        errors.New(`filter is not supported, use userName eq "..." for users and displayName eq "..." for groups`),
+}
+
+var ErrInvalidSCIMPatch = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_scim_patch",
+	Err:        errors.New("patch operation is malformed or targets an attribute that cannot be changed"),
+}
+
+var ErrSCIMUnknownGroup = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "scim_unknown_group",
+	Err:        errors.New("department id does not name an existing group"),
+}
+
+var ErrSCIMUnknownMember = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "scim_unknown_member",
+	Err:        errors.New("member does not name an existing user"),
+}
+
+var ErrSCIMMemberRemoval = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "scim_member_removal",
+	Err:        errors.New("employees always belong to a department, move them by adding them to another group"),
+}
+
+var ErrSCIMDeactivation = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "scim_deactivation",
+	Err:        errors.New("employees cannot be deactivated, delete them instead"),
+}
+
+var ErrInvalidPagination = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "invalid_pagination",
+	Err:        errors.New("limit must be between 1 and 100 and offset must not be negative"),
+}
+
+var ErrVersionRequired = &RequestError{
+	StatusCode: http.StatusPreconditionRequired,
+	Code:       "version_required",
+	Err:        errors.New("version argument is required, send the version of the resource being changed"),
+}
+
+var ErrGraphQLTooDeep = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "graphql_too_deep",
+	Err:        errors.New("query nests fields too deeply"),
+}
+
+var ErrGraphQLTooComplex = &RequestError{
+	StatusCode: http.StatusBadRequest,
+	Code:       "graphql_too_complex",
+	Err:        errors.New("query asks for too much at once, request fewer fields or smaller pages"),
+}
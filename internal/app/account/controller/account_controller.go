@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+)
+
+type accountController struct {
+	accountService contracts.AccountService
+}
+
+func InitNewController(router fiber.Router, accountService contracts.AccountService, middleware *middlewares.Middleware) {
+	controller := &accountController{
+		accountService: accountService,
+	}
+
+	route := router.Group("/v1/auth")
+	route.Post("/password/forgot", controller.forgotPassword)
+	route.Post("/password/reset", controller.resetPassword)
+	route.Post("/verify/request", middleware.RequireAdmin(), controller.requestVerification)
+	route.Post("/verify", controller.verifyEmail)
+}
+
+func (ac *accountController) forgotPassword(ctx *fiber.Ctx) error {
+	var req dto.ForgotPasswordRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	if err := ac.accountService.ForgotPassword(ctx.UserContext(), req); err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusAccepted, fiber.Map{
+		"message": "If the email is registered, a reset link is on its way",
+	})
+}
+
+func (ac *accountController) resetPassword(ctx *fiber.Ctx) error {
+	var req dto.ResetPasswordRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	if err := ac.accountService.ResetPassword(ctx.UserContext(), req); err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, fiber.Map{
+		"message": "Password updated successfully",
+	})
+}
+
+func (ac *accountController) requestVerification(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	if err := ac.accountService.RequestVerification(ctx.UserContext(), managerID); err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusAccepted, fiber.Map{
+		"message": "Verification email sent",
+	})
+}
+
+func (ac *accountController) verifyEmail(ctx *fiber.Ctx) error {
+	var req dto.VerifyEmailRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	if err := ac.accountService.VerifyEmail(ctx.UserContext(), req); err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, fiber.Map{
+		"message": "Email verified successfully",
+	})
+}
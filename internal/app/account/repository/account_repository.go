@@ -0,0 +1,185 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	PurposePasswordReset = "password_reset"
+	PurposeVerifyEmail   = "verify_email"
+)
+
+const (
+	queryGetManagerByID = "SELECT * FROM managers WHERE id = $1"
+	// Issuing a token revokes the ones still pending for the same purpose, only the latest email works
+	queryRevokeTokens = "UPDATE auth_tokens SET used_at = NOW() WHERE manager_id = $1 AND purpose = $2 AND used_at IS NULL"
+	queryInsertToken  = "INSERT INTO auth_tokens (manager_id, purpose, token_hash, expires_at) VALUES (:manager_id, :purpose, :token_hash, :expires_at)"
+	queryInsertEmail  = "INSERT INTO outbox_emails (recipient, subject, body) VALUES (:recipient, :subject, :body)"
+	// The same statements by email, they change nothing when no manager has it
+	queryRevokeTokensByEmail = `
+	UPDATE auth_tokens SET used_at = NOW()
+	WHERE manager_id = (SELECT id FROM managers WHERE email = $1) AND purpose = $2 AND used_at IS NULL`
+	queryInsertTokenByEmail = `
+	INSERT INTO auth_tokens (manager_id, purpose, token_hash, expires_at)
+	SELECT id, $2, $3, $4 FROM managers WHERE email = $1`
+	queryInsertEmailByEmail = `
+	INSERT INTO outbox_emails (recipient, subject, body)
+	SELECT email, $2, $3 FROM managers WHERE email = $1`
+	queryConsumeToken = `
+	UPDATE auth_tokens SET used_at = NOW()
+	WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+	RETURNING manager_id`
+	queryUpdatePassword = "UPDATE managers SET password = $1 WHERE id = $2"
+	queryMarkVerified   = `
+	UPDATE managers SET email_verified_at = NOW(), version = version + 1, updated_at = NOW()
+	WHERE id = $1 AND email_verified_at IS NULL`
+)
+
+type accountRepository struct {
+	DB *sqlx.DB
+}
+
+func NewAccountRepository(db *sqlx.DB) contracts.AccountRepository {
+	return &accountRepository{DB: db}
+}
+
+func (repo *accountRepository) GetManagerByID(ctx context.Context, id int) (*entity.Manager, error) {
+	ctx, span := tracing.StartDB(ctx, "AccountRepository.GetManagerByID", queryGetManagerByID)
+	defer span.End()
+
+	var manager entity.Manager
+	if err := repo.DB.GetContext(ctx, &manager, queryGetManagerByID, id); err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return &manager, nil
+}
+
+// IssueToken stores the token and queues its email in one transaction, so a token is never
+// created without the email that delivers it.
+func (repo *accountRepository) IssueToken(ctx context.Context, token entity.AuthToken, email entity.OutboxEmail) error {
+	ctx, span := tracing.StartDB(ctx, "AccountRepository.IssueToken", queryInsertToken)
+	defer span.End()
+
+	tx, err := repo.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, queryRevokeTokens, token.ManagerID, token.Purpose); err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	if _, err := tx.NamedExecContext(ctx, queryInsertToken, token); err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	if _, err := tx.NamedExecContext(ctx, queryInsertEmail, email); err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return tracing.RecordError(span, tx.Commit())
+}
+
+// IssueTokenByEmail looks the manager up in the statements themselves rather than beforehand, so
+// an unknown email takes as long as a registered one and can't be told apart by timing.
+func (repo *accountRepository) IssueTokenByEmail(ctx context.Context, token entity.AuthToken, email entity.OutboxEmail) error {
+	ctx, span := tracing.StartDB(ctx, "AccountRepository.IssueTokenByEmail", queryInsertTokenByEmail)
+	defer span.End()
+
+	tx, err := repo.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, queryRevokeTokensByEmail, email.Recipient, token.Purpose); err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	res, err := tx.ExecContext(ctx, queryInsertTokenByEmail, email.Recipient, token.Purpose, token.TokenHash, token.ExpiresAt)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, queryInsertEmailByEmail, email.Recipient, email.Subject, email.Body); err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	issued, err := res.RowsAffected()
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+	if issued == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+// ResetPassword consumes a reset token and sets the new password hash atomically.
+// It returns sql.ErrNoRows when the token is unknown, expired or already used.
+func (repo *accountRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "AccountRepository.ResetPassword", queryConsumeToken)
+	defer span.End()
+
+	tx, err := repo.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+	defer tx.Rollback()
+
+	var managerID int
+	if err := tx.GetContext(ctx, &managerID, queryConsumeToken, tokenHash, PurposePasswordReset); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, queryUpdatePassword, passwordHash, managerID); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	return managerID, nil
+}
+
+// VerifyEmail consumes a verification token and marks the manager's email as verified.
+// It returns sql.ErrNoRows when the token is unknown, expired or already used.
+func (repo *accountRepository) VerifyEmail(ctx context.Context, tokenHash string) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "AccountRepository.VerifyEmail", queryConsumeToken)
+	defer span.End()
+
+	tx, err := repo.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+	defer tx.Rollback()
+
+	var managerID int
+	if err := tx.GetContext(ctx, &managerID, queryConsumeToken, tokenHash, PurposeVerifyEmail); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, queryMarkVerified, managerID); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	return managerID, nil
+}
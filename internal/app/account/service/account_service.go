@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/account/repository"
+	lockoutSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/lockout/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/hasher"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+// Config holds the links and lifetimes used in account emails. Zero TTLs fall back to
+// one hour for password resets and two days for email verification.
+type Config struct {
+	WebURL    string
+	ResetTTL  time.Duration
+	VerifyTTL time.Duration
+}
+
+type accountService struct {
+	repo      contracts.AccountRepository
+	lockout   contracts.LockoutService
+	hasher    hasher.PasswordHasher
+	validator validator.ValidatorInterface
+	config    Config
+}
+
+func NewAccountService(
+	repo contracts.AccountRepository,
+	lockout contracts.LockoutService,
+	hasher hasher.PasswordHasher,
+	validator validator.ValidatorInterface,
+	config Config,
+) contracts.AccountService {
+	if config.ResetTTL <= 0 {
+		config.ResetTTL = time.Hour
+	}
+	if config.VerifyTTL <= 0 {
+		config.VerifyTTL = 48 * time.Hour
+	}
+
+	return &accountService{
+		repo:      repo,
+		lockout:   lockout,
+		hasher:    hasher,
+		validator: validator,
+		config:    config,
+	}
+}
+
+// ForgotPassword emails a reset link when the email belongs to a manager. It succeeds either
+// way and does the same work, so the endpoint can't be used to find out which emails are
+// registered, not even by timing it.
+func (s *accountService) ForgotPassword(ctx context.Context, req dto.ForgotPasswordRequest) error {
+	ctx, span := tracing.Start(ctx, "AccountService.ForgotPassword")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
+	valErr := s.validator.Validate(req)
+	valSpan.End()
+	if valErr != nil {
+		return valErr
+	}
+
+	token, email, err := s.prepare(req.Email, repository.PurposePasswordReset, s.config.ResetTTL, func(token string) (string, string) {
+		return "Reset your GoGoManager password", fmt.Sprintf(
+			"Someone asked to reset the password of your GoGoManager account.\n\n"+
+				"%s\n\n"+
+				"The link expires in %s and works once. If it wasn't you, ignore this email.\n",
+			s.link("/reset-password", token), s.config.ResetTTL,
+		)
+	})
+	if err != nil {
+		return err
+	}
+
+	err = s.repo.IssueTokenByEmail(ctx, token, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		log.DebugCtx(ctx, nil, "[AccountService][ForgotPassword] no manager for email, nothing sent")
+		return nil
+	}
+
+	return err
+}
+
+func (s *accountService) ResetPassword(ctx context.Context, req dto.ResetPasswordRequest) error {
+	ctx, span := tracing.Start(ctx, "AccountService.ResetPassword")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
+	valErr := s.validator.Validate(req)
+	valSpan.End()
+	if valErr != nil {
+		return valErr
+	}
+
+	hashedPassword, err := s.hasher.Hash(req.Password)
+	if err != nil {
+		return err
+	}
+
+	managerID, err := s.repo.ResetPassword(ctx, hashToken(req.Token), hashedPassword)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrInvalidAuthToken
+		}
+
+		return err
+	}
+
+	// Proving access to the mailbox is enough to lift a lockout on the account
+	manager, err := s.repo.GetManagerByID(ctx, managerID)
+	if err != nil {
+		return err
+	}
+
+	return s.lockout.Succeed(ctx, lockoutSvc.Account(lockoutSvc.KindManager, manager.Email))
+}
+
+func (s *accountService) RequestVerification(ctx context.Context, managerID int) error {
+	ctx, span := tracing.Start(ctx, "AccountService.RequestVerification")
+	defer span.End()
+
+	manager, err := s.repo.GetManagerByID(ctx, managerID)
+	if err != nil {
+		return err
+	}
+
+	if manager.EmailVerifiedAt != nil {
+		return domain.ErrEmailAlreadyVerified
+	}
+
+	return s.issue(ctx, manager, repository.PurposeVerifyEmail, s.config.VerifyTTL, func(token string) (string, string) {
+		return "Verify your GoGoManager email", fmt.Sprintf(
+			"Welcome to GoGoManager! Confirm your email address with the link below.\n\n"+
+				"%s\n\n"+
+				"The link expires in %s.\n",
+			s.link("/verify-email", token), s.config.VerifyTTL,
+		)
+	})
+}
+
+func (s *accountService) VerifyEmail(ctx context.Context, req dto.VerifyEmailRequest) error {
+	ctx, span := tracing.Start(ctx, "AccountService.VerifyEmail")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
+	valErr := s.validator.Validate(req)
+	valSpan.End()
+	if valErr != nil {
+		return valErr
+	}
+
+	_, err := s.repo.VerifyEmail(ctx, hashToken(req.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrInvalidAuthToken
+		}
+
+		return err
+	}
+
+	return nil
+}
+
+func (s *accountService) issue(
+	ctx context.Context,
+	manager *entity.Manager,
+	purpose string,
+	ttl time.Duration,
+	render func(token string) (subject, body string),
+) error {
+	token, email, err := s.prepare(manager.Email, purpose, ttl, render)
+	if err != nil {
+		return err
+	}
+
+	token.ManagerID = manager.ID
+
+	return s.repo.IssueToken(ctx, token, email)
+}
+
+// prepare creates a token and the email delivering it to recipient
+func (s *accountService) prepare(
+	recipient string,
+	purpose string,
+	ttl time.Duration,
+	render func(token string) (subject, body string),
+) (entity.AuthToken, entity.OutboxEmail, error) {
+	token, err := newToken()
+	if err != nil {
+		return entity.AuthToken{}, entity.OutboxEmail{}, err
+	}
+
+	authToken := entity.AuthToken{
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	subject, body := render(token)
+	email := entity.OutboxEmail{
+		Recipient: recipient,
+		Subject:   subject,
+		Body:      body,
+	}
+
+	return authToken, email, nil
+}
+
+// link points at the web app page handling the token, or is the bare token when no web URL is configured
+func (s *accountService) link(path, token string) string {
+	if s.config.WebURL == "" {
+		return "Your token: " + token
+	}
+
+	return strings.TrimSuffix(s.config.WebURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// newToken returns 256 random bits; only their SHA-256 is stored with the token, so a database
+// leak does not expose usable tokens. The email carrying the link has its body cleared once sent.
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
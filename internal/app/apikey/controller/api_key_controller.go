@@ -0,0 +1,72 @@
+package controller
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+)
+
+type apiKeyController struct {
+	apiKeyService contracts.APIKeyService
+}
+
+// InitNewController mounts key management. RequireAdmin is called without scopes, so these
+// endpoints need a manager token: an API key can't mint or revoke keys.
+func InitNewController(router fiber.Router, apiKeyService contracts.APIKeyService, middleware *middlewares.Middleware) {
+	controller := &apiKeyController{
+		apiKeyService: apiKeyService,
+	}
+
+	route := router.Group("/v1/api-keys")
+	route.Post("/", middleware.RequireAdmin(), controller.create)
+	route.Get("/", middleware.RequireAdmin(), controller.list)
+	route.Delete("/:id", middleware.RequireAdmin(), controller.revoke)
+}
+
+func (c *apiKeyController) create(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	var req dto.CreateAPIKeyRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	res, err := c.apiKeyService.Create(ctx.UserContext(), managerID, req)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusCreated, res)
+}
+
+func (c *apiKeyController) list(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	res, err := c.apiKeyService.List(ctx.UserContext(), managerID)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+func (c *apiKeyController) revoke(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	id, err := ctx.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return domain.ErrAPIKeyNotFound
+	}
+
+	if err := c.apiKeyService.Revoke(ctx.UserContext(), managerID, id); err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, fiber.Map{
+		"message": "API key revoked",
+	})
+}
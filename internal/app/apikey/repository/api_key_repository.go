@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	apiKeyColumns = "id, manager_id, name, prefix, key_hash, scopes, expires_at, last_used_at, revoked_at, created_at"
+
+	queryCreate = `
+	INSERT INTO api_keys (manager_id, name, prefix, key_hash, scopes, expires_at)
+	VALUES (:manager_id, :name, :prefix, :key_hash, :scopes, :expires_at)
+	RETURNING ` + apiKeyColumns
+	queryListByManager = "SELECT " + apiKeyColumns + " FROM api_keys WHERE manager_id = $1 ORDER BY id DESC"
+	queryRevoke        = "UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND manager_id = $2 AND revoked_at IS NULL"
+	queryGetByPrefix   = `
+	SELECT k.id, k.manager_id, k.name, k.prefix, k.key_hash, k.scopes, k.expires_at, k.last_used_at,
+		k.revoked_at, k.created_at, m.email AS manager_email
+	FROM api_keys k
+	JOIN managers m ON m.id = k.manager_id
+	WHERE k.prefix = $1 AND k.revoked_at IS NULL AND (k.expires_at IS NULL OR k.expires_at > NOW())`
+	// last_used_at is only written once a minute per key so busy integrations don't turn every read into a write
+	queryTouchLastUsed = `
+	UPDATE api_keys SET last_used_at = NOW()
+	WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')`
+)
+
+type apiKeyRepository struct {
+	DB *sqlx.DB
+}
+
+func NewAPIKeyRepository(db *sqlx.DB) contracts.APIKeyRepository {
+	return &apiKeyRepository{DB: db}
+}
+
+func (repo *apiKeyRepository) Create(ctx context.Context, key entity.APIKey) (entity.APIKey, error) {
+	ctx, span := tracing.StartDB(ctx, "APIKeyRepository.Create", queryCreate)
+	defer span.End()
+
+	rows, err := repo.DB.NamedQueryContext(ctx, queryCreate, key)
+	if err != nil {
+		return entity.APIKey{}, tracing.RecordError(span, err)
+	}
+	defer rows.Close()
+
+	var created entity.APIKey
+	if rows.Next() {
+		if err := rows.StructScan(&created); err != nil {
+			return entity.APIKey{}, tracing.RecordError(span, err)
+		}
+	}
+
+	return created, tracing.RecordError(span, rows.Err())
+}
+
+func (repo *apiKeyRepository) ListByManager(ctx context.Context, managerID int) ([]entity.APIKey, error) {
+	ctx, span := tracing.StartDB(ctx, "APIKeyRepository.ListByManager", queryListByManager)
+	defer span.End()
+
+	keys := []entity.APIKey{}
+	err := repo.DB.SelectContext(ctx, &keys, queryListByManager, managerID)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return keys, nil
+}
+
+func (repo *apiKeyRepository) Revoke(ctx context.Context, managerID, id int) (bool, error) {
+	ctx, span := tracing.StartDB(ctx, "APIKeyRepository.Revoke", queryRevoke)
+	defer span.End()
+
+	result, err := repo.DB.ExecContext(ctx, queryRevoke, id, managerID)
+	if err != nil {
+		return false, tracing.RecordError(span, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, tracing.RecordError(span, err)
+	}
+
+	return rows > 0, nil
+}
+
+func (repo *apiKeyRepository) GetActiveByPrefix(ctx context.Context, prefix string) (*entity.APIKey, error) {
+	ctx, span := tracing.StartDB(ctx, "APIKeyRepository.GetActiveByPrefix", queryGetByPrefix)
+	defer span.End()
+
+	var key entity.APIKey
+	err := repo.DB.GetContext(ctx, &key, queryGetByPrefix, prefix)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return &key, nil
+}
+
+func (repo *apiKeyRepository) TouchLastUsed(ctx context.Context, id int) error {
+	ctx, span := tracing.StartDB(ctx, "APIKeyRepository.TouchLastUsed", queryTouchLastUsed)
+	defer span.End()
+
+	_, err := repo.DB.ExecContext(ctx, queryTouchLastUsed, id)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+const (
+	// Keys look like ggm_<12 hex prefix>_<43 char secret>. The prefix is stored in clear to find
+	// the key and to let managers tell their keys apart; only a hash of the whole key is kept.
+	KeyTag       = "ggm_"
+	prefixLength = 12
+	secretSize   = 32
+)
+
+type apiKeyService struct {
+	repo      contracts.APIKeyRepository
+	validator validator.ValidatorInterface
+}
+
+func NewAPIKeyService(repo contracts.APIKeyRepository, validator validator.ValidatorInterface) contracts.APIKeyService {
+	return &apiKeyService{
+		repo:      repo,
+		validator: validator,
+	}
+}
+
+func (s *apiKeyService) Create(ctx context.Context, managerID int, req dto.CreateAPIKeyRequest) (dto.CreateAPIKeyRes, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Create")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
+	valErr := s.validator.Validate(req)
+	valSpan.End()
+	if valErr != nil {
+		return dto.CreateAPIKeyRes{}, valErr
+	}
+
+	prefix, rawKey, err := newKey()
+	if err != nil {
+		return dto.CreateAPIKeyRes{}, err
+	}
+
+	key := entity.APIKey{
+		ManagerID: managerID,
+		Name:      req.Name,
+		Prefix:    prefix,
+		KeyHash:   hashKey(rawKey),
+		Scopes:    strings.Join(req.Scopes, ","),
+	}
+
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		key.ExpiresAt = &expiresAt
+	}
+
+	created, err := s.repo.Create(ctx, key)
+	if err != nil {
+		return dto.CreateAPIKeyRes{}, err
+	}
+
+	log.InfoCtx(ctx, log.LogInfo{
+		"api_key_id": created.ID,
+		"scopes":     created.Scopes,
+	}, "[APIKeyService][Create] api key created")
+
+	return dto.CreateAPIKeyRes{
+		APIKeyRes: toRes(created),
+		Key:       rawKey,
+	}, nil
+}
+
+func (s *apiKeyService) List(ctx context.Context, managerID int) ([]dto.APIKeyRes, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.List")
+	defer span.End()
+
+	keys, err := s.repo.ListByManager(ctx, managerID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.APIKeyRes, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, toRes(key))
+	}
+
+	return res, nil
+}
+
+func (s *apiKeyService) Revoke(ctx context.Context, managerID, id int) error {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Revoke")
+	defer span.End()
+
+	revoked, err := s.repo.Revoke(ctx, managerID, id)
+	if err != nil {
+		return err
+	}
+
+	if !revoked {
+		return domain.ErrAPIKeyNotFound
+	}
+
+	log.InfoCtx(ctx, log.LogInfo{
+		"api_key_id": id,
+	}, "[APIKeyService][Revoke] api key revoked")
+
+	return nil
+}
+
+func (s *apiKeyService) Authenticate(ctx context.Context, rawKey string) (*entity.APIKey, error) {
+	ctx, span := tracing.Start(ctx, "APIKeyService.Authenticate")
+	defer span.End()
+
+	prefix, ok := parsePrefix(rawKey)
+	if !ok {
+		return nil, domain.ErrInvalidAPIKey
+	}
+
+	key, err := s.repo.GetActiveByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrInvalidAPIKey
+		}
+
+		return nil, err
+	}
+
+	if subtle.ConstantTimeCompare([]byte(key.KeyHash), []byte(hashKey(rawKey))) != 1 {
+		return nil, domain.ErrInvalidAPIKey
+	}
+
+	if err := s.repo.TouchLastUsed(ctx, key.ID); err != nil {
+		log.WarnCtx(ctx, log.LogInfo{
+			"api_key_id": key.ID,
+			"error":      err.Error(),
+		}, "[APIKeyService][Authenticate] failed to record key usage")
+	}
+
+	return key, nil
+}
+
+func newKey() (string, string, error) {
+	b := make([]byte, prefixLength/2+secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+
+	prefix := hex.EncodeToString(b[:prefixLength/2])
+	secret := base64.RawURLEncoding.EncodeToString(b[prefixLength/2:])
+
+	return prefix, KeyTag + prefix + "_" + secret, nil
+}
+
+func parsePrefix(rawKey string) (string, bool) {
+	rest, found := strings.CutPrefix(rawKey, KeyTag)
+	if !found || len(rest) <= prefixLength+1 || rest[prefixLength] != '_' {
+		return "", false
+	}
+
+	return rest[:prefixLength], true
+}
+
+func hashKey(rawKey string) string {
+	sum := sha256.Sum256([]byte(rawKey))
+	return hex.EncodeToString(sum[:])
+}
+
+func toRes(key entity.APIKey) dto.APIKeyRes {
+	return dto.APIKeyRes{
+		ID:         key.ID,
+		Name:       key.Name,
+		Prefix:     KeyTag + key.Prefix,
+		Scopes:     key.ScopeList(),
+		ExpiresAt:  key.ExpiresAt,
+		LastUsedAt: key.LastUsedAt,
+		RevokedAt:  key.RevokedAt,
+		CreatedAt:  key.CreatedAt,
+	}
+}
@@ -0,0 +1,58 @@
+package controller
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+)
+
+type auditController struct {
+	auditService contracts.AuditService
+}
+
+func InitNewController(router fiber.Router, auditService contracts.AuditService, middleware *middlewares.Middleware) {
+	controller := &auditController{
+		auditService: auditService,
+	}
+
+	route := router.Group("/v1/audit")
+	route.Get("/", middleware.RequireAdmin(enums.ScopeAuditRead), controller.list)
+	route.Get("/verify", middleware.RequireAdmin(enums.ScopeAuditRead), controller.verify)
+}
+
+func (c *auditController) list(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	query := dto.AuditQuery{Limit: 20}
+	if err := ctx.QueryParser(&query); err != nil {
+		return domain.ErrInvalidQueryParam
+	}
+
+	res, err := c.auditService.List(ctx.UserContext(), managerID, query)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+func (c *auditController) verify(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	query := dto.AuditVerifyQuery{Limit: 500}
+	if err := ctx.QueryParser(&query); err != nil {
+		return domain.ErrInvalidQueryParam
+	}
+
+	res, err := c.auditService.Verify(ctx.UserContext(), managerID, query)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
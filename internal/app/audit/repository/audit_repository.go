@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+// chainLock is the advisory lock serializing appends, so two events never link to the same predecessor
+const chainLock = 0x61756469
+
+const (
+	auditColumns = `id, tenant_id, actor_kind, actor_id, api_key_id, action, entity_type, entity_id,
+		before, after, ip, request_id, created_at, prev_hash, hash`
+
+	queryLock     = "SELECT pg_advisory_xact_lock($1)"
+	queryLastHash = "SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1"
+	queryAppend   = `
+	INSERT INTO audit_events (tenant_id, actor_kind, actor_id, api_key_id, action, entity_type, entity_id,
+		before, after, ip, request_id, created_at, prev_hash, hash)
+	VALUES (:tenant_id, :actor_kind, :actor_id, :api_key_id, :action, :entity_type, :entity_id,
+		:before, :after, :ip, :request_id, :created_at, :prev_hash, :hash)`
+	queryFindBase = "SELECT " + auditColumns + " FROM audit_events WHERE tenant_id = :tenant_id"
+	// The predecessor may belong to another tenant, only its hash is read
+	queryListLinks = `
+	SELECT ` + auditColumns + `,
+		(SELECT p.hash FROM audit_events p WHERE p.id < e.id ORDER BY p.id DESC LIMIT 1) AS predecessor_hash
+	FROM audit_events e
+	WHERE tenant_id = $1 AND id > $2
+	ORDER BY id LIMIT $3`
+)
+
+type auditRepository struct {
+	DB *sqlx.DB
+	tx dbtx.TransactorInterface
+}
+
+func NewAuditRepository(db *sqlx.DB) contracts.AuditRepository {
+	return &auditRepository{DB: db, tx: dbtx.NewTransactor(db)}
+}
+
+// Append joins the transaction in ctx when there is one. The chain lock is then held until the
+// mutation it records commits, so events are linked in the order they become visible.
+func (repo *auditRepository) Append(ctx context.Context, event entity.AuditEvent, seal func(entity.AuditEvent) string) error {
+	ctx, span := tracing.StartDB(ctx, "AuditRepository.Append", queryAppend)
+	defer span.End()
+
+	err := repo.tx.WithinTx(ctx, func(ctx context.Context) error {
+		tx := dbtx.From(ctx, repo.DB)
+
+		if _, err := tx.ExecContext(ctx, queryLock, chainLock); err != nil {
+			return err
+		}
+
+		err := tx.GetContext(ctx, &event.PrevHash, queryLastHash)
+		if errors.Is(err, sql.ErrNoRows) {
+			event.PrevHash = entity.AuditGenesisHash
+		} else if err != nil {
+			return err
+		}
+
+		event.Hash = seal(event)
+
+		_, err = tx.NamedExecContext(ctx, queryAppend, event)
+		return err
+	})
+
+	return tracing.RecordError(span, err)
+}
+
+func (repo *auditRepository) Find(ctx context.Context, filter dto.AuditFilter) ([]entity.AuditEvent, error) {
+	query := queryFindBase
+
+	args := map[string]interface{}{
+		"tenant_id": filter.TenantID,
+	}
+	if filter.ActorID != "" {
+		query += " AND actor_id = :actor_id"
+		args["actor_id"] = filter.ActorID
+	}
+	if filter.Action != "" {
+		query += " AND action = :action"
+		args["action"] = filter.Action
+	}
+	if filter.EntityType != "" {
+		query += " AND entity_type = :entity_type"
+		args["entity_type"] = filter.EntityType
+	}
+	if filter.EntityID != "" {
+		query += " AND entity_id = :entity_id"
+		args["entity_id"] = filter.EntityID
+	}
+	if filter.From != nil {
+		query += " AND created_at >= :from"
+		args["from"] = *filter.From
+	}
+	if filter.To != nil {
+		query += " AND created_at < :to"
+		args["to"] = *filter.To
+	}
+
+	query += " ORDER BY id DESC LIMIT :limit OFFSET :offset"
+	args["limit"] = filter.Limit
+	args["offset"] = filter.Offset
+
+	finalQuery, finalArgs, err := sqlx.Named(query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	finalQuery = repo.DB.Rebind(finalQuery)
+
+	ctx, span := tracing.StartDB(ctx, "AuditRepository.Find", finalQuery)
+	defer span.End()
+
+	events := []entity.AuditEvent{}
+	if err := repo.DB.SelectContext(ctx, &events, finalQuery, finalArgs...); err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return events, nil
+}
+
+func (repo *auditRepository) ListLinks(ctx context.Context, tenantID int, afterID int64, limit int) ([]entity.AuditLink, error) {
+	ctx, span := tracing.StartDB(ctx, "AuditRepository.ListLinks", queryListLinks)
+	defer span.End()
+
+	links := []entity.AuditLink{}
+	if err := repo.DB.SelectContext(ctx, &links, queryListLinks, tenantID, afterID, limit); err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return links, nil
+}
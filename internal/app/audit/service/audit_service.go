@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+const actorAnonymous = "anonymous"
+
+// redactedFields never reach the log, whatever the entity calls them
+var redactedFields = map[string]bool{
+	"password": true,
+}
+
+type auditService struct {
+	repo      contracts.AuditRepository
+	validator validator.ValidatorInterface
+}
+
+func NewAuditService(repo contracts.AuditRepository, validator validator.ValidatorInterface) contracts.AuditService {
+	return &auditService{
+		repo:      repo,
+		validator: validator,
+	}
+}
+
+func (s *auditService) Record(ctx context.Context, record dto.AuditRecord) error {
+	ctx, span := tracing.Start(ctx, "AuditService.Record")
+	defer span.End()
+
+	return tracing.RecordError(span, s.append(ctx, record))
+}
+
+func (s *auditService) append(ctx context.Context, record dto.AuditRecord) error {
+
+	principal, ok := reqctx.GetPrincipal(ctx)
+	if record.Actor != nil {
+		principal, ok = *record.Actor, true
+	}
+
+	if !ok {
+		principal.Kind = actorAnonymous
+	}
+
+	before, after, err := diff(record.Before, record.After)
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Append(ctx, entity.AuditEvent{
+		TenantID:   optional(principal.TenantID),
+		ActorKind:  principal.Kind,
+		ActorID:    principal.UserID,
+		APIKeyID:   optional(principal.APIKeyID),
+		Action:     record.Action.String(),
+		EntityType: record.EntityType,
+		EntityID:   record.EntityID,
+		Before:     before,
+		After:      after,
+		IP:         reqctx.ClientIP(ctx),
+		RequestID:  reqctx.RequestID(ctx),
+		// Postgres keeps microseconds, the hash has to survive the round trip
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}, hashEvent)
+}
+
+func (s *auditService) List(ctx context.Context, tenantID int, query dto.AuditQuery) ([]dto.AuditEventRes, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.List")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
+	valErr := s.validator.Validate(query)
+	valSpan.End()
+	if valErr != nil {
+		return nil, valErr
+	}
+
+	filter := dto.AuditFilter{
+		TenantID:   tenantID,
+		ActorID:    query.ActorID,
+		Action:     query.Action,
+		EntityType: query.EntityType,
+		EntityID:   query.EntityID,
+		Limit:      query.Limit,
+		Offset:     query.Offset,
+	}
+
+	// The validator already checked the format
+	if query.From != "" {
+		from, _ := time.Parse(time.RFC3339, query.From)
+		filter.From = &from
+	}
+	if query.To != "" {
+		to, _ := time.Parse(time.RFC3339, query.To)
+		filter.To = &to
+	}
+
+	events, err := s.repo.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.AuditEventRes, 0, len(events))
+	for _, event := range events {
+		item := dto.AuditEventRes{
+			ID:         event.ID,
+			ActorKind:  event.ActorKind,
+			ActorID:    event.ActorID,
+			APIKeyID:   event.APIKeyID,
+			Action:     event.Action,
+			EntityType: event.EntityType,
+			EntityID:   event.EntityID,
+			IP:         event.IP,
+			RequestID:  event.RequestID,
+			CreatedAt:  event.CreatedAt,
+			Hash:       event.Hash,
+		}
+
+		if event.Before != nil {
+			if err := json.Unmarshal(event.Before, &item.Before); err != nil {
+				return nil, err
+			}
+		}
+		if event.After != nil {
+			if err := json.Unmarshal(event.After, &item.After); err != nil {
+				return nil, err
+			}
+		}
+
+		res = append(res, item)
+	}
+
+	return res, nil
+}
+
+// Verify checks a page of the tenant's events, recomputing each hash and comparing each link
+// with the event before it in the chain, whichever tenant's it is. The chain is shared, but a
+// tenant only ever reads its own events and a bounded number of them per request.
+func (s *auditService) Verify(ctx context.Context, tenantID int, query dto.AuditVerifyQuery) (dto.AuditVerifyRes, error) {
+	ctx, span := tracing.Start(ctx, "AuditService.Verify")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
+	valErr := s.validator.Validate(query)
+	valSpan.End()
+	if valErr != nil {
+		return dto.AuditVerifyRes{}, valErr
+	}
+
+	links, err := s.repo.ListLinks(ctx, tenantID, query.AfterID, query.Limit)
+	if err != nil {
+		return dto.AuditVerifyRes{}, err
+	}
+
+	res := dto.AuditVerifyRes{Valid: true}
+	for _, link := range links {
+		predecessor := entity.AuditGenesisHash
+		if link.PredecessorHash != nil {
+			predecessor = *link.PredecessorHash
+		}
+
+		if link.PrevHash != predecessor || hashEvent(link.AuditEvent) != link.Hash {
+			id := link.ID
+			res.Valid = false
+			res.BrokenAt = &id
+
+			log.WarnCtx(ctx, log.LogInfo{
+				"event_id": link.ID,
+			}, "[AuditService][Verify] audit chain is broken")
+
+			return res, nil
+		}
+
+		res.Head = link.Hash
+		res.Checked++
+	}
+
+	if len(links) == query.Limit {
+		next := links[len(links)-1].ID
+		res.Next = &next
+	}
+
+	return res, nil
+}
+
+// hashEvent chains an event to its predecessor. The fields are encoded as JSON in a fixed
+// order; changing this encoding invalidates every existing chain.
+func hashEvent(event entity.AuditEvent) string {
+	payload, _ := json.Marshal(struct {
+		PrevHash   string          `json:"prev_hash"`
+		TenantID   *int            `json:"tenant_id"`
+		ActorKind  string          `json:"actor_kind"`
+		ActorID    string          `json:"actor_id"`
+		APIKeyID   *int            `json:"api_key_id"`
+		Action     string          `json:"action"`
+		EntityType string          `json:"entity_type"`
+		EntityID   string          `json:"entity_id"`
+		Before     json.RawMessage `json:"before"`
+		After      json.RawMessage `json:"after"`
+		IP         string          `json:"ip"`
+		RequestID  string          `json:"request_id"`
+		CreatedAt  string          `json:"created_at"`
+	}{
+		PrevHash:   event.PrevHash,
+		TenantID:   event.TenantID,
+		ActorKind:  event.ActorKind,
+		ActorID:    event.ActorID,
+		APIKeyID:   event.APIKeyID,
+		Action:     event.Action,
+		EntityType: event.EntityType,
+		EntityID:   event.EntityID,
+		Before:     event.Before,
+		After:      event.After,
+		IP:         event.IP,
+		RequestID:  event.RequestID,
+		CreatedAt:  event.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// diff encodes both states as JSON objects without redacted fields and, when there are two,
+// drops the fields they agree on
+func diff(before, after any) ([]byte, []byte, error) {
+	beforeFields, err := fields(before)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	afterFields, err := fields(after)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if beforeFields != nil && afterFields != nil {
+		for name, value := range beforeFields {
+			if other, ok := afterFields[name]; ok && reflect.DeepEqual(value, other) {
+				delete(beforeFields, name)
+				delete(afterFields, name)
+			}
+		}
+	}
+
+	beforeJSON, err := encode(beforeFields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	afterJSON, err := encode(afterFields)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return beforeJSON, afterJSON, nil
+}
+
+func fields(v any) (map[string]any, error) {
+	if v == nil || reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil() {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var out map[string]any
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return nil, err
+	}
+
+	for name := range out {
+		if redactedFields[strings.ToLower(name)] {
+			delete(out, name)
+		}
+	}
+
+	return out, nil
+}
+
+// encode sorts the keys, so equal states always produce the same bytes
+func encode(fields map[string]any) ([]byte, error) {
+	if fields == nil {
+		return nil, nil
+	}
+
+	return json.Marshal(fields)
+}
+
+func optional(id int) *int {
+	if id == 0 {
+		return nil
+	}
+
+	return &id
+}
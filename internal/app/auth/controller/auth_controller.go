@@ -27,7 +27,7 @@ func (ac *authController) registerUser(ctx *fiber.Ctx) error {
 		return err
 	}
 
-	res, err := ac.authService.RegisterUser(ctx.Context(), req)
+	res, err := ac.authService.RegisterUser(ctx.UserContext(), req)
 	if err != nil {
 		return err
 	}
@@ -41,7 +41,7 @@ func (ac *authController) loginUser(ctx *fiber.Ctx) error {
 		return err
 	}
 
-	res, err := ac.authService.LoginUser(ctx.Context(), req)
+	res, err := ac.authService.LoginUser(ctx.UserContext(), req)
 	if err != nil {
 		return err
 	}
@@ -7,6 +7,29 @@ import (
 	"github.com/jmoiron/sqlx"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	queryGetUserByEmail = `
+	SELECT
+		u.id AS "id",
+		u.name AS "name",
+		u.email AS "email",
+		u.password AS "password",
+		u.role_id AS "role_id",
+		u.created_at AS "created_at",
+		u.updated_at AS "updated_at",
+		u.deleted_at AS "deleted_at",
+		r.id AS "role.id",
+		r.name AS "role.name"
+	FROM users u
+	LEFT JOIN roles r ON u.role_id = r.id
+	WHERE email = $1
+	AND deleted_at IS NULL`
+	queryRegisterUser = "INSERT INTO users (id, email, password, name) VALUES ($1, $2, $3, $4)"
+	// Only replaces the hash it was computed from, so a concurrent password change wins
+	queryRehashPassword = "UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2 AND password = $3"
 )
 
 type authRepository struct {
@@ -21,43 +44,45 @@ func NewAuthRepository(conn *sqlx.DB) contracts.AuthRepository {
 }
 
 func (a *authRepository) GetUserByEmail(ctx context.Context, email string) (*entity.User, error) {
+	ctx, span := tracing.StartDB(ctx, "AuthRepository.GetUserByEmail", queryGetUserByEmail)
+	defer span.End()
+
 	var user entity.User
-	err := a.conn.GetContext(ctx, &user, `
-		SELECT
-			u.id AS "id",
-			u.name AS "name",
-			u.email AS "email",
-			u.password AS "password",
-			u.role_id AS "role_id",
-			u.created_at AS "created_at",
-			u.updated_at AS "updated_at",
-			u.deleted_at AS "deleted_at",
-			r.id AS "role.id",
-			r.name AS "role.name"
-		FROM users u
-		LEFT JOIN roles r ON u.role_id = r.id
-		WHERE email = $1
-		AND deleted_at IS NULL
-	`, email)
+	err := a.conn.GetContext(ctx, &user, queryGetUserByEmail, email)
 	if err != nil {
-		return nil, err
+		return nil, tracing.RecordError(span, err)
 	}
 
 	return &user, nil
 }
 
 func (a *authRepository) RegisterUser(ctx context.Context, user entity.User) (uuid.UUID, error) {
+	ctx, span := tracing.StartDB(ctx, "AuthRepository.RegisterUser", queryRegisterUser)
+	defer span.End()
+
 	_, err := a.conn.ExecContext(
 		ctx,
-		"INSERT INTO users (id, email, password, name) VALUES ($1, $2, $3, $4)",
+		queryRegisterUser,
 		user.ID,
 		user.Email,
 		user.Password,
 		user.Name,
 	)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, tracing.RecordError(span, err)
 	}
 
 	return user.ID, nil
 }
+
+func (a *authRepository) RehashPassword(ctx context.Context, id uuid.UUID, oldHash, newHash string) error {
+	ctx, span := tracing.StartDB(ctx, "AuthRepository.RehashPassword", queryRehashPassword)
+	defer span.End()
+
+	_, err := a.conn.ExecContext(ctx, queryRehashPassword, newHash, id, oldHash)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return nil
+}
@@ -5,12 +5,14 @@ import (
 	"database/sql"
 	"errors"
 
-	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
-	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/bcrypt"
+	lockoutSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/lockout/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/hasher"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/uuid"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
 )
@@ -20,7 +22,9 @@ type authService struct {
 	validator validator.ValidatorInterface
 	uuid      uuid.UUIDInterface
 	jwt       jwt.JwtInterface
-	bcrypt    bcrypt.BcryptInterface
+	hasher    hasher.PasswordHasher
+	lockout   contracts.LockoutService
+	mfa       contracts.MFAService
 }
 
 func NewAuthService(
@@ -28,19 +32,28 @@ func NewAuthService(
 	validator validator.ValidatorInterface,
 	uuid uuid.UUIDInterface,
 	jwt jwt.JwtInterface,
-	bcrypt bcrypt.BcryptInterface,
+	hasher hasher.PasswordHasher,
+	lockout contracts.LockoutService,
+	mfa contracts.MFAService,
 ) contracts.AuthService {
 	return &authService{
 		authRepo:  authRepo,
 		validator: validator,
 		uuid:      uuid,
 		jwt:       jwt,
-		bcrypt:    bcrypt,
+		hasher:    hasher,
+		lockout:   lockout,
+		mfa:       mfa,
 	}
 }
 
 func (s *authService) RegisterUser(ctx context.Context, req dto.RegisterRequest) (dto.RegisterResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.RegisterUser")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := s.validator.Validate(req)
+	valSpan.End()
 	if valErr != nil {
 		return dto.RegisterResponse{}, valErr
 	}
@@ -59,7 +72,7 @@ func (s *authService) RegisterUser(ctx context.Context, req dto.RegisterRequest)
 		return dto.RegisterResponse{}, err
 	}
 
-	hashedPassword, err := s.bcrypt.Hash(req.Password)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return dto.RegisterResponse{}, err
 	}
@@ -84,26 +97,61 @@ func (s *authService) RegisterUser(ctx context.Context, req dto.RegisterRequest)
 }
 
 func (s *authService) LoginUser(ctx context.Context, req dto.LoginRequest) (dto.LoginResponse, error) {
+	ctx, span := tracing.Start(ctx, "AuthService.LoginUser")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := s.validator.Validate(req)
+	valSpan.End()
 	if valErr != nil {
 		return dto.LoginResponse{}, valErr
 	}
 
+	account := lockoutSvc.Account(lockoutSvc.KindUser, req.Email)
+	if err := s.lockout.Check(ctx, account); err != nil {
+		return dto.LoginResponse{}, err
+	}
+
 	user, err := s.authRepo.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return dto.LoginResponse{}, domain.ErrEmailNotFound
+			// Same cost and error as a wrong password, so unknown emails can't be told apart
+			s.hasher.CompareDummy(req.Password)
+			return dto.LoginResponse{}, s.lockout.Fail(ctx, account)
 		}
 
 		return dto.LoginResponse{}, err
 	}
 
-	isValid := s.bcrypt.Compare(req.Password, user.Password)
+	isValid, needsRehash := s.hasher.Verify(req.Password, user.Password)
 	if !isValid {
-		return dto.LoginResponse{}, domain.ErrCredentialsNotMatch
+		return dto.LoginResponse{}, s.lockout.Fail(ctx, account)
+	}
+
+	if needsRehash {
+		s.rehash(ctx, user, req.Password)
+	}
+
+	// With 2FA the failures are only cleared once the second factor checks out
+	challenge, err := s.mfa.Challenge(ctx, dto.MFASubject{
+		Kind:     lockoutSvc.KindUser,
+		ID:       user.ID.String(),
+		Email:    user.Email,
+		RoleName: user.Role.Name,
+	})
+	if err != nil {
+		return dto.LoginResponse{}, err
 	}
 
-	accessToken, err := s.jwt.Create(user.ID, user.Role.Name)
+	if challenge != nil {
+		return dto.LoginResponse{MFA: challenge}, nil
+	}
+
+	if err := s.lockout.Succeed(ctx, account); err != nil {
+		return dto.LoginResponse{}, err
+	}
+
+	accessToken, err := s.jwt.Create(user.ID, user.Email, user.Role.Name)
 	if err != nil {
 		return dto.LoginResponse{}, err
 	}
@@ -114,3 +162,19 @@ func (s *authService) LoginUser(ctx context.Context, req dto.LoginRequest) (dto.
 
 	return res, nil
 }
+
+// rehash upgrades a password hash made with an older algorithm or weaker costs. The login
+// goes on with the old hash if it fails.
+func (s *authService) rehash(ctx context.Context, user *entity.User, password string) {
+	newHash, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.authRepo.RehashPassword(ctx, user.ID, user.Password, newHash)
+	}
+
+	if err != nil {
+		log.WarnCtx(ctx, log.LogInfo{
+			"user_id": user.ID.String(),
+			"error":   err.Error(),
+		}, "[AuthService][rehash] failed to upgrade password hash")
+	}
+}
@@ -4,160 +4,150 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/etag"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/metrics"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
 )
 
 type departmentController struct {
 	service    contracts.DepartmentService
 	middleware *middlewares.Middleware
+	metrics    metrics.MetricsInterface
 }
 
 func InitNewController(
 	router fiber.Router,
 	departmentService contracts.DepartmentService,
 	middleware *middlewares.Middleware,
+	metrics metrics.MetricsInterface,
 ) {
 	controller := &departmentController{
 		service:    departmentService,
 		middleware: middleware,
+		metrics:    metrics,
 	}
 
 	route := router.Group("/v1")
 
-	route.Post("/department", middleware.RequireAdmin(), controller.Create)
-	route.Get("/department", middleware.RequireAdmin(), controller.Get)
-	route.Patch("/department/:departmentid", middleware.RequireAdmin(), controller.Update)
-	route.Patch("/department/", middleware.RequireAdmin(), func(ctx *fiber.Ctx) error {
-		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Department ID is required",
-		})
+	route.Post("/department", middleware.RequireAdmin(enums.ScopeDepartmentWrite), middleware.Idempotent(), controller.Create)
+	route.Get("/department", middleware.RequireAdmin(enums.ScopeRead), controller.Get)
+	route.Get("/department/:departmentid", middleware.RequireAdmin(enums.ScopeRead), controller.GetByID)
+	route.Patch("/department/:departmentid", middleware.RequireAdmin(enums.ScopeDepartmentWrite), middleware.Precondition(), controller.Update)
+	route.Patch("/department/", middleware.RequireAdmin(enums.ScopeDepartmentWrite), func(ctx *fiber.Ctx) error {
+		return domain.ErrDepartmentIDRequired
 	})
-	route.Delete("/department/:departmentid", middleware.RequireAdmin(), controller.Delete)
-	route.Delete("/department/", middleware.RequireAdmin(), func(ctx *fiber.Ctx) error {
-		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Department ID is required",
-		})
+	route.Delete("/department/:departmentid", middleware.RequireAdmin(enums.ScopeDepartmentWrite), middleware.Precondition(), controller.Delete)
+	route.Delete("/department/", middleware.RequireAdmin(enums.ScopeDepartmentWrite), func(ctx *fiber.Ctx) error {
+		return domain.ErrDepartmentIDRequired
 	})
 }
 
 func (c *departmentController) Create(ctx *fiber.Ctx) error {
-	var requestBody struct {
-		ManagerID int    `json:"managerId"`
-		Name      string `json:"name"`
-	} // manager id need to get from token
-
-	if err := ctx.BodyParser(&requestBody); err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	var req dto.DepartmentReq
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
 	}
 
 	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
-	requestBody.ManagerID = managerID
 
-	departmentRes, err := c.service.Create(ctx.Context(), requestBody.ManagerID, requestBody.Name)
+	departmentRes, err := c.service.Create(ctx.UserContext(), managerID, req.Name)
 	if err != nil {
 		return err
 	}
 
-	return ctx.Status(fiber.StatusCreated).JSON(departmentRes)
+	c.metrics.DepartmentCreated(strconv.Itoa(managerID))
+
+	return response.SendResponse(ctx, fiber.StatusCreated, departmentRes)
 }
 func (c *departmentController) Update(ctx *fiber.Ctx) error {
-	var requestBody struct {
-		Name string `json:"name"`
-	}
-
-	if err := ctx.BodyParser(&requestBody); err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+	var req dto.DepartmentReq
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
 	}
 
 	departmentID := ctx.Params("departmentid")
 	id, err := strconv.Atoi(departmentID)
 	if err != nil {
-		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Invalid department ID",
-		})
+		return domain.ErrInvalidDepartmentID
 	}
 
-	departmentRes, err := c.service.Update(ctx.Context(), id, requestBody.Name)
+	departmentRes, err := c.service.Update(ctx.UserContext(), reqctx.TenantID(ctx.UserContext()), id, req.Name, ctx.Get(fiber.HeaderIfMatch))
 	if err != nil {
 		return err
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(departmentRes)
+	return etag.SendResponse(ctx, fiber.StatusOK, departmentRes.Version, departmentRes)
 }
-func (c *departmentController) Get(ctx *fiber.Ctx) error {
-	limit, err := strconv.Atoi(ctx.Query("limit", "5"))
+
+func (c *departmentController) GetByID(ctx *fiber.Ctx) error {
+	id, err := strconv.Atoi(ctx.Params("departmentid"))
 	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid limit query parameter",
-		})
+		return domain.ErrInvalidDepartmentID
 	}
 
-	offset, err := strconv.Atoi(ctx.Query("offset", "0"))
+	departmentRes, err := c.service.FindOwned(ctx.UserContext(), reqctx.TenantID(ctx.UserContext()), id)
 	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid offset query parameter",
-		})
+		return err
 	}
 
-	name := ctx.Query("name", "")
+	return etag.SendResponse(ctx, fiber.StatusOK, departmentRes.Version, departmentRes)
+}
+func (c *departmentController) Get(ctx *fiber.Ctx) error {
+	query := dto.DepartmentQuery{Limit: 5}
+	if err := ctx.QueryParser(&query); err != nil {
+		return domain.ErrInvalidQueryParam
+	}
+
+	limit, offset, name := query.Limit, query.Offset, query.Name
 
 	var departmentRes []dto.DepartmentRes
 
 	if name != "" {
-		departments, err := c.service.FindByName(ctx.Context(), limit, offset, name)
+		departments, err := c.service.FindByName(ctx.UserContext(), limit, offset, name)
 		if err != nil {
-			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": err.Error(),
-			})
+			return err
 		}
 
 		for _, dept := range departments {
-			departmentRes = append(departmentRes, dto.DepartmentRes{
-				ID:   dept.ID,
-				Name: dept.Name,
-			})
+			departmentRes = append(departmentRes, *dept)
 		}
 	} else {
-		departments, err := c.service.FindAll(ctx.Context(), limit, offset)
+		departments, err := c.service.FindAll(ctx.UserContext(), limit, offset)
 		if err != nil {
-			return ctx.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": err.Error(),
-			})
+			return err
 		}
 
 		for _, dept := range departments {
-			departmentRes = append(departmentRes, dto.DepartmentRes{
-				ID:   dept.ID,
-				Name: dept.Name,
-			})
+			departmentRes = append(departmentRes, *dept)
 		}
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(departmentRes)
+	return response.SendResponse(ctx, fiber.StatusOK, departmentRes)
 }
 
 func (c *departmentController) Delete(ctx *fiber.Ctx) error {
 	departmentID := ctx.Params("departmentid")
 	id, err := strconv.Atoi(departmentID)
 	if err != nil {
-		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Invalid department ID",
-		})
+		return domain.ErrInvalidDepartmentID
 	}
 
-	err = c.service.Delete(ctx.Context(), id)
+	err = c.service.Delete(ctx.UserContext(), reqctx.TenantID(ctx.UserContext()), id, ctx.Get(fiber.HeaderIfMatch))
 	if err != nil {
 		return err
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+	c.metrics.DepartmentDeleted(strconv.Itoa(managerID))
+
+	return response.SendResponse(ctx, fiber.StatusOK, fiber.Map{
 		"message": "Department deleted successfully",
 	})
 }
@@ -2,10 +2,13 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
 )
 
 type departmentRepository struct {
@@ -14,11 +17,20 @@ type departmentRepository struct {
 
 const (
 	queryCreate                 = "INSERT INTO departments (name, manager_id, created_at) VALUES($1, $2, $3) RETURNING id"
-	queryDelete                 = "DELETE FROM departments WHERE id = $1"
-	queryFindAllWithLimitOffset = "SELECT * FROM departments LIMIT $1 OFFSET $2"
+	queryDelete                 = "DELETE FROM departments WHERE id = $1 AND version = $2"
+	queryFindAllWithLimitOffset = "SELECT * FROM departments ORDER BY id LIMIT $1 OFFSET $2"
 	queryFindAll                = "SELECT * FROM departments"
-	queryFindByName             = "SELECT * FROM departments WHERE name ILIKE $1 ORDER BY name ASC LIMIT $2 OFFSET $3"
-	queryUpdate                 = "UPDATE departments SET name = $1 WHERE id = $2"
+	queryFindByName             = "SELECT * FROM departments WHERE name ILIKE $1 ORDER BY name ASC, id LIMIT $2 OFFSET $3"
+	queryFindByID               = "SELECT * FROM departments WHERE id = $1"
+	queryFindByIDs              = "SELECT * FROM departments WHERE id = ANY($1)"
+	queryFindByManager          = "SELECT * FROM departments WHERE manager_id = $1 ORDER BY id LIMIT $2 OFFSET $3"
+	queryCountByManager         = "SELECT COUNT(*) FROM departments WHERE manager_id = $1"
+	querySearchByManager        = "SELECT * FROM departments WHERE manager_id = $1 AND name ILIKE $2 ORDER BY name ASC, id LIMIT $3 OFFSET $4"
+	queryFindByManagerAndName   = "SELECT * FROM departments WHERE manager_id = $1 AND LOWER(name) = LOWER($2) ORDER BY id LIMIT $3 OFFSET $4"
+	queryCountByManagerAndName  = "SELECT COUNT(*) FROM departments WHERE manager_id = $1 AND LOWER(name) = LOWER($2)"
+	queryUpdate                 = `
+	UPDATE departments SET name = $1, version = version + 1, updated_at = NOW()
+	WHERE id = $2 AND version = $3 RETURNING version`
 )
 
 func NewDepartmentRepository(db *sqlx.DB) contracts.DepartmentRepository {
@@ -26,77 +38,181 @@ func NewDepartmentRepository(db *sqlx.DB) contracts.DepartmentRepository {
 }
 
 func (repo *departmentRepository) Create(ctx context.Context, data entity.Department) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "DepartmentRepository.Create", queryCreate)
+	defer span.End()
+
 	var id int
 
-	err := repo.DB.QueryRowContext(ctx, queryCreate, data.Name, data.ManagerID, data.CreatedAt).Scan(&id)
+	err := dbtx.From(ctx, repo.DB).QueryRowContext(ctx, queryCreate, data.Name, data.ManagerID, data.CreatedAt).Scan(&id)
 	if err != nil {
-		return 0, err
+		return 0, tracing.RecordError(span, err)
 	}
 
 	return id, nil
 }
 
-func (repo *departmentRepository) Delete(ctx context.Context, id int) error {
-	_, err := repo.DB.ExecContext(ctx, queryDelete, id)
+func (repo *departmentRepository) Delete(ctx context.Context, id, version int) error {
+	ctx, span := tracing.StartDB(ctx, "DepartmentRepository.Delete", queryDelete)
+	defer span.End()
+
+	result, err := dbtx.From(ctx, repo.DB).ExecContext(ctx, queryDelete, id, version)
 	if err != nil {
-		return err
+		return tracing.RecordError(span, err)
+	}
+
+	rowsAffected, _ := result.RowsAffected()
+	if rowsAffected == 0 {
+		return tracing.RecordError(span, sql.ErrNoRows)
 	}
 
 	return nil
 }
 
 func (repo *departmentRepository) FindAll(ctx context.Context) ([]*entity.Department, error) {
+	ctx, span := tracing.StartDB(ctx, "DepartmentRepository.FindAll", queryFindAll)
+	defer span.End()
+
 	var listDepartment []*entity.Department
 
-	err := repo.DB.SelectContext(ctx, &listDepartment, queryFindAll)
+	err := dbtx.From(ctx, repo.DB).SelectContext(ctx, &listDepartment, queryFindAll)
 	if err != nil {
-		return nil, err
+		return nil, tracing.RecordError(span, err)
 	}
 
 	return listDepartment, nil
 }
 
 func (repo *departmentRepository) FindByName(ctx context.Context, name string, limit, offset int) ([]*entity.Department, error) {
+	ctx, span := tracing.StartDB(ctx, "DepartmentRepository.FindByName", queryFindByName)
+	defer span.End()
 
 	var listDepartment []*entity.Department
 	searchTerm := "%" + name + "%"
-	err := repo.DB.SelectContext(ctx, &listDepartment, queryFindByName, searchTerm, limit, offset)
+	err := dbtx.From(ctx, repo.DB).SelectContext(ctx, &listDepartment, queryFindByName, searchTerm, limit, offset)
 	if err != nil {
-		return nil, err
+		return nil, tracing.RecordError(span, err)
 	}
 
 	return listDepartment, nil
 }
 
-func (repo *departmentRepository) Update(ctx context.Context, id int, newName string) (int, error) {
-	result, err := repo.DB.ExecContext(ctx, queryUpdate, newName, id)
+func (repo *departmentRepository) Update(ctx context.Context, id, version int, newName string) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "DepartmentRepository.Update", queryUpdate)
+	defer span.End()
+
+	var newVersion int
+	err := dbtx.From(ctx, repo.DB).GetContext(ctx, &newVersion, queryUpdate, newName, id, version)
 	if err != nil {
-		return 0, err
+		return 0, tracing.RecordError(span, err)
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-
-	return int(rowsAffected), nil
+	return newVersion, nil
 }
 
 func (repo *departmentRepository) FindAllWithLimitOffset(ctx context.Context, limit int, offset int) ([]*entity.Department, error) {
+	ctx, span := tracing.StartDB(ctx, "DepartmentRepository.FindAllWithLimitOffset", queryFindAllWithLimitOffset)
+	defer span.End()
+
 	var listDepartment []*entity.Department
 
-	err := repo.DB.SelectContext(ctx, &listDepartment, queryFindAllWithLimitOffset, limit, offset)
+	err := dbtx.From(ctx, repo.DB).SelectContext(ctx, &listDepartment, queryFindAllWithLimitOffset, limit, offset)
 	if err != nil {
-		return nil, err
+		return nil, tracing.RecordError(span, err)
 	}
 
 	return listDepartment, nil
 }
 
 func (repo *departmentRepository) FindByID(ctx context.Context, id int) (*entity.Department, error) {
+	ctx, span := tracing.StartDB(ctx, "DepartmentRepository.FindByID", queryFindByID)
+	defer span.End()
+
 	var department entity.Department
 
-	err := repo.DB.GetContext(ctx, &department, "SELECT * FROM departments WHERE id = $1", id)
+	err := dbtx.From(ctx, repo.DB).GetContext(ctx, &department, queryFindByID, id)
 	if err != nil {
-		return nil, err
+		return nil, tracing.RecordError(span, err)
 	}
 
 	return &department, nil
 }
+
+func (repo *departmentRepository) FindByIDs(ctx context.Context, ids []int) ([]*entity.Department, error) {
+	ctx, span := tracing.StartDB(ctx, "DepartmentRepository.FindByIDs", queryFindByIDs)
+	defer span.End()
+
+	var listDepartment []*entity.Department
+
+	err := dbtx.From(ctx, repo.DB).SelectContext(ctx, &listDepartment, queryFindByIDs, ids)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return listDepartment, nil
+}
+
+func (repo *departmentRepository) FindByManager(ctx context.Context, managerID, limit, offset int) ([]*entity.Department, error) {
+	ctx, span := tracing.StartDB(ctx, "DepartmentRepository.FindByManager", queryFindByManager)
+	defer span.End()
+
+	var listDepartment []*entity.Department
+
+	err := dbtx.From(ctx, repo.DB).SelectContext(ctx, &listDepartment, queryFindByManager, managerID, limit, offset)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return listDepartment, nil
+}
+
+func (repo *departmentRepository) CountByManager(ctx context.Context, managerID int) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "DepartmentRepository.CountByManager", queryCountByManager)
+	defer span.End()
+
+	var count int
+	if err := dbtx.From(ctx, repo.DB).GetContext(ctx, &count, queryCountByManager, managerID); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	return count, nil
+}
+
+func (repo *departmentRepository) FindByManagerAndName(ctx context.Context, managerID int, name string, limit, offset int) ([]*entity.Department, error) {
+	ctx, span := tracing.StartDB(ctx, "DepartmentRepository.FindByManagerAndName", queryFindByManagerAndName)
+	defer span.End()
+
+	var listDepartment []*entity.Department
+
+	err := dbtx.From(ctx, repo.DB).SelectContext(ctx, &listDepartment, queryFindByManagerAndName, managerID, name, limit, offset)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return listDepartment, nil
+}
+
+func (repo *departmentRepository) CountByManagerAndName(ctx context.Context, managerID int, name string) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "DepartmentRepository.CountByManagerAndName", queryCountByManagerAndName)
+	defer span.End()
+
+	var count int
+	if err := dbtx.From(ctx, repo.DB).GetContext(ctx, &count, queryCountByManagerAndName, managerID, name); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	return count, nil
+}
+
+func (repo *departmentRepository) SearchByManager(ctx context.Context, managerID int, name string, limit, offset int) ([]*entity.Department, error) {
+	ctx, span := tracing.StartDB(ctx, "DepartmentRepository.SearchByManager", querySearchByManager)
+	defer span.End()
+
+	var listDepartment []*entity.Department
+	searchTerm := "%" + name + "%"
+	err := dbtx.From(ctx, repo.DB).SelectContext(ctx, &listDepartment, querySearchByManager, managerID, searchTerm, limit, offset)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return listDepartment, nil
+}
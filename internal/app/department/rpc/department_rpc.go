@@ -0,0 +1,184 @@
+package rpc
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/grpc/request"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/metrics"
+	pb "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/pb/gogomanager/v1"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+	"google.golang.org/grpc"
+)
+
+// Scopes are the scopes an API key needs for each method, the same as for the matching route
+var Scopes = middlewares.GRPCScopes{
+	pb.DepartmentService_CreateDepartment_FullMethodName:  {enums.ScopeDepartmentWrite},
+	pb.DepartmentService_GetDepartment_FullMethodName:     {enums.ScopeRead},
+	pb.DepartmentService_ListDepartments_FullMethodName:   {enums.ScopeRead},
+	pb.DepartmentService_StreamDepartments_FullMethodName: {enums.ScopeRead},
+	pb.DepartmentService_UpdateDepartment_FullMethodName:  {enums.ScopeDepartmentWrite},
+	pb.DepartmentService_DeleteDepartment_FullMethodName:  {enums.ScopeDepartmentWrite},
+}
+
+// departmentServer serves the calling manager's departments alone, like SCIM does. Those of other
+// managers are not found.
+type departmentServer struct {
+	pb.UnimplementedDepartmentServiceServer
+	service contracts.DepartmentService
+	metrics metrics.MetricsInterface
+	// requireVersion rejects changes made without a version, like If-Match on REST
+	requireVersion bool
+}
+
+// Register serves the department service on registrar, over the service the REST endpoints use
+func Register(
+	registrar grpc.ServiceRegistrar,
+	departmentService contracts.DepartmentService,
+	metrics metrics.MetricsInterface,
+	requireVersion bool,
+) {
+	pb.RegisterDepartmentServiceServer(registrar, &departmentServer{
+		service:        departmentService,
+		metrics:        metrics,
+		requireVersion: requireVersion,
+	})
+}
+
+func (s *departmentServer) CreateDepartment(ctx context.Context, req *pb.CreateDepartmentRequest) (*pb.Department, error) {
+	managerID := reqctx.TenantID(ctx)
+
+	res, err := s.service.Create(ctx, managerID, req.GetName())
+	if err != nil {
+		return nil, err
+	}
+
+	s.metrics.DepartmentCreated(strconv.Itoa(managerID))
+
+	return toDepartment(res), nil
+}
+
+func (s *departmentServer) GetDepartment(ctx context.Context, req *pb.GetDepartmentRequest) (*pb.Department, error) {
+	id, err := departmentID(req.GetDepartmentId())
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.service.FindOwned(ctx, reqctx.TenantID(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDepartment(res), nil
+}
+
+func (s *departmentServer) ListDepartments(ctx context.Context, req *pb.ListDepartmentsRequest) (*pb.ListDepartmentsResponse, error) {
+	limit, offset, err := request.Page(req.GetLimit(), req.GetOffset())
+	if err != nil {
+		return nil, err
+	}
+
+	departments, err := s.find(ctx, req.GetName(), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.ListDepartmentsResponse{Departments: make([]*pb.Department, 0, len(departments))}
+	for _, department := range departments {
+		res.Departments = append(res.Departments, toDepartment(department))
+	}
+
+	return res, nil
+}
+
+// StreamDepartments sends the departments a page at a time until a page comes back short
+func (s *departmentServer) StreamDepartments(req *pb.StreamDepartmentsRequest, stream grpc.ServerStreamingServer[pb.Department]) error {
+	ctx := stream.Context()
+	pageSize := request.PageSize(req.GetPageSize())
+
+	for offset := 0; ; offset += pageSize {
+		departments, err := s.find(ctx, req.GetName(), pageSize, offset)
+		if err != nil {
+			return err
+		}
+
+		for _, department := range departments {
+			if err := stream.Send(toDepartment(department)); err != nil {
+				return err
+			}
+		}
+
+		if len(departments) < pageSize {
+			return nil
+		}
+	}
+}
+
+func (s *departmentServer) UpdateDepartment(ctx context.Context, req *pb.UpdateDepartmentRequest) (*pb.Department, error) {
+	id, err := departmentID(req.GetDepartmentId())
+	if err != nil {
+		return nil, err
+	}
+
+	ifMatch, err := request.IfMatch(req.Version, s.requireVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.service.Update(ctx, reqctx.TenantID(ctx), id, req.GetName(), ifMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDepartment(res), nil
+}
+
+func (s *departmentServer) DeleteDepartment(ctx context.Context, req *pb.DeleteDepartmentRequest) (*pb.DeleteDepartmentResponse, error) {
+	id, err := departmentID(req.GetDepartmentId())
+	if err != nil {
+		return nil, err
+	}
+
+	ifMatch, err := request.IfMatch(req.Version, s.requireVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.service.Delete(ctx, reqctx.TenantID(ctx), id, ifMatch); err != nil {
+		return nil, err
+	}
+
+	s.metrics.DepartmentDeleted(strconv.Itoa(reqctx.TenantID(ctx)))
+
+	return &pb.DeleteDepartmentResponse{}, nil
+}
+
+func (s *departmentServer) find(ctx context.Context, name string, limit, offset int) ([]*dto.DepartmentRes, error) {
+	return s.service.FindByManager(ctx, reqctx.TenantID(ctx), name, limit, offset)
+}
+
+func departmentID(raw string) (int, error) {
+	if raw == "" {
+		return 0, domain.ErrDepartmentIDRequired
+	}
+
+	id, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, domain.ErrInvalidDepartmentID
+	}
+
+	return id, nil
+}
+
+func toDepartment(department *dto.DepartmentRes) *pb.Department {
+	return &pb.Department{
+		DepartmentId: department.ID,
+		Name:         department.Name,
+		Version:      int32(department.Version),
+	}
+}
@@ -4,34 +4,47 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"strconv"
 	"time"
 
-	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/etag"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
 )
 
 type departmentService struct {
 	repo      contracts.DepartmentRepository
 	validator validator.ValidatorInterface
+	audit     contracts.AuditService
+	events    contracts.OutboxEventService
+	tx        dbtx.TransactorInterface
 }
 
-func NewDepartmentService(repository contracts.DepartmentRepository, validator validator.ValidatorInterface) contracts.DepartmentService {
-	return departmentService{repo: repository, validator: validator}
+func NewDepartmentService(
+	repository contracts.DepartmentRepository,
+	validator validator.ValidatorInterface,
+	audit contracts.AuditService,
+	events contracts.OutboxEventService,
+	tx dbtx.TransactorInterface,
+) contracts.DepartmentService {
+	return departmentService{repo: repository, validator: validator, audit: audit, events: events, tx: tx}
 }
 
 func (d departmentService) Create(ctx context.Context, managerId int, name string) (*dto.DepartmentRes, error) {
-	type Request struct {
-		Name string `json:"name" validate:"required,min=4,max=33"`
-	}
+	ctx, span := tracing.Start(ctx, "DepartmentService.Create")
+	defer span.End()
 
-	req := Request{Name: name}
+	req := dto.DepartmentReq{Name: name}
 
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := d.validator.Validate(&req)
+	valSpan.End()
 	if valErr != nil {
 		return nil, valErr
 	}
@@ -42,35 +55,79 @@ func (d departmentService) Create(ctx context.Context, managerId int, name strin
 		CreatedAt: time.Now(),
 	}
 
-	id, err := d.repo.Create(ctx, department)
+	var res *dto.DepartmentRes
+	err := d.tx.WithinTx(ctx, func(ctx context.Context) error {
+		id, err := d.repo.Create(ctx, department)
+		if err != nil {
+			return err
+		}
+
+		department.ID = id
+		department.Version = 1
+		res = toDepartmentRes(department)
+
+		if err := d.events.Record(ctx, managerId, res.ID, enums.WebhookDepartmentCreated, res, nil); err != nil {
+			return err
+		}
+
+		return d.audit.Record(ctx, dto.AuditRecord{
+			Action:     enums.AuditCreate,
+			EntityType: dto.AuditEntityDepartment,
+			EntityID:   res.ID,
+			After:      department,
+		})
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	return &dto.DepartmentRes{
-		ID:   strconv.Itoa(id),
-		Name: department.Name,
-	}, nil
+	return res, nil
 }
 
-func (d departmentService) Delete(ctx context.Context, id int) error {
-	_, err := d.repo.FindByID(ctx, id)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("department with id %d not found", id))
-		}
+func (d departmentService) Delete(ctx context.Context, managerID, id int, ifMatch string) error {
+	ctx, span := tracing.Start(ctx, "DepartmentService.Delete")
+	defer span.End()
 
+	department, err := d.find(ctx, managerID, id)
+	if err != nil {
 		return err
 	}
 
-	err = d.repo.Delete(ctx, id)
+	if ifMatch != "" && !etag.Matches(ifMatch, department.Version) {
+		return domain.ErrPreconditionFailed
+	}
+
+	err = d.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := d.repo.Delete(ctx, id, department.Version); err != nil {
+			return err
+		}
+
+		if err := d.events.Record(ctx, department.ManagerID, strconv.Itoa(id), enums.WebhookDepartmentDeleted, toDepartmentRes(*department), nil); err != nil {
+			return err
+		}
+
+		return d.audit.Record(ctx, dto.AuditRecord{
+			Action:     enums.AuditDelete,
+			EntityType: dto.AuditEntityDepartment,
+			EntityID:   strconv.Itoa(id),
+			Before:     department,
+		})
+	})
 	if err != nil {
+		// It was there a moment ago, someone changed or deleted it in between
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrPreconditionFailed
+		}
 		return err
 	}
+
 	return nil
 }
 
 func (d departmentService) FindAll(ctx context.Context, limit int, offset int) ([]*dto.DepartmentRes, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentService.FindAll")
+	defer span.End()
+
 	var departments []*entity.Department
 	var err error
 
@@ -82,16 +139,15 @@ func (d departmentService) FindAll(ctx context.Context, limit int, offset int) (
 
 	var departmentRes []*dto.DepartmentRes
 	for _, dept := range departments {
-		departmentRes = append(departmentRes, &dto.DepartmentRes{
-			ID:   strconv.Itoa(dept.ID),
-			Name: dept.Name,
-		})
+		departmentRes = append(departmentRes, toDepartmentRes(*dept))
 	}
 
 	return departmentRes, nil
 }
 
 func (d departmentService) FindByName(ctx context.Context, limit int, offset int, name string) ([]*dto.DepartmentRes, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentService.FindByName")
+	defer span.End()
 
 	departments, err := d.repo.FindByName(ctx, name, limit, offset)
 	if err != nil {
@@ -100,43 +156,147 @@ func (d departmentService) FindByName(ctx context.Context, limit int, offset int
 
 	var departmentRes []*dto.DepartmentRes
 	for _, dept := range departments {
-		departmentRes = append(departmentRes, &dto.DepartmentRes{
-			ID:   strconv.Itoa(dept.ID),
-			Name: dept.Name,
-		})
+		departmentRes = append(departmentRes, toDepartmentRes(*dept))
+	}
+
+	return departmentRes, nil
+}
+
+func (d departmentService) FindByManager(ctx context.Context, managerID int, name string, limit, offset int) ([]*dto.DepartmentRes, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentService.FindByManager")
+	defer span.End()
+
+	var departments []*entity.Department
+	var err error
+	if name != "" {
+		departments, err = d.repo.SearchByManager(ctx, managerID, name, limit, offset)
+	} else {
+		departments, err = d.repo.FindByManager(ctx, managerID, limit, offset)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	departmentRes := make([]*dto.DepartmentRes, 0, len(departments))
+	for _, dept := range departments {
+		departmentRes = append(departmentRes, toDepartmentRes(*dept))
 	}
 
 	return departmentRes, nil
 }
 
-func (d departmentService) Update(ctx context.Context, id int, name string) (*dto.DepartmentRes, error) {
-	type Request struct {
-		Name string `json:"name" validate:"required,min=4,max=33"`
+func (d departmentService) FindOwned(ctx context.Context, managerID, id int) (*dto.DepartmentRes, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentService.FindOwned")
+	defer span.End()
+
+	department, err := d.find(ctx, managerID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDepartmentRes(*department), nil
+}
+
+func (d departmentService) FindByIDs(ctx context.Context, managerID int, ids []int) ([]*dto.DepartmentRes, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentService.FindByIDs")
+	defer span.End()
+
+	departments, err := d.repo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	departmentRes := make([]*dto.DepartmentRes, 0, len(departments))
+	for _, dept := range departments {
+		if dept.ManagerID != managerID {
+			continue
+		}
+		departmentRes = append(departmentRes, toDepartmentRes(*dept))
 	}
 
-	req := Request{Name: name}
+	return departmentRes, nil
+}
+
+func (d departmentService) Update(ctx context.Context, managerID, id int, name, ifMatch string) (*dto.DepartmentRes, error) {
+	ctx, span := tracing.Start(ctx, "DepartmentService.Update")
+	defer span.End()
+
+	req := dto.DepartmentReq{Name: name}
 
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := d.validator.Validate(&req)
+	valSpan.End()
 	if valErr != nil {
 		return nil, valErr
 	}
 
-	_, err := d.repo.FindByID(ctx, id)
+	department, err := d.find(ctx, managerID, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("department with id %d not found", id))
+		return nil, err
+	}
+
+	if ifMatch != "" && !etag.Matches(ifMatch, department.Version) {
+		return nil, domain.ErrPreconditionFailed
+	}
+
+	updated := *department
+	updated.Name = name
+
+	var res *dto.DepartmentRes
+	err = d.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		updated.Version, err = d.repo.Update(ctx, id, department.Version, name)
+		if err != nil {
+			return err
+		}
+
+		res = toDepartmentRes(updated)
+		if err := d.events.Record(ctx, department.ManagerID, res.ID, enums.WebhookDepartmentUpdated, res, toDepartmentRes(*department)); err != nil {
+			return err
 		}
 
+		return d.audit.Record(ctx, dto.AuditRecord{
+			Action:     enums.AuditUpdate,
+			EntityType: dto.AuditEntityDepartment,
+			EntityID:   strconv.Itoa(id),
+			Before:     department,
+			After:      updated,
+		})
+	})
+	if err != nil {
+		// Without If-Match the update still only applies to the version read above
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrPreconditionFailed
+		}
 		return nil, err
 	}
 
-	_, err = d.repo.Update(ctx, id, name)
+	return res, nil
+}
+
+// find looks the department up, not found unless the manager manages it
+func (d departmentService) find(ctx context.Context, managerID, id int) (*entity.Department, error) {
+	department, err := d.repo.FindByID(ctx, id)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrDepartmentNotFound
+		}
+
 		return nil, err
 	}
 
+	// Not found rather than forbidden, so other managers' departments can't be told apart from missing ones
+	if department.ManagerID != managerID {
+		return nil, domain.ErrDepartmentNotFound
+	}
+
+	return department, nil
+}
+
+func toDepartmentRes(department entity.Department) *dto.DepartmentRes {
 	return &dto.DepartmentRes{
-		ID:   strconv.Itoa(id),
-		Name: name,
-	}, nil
+		ID:      strconv.Itoa(department.ID),
+		Name:    department.Name,
+		Version: department.Version,
+	}
 }
@@ -4,128 +4,130 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/etag"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/metrics"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
 )
 
 type employeeController struct {
 	employeeService contracts.EmployeeService
 	middleware      *middlewares.Middleware
+	metrics         metrics.MetricsInterface
 }
 
 func InitNewController(
 	router fiber.Router,
 	employeeService contracts.EmployeeService,
 	middleware *middlewares.Middleware,
+	metrics metrics.MetricsInterface,
 ) {
 	controller := &employeeController{
 		employeeService: employeeService,
 		middleware:      middleware,
+		metrics:         metrics,
 	}
 
 	route := router.Group("/v1/employee")
 
-	route.Post("/", middleware.RequireAdmin(), controller.Create)
-	route.Get("/", middleware.RequireAdmin(), controller.Get)
-	route.Patch("/:identityNumber", middleware.RequireAdmin(), controller.Update)
-	route.Patch("/", middleware.RequireAdmin(), func(ctx *fiber.Ctx) error {
-		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Identity Number is required",
-		})
+	route.Post("/", middleware.RequireAdmin(enums.ScopeEmployeeWrite), middleware.Idempotent(), controller.Create)
+	route.Get("/", middleware.RequireAdmin(enums.ScopeRead), controller.Get)
+	route.Get("/:identityNumber", middleware.RequireAdmin(enums.ScopeRead), controller.GetByIdentityNumber)
+	route.Patch("/:identityNumber", middleware.RequireAdmin(enums.ScopeEmployeeWrite), middleware.Precondition(), controller.Update)
+	route.Patch("/", middleware.RequireAdmin(enums.ScopeEmployeeWrite), func(ctx *fiber.Ctx) error {
+		return domain.ErrIdentityNumberRequired
 	})
-	route.Delete("/:identityNumber", middleware.RequireAdmin(), controller.Delete)
-	route.Delete("/", middleware.RequireAdmin(), func(ctx *fiber.Ctx) error {
-		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Identity Number is required",
-		})
+	route.Delete("/:identityNumber", middleware.RequireAdmin(enums.ScopeEmployeeWrite), middleware.Precondition(), controller.Delete)
+	route.Delete("/", middleware.RequireAdmin(enums.ScopeEmployeeWrite), func(ctx *fiber.Ctx) error {
+		return domain.ErrIdentityNumberRequired
 	})
 }
 
 func (c *employeeController) Create(ctx *fiber.Ctx) error {
 	var req dto.EmployeeCreateReq
 	if err := ctx.BodyParser(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return domain.ErrInvalidRequestBody
 	}
 
-	res, err := c.employeeService.Create(ctx.Context(), req)
+	res, err := c.employeeService.Create(ctx.UserContext(), req)
 	if err != nil {
 		return err
 	}
 
-	return ctx.Status(fiber.StatusCreated).JSON(res)
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+	c.metrics.EmployeeCreated(strconv.Itoa(managerID))
+
+	return response.SendResponse(ctx, fiber.StatusCreated, res)
 }
 
 func (c *employeeController) Get(ctx *fiber.Ctx) error {
-	identityNumber := ctx.Query("identityNumber", "")
-	name := ctx.Query("name", "")
-	gender := ctx.Query("gender", "")
-	departmentID, err := strconv.Atoi(ctx.Query("departmentId", "0"))
-	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid department ID query parameter",
-		})
-	}
-
-	limit, err := strconv.Atoi(ctx.Query("limit", "5"))
-	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid limit query parameter",
-		})
+	query := dto.EmployeeQuery{Limit: 5}
+	if err := ctx.QueryParser(&query); err != nil {
+		return domain.ErrInvalidQueryParam
 	}
 
-	offset, err := strconv.Atoi(ctx.Query("offset", "0"))
-	if err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid offset query parameter",
-		})
-	}
-
-	res, err := c.employeeService.Find(ctx.Context(), identityNumber, name, gender, departmentID, limit, offset)
+	res, err := c.employeeService.Find(ctx.UserContext(), reqctx.TenantID(ctx.UserContext()), query.IdentityNumber, query.Name, query.Gender, query.DepartmentID, query.Limit, query.Offset)
 	if err != nil {
 		return err
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(res)
+	return response.SendResponse(ctx, fiber.StatusOK, res)
 }
 
 func (c *employeeController) Update(ctx *fiber.Ctx) error {
 	var req dto.EmployeeUpdateReq
 	if err := ctx.BodyParser(&req); err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return domain.ErrInvalidRequestBody
 	}
 
 	identityNumber := ctx.Params("identityNumber")
 	if identityNumber == "" {
-		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Invalid identity number",
-		})
+		return domain.ErrIdentityNumberRequired
 	}
 
-	res, err := c.employeeService.Update(ctx.Context(), req, identityNumber)
+	res, err := c.employeeService.Update(ctx.UserContext(), reqctx.TenantID(ctx.UserContext()), req, identityNumber, ctx.Get(fiber.HeaderIfMatch))
 	if err != nil {
 		return err
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(res)
+	return etag.SendResponse(ctx, fiber.StatusOK, res.Version, res)
+}
+
+func (c *employeeController) GetByIdentityNumber(ctx *fiber.Ctx) error {
+	identityNumber := ctx.Params("identityNumber")
+	if identityNumber == "" {
+		return domain.ErrIdentityNumberRequired
+	}
+
+	res, err := c.employeeService.FindByIdentityNumber(ctx.UserContext(), reqctx.TenantID(ctx.UserContext()), identityNumber)
+	if err != nil {
+		return err
+	}
+
+	return etag.SendResponse(ctx, fiber.StatusOK, res.Version, res)
 }
 
 func (c *employeeController) Delete(ctx *fiber.Ctx) error {
 	identityNumber := ctx.Params("identityNumber")
 	if identityNumber == "" {
-		return ctx.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Invalid identity number",
-		})
+		return domain.ErrIdentityNumberRequired
 	}
 
-	err := c.employeeService.Delete(ctx.Context(), identityNumber)
+	err := c.employeeService.Delete(ctx.UserContext(), reqctx.TenantID(ctx.UserContext()), identityNumber, ctx.Get(fiber.HeaderIfMatch))
 	if err != nil {
 		return err
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(fiber.Map{
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+	c.metrics.EmployeeDeleted(strconv.Itoa(managerID))
+
+	return response.SendResponse(ctx, fiber.StatusOK, fiber.Map{
 		"message": "Employee deleted successfully",
 	})
 }
@@ -0,0 +1,219 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+// Field names are bound into the ciphertexts and blind indexes, renaming them makes existing rows unreadable
+const (
+	fieldIdentityNumber = "employees.identity_number"
+	fieldName           = "employees.name"
+)
+
+const (
+	querySelectForRekey = "SELECT " + employeeColumns + `
+	FROM employees WHERE key_id IS DISTINCT FROM $1 AND erased_at IS NULL ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED`
+	querySelectForDecrypt = "SELECT " + employeeColumns + `
+	FROM employees WHERE key_id IS NOT NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`
+	querySeal = `
+	UPDATE employees
+		SET identity_number = NULL,
+		name = NULL,
+		identity_number_enc = $1,
+		identity_number_bidx = $2,
+		name_enc = $3,
+		name_bidx = $4,
+		data_key = $5,
+		key_id = $6
+	WHERE id = $7`
+	queryUnseal = `
+	UPDATE employees
+		SET identity_number = $1,
+		name = $2,
+		identity_number_enc = NULL,
+		identity_number_bidx = NULL,
+		name_enc = NULL,
+		name_bidx = NULL,
+		data_key = NULL,
+		key_id = NULL
+	WHERE id = $3`
+)
+
+// employeeRow is an employee as stored: plaintext while key_id is NULL, sealed otherwise
+type employeeRow struct {
+	ID                int            `db:"id"`
+	IdentityNumber    sql.NullString `db:"identity_number"`
+	Name              sql.NullString `db:"name"`
+	IdentityNumberEnc []byte         `db:"identity_number_enc"`
+	NameEnc           []byte         `db:"name_enc"`
+	DataKey           []byte         `db:"data_key"`
+	KeyID             sql.NullString `db:"key_id"`
+	EmployeeImageURI  sql.NullString `db:"employee_image_uri"`
+	Gender            sql.NullString `db:"gender"`
+	DepartmentID      int            `db:"department_id"`
+	Version           int            `db:"version"`
+	CreatedAt         time.Time      `db:"created_at"`
+	UpdatedAt         time.Time      `db:"updated_at"`
+}
+
+type sealedEmployee struct {
+	IdentityNumberEnc  []byte
+	IdentityNumberBidx string
+	NameEnc            []byte
+	NameBidx           string
+	DataKey            []byte
+	KeyID              string
+}
+
+// seal encrypts the identity number and name under a fresh data key wrapped with the active key
+func (e *employeeRepository) seal(employee entity.Employee) (sealedEmployee, error) {
+	dataKey, err := e.crypt.NewDataKey()
+	if err != nil {
+		return sealedEmployee{}, err
+	}
+
+	identityNumber, err := dataKey.Encrypt(fieldIdentityNumber, employee.IdentityNumber)
+	if err != nil {
+		return sealedEmployee{}, err
+	}
+
+	name, err := dataKey.Encrypt(fieldName, employee.Name)
+	if err != nil {
+		return sealedEmployee{}, err
+	}
+
+	return sealedEmployee{
+		IdentityNumberEnc:  identityNumber,
+		IdentityNumberBidx: e.identityNumberIndex(employee.IdentityNumber),
+		NameEnc:            name,
+		NameBidx:           e.nameIndex(employee.Name),
+		DataKey:            dataKey.Wrapped,
+		KeyID:              dataKey.KeyID,
+	}, nil
+}
+
+func (e *employeeRepository) open(row employeeRow) (entity.Employee, error) {
+	employee := entity.Employee{
+		ID:               row.ID,
+		IdentityNumber:   row.IdentityNumber.String,
+		Name:             row.Name.String,
+		EmployeeImageURI: row.EmployeeImageURI.String,
+		Gender:           row.Gender.String,
+		DepartmentID:     row.DepartmentID,
+		Version:          row.Version,
+		CreatedAt:        row.CreatedAt,
+		UpdatedAt:        row.UpdatedAt,
+	}
+
+	if !row.KeyID.Valid {
+		return employee, nil
+	}
+
+	dataKey, err := e.crypt.OpenDataKey(row.KeyID.String, row.DataKey)
+	if err != nil {
+		return entity.Employee{}, err
+	}
+
+	if employee.IdentityNumber, err = dataKey.Decrypt(fieldIdentityNumber, row.IdentityNumberEnc); err != nil {
+		return entity.Employee{}, err
+	}
+
+	if employee.Name, err = dataKey.Decrypt(fieldName, row.NameEnc); err != nil {
+		return entity.Employee{}, err
+	}
+
+	return employee, nil
+}
+
+func (e *employeeRepository) identityNumberIndex(identityNumber string) string {
+	return e.crypt.BlindIndex(fieldIdentityNumber, identityNumber)
+}
+
+func (e *employeeRepository) nameIndex(name string) string {
+	return e.crypt.BlindIndex(fieldName, normalizeName(name))
+}
+
+func normalizeName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// Reencrypt seals up to limit rows that are still plaintext or use another key than the active one,
+// with a fresh data key each. Rows locked by a concurrent run are skipped.
+func (e *employeeRepository) Reencrypt(ctx context.Context, limit int) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "EmployeeRepository.Reencrypt", querySelectForRekey)
+	defer span.End()
+
+	tx, err := e.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+	defer tx.Rollback()
+
+	rows := []employeeRow{}
+	if err := tx.SelectContext(ctx, &rows, querySelectForRekey, e.crypt.ActiveKeyID(), limit); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	for _, row := range rows {
+		employee, err := e.open(row)
+		if err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+
+		sealed, err := e.seal(employee)
+		if err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+
+		_, err = tx.ExecContext(ctx, querySeal,
+			sealed.IdentityNumberEnc,
+			sealed.IdentityNumberBidx,
+			sealed.NameEnc,
+			sealed.NameBidx,
+			sealed.DataKey,
+			sealed.KeyID,
+			row.ID,
+		)
+		if err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+	}
+
+	return len(rows), tracing.RecordError(span, tx.Commit())
+}
+
+// Decrypt writes up to limit sealed rows back in plaintext, to roll the encryption migration back
+func (e *employeeRepository) Decrypt(ctx context.Context, limit int) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "EmployeeRepository.Decrypt", querySelectForDecrypt)
+	defer span.End()
+
+	tx, err := e.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+	defer tx.Rollback()
+
+	rows := []employeeRow{}
+	if err := tx.SelectContext(ctx, &rows, querySelectForDecrypt, limit); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	for _, row := range rows {
+		employee, err := e.open(row)
+		if err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, queryUnseal, employee.IdentityNumber, employee.Name, row.ID); err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+	}
+
+	return len(rows), tracing.RecordError(span, tx.Commit())
+}
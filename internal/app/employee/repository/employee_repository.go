@@ -2,68 +2,135 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
-	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/fieldcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
 )
 
 type employeeRepository struct {
-	DB *sqlx.DB
+	DB    *sqlx.DB
+	crypt fieldcrypt.FieldCryptInterface
 }
 
 const (
+	employeeColumns = `id, identity_number, name, identity_number_enc, name_enc, data_key, key_id,
+		employee_image_uri, gender, department_id, version, created_at, updated_at`
+	// Rows written before encryption was enabled are matched on their plaintext until rekey seals them
+	matchIdentityNumber = "(identity_number_bidx = $1 OR (key_id IS NULL AND identity_number = $2))"
+
 	queryCreate = `
-	INSERT INTO employees (identity_number, name, employee_image_uri, gender, department_id)
-	VALUES ($1, $2, $3, $4, $5) RETURNING id`
-	queryFindBase             = "SELECT * FROM employees WHERE 1=1"
-	queryFindByIdentityNumber = "SELECT * FROM employees WHERE identity_number = $1"
-	queryDelete               = "DELETE FROM employees WHERE identity_number = $1"
+	INSERT INTO employees (identity_number_enc, identity_number_bidx, name_enc, name_bidx, data_key, key_id,
+		employee_image_uri, gender, department_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id`
+	queryFindBase             = "SELECT " + employeeColumns + " FROM employees WHERE erased_at IS NULL"
+	queryFindByIdentityNumber = "SELECT " + employeeColumns + " FROM employees WHERE erased_at IS NULL AND " + matchIdentityNumber
+	queryDelete               = "DELETE FROM employees WHERE id = $1 AND version = $2 AND erased_at IS NULL"
 	queryUpdate               = `
 		UPDATE employees
-			SET name = $1,
- 			identity_number = $2,
-    		gender = $3,
-    		department_id = $4,
-    		employee_image_uri = $5
-		WHERE id = $6`
+			SET identity_number = NULL,
+			name = NULL,
+			identity_number_enc = $1,
+			identity_number_bidx = $2,
+			name_enc = $3,
+			name_bidx = $4,
+			data_key = $5,
+			key_id = $6,
+			gender = $7,
+			department_id = $8,
+			employee_image_uri = $9,
+			version = version + 1,
+			updated_at = NOW()
+		WHERE id = $10 AND version = $11 AND erased_at IS NULL
+		RETURNING version`
+	queryErase = `
+		UPDATE employees
+			SET identity_number = NULL,
+			name = NULL,
+			identity_number_enc = NULL,
+			identity_number_bidx = NULL,
+			name_enc = NULL,
+			name_bidx = NULL,
+			data_key = NULL,
+			key_id = NULL,
+			gender = NULL,
+			employee_image_uri = NULL,
+			erased_at = NOW()
+		WHERE id = $1 AND erased_at IS NULL`
+	// Numbers the employees of each department to page them apart in one query
+	queryFindByDepartmentIDs = `
+	SELECT ` + employeeColumns + ` FROM (
+		SELECT *, ROW_NUMBER() OVER (PARTITION BY department_id ORDER BY id) AS position
+		FROM employees WHERE erased_at IS NULL AND department_id = ANY($1)
+		AND department_id IN (SELECT id FROM departments WHERE manager_id = $4)
+	) ranked
+	WHERE position > $3 AND position <= $2 + $3
+	ORDER BY department_id, position`
+	queryFindByManager = queryFindBase + `
+	AND department_id IN (SELECT id FROM departments WHERE manager_id = $1)
+	ORDER BY id LIMIT $2 OFFSET $3`
+	queryCountByManager = `
+	SELECT COUNT(*) FROM employees
+	WHERE erased_at IS NULL AND department_id IN (SELECT id FROM departments WHERE manager_id = $1)`
 )
 
-func NewEmployeeRepository(db *sqlx.DB) contracts.EmployeeRepository {
-	return &employeeRepository{DB: db}
+func NewEmployeeRepository(db *sqlx.DB, crypt fieldcrypt.FieldCryptInterface) contracts.EmployeeRepository {
+	return &employeeRepository{DB: db, crypt: crypt}
 }
 
-func (e *employeeRepository) Create(ctx context.Context, data entity.Employee) error {
-	log.Info(log.LogInfo{
-		"identityNumber": data.IdentityNumber,
-	}, "[EmployeeRepository.Create]")
+func (e *employeeRepository) Create(ctx context.Context, data entity.Employee) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "EmployeeRepository.Create", queryCreate)
+	defer span.End()
+
+	sealed, err := e.seal(data)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
 
-	_, err := e.DB.ExecContext(
+	var id int
+	err = dbtx.From(ctx, e.DB).GetContext(
 		ctx,
+		&id,
 		queryCreate,
-		data.IdentityNumber,
-		data.Name,
+		sealed.IdentityNumberEnc,
+		sealed.IdentityNumberBidx,
+		sealed.NameEnc,
+		sealed.NameBidx,
+		sealed.DataKey,
+		sealed.KeyID,
 		data.EmployeeImageURI,
 		data.Gender,
 		data.DepartmentID,
 	)
 	if err != nil {
-		return err
+		return 0, tracing.RecordError(span, err)
 	}
-	return nil
+
+	return id, nil
 }
 
 func (e *employeeRepository) FindByIdentityNumber(
 	ctx context.Context,
 	identityNumber string,
 ) (*entity.Employee, error) {
-	var employee entity.Employee
+	ctx, span := tracing.StartDB(ctx, "EmployeeRepository.FindByIdentityNumber", queryFindByIdentityNumber)
+	defer span.End()
+
+	var row employeeRow
 
-	err := e.DB.GetContext(ctx, &employee, queryFindByIdentityNumber, identityNumber)
+	err := dbtx.From(ctx, e.DB).GetContext(ctx, &row, queryFindByIdentityNumber, e.identityNumberIndex(identityNumber), identityNumber)
 	if err != nil {
-		return nil, err
+		return nil, tracing.RecordError(span, err)
+	}
+
+	employee, err := e.open(row)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
 	}
 
 	return &employee, nil
@@ -71,6 +138,7 @@ func (e *employeeRepository) FindByIdentityNumber(
 
 func (e *employeeRepository) Find(
 	ctx context.Context,
+	managerID int,
 	identityNumber string,
 	name string,
 	gender string,
@@ -78,18 +146,21 @@ func (e *employeeRepository) Find(
 	limit int,
 	offset int,
 ) ([]*entity.Employee, error) {
-	employees := []*entity.Employee{}
+	rows := []employeeRow{}
 
 	query := queryFindBase
 
 	args := map[string]interface{}{}
 	if identityNumber != "" {
-		query += " AND identity_number = :identity_number"
+		query += " AND (identity_number_bidx = :identity_number_bidx OR (key_id IS NULL AND identity_number = :identity_number))"
+		args["identity_number_bidx"] = e.identityNumberIndex(identityNumber)
 		args["identity_number"] = identityNumber
 	}
 	if name != "" {
-		query += " AND name ILIKE :name"
-		args["name"] = "%" + name + "%"
+		// Encrypted names can only be matched whole, case-insensitively
+		query += " AND (name_bidx = :name_bidx OR (key_id IS NULL AND LOWER(name) = :name))"
+		args["name_bidx"] = e.nameIndex(name)
+		args["name"] = normalizeName(name)
 	}
 	if gender != "" {
 		query += " AND gender = :gender"
@@ -99,8 +170,13 @@ func (e *employeeRepository) Find(
 		query += " AND department_id = :department_id"
 		args["department_id"] = departmentID
 	}
+	if managerID != 0 {
+		query += " AND department_id IN (SELECT id FROM departments WHERE manager_id = :manager_id)"
+		args["manager_id"] = managerID
+	}
 
-	query += " LIMIT :limit OFFSET :offset"
+	// Ordered so pages read one after another neither skip nor repeat rows
+	query += " ORDER BY id LIMIT :limit OFFSET :offset"
 	args["limit"] = limit
 	args["offset"] = offset
 
@@ -111,32 +187,167 @@ func (e *employeeRepository) Find(
 
 	finalQuery = e.DB.Rebind(finalQuery)
 
-	err = e.DB.SelectContext(ctx, &employees, finalQuery, finalArgs...)
+	ctx, span := tracing.StartDB(ctx, "EmployeeRepository.Find", finalQuery)
+	defer span.End()
+
+	err = dbtx.From(ctx, e.DB).SelectContext(ctx, &rows, finalQuery, finalArgs...)
 	if err != nil {
-		return nil, err
+		return nil, tracing.RecordError(span, err)
+	}
+
+	employees := make([]*entity.Employee, 0, len(rows))
+	for _, row := range rows {
+		employee, err := e.open(row)
+		if err != nil {
+			return nil, tracing.RecordError(span, err)
+		}
+
+		employees = append(employees, &employee)
+	}
+
+	return employees, nil
+}
+
+func (e *employeeRepository) FindByManager(ctx context.Context, managerID, limit, offset int) ([]*entity.Employee, error) {
+	ctx, span := tracing.StartDB(ctx, "EmployeeRepository.FindByManager", queryFindByManager)
+	defer span.End()
+
+	rows := []employeeRow{}
+
+	err := dbtx.From(ctx, e.DB).SelectContext(ctx, &rows, queryFindByManager, managerID, limit, offset)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	employees := make([]*entity.Employee, 0, len(rows))
+	for _, row := range rows {
+		employee, err := e.open(row)
+		if err != nil {
+			return nil, tracing.RecordError(span, err)
+		}
+
+		employees = append(employees, &employee)
+	}
+
+	return employees, nil
+}
+
+func (e *employeeRepository) CountByManager(ctx context.Context, managerID int) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "EmployeeRepository.CountByManager", queryCountByManager)
+	defer span.End()
+
+	var count int
+	if err := dbtx.From(ctx, e.DB).GetContext(ctx, &count, queryCountByManager, managerID); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	return count, nil
+}
+
+func (e *employeeRepository) FindByDepartmentIDs(
+	ctx context.Context,
+	managerID int,
+	departmentIDs []int,
+	limit int,
+	offset int,
+) ([]*entity.Employee, error) {
+	ctx, span := tracing.StartDB(ctx, "EmployeeRepository.FindByDepartmentIDs", queryFindByDepartmentIDs)
+	defer span.End()
+
+	rows := []employeeRow{}
+
+	err := dbtx.From(ctx, e.DB).SelectContext(ctx, &rows, queryFindByDepartmentIDs, departmentIDs, limit, offset, managerID)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	employees := make([]*entity.Employee, 0, len(rows))
+	for _, row := range rows {
+		employee, err := e.open(row)
+		if err != nil {
+			return nil, tracing.RecordError(span, err)
+		}
+
+		employees = append(employees, &employee)
 	}
 
 	return employees, nil
 }
 
-func (e *employeeRepository) Update(ctx context.Context, data entity.Employee) error {
+// Update writes data over the row if it is still at data.Version and returns the new version.
+// sql.ErrNoRows means the row was changed or deleted since it was read.
+func (e *employeeRepository) Update(ctx context.Context, data entity.Employee) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "EmployeeRepository.Update", queryUpdate)
+	defer span.End()
+
+	sealed, err := e.seal(data)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
 
-	_, err := e.DB.ExecContext(ctx, queryUpdate,
-		data.Name,
-		data.IdentityNumber,
+	var version int
+	err = dbtx.From(ctx, e.DB).GetContext(ctx, &version, queryUpdate,
+		sealed.IdentityNumberEnc,
+		sealed.IdentityNumberBidx,
+		sealed.NameEnc,
+		sealed.NameBidx,
+		sealed.DataKey,
+		sealed.KeyID,
 		data.Gender,
 		data.DepartmentID,
-		data.EmployeeImageURI,
+		// A cleared image is stored as NULL, like on erased rows
+		sql.NullString{String: data.EmployeeImageURI, Valid: data.EmployeeImageURI != ""},
 		data.ID,
+		data.Version,
 	)
 	if err != nil {
-		return fmt.Errorf("failed to update employee: %w", err)
+		return 0, tracing.RecordError(span, fmt.Errorf("failed to update employee: %w", err))
+	}
+
+	return version, nil
+}
+
+// Delete removes the row if it is still at version, sql.ErrNoRows otherwise
+func (e *employeeRepository) Delete(ctx context.Context, id, version int) error {
+	ctx, span := tracing.StartDB(ctx, "EmployeeRepository.Delete", queryDelete)
+	defer span.End()
+
+	result, err := dbtx.From(ctx, e.DB).ExecContext(ctx, queryDelete, id, version)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	if affected == 0 {
+		return tracing.RecordError(span, sql.ErrNoRows)
 	}
 
 	return nil
 }
 
-func (e *employeeRepository) Delete(ctx context.Context, id string) error {
-	_, err := e.DB.ExecContext(ctx, queryDelete, id)
-	return err
+// Erase clears everything identifying the employee but keeps the row, so its id stays valid.
+// Erased employees are invisible to every other method.
+func (e *employeeRepository) Erase(ctx context.Context, id int) error {
+	ctx, span := tracing.StartDB(ctx, "EmployeeRepository.Erase", queryErase)
+	defer span.End()
+
+	result, err := dbtx.From(ctx, e.DB).ExecContext(ctx, queryErase, id)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	if affected == 0 {
+		return tracing.RecordError(span, sql.ErrNoRows)
+	}
+
+	return nil
 }
@@ -0,0 +1,236 @@
+package rpc
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/grpc/request"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/metrics"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/patch"
+	pb "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/pb/gogomanager/v1"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+	"google.golang.org/grpc"
+)
+
+// Scopes are the scopes an API key needs for each method, the same as for the matching route
+var Scopes = middlewares.GRPCScopes{
+	pb.EmployeeService_CreateEmployee_FullMethodName:  {enums.ScopeEmployeeWrite},
+	pb.EmployeeService_GetEmployee_FullMethodName:     {enums.ScopeRead},
+	pb.EmployeeService_ListEmployees_FullMethodName:   {enums.ScopeRead},
+	pb.EmployeeService_StreamEmployees_FullMethodName: {enums.ScopeRead},
+	pb.EmployeeService_UpdateEmployee_FullMethodName:  {enums.ScopeEmployeeWrite},
+	pb.EmployeeService_DeleteEmployee_FullMethodName:  {enums.ScopeEmployeeWrite},
+}
+
+// employeeServer serves the employees of the calling manager's departments alone, like SCIM does.
+// Those of other managers are not found.
+type employeeServer struct {
+	pb.UnimplementedEmployeeServiceServer
+	service     contracts.EmployeeService
+	departments contracts.DepartmentService
+	metrics     metrics.MetricsInterface
+	// requireVersion rejects changes made without a version, like If-Match on REST
+	requireVersion bool
+}
+
+// Register serves the employee service on registrar, over the service the REST endpoints use
+func Register(
+	registrar grpc.ServiceRegistrar,
+	employeeService contracts.EmployeeService,
+	departmentService contracts.DepartmentService,
+	metrics metrics.MetricsInterface,
+	requireVersion bool,
+) {
+	pb.RegisterEmployeeServiceServer(registrar, &employeeServer{
+		service:        employeeService,
+		departments:    departmentService,
+		metrics:        metrics,
+		requireVersion: requireVersion,
+	})
+}
+
+func (s *employeeServer) CreateEmployee(ctx context.Context, req *pb.CreateEmployeeRequest) (*pb.Employee, error) {
+	if err := s.checkDepartment(ctx, req.GetDepartmentId()); err != nil {
+		return nil, err
+	}
+
+	res, err := s.service.Create(ctx, dto.EmployeeCreateReq{
+		IdentityNumber:   req.GetIdentityNumber(),
+		Name:             req.GetName(),
+		EmployeeImageURI: req.GetEmployeeImageUri(),
+		Gender:           req.GetGender(),
+		DepartmentID:     req.GetDepartmentId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.metrics.EmployeeCreated(strconv.Itoa(reqctx.TenantID(ctx)))
+
+	return toEmployee(res), nil
+}
+
+func (s *employeeServer) GetEmployee(ctx context.Context, req *pb.GetEmployeeRequest) (*pb.Employee, error) {
+	if req.GetIdentityNumber() == "" {
+		return nil, domain.ErrIdentityNumberRequired
+	}
+
+	res, err := s.service.FindByIdentityNumber(ctx, reqctx.TenantID(ctx), req.GetIdentityNumber())
+	if err != nil {
+		return nil, err
+	}
+
+	return toEmployee(res), nil
+}
+
+func (s *employeeServer) ListEmployees(ctx context.Context, req *pb.ListEmployeesRequest) (*pb.ListEmployeesResponse, error) {
+	limit, offset, err := request.Page(req.GetLimit(), req.GetOffset())
+	if err != nil {
+		return nil, err
+	}
+
+	employees, err := s.find(ctx, req.GetFilter(), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	res := &pb.ListEmployeesResponse{Employees: make([]*pb.Employee, 0, len(employees))}
+	for _, employee := range employees {
+		res.Employees = append(res.Employees, toEmployee(employee))
+	}
+
+	return res, nil
+}
+
+// StreamEmployees sends the employees a page at a time until a page comes back short
+func (s *employeeServer) StreamEmployees(req *pb.StreamEmployeesRequest, stream grpc.ServerStreamingServer[pb.Employee]) error {
+	ctx := stream.Context()
+	pageSize := request.PageSize(req.GetPageSize())
+
+	for offset := 0; ; offset += pageSize {
+		employees, err := s.find(ctx, req.GetFilter(), pageSize, offset)
+		if err != nil {
+			return err
+		}
+
+		for _, employee := range employees {
+			if err := stream.Send(toEmployee(employee)); err != nil {
+				return err
+			}
+		}
+
+		if len(employees) < pageSize {
+			return nil
+		}
+	}
+}
+
+func (s *employeeServer) UpdateEmployee(ctx context.Context, req *pb.UpdateEmployeeRequest) (*pb.Employee, error) {
+	if req.GetIdentityNumber() == "" {
+		return nil, domain.ErrIdentityNumberRequired
+	}
+
+	ifMatch, err := request.IfMatch(req.Version, s.requireVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkDepartment(ctx, req.GetDepartmentId()); err != nil {
+		return nil, err
+	}
+
+	res, err := s.service.Update(ctx, reqctx.TenantID(ctx), dto.EmployeeUpdateReq{
+		IdentityNumber:   optional(req.NewIdentityNumber),
+		Name:             optional(req.Name),
+		EmployeeImageURI: optional(req.EmployeeImageUri),
+		Gender:           optional(req.Gender),
+		DepartmentID:     optional(req.DepartmentId),
+	}, req.GetIdentityNumber(), ifMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	return toEmployee(res), nil
+}
+
+func (s *employeeServer) DeleteEmployee(ctx context.Context, req *pb.DeleteEmployeeRequest) (*pb.DeleteEmployeeResponse, error) {
+	if req.GetIdentityNumber() == "" {
+		return nil, domain.ErrIdentityNumberRequired
+	}
+
+	ifMatch, err := request.IfMatch(req.Version, s.requireVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.service.Delete(ctx, reqctx.TenantID(ctx), req.GetIdentityNumber(), ifMatch); err != nil {
+		return nil, err
+	}
+
+	s.metrics.EmployeeDeleted(strconv.Itoa(reqctx.TenantID(ctx)))
+
+	return &pb.DeleteEmployeeResponse{}, nil
+}
+
+func (s *employeeServer) find(ctx context.Context, filter *pb.EmployeeFilter, limit, offset int) ([]*dto.EmployeeDataRes, error) {
+	var departmentID int
+	if filter.GetDepartmentId() != "" {
+		id, err := strconv.Atoi(filter.GetDepartmentId())
+		if err != nil {
+			return nil, domain.ErrInvalidDepartmentID
+		}
+		departmentID = id
+	}
+
+	return s.service.Find(
+		ctx,
+		reqctx.TenantID(ctx),
+		filter.GetIdentityNumber(),
+		filter.GetName(),
+		filter.GetGender(),
+		departmentID,
+		limit,
+		offset,
+	)
+}
+
+// checkDepartment fails with ErrDepartmentNotFound unless the tenant manages the department.
+// Without a department there is nothing to check, validation reports it when it is required.
+func (s *employeeServer) checkDepartment(ctx context.Context, departmentID string) error {
+	if departmentID == "" {
+		return nil
+	}
+
+	id, err := strconv.Atoi(departmentID)
+	if err != nil {
+		return domain.ErrDepartmentNotFound
+	}
+
+	_, err = s.departments.FindOwned(ctx, reqctx.TenantID(ctx), id)
+	return err
+}
+
+// optional is the patch member of an optional field, which is left out when it is not set
+func optional(value *string) patch.Field[string] {
+	if value == nil {
+		return patch.Field[string]{}
+	}
+
+	return patch.Value(*value)
+}
+
+func toEmployee(employee *dto.EmployeeDataRes) *pb.Employee {
+	return &pb.Employee{
+		IdentityNumber:   employee.IdentityNumber,
+		Name:             employee.Name,
+		EmployeeImageUri: employee.EmployeeImageURI,
+		Gender:           employee.Gender,
+		DepartmentId:     employee.DepartmentID,
+		Version:          int32(employee.Version),
+	}
+}
@@ -9,47 +9,62 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/etag"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
 )
 
 type employeeService struct {
-	repo      contracts.EmployeeRepository
-	validator validator.ValidatorInterface
+	repo        contracts.EmployeeRepository
+	departments contracts.DepartmentRepository
+	validator   validator.ValidatorInterface
+	audit       contracts.AuditService
+	events      contracts.OutboxEventService
+	tx          dbtx.TransactorInterface
 }
 
 func NewEmployeeService(
 	repository contracts.EmployeeRepository,
+	departments contracts.DepartmentRepository,
 	validator validator.ValidatorInterface,
+	audit contracts.AuditService,
+	events contracts.OutboxEventService,
+	tx dbtx.TransactorInterface,
 ) contracts.EmployeeService {
-	return employeeService{repo: repository, validator: validator}
+	return employeeService{
+		repo:        repository,
+		departments: departments,
+		validator:   validator,
+		audit:       audit,
+		events:      events,
+		tx:          tx,
+	}
 }
 
 func (e employeeService) Create(
 	ctx context.Context,
 	data dto.EmployeeCreateReq,
 ) (*dto.EmployeeDataRes, error) {
+	ctx, span := tracing.Start(ctx, "EmployeeService.Create")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := e.validator.Validate(&data)
+	valSpan.End()
 	if valErr != nil {
 		return nil, valErr
 	}
 
-	employeeImageUri, err := url.ParseRequestURI(data.EmployeeImageURI)
+	err := validateImageURI(data.EmployeeImageURI)
 	if err != nil {
-		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid employee image uri")
-	}
-
-	if employeeImageUri.Scheme == "" || employeeImageUri.Host == "" {
-		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid employee image uri")
-	}
-
-	// Additional validation: Check if the host contains a domain or is not empty
-	if !strings.Contains(employeeImageUri.Host, ".") {
-		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid employee image uri")
+		return nil, err
 	}
 
 	strDepartmentID, _ := strconv.Atoi(data.DepartmentID)
@@ -61,51 +76,103 @@ func (e employeeService) Create(
 		EmployeeImageURI: data.EmployeeImageURI,
 	}
 
-	err = e.repo.Create(ctx, employee)
-	if err != nil {
-		return nil, err
-	}
-
 	employeeDataRes := dto.EmployeeDataRes{
 		IdentityNumber:   data.IdentityNumber,
 		Name:             data.Name,
 		Gender:           data.Gender,
 		DepartmentID:     data.DepartmentID,
 		EmployeeImageURI: data.EmployeeImageURI,
+		Version:          1,
+	}
+
+	// The event and the audit record are written with the employee, none exists without the others
+	err = e.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		employee.ID, err = e.repo.Create(ctx, employee)
+		if err != nil {
+			return err
+		}
+
+		owner, err := e.owner(ctx, employee.DepartmentID)
+		if err != nil {
+			return err
+		}
+
+		if err := e.events.Record(ctx, owner, strconv.Itoa(employee.ID), enums.WebhookEmployeeCreated, employeeDataRes, nil); err != nil {
+			return err
+		}
+
+		return e.audit.Record(ctx, dto.AuditRecord{
+			Action:     enums.AuditCreate,
+			EntityType: dto.AuditEntityEmployee,
+			EntityID:   strconv.Itoa(employee.ID),
+			After:      auditView(employee),
+		})
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	log.Info(log.LogInfo{
-		"employeeDataRes": employeeDataRes,
-	}, "[EmployeeService.Create]")
+	log.DebugCtx(ctx, log.LogInfo{
+		"departmentId": data.DepartmentID,
+	}, "[EmployeeService.Create] employee created")
 
 	return &employeeDataRes, nil
 }
 
 func (e employeeService) Delete(
 	ctx context.Context,
+	managerID int,
 	identityNumber string,
+	ifMatch string,
 ) error {
-	_, err := e.repo.FindByIdentityNumber(ctx, identityNumber)
+	ctx, span := tracing.Start(ctx, "EmployeeService.Delete")
+	defer span.End()
+
+	employee, err := e.find(ctx, managerID, identityNumber)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("employee with id %s not found", identityNumber))
-		}
+		return err
+	}
 
-		return nil
+	if ifMatch != "" && !etag.Matches(ifMatch, employee.Version) {
+		return domain.ErrPreconditionFailed
 	}
 
-	err = e.repo.Delete(ctx, identityNumber)
+	err = e.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := e.repo.Delete(ctx, employee.ID, employee.Version); err != nil {
+			return err
+		}
+
+		owner, err := e.owner(ctx, employee.DepartmentID)
+		if err != nil {
+			return err
+		}
+
+		if err := e.events.Record(ctx, owner, strconv.Itoa(employee.ID), enums.WebhookEmployeeDeleted, toEmployeeDataRes(*employee), nil); err != nil {
+			return err
+		}
+
+		return e.audit.Record(ctx, dto.AuditRecord{
+			Action:     enums.AuditDelete,
+			EntityType: dto.AuditEntityEmployee,
+			EntityID:   strconv.Itoa(employee.ID),
+			Before:     auditView(*employee),
+		})
+	})
 	if err != nil {
+		// It was there a moment ago, someone changed or deleted it in between
 		if errors.Is(err, sql.ErrNoRows) {
-			return fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("employee with id %s not found", identityNumber))
+			return domain.ErrPreconditionFailed
 		}
 		return fmt.Errorf("failed to delete employee: %w", err)
 	}
+
 	return nil
 }
 
 func (e employeeService) Find(
 	ctx context.Context,
+	managerID int,
 	identityNumber string,
 	name string,
 	gender string,
@@ -113,8 +180,12 @@ func (e employeeService) Find(
 	limit int,
 	offset int,
 ) ([]*dto.EmployeeDataRes, error) {
+	ctx, span := tracing.Start(ctx, "EmployeeService.Find")
+	defer span.End()
+
 	listData, err := e.repo.Find(
 		ctx,
+		managerID,
 		identityNumber,
 		name,
 		gender,
@@ -128,111 +199,277 @@ func (e employeeService) Find(
 
 	listResponseData := []*dto.EmployeeDataRes{}
 	for _, data := range listData {
-		responseData := dto.EmployeeDataRes{
-			IdentityNumber:   data.IdentityNumber,
-			Name:             data.Name,
-			EmployeeImageURI: data.EmployeeImageURI,
-			Gender:           data.Gender,
-			DepartmentID:     fmt.Sprintf("%d", data.DepartmentID),
-		}
-		listResponseData = append(listResponseData, &responseData)
+		listResponseData = append(listResponseData, toEmployeeDataRes(*data))
 	}
 
-	log.Info(log.LogInfo{
-		"listResponseData": listResponseData,
-	}, "[EmployeeService.Find]")
+	log.DebugCtx(ctx, log.LogInfo{
+		"count": len(listResponseData),
+	}, "[EmployeeService.Find] employees found")
 
 	return listResponseData, nil
 }
 
+func (e employeeService) FindByIdentityNumber(
+	ctx context.Context,
+	managerID int,
+	identityNumber string,
+) (*dto.EmployeeDataRes, error) {
+	ctx, span := tracing.Start(ctx, "EmployeeService.FindByIdentityNumber")
+	defer span.End()
+
+	employee, err := e.find(ctx, managerID, identityNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	return toEmployeeDataRes(*employee), nil
+}
+
+func (e employeeService) FindByDepartmentIDs(
+	ctx context.Context,
+	managerID int,
+	departmentIDs []int,
+	limit int,
+	offset int,
+) ([]*dto.EmployeeDataRes, error) {
+	ctx, span := tracing.Start(ctx, "EmployeeService.FindByDepartmentIDs")
+	defer span.End()
+
+	listData, err := e.repo.FindByDepartmentIDs(ctx, managerID, departmentIDs, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	listResponseData := make([]*dto.EmployeeDataRes, 0, len(listData))
+	for _, data := range listData {
+		listResponseData = append(listResponseData, toEmployeeDataRes(*data))
+	}
+
+	return listResponseData, nil
+}
+
+func (e employeeService) FindByManager(
+	ctx context.Context,
+	managerID int,
+	limit int,
+	offset int,
+) ([]*dto.EmployeeDataRes, int, error) {
+	ctx, span := tracing.Start(ctx, "EmployeeService.FindByManager")
+	defer span.End()
+
+	listData, err := e.repo.FindByManager(ctx, managerID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	total, err := e.repo.CountByManager(ctx, managerID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	listResponseData := make([]*dto.EmployeeDataRes, 0, len(listData))
+	for _, data := range listData {
+		listResponseData = append(listResponseData, toEmployeeDataRes(*data))
+	}
+
+	return listResponseData, total, nil
+}
+
 func (e employeeService) Update(
 	ctx context.Context,
+	managerID int,
 	data dto.EmployeeUpdateReq,
 	identityNumber string,
+	ifMatch string,
 ) (*dto.EmployeeDataRes, error) {
+	ctx, span := tracing.Start(ctx, "EmployeeService.Update")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := e.validator.Validate(&data)
+	valSpan.End()
 	if valErr != nil {
 		return nil, valErr
 	}
 
-	employeeImageUri, err := url.ParseRequestURI(data.EmployeeImageURI)
+	if imageURI, ok := data.EmployeeImageURI.Get(); ok && imageURI != "" {
+		if err := validateImageURI(imageURI); err != nil {
+			return nil, err
+		}
+	}
+
+	oldData, err := e.find(ctx, managerID, identityNumber)
 	if err != nil {
-		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid employee image uri")
+		return nil, err
 	}
 
-	if employeeImageUri.Scheme == "" || employeeImageUri.Host == "" {
-		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid employee image uri")
+	if ifMatch != "" && !etag.Matches(ifMatch, oldData.Version) {
+		return nil, domain.ErrPreconditionFailed
 	}
 
-	// Additional validation: Check if the host contains a domain or is not empty
-	if !strings.Contains(employeeImageUri.Host, ".") {
-		return nil, fiber.NewError(fiber.StatusBadRequest, "invalid employee image uri")
+	updatedData := generateUpdateData(data, *oldData)
+	if updatedData.DepartmentID != oldData.DepartmentID {
+		if err := e.checkDepartment(ctx, managerID, updatedData.DepartmentID); err != nil {
+			return nil, err
+		}
 	}
 
-	oldData, err := e.repo.FindByIdentityNumber(ctx, identityNumber)
+	var res *dto.EmployeeDataRes
+	err = e.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		updatedData.Version, err = e.repo.Update(ctx, updatedData)
+		if err != nil {
+			return err
+		}
+
+		owner, err := e.owner(ctx, updatedData.DepartmentID)
+		if err != nil {
+			return err
+		}
+
+		aggregateID, previous := strconv.Itoa(oldData.ID), toEmployeeDataRes(*oldData)
+		res = toEmployeeDataRes(updatedData)
+		if err := e.events.Record(ctx, owner, aggregateID, enums.WebhookEmployeeUpdated, res, previous); err != nil {
+			return err
+		}
+
+		if updatedData.DepartmentID != oldData.DepartmentID {
+			if err := e.events.Record(ctx, owner, aggregateID, enums.WebhookEmployeeMoved, res, previous); err != nil {
+				return err
+			}
+		}
+
+		return e.audit.Record(ctx, dto.AuditRecord{
+			Action:     enums.AuditUpdate,
+			EntityType: dto.AuditEntityEmployee,
+			EntityID:   aggregateID,
+			Before:     auditView(*oldData),
+			After:      auditView(updatedData),
+		})
+	})
 	if err != nil {
+		// Without If-Match the update still only applies to the version read above,
+		// a concurrent change is never silently overwritten
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fiber.NewError(fiber.StatusNotFound, fmt.Sprintf("employee with id %s not found", identityNumber))
+			return nil, domain.ErrPreconditionFailed
 		}
-		return nil, fmt.Errorf("failed to update employee: %w", err)
+		return nil, err
 	}
 
-	updatedData := generateUpdateData(data, *oldData)
+	return res, nil
+}
 
-	err = e.repo.Update(ctx, updatedData)
+// find looks the employee up, not found unless it is in a department of the manager. A managerID of 0
+// finds the employee whoever manages it.
+func (e employeeService) find(ctx context.Context, managerID int, identityNumber string) (*entity.Employee, error) {
+	employee, err := e.repo.FindByIdentityNumber(ctx, identityNumber)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrEmployeeNotFound
+		}
 		return nil, err
 	}
-	res := &dto.EmployeeDataRes{
-		IdentityNumber:   updatedData.IdentityNumber,
-		Name:             updatedData.Name,
-		EmployeeImageURI: updatedData.EmployeeImageURI,
-		Gender:           updatedData.Gender,
-		DepartmentID:     fmt.Sprintf("%d", updatedData.DepartmentID),
+
+	if managerID == 0 {
+		return employee, nil
+	}
+
+	// Not found rather than forbidden, so other managers' employees can't be told apart from missing ones
+	owner, err := e.owner(ctx, employee.DepartmentID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && owner != managerID) {
+		return nil, domain.ErrEmployeeNotFound
 	}
-	return res, nil
+	if err != nil {
+		return nil, err
+	}
+
+	return employee, nil
 }
 
-func generateUpdateData(
-	newData dto.EmployeeUpdateReq,
-	oldData entity.Employee,
-) entity.Employee {
-	updatedData := oldData
+// checkDepartment fails with ErrDepartmentNotFound unless the department exists and is the manager's,
+// whoever manages it when managerID is 0
+func (e employeeService) checkDepartment(ctx context.Context, managerID, departmentID int) error {
+	owner, err := e.owner(ctx, departmentID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && managerID != 0 && owner != managerID) {
+		return domain.ErrDepartmentNotFound
+	}
 
-	log.Error(log.LogInfo{
-		"newData": newData,
-		"oldData": oldData,
-	}, "[generateUpdateData]")
+	return err
+}
 
-	if newData.IdentityNumber != "" {
-		updatedData.IdentityNumber = newData.IdentityNumber
+// owner is the manager of the department, the tenant the events of its employees go to
+func (e employeeService) owner(ctx context.Context, departmentID int) (int, error) {
+	department, err := e.departments.FindByID(ctx, departmentID)
+	if err != nil {
+		return 0, err
 	}
 
-	if newData.Name != "" {
-		updatedData.Name = newData.Name
+	return department.ManagerID, nil
+}
+
+func toEmployeeDataRes(employee entity.Employee) *dto.EmployeeDataRes {
+	return &dto.EmployeeDataRes{
+		IdentityNumber:   employee.IdentityNumber,
+		Name:             employee.Name,
+		EmployeeImageURI: employee.EmployeeImageURI,
+		Gender:           employee.Gender,
+		DepartmentID:     fmt.Sprintf("%d", employee.DepartmentID),
+		Version:          employee.Version,
 	}
+}
 
-	if newData.EmployeeImageURI != "" {
-		updatedData.EmployeeImageURI = newData.EmployeeImageURI
+func validateImageURI(raw string) error {
+	employeeImageUri, err := url.ParseRequestURI(raw)
+	if err != nil {
+		return domain.ErrInvalidEmployeeImageURI
 	}
 
-	if newData.DepartmentID != "" {
-		log.Info(log.LogInfo{
-			"newData.DepartmentID": newData.DepartmentID,
-			"is not empty":         newData.DepartmentID != "",
-			"is not nil":           &newData.DepartmentID != nil,
-			"oldData.DepartmentID": oldData.DepartmentID,
-		}, "newData.DepartmentID")
-		updatedData.DepartmentID, _ = strconv.Atoi(newData.DepartmentID)
+	if employeeImageUri.Scheme == "" || employeeImageUri.Host == "" {
+		return domain.ErrInvalidEmployeeImageURI
 	}
 
-	if newData.Gender != "" {
-		updatedData.Gender = newData.Gender
+	// Additional validation: Check if the host contains a domain or is not empty
+	if !strings.Contains(employeeImageUri.Host, ".") {
+		return domain.ErrInvalidEmployeeImageURI
 	}
 
-	log.Info(log.LogInfo{
-		"updatedData": updatedData,
-	}, "updatedData")
+	return nil
+}
+
+// generateUpdateData merges the patch into the stored employee. Validation already rejected
+// null for every member but the image, which null clears.
+func generateUpdateData(
+	newData dto.EmployeeUpdateReq,
+	oldData entity.Employee,
+) entity.Employee {
+	updatedData := oldData
+
+	updatedData.IdentityNumber = newData.IdentityNumber.Apply(oldData.IdentityNumber)
+	updatedData.Name = newData.Name.Apply(oldData.Name)
+	updatedData.EmployeeImageURI = newData.EmployeeImageURI.Apply(oldData.EmployeeImageURI)
+	updatedData.Gender = newData.Gender.Apply(oldData.Gender)
+
+	if departmentID, ok := newData.DepartmentID.Get(); ok {
+		updatedData.DepartmentID, _ = strconv.Atoi(departmentID)
+	}
 
 	return updatedData
 }
+
+// auditView masks the identity number and name: the audit log is kept forever and is not encrypted.
+// What is left is enough to tell records apart in a diff.
+func auditView(employee entity.Employee) entity.Employee {
+	identityNumber := []rune(employee.IdentityNumber)
+	for i := 0; i < len(identityNumber)-4; i++ {
+		identityNumber[i] = '*'
+	}
+	employee.IdentityNumber = string(identityNumber)
+
+	name := []rune(employee.Name)
+	for i := 1; i < len(name); i++ {
+		name[i] = '*'
+	}
+	employee.Name = string(name)
+
+	return employee
+}
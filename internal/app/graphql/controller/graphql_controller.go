@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+)
+
+const Path = "/graphql"
+
+type graphQLController struct {
+	graphQLService contracts.GraphQLService
+}
+
+// InitNewController mounts the GraphQL endpoint. It authenticates like the REST endpoints it
+// stands in for: API keys need one of their scopes to get in, and each field checks the scope
+// it needs on its own.
+func InitNewController(router fiber.Router, graphQLService contracts.GraphQLService, middleware *middlewares.Middleware) {
+	controller := &graphQLController{
+		graphQLService: graphQLService,
+	}
+
+	router.Post(
+		Path,
+		middleware.RequireAdmin(enums.ScopeRead, enums.ScopeDepartmentWrite, enums.ScopeEmployeeWrite),
+		controller.execute,
+	)
+}
+
+func (c *graphQLController) execute(ctx *fiber.Ctx) error {
+	var req dto.GraphQLRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	res := c.graphQLService.Execute(ctx.UserContext(), req)
+
+	// Sent as is: clients expect the data and errors members at the top level
+	return ctx.Status(fiber.StatusOK).JSON(res)
+}
@@ -0,0 +1,190 @@
+package service
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+)
+
+const (
+	// maxDepth is how deeply fields may nest, enough for the introspection query GraphQL tools send
+	maxDepth = 13
+	// maxCost bounds how much an operation may resolve. Every field costs one and the selection of
+	// a page counts once per item of the page: a full page of departments with a full page of
+	// employees each fits, another page nested under it doesn't.
+	maxCost = 50000
+)
+
+// checkLimits rejects documents nesting fields deeper than maxDepth or costing more than maxCost,
+// before anything is resolved. Documents that don't parse are left to graphql.Do to report.
+func checkLimits(schema graphql.Schema, query string, variables map[string]interface{}) error {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil
+	}
+
+	c := &costing{
+		schema:    schema,
+		variables: variables,
+		fragments: map[string]*ast.FragmentDefinition{},
+		expanding: map[string]bool{},
+	}
+	for _, definition := range document.Definitions {
+		if fragment, ok := definition.(*ast.FragmentDefinition); ok {
+			c.fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		root := schema.QueryType()
+		if operation.Operation == ast.OperationTypeMutation {
+			root = schema.MutationType()
+		}
+
+		// Operations are costed apart, only one of them runs
+		cost, err := c.selectionCost(root, operation.SelectionSet, 1)
+		if err != nil {
+			return err
+		}
+		if cost > maxCost {
+			return domain.ErrGraphQLTooComplex
+		}
+	}
+
+	return nil
+}
+
+type costing struct {
+	schema    graphql.Schema
+	variables map[string]interface{}
+	fragments map[string]*ast.FragmentDefinition
+	// expanding holds the fragments being expanded, a fragment spreading itself is left to validation
+	expanding map[string]bool
+}
+
+// selectionCost is the cost of a selection set of parent, its fields being at depth. parent is
+// nil under fields the schema doesn't define, like the introspection ones.
+func (c *costing) selectionCost(parent *graphql.Object, set *ast.SelectionSet, depth int) (int, error) {
+	if set == nil {
+		return 0, nil
+	}
+
+	cost := 0
+	for _, selection := range set.Selections {
+		var selectionCost int
+		var err error
+
+		switch selection := selection.(type) {
+		case *ast.Field:
+			selectionCost, err = c.fieldCost(parent, selection, depth)
+		case *ast.InlineFragment:
+			selectionCost, err = c.selectionCost(c.typed(parent, selection.TypeCondition), selection.SelectionSet, depth)
+		case *ast.FragmentSpread:
+			fragment, ok := c.fragments[selection.Name.Value]
+			if !ok || c.expanding[fragment.Name.Value] {
+				continue
+			}
+
+			c.expanding[fragment.Name.Value] = true
+			selectionCost, err = c.selectionCost(c.typed(parent, fragment.TypeCondition), fragment.SelectionSet, depth)
+			delete(c.expanding, fragment.Name.Value)
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		// Stopping as soon as the limit is crossed keeps fragments spread over and over cheap to reject
+		cost += selectionCost
+		if cost > maxCost {
+			return cost, nil
+		}
+	}
+
+	return cost, nil
+}
+
+func (c *costing) fieldCost(parent *graphql.Object, field *ast.Field, depth int) (int, error) {
+	if depth > maxDepth {
+		return 0, domain.ErrGraphQLTooDeep
+	}
+
+	var definition *graphql.FieldDefinition
+	if parent != nil {
+		definition = parent.Fields()[field.Name.Value]
+	}
+
+	var child *graphql.Object
+	items := 1
+	if definition != nil {
+		child, _ = graphql.GetNamed(definition.Type).(*graphql.Object)
+		if hasArg(definition, "limit") {
+			items = c.limit(field)
+		}
+	}
+
+	cost, err := c.selectionCost(child, field.SelectionSet, depth+1)
+	if err != nil {
+		return 0, err
+	}
+
+	return 1 + items*cost, nil
+}
+
+// typed is the type a fragment applies to, parent when it names none
+func (c *costing) typed(parent *graphql.Object, condition *ast.Named) *graphql.Object {
+	if condition == nil {
+		return parent
+	}
+
+	object, _ := c.schema.Type(condition.Name.Value).(*graphql.Object)
+	return object
+}
+
+// limit is the size of the page the field asks for, capped like the resolvers cap it
+func (c *costing) limit(field *ast.Field) int {
+	limit := defaultLimit
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "limit" {
+			continue
+		}
+
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if n, err := strconv.Atoi(value.Value); err == nil {
+				limit = n
+			}
+		case *ast.Variable:
+			switch n := c.variables[value.Name.Value].(type) {
+			case float64:
+				limit = int(n)
+			case int:
+				limit = n
+			case json.Number:
+				if i, err := n.Int64(); err == nil {
+					limit = int(i)
+				}
+			}
+		}
+	}
+
+	return min(max(limit, 1), maxLimit)
+}
+
+func hasArg(definition *graphql.FieldDefinition, name string) bool {
+	for _, arg := range definition.Args {
+		if arg.Name() == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/graph-gophers/dataloader/v7"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+)
+
+// batchWait is how long a loader collects keys before loading them. The executor resolves a
+// whole level of the result before waiting on any of it, so every key of a level is in by then.
+const batchWait = 2 * time.Millisecond
+
+type loadersKey struct{}
+
+// loaders batch the lookups resolvers make per item of a list into one query per level
+type loaders struct {
+	department *dataloader.Loader[int, *dto.DepartmentRes]
+	employees  *dataloader.Loader[employeePage, []*dto.EmployeeDataRes]
+}
+
+// employeePage is a page of the employees of a department
+type employeePage struct {
+	departmentID int
+	limit        int
+	offset       int
+}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+func (s *graphQLService) newLoaders() *loaders {
+	return &loaders{
+		department: dataloader.NewBatchedLoader(
+			s.loadDepartments,
+			dataloader.WithWait[int, *dto.DepartmentRes](batchWait),
+		),
+		employees: dataloader.NewBatchedLoader(
+			s.loadEmployees,
+			dataloader.WithWait[employeePage, []*dto.EmployeeDataRes](batchWait),
+		),
+	}
+}
+
+// loadDepartments loads the tenant's departments by id, nil for the ones that do not exist or
+// belong to another manager
+func (s *graphQLService) loadDepartments(ctx context.Context, ids []int) []*dataloader.Result[*dto.DepartmentRes] {
+	results := make([]*dataloader.Result[*dto.DepartmentRes], len(ids))
+
+	departments, err := s.departments.FindByIDs(ctx, reqctx.TenantID(ctx), ids)
+	if err != nil {
+		for i := range results {
+			results[i] = &dataloader.Result[*dto.DepartmentRes]{Error: err}
+		}
+		return results
+	}
+
+	byID := make(map[string]*dto.DepartmentRes, len(departments))
+	for _, department := range departments {
+		byID[department.ID] = department
+	}
+
+	for i, id := range ids {
+		results[i] = &dataloader.Result[*dto.DepartmentRes]{Data: byID[strconv.Itoa(id)]}
+	}
+
+	return results
+}
+
+// loadEmployees loads pages of employees, one query for all the departments asking for the same page.
+// Departments of other managers than the tenant have none.
+func (s *graphQLService) loadEmployees(ctx context.Context, pages []employeePage) []*dataloader.Result[[]*dto.EmployeeDataRes] {
+	results := make([]*dataloader.Result[[]*dto.EmployeeDataRes], len(pages))
+
+	type window struct{ limit, offset int }
+	departmentIDs := map[window][]int{}
+	for _, page := range pages {
+		w := window{page.limit, page.offset}
+		departmentIDs[w] = append(departmentIDs[w], page.departmentID)
+	}
+
+	found := make(map[employeePage][]*dto.EmployeeDataRes, len(pages))
+	for w, ids := range departmentIDs {
+		employees, err := s.employees.FindByDepartmentIDs(ctx, reqctx.TenantID(ctx), ids, w.limit, w.offset)
+		if err != nil {
+			for i := range results {
+				results[i] = &dataloader.Result[[]*dto.EmployeeDataRes]{Error: err}
+			}
+			return results
+		}
+
+		for _, employee := range employees {
+			departmentID, _ := strconv.Atoi(employee.DepartmentID)
+			page := employeePage{departmentID: departmentID, limit: w.limit, offset: w.offset}
+			found[page] = append(found[page], employee)
+		}
+	}
+
+	for i, page := range pages {
+		employees := found[page]
+		if employees == nil {
+			employees = []*dto.EmployeeDataRes{}
+		}
+
+		results[i] = &dataloader.Result[[]*dto.EmployeeDataRes]{Data: employees}
+	}
+
+	return results
+}
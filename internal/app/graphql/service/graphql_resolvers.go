@@ -0,0 +1,380 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/etag"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/patch"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+)
+
+const (
+	// defaultLimit is the size of pages asked for without a limit, as on REST
+	defaultLimit = 5
+	// maxLimit caps pages, which nest: a page of departments each with a page of employees
+	maxLimit = 100
+)
+
+func (s *graphQLService) me(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, enums.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	return s.managers.GetManagerById(p.Context, reqctx.TenantID(p.Context))
+}
+
+func (s *graphQLService) listDepartments(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, enums.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	limit, offset, err := page(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	name, _ := p.Args["name"].(string)
+	departments, err := s.departments.FindByManager(p.Context, reqctx.TenantID(p.Context), name, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	// Employees of these departments asking for their department get it without a query
+	loader := loadersFrom(p.Context).department
+	for _, department := range departments {
+		if id, err := strconv.Atoi(department.ID); err == nil {
+			loader.Prime(p.Context, id, department)
+		}
+	}
+
+	return departments, nil
+}
+
+func (s *graphQLService) department(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, enums.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	id, err := departmentID(p.Args["departmentId"])
+	if err != nil {
+		return nil, err
+	}
+
+	thunk := loadersFrom(p.Context).department.Load(p.Context, id)
+	return func() (interface{}, error) {
+		return thunk()
+	}, nil
+}
+
+func (s *graphQLService) listEmployees(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, enums.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	limit, offset, err := page(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	var department int
+	if raw, ok := p.Args["departmentId"]; ok {
+		if department, err = departmentID(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	identityNumber, _ := p.Args["identityNumber"].(string)
+	name, _ := p.Args["name"].(string)
+	gender, _ := p.Args["gender"].(string)
+
+	return s.employees.Find(p.Context, reqctx.TenantID(p.Context), identityNumber, name, gender, department, limit, offset)
+}
+
+func (s *graphQLService) employee(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, enums.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	employee, err := s.employees.FindByIdentityNumber(p.Context, reqctx.TenantID(p.Context), p.Args["identityNumber"].(string))
+	if errors.Is(err, domain.ErrEmployeeNotFound) {
+		return nil, nil
+	}
+
+	return employee, err
+}
+
+func (s *graphQLService) departmentEmployees(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, enums.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	limit, offset, err := page(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := departmentID(p.Source.(*dto.DepartmentRes).ID)
+	if err != nil {
+		return nil, err
+	}
+
+	thunk := loadersFrom(p.Context).employees.Load(p.Context, employeePage{
+		departmentID: id,
+		limit:        limit,
+		offset:       offset,
+	})
+	return func() (interface{}, error) {
+		return thunk()
+	}, nil
+}
+
+func (s *graphQLService) employeeDepartment(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, enums.ScopeRead); err != nil {
+		return nil, err
+	}
+
+	id, err := departmentID(p.Source.(*dto.EmployeeDataRes).DepartmentID)
+	if err != nil {
+		return nil, err
+	}
+
+	thunk := loadersFrom(p.Context).department.Load(p.Context, id)
+	return func() (interface{}, error) {
+		return thunk()
+	}, nil
+}
+
+func (s *graphQLService) createDepartment(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, enums.ScopeDepartmentWrite); err != nil {
+		return nil, err
+	}
+
+	managerID := reqctx.TenantID(p.Context)
+
+	department, err := s.departments.Create(p.Context, managerID, p.Args["name"].(string))
+	if err != nil {
+		return nil, err
+	}
+
+	s.metrics.DepartmentCreated(strconv.Itoa(managerID))
+
+	return department, nil
+}
+
+func (s *graphQLService) updateDepartment(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, enums.ScopeDepartmentWrite); err != nil {
+		return nil, err
+	}
+
+	id, err := departmentID(p.Args["departmentId"])
+	if err != nil {
+		return nil, err
+	}
+
+	ifMatch, err := s.ifMatch(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.departments.Update(p.Context, reqctx.TenantID(p.Context), id, p.Args["name"].(string), ifMatch)
+}
+
+func (s *graphQLService) deleteDepartment(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, enums.ScopeDepartmentWrite); err != nil {
+		return nil, err
+	}
+
+	id, err := departmentID(p.Args["departmentId"])
+	if err != nil {
+		return nil, err
+	}
+
+	ifMatch, err := s.ifMatch(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.departments.Delete(p.Context, reqctx.TenantID(p.Context), id, ifMatch); err != nil {
+		return nil, err
+	}
+
+	s.metrics.DepartmentDeleted(strconv.Itoa(reqctx.TenantID(p.Context)))
+
+	return true, nil
+}
+
+func (s *graphQLService) createEmployee(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, enums.ScopeEmployeeWrite); err != nil {
+		return nil, err
+	}
+
+	input := p.Args["input"].(map[string]interface{})
+	if err := s.checkDepartment(p.Context, input["departmentId"]); err != nil {
+		return nil, err
+	}
+
+	employee, err := s.employees.Create(p.Context, dto.EmployeeCreateReq{
+		IdentityNumber:   input["identityNumber"].(string),
+		Name:             input["name"].(string),
+		EmployeeImageURI: input["employeeImageUri"].(string),
+		Gender:           input["gender"].(string),
+		DepartmentID:     input["departmentId"].(string),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.metrics.EmployeeCreated(strconv.Itoa(reqctx.TenantID(p.Context)))
+
+	return employee, nil
+}
+
+func (s *graphQLService) updateEmployee(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, enums.ScopeEmployeeWrite); err != nil {
+		return nil, err
+	}
+
+	ifMatch, err := s.ifMatch(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	identityNumber := p.Args["identityNumber"].(string)
+	input := p.Args["input"].(map[string]interface{})
+	if err := s.checkDepartment(p.Context, input["departmentId"]); err != nil {
+		return nil, err
+	}
+
+	req := dto.EmployeeUpdateReq{
+		IdentityNumber:   patchField(input, "identityNumber"),
+		Name:             patchField(input, "name"),
+		EmployeeImageURI: patchField(input, "employeeImageUri"),
+		Gender:           patchField(input, "gender"),
+		DepartmentID:     patchField(input, "departmentId"),
+	}
+
+	return s.employees.Update(p.Context, reqctx.TenantID(p.Context), req, identityNumber, ifMatch)
+}
+
+func (s *graphQLService) deleteEmployee(p graphql.ResolveParams) (interface{}, error) {
+	if err := requireScope(p.Context, enums.ScopeEmployeeWrite); err != nil {
+		return nil, err
+	}
+
+	ifMatch, err := s.ifMatch(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	identityNumber := p.Args["identityNumber"].(string)
+	if err := s.employees.Delete(p.Context, reqctx.TenantID(p.Context), identityNumber, ifMatch); err != nil {
+		return nil, err
+	}
+
+	s.metrics.EmployeeDeleted(strconv.Itoa(reqctx.TenantID(p.Context)))
+
+	return true, nil
+}
+
+func (s *graphQLService) updateManager(p graphql.ResolveParams) (interface{}, error) {
+	// The profile can't be changed with an API key, whatever its scopes, as on REST
+	if principal, _ := reqctx.GetPrincipal(p.Context); principal.APIKeyID != 0 {
+		return nil, domain.ErrAPIKeyScopeDenied
+	}
+
+	ifMatch, err := s.ifMatch(p.Args)
+	if err != nil {
+		return nil, err
+	}
+
+	input := p.Args["input"].(map[string]interface{})
+	req := dto.UpdateManagerRequest{
+		Email:           patchField(input, "email"),
+		Name:            patchField(input, "name"),
+		UserImageUri:    patchField(input, "userImageUri"),
+		CompanyName:     patchField(input, "companyName"),
+		CompanyImageUri: patchField(input, "companyImageUri"),
+	}
+
+	return s.managers.UpdateManagerById(p.Context, reqctx.TenantID(p.Context), req, ifMatch)
+}
+
+// checkDepartment fails with ErrDepartmentNotFound unless the tenant manages the department.
+// Without a department there is nothing to check, validation reports it when it is required.
+func (s *graphQLService) checkDepartment(ctx context.Context, raw interface{}) error {
+	departmentID, _ := raw.(string)
+	if departmentID == "" {
+		return nil
+	}
+
+	id, err := strconv.Atoi(departmentID)
+	if err != nil {
+		return domain.ErrDepartmentNotFound
+	}
+
+	_, err = s.departments.FindOwned(ctx, reqctx.TenantID(ctx), id)
+	return err
+}
+
+// ifMatch turns the version argument into the If-Match the services compare, empty without one
+func (s *graphQLService) ifMatch(args map[string]interface{}) (string, error) {
+	version, ok := args["version"].(int)
+	if !ok {
+		if s.requireVersion {
+			return "", domain.ErrVersionRequired
+		}
+		return "", nil
+	}
+
+	return etag.Format(version), nil
+}
+
+// requireScope rejects API keys without scope, tokens may do everything
+func requireScope(ctx context.Context, scope enums.ScopeEnum) error {
+	principal, _ := reqctx.GetPrincipal(ctx)
+	if !principal.HasScope(scope.String()) {
+		return domain.ErrAPIKeyScopeDenied
+	}
+
+	return nil
+}
+
+func page(args map[string]interface{}) (int, int, error) {
+	limit, ok := args["limit"].(int)
+	if !ok {
+		limit = defaultLimit
+	}
+	offset, _ := args["offset"].(int)
+
+	if limit < 1 || limit > maxLimit || offset < 0 {
+		return 0, 0, domain.ErrInvalidPagination
+	}
+
+	return limit, offset, nil
+}
+
+func departmentID(raw interface{}) (int, error) {
+	id, err := strconv.Atoi(raw.(string))
+	if err != nil {
+		return 0, domain.ErrInvalidDepartmentID
+	}
+
+	return id, nil
+}
+
+// patchField is the patch member for a field of an input object. GraphQL drops null fields,
+// so null keeps the value like a field left out does.
+func patchField(input map[string]interface{}, name string) patch.Field[string] {
+	value, ok := input[name].(string)
+	if !ok {
+		return patch.Field[string]{}
+	}
+
+	return patch.Value(value)
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"github.com/graphql-go/graphql"
+)
+
+// newSchema builds the schema. Queries read what the REST GET endpoints do and mutations
+// change it the same way, through the same services.
+func (s *graphQLService) newSchema() (graphql.Schema, error) {
+	manager := graphql.NewObject(graphql.ObjectConfig{
+		Name:        "Manager",
+		Description: "The manager signed in, owner of the departments and employees",
+		Fields: graphql.Fields{
+			"email":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"name":            &graphql.Field{Type: graphql.String},
+			"userImageUri":    &graphql.Field{Type: graphql.String},
+			"companyName":     &graphql.Field{Type: graphql.String},
+			"companyImageUri": &graphql.Field{Type: graphql.String},
+			"emailVerified":   &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+			"version":         &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	department := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Department",
+		Fields: graphql.Fields{
+			"departmentId": &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"name":         &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"version":      &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	employee := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Employee",
+		Fields: graphql.Fields{
+			"identityNumber":   &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"name":             &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"employeeImageUri": &graphql.Field{Type: graphql.String},
+			"gender":           &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+			"departmentId":     &graphql.Field{Type: graphql.NewNonNull(graphql.ID)},
+			"version":          &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		},
+	})
+
+	// The two refer to each other, so these are added once both exist
+	department.AddFieldConfig("employees", &graphql.Field{
+		Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(employee))),
+		Description: "A page of the employees of the department, ordered by when they were added",
+		Args:        pageArgs(),
+		Resolve:     s.departmentEmployees,
+	})
+	employee.AddFieldConfig("department", &graphql.Field{
+		Type:    department,
+		Resolve: s.employeeDepartment,
+	})
+
+	employeeInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "EmployeeInput",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"identityNumber":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"name":             &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"employeeImageUri": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"gender":           &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+			"departmentId":     &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.ID)},
+		},
+	})
+
+	// Patches keep the fields left out, null included; an empty string clears the optional ones
+	employeePatch := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "EmployeePatch",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"identityNumber":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"name":             &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"employeeImageUri": &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"gender":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"departmentId":     &graphql.InputObjectFieldConfig{Type: graphql.ID},
+		},
+	})
+
+	managerPatch := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name: "ManagerPatch",
+		Fields: graphql.InputObjectConfigFieldMap{
+			"email":           &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"name":            &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"userImageUri":    &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"companyName":     &graphql.InputObjectFieldConfig{Type: graphql.String},
+			"companyImageUri": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		},
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"me": &graphql.Field{
+				Type:    graphql.NewNonNull(manager),
+				Resolve: s.me,
+			},
+			"departments": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(department))),
+				Description: "A page of departments, those whose name contains name when it is given",
+				Args: withPage(graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.String},
+				}),
+				Resolve: s.listDepartments,
+			},
+			"department": &graphql.Field{
+				Type: department,
+				Args: graphql.FieldConfigArgument{
+					"departmentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				},
+				Resolve: s.department,
+			},
+			"employees": &graphql.Field{
+				Type:        graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(employee))),
+				Description: "A page of employees; identity numbers and names only match whole values",
+				Args: withPage(graphql.FieldConfigArgument{
+					"identityNumber": &graphql.ArgumentConfig{Type: graphql.String},
+					"name":           &graphql.ArgumentConfig{Type: graphql.String},
+					"gender":         &graphql.ArgumentConfig{Type: graphql.String},
+					"departmentId":   &graphql.ArgumentConfig{Type: graphql.ID},
+				}),
+				Resolve: s.listEmployees,
+			},
+			"employee": &graphql.Field{
+				Type: employee,
+				Args: graphql.FieldConfigArgument{
+					"identityNumber": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: s.employee,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createDepartment": &graphql.Field{
+				Type: graphql.NewNonNull(department),
+				Args: graphql.FieldConfigArgument{
+					"name": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				},
+				Resolve: s.createDepartment,
+			},
+			"updateDepartment": &graphql.Field{
+				Type: graphql.NewNonNull(department),
+				Args: withVersion(graphql.FieldConfigArgument{
+					"departmentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"name":         &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				}),
+				Resolve: s.updateDepartment,
+			},
+			"deleteDepartment": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: withVersion(graphql.FieldConfigArgument{
+					"departmentId": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+				}),
+				Resolve: s.deleteDepartment,
+			},
+			"createEmployee": &graphql.Field{
+				Type: graphql.NewNonNull(employee),
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(employeeInput)},
+				},
+				Resolve: s.createEmployee,
+			},
+			"updateEmployee": &graphql.Field{
+				Type: graphql.NewNonNull(employee),
+				Args: withVersion(graphql.FieldConfigArgument{
+					"identityNumber": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+					"input":          &graphql.ArgumentConfig{Type: graphql.NewNonNull(employeePatch)},
+				}),
+				Resolve: s.updateEmployee,
+			},
+			"deleteEmployee": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: withVersion(graphql.FieldConfigArgument{
+					"identityNumber": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)},
+				}),
+				Resolve: s.deleteEmployee,
+			},
+			"updateManager": &graphql.Field{
+				Type:        graphql.NewNonNull(manager),
+				Description: "Update the manager signed in; only manager tokens may, API keys cannot",
+				Args: withVersion(graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(managerPatch)},
+				}),
+				Resolve: s.updateManager,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{
+		Query:    query,
+		Mutation: mutation,
+	})
+}
+
+func pageArgs() graphql.FieldConfigArgument {
+	return withPage(graphql.FieldConfigArgument{})
+}
+
+// withPage adds the limit and offset of a list to args, defaulting as on REST
+func withPage(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args["limit"] = &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultLimit}
+	args["offset"] = &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0}
+	return args
+}
+
+// withVersion adds the version a change applies to, the counterpart of If-Match
+func withVersion(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	args["version"] = &graphql.ArgumentConfig{
+		Type:        graphql.Int,
+		Description: "The version of the resource the change applies to; it is rejected when the resource changed since",
+	}
+	return args
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	errorhandler "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/error_handler"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/metrics"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+// graphQLService serves the calling manager's departments and their employees alone, like gRPC
+// does. Those of other managers are not found.
+type graphQLService struct {
+	schema      graphql.Schema
+	managers    contracts.ManagerService
+	departments contracts.DepartmentService
+	employees   contracts.EmployeeService
+	metrics     metrics.MetricsInterface
+	// requireVersion rejects changes to versioned resources made without a version, like If-Match on REST
+	requireVersion bool
+}
+
+func NewGraphQLService(
+	managers contracts.ManagerService,
+	departments contracts.DepartmentService,
+	employees contracts.EmployeeService,
+	metrics metrics.MetricsInterface,
+	requireVersion bool,
+) contracts.GraphQLService {
+	s := &graphQLService{
+		managers:       managers,
+		departments:    departments,
+		employees:      employees,
+		metrics:        metrics,
+		requireVersion: requireVersion,
+	}
+
+	schema, err := s.newSchema()
+	if err != nil {
+		// The schema is static, failing to build it is a programming error
+		panic("graphql: invalid schema: " + err.Error())
+	}
+	s.schema = schema
+
+	return s
+}
+
+func (s *graphQLService) Execute(ctx context.Context, req dto.GraphQLRequest) *dto.GraphQLResponse {
+	ctx, span := tracing.Start(ctx, "GraphQLService.Execute")
+	defer span.End()
+
+	if err := checkLimits(s.schema, req.Query, req.Variables); err != nil {
+		return &dto.GraphQLResponse{Errors: []dto.GraphQLError{toGraphQLError(ctx, gqlerrors.FormatError(err))}}
+	}
+
+	// Loaders cache what they load, a fresh set per operation keeps them from serving stale rows
+	ctx = withLoaders(ctx, s.newLoaders())
+
+	result := graphql.Do(graphql.Params{
+		Schema:         s.schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+
+	res := &dto.GraphQLResponse{Data: result.Data}
+	for _, err := range result.Errors {
+		res.Errors = append(res.Errors, toGraphQLError(ctx, err))
+	}
+
+	return res
+}
+
+// toGraphQLError gives the error of a field the code and status REST would answer it with.
+// Errors in the operation itself, which did not come from a resolver, are kept as they are.
+func toGraphQLError(ctx context.Context, formatted gqlerrors.FormattedError) dto.GraphQLError {
+	res := dto.GraphQLError{
+		Message: formatted.Message,
+		Path:    formatted.Path,
+	}
+
+	err := formatted.OriginalError()
+	var located *gqlerrors.Error
+	if errors.As(err, &located) {
+		err = located.OriginalError
+	}
+	if err == nil {
+		return res
+	}
+
+	var valErr validator.ValidationErrors
+	var reqErr *domain.RequestError
+
+	switch {
+	case errors.As(err, &valErr):
+		res.Message = valErr.Error()
+		res.Extensions = &dto.GraphQLErrorExtension{
+			Code:   errorhandler.CodeValidationFailed,
+			Status: http.StatusBadRequest,
+			Errors: valErr,
+		}
+	case errors.As(err, &reqErr):
+		res.Message = reqErr.Error()
+		res.Extensions = &dto.GraphQLErrorExtension{
+			Code:   reqErr.Code,
+			Status: reqErr.StatusCode,
+		}
+	default:
+		log.ErrorCtx(ctx, log.LogInfo{
+			"error": err.Error(),
+			"path":  formatted.Path,
+		}, "[GraphQLService.Execute] unhandled error")
+
+		res.Message = "internal server error"
+		res.Extensions = &dto.GraphQLErrorExtension{
+			Code:   errorhandler.CodeInternalError,
+			Status: http.StatusInternalServerError,
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	idempotencyKeyColumns = "tenant_id, key, fingerprint, status_code, content_type, body, locked_at, created_at, expires_at"
+
+	queryReserve = `
+	INSERT INTO idempotency_keys (tenant_id, key, fingerprint, expires_at)
+	VALUES ($1, $2, $3, $4)
+	ON CONFLICT (tenant_id, key) DO UPDATE
+		SET fingerprint = EXCLUDED.fingerprint,
+		status_code = NULL,
+		content_type = NULL,
+		body = NULL,
+		locked_at = NOW(),
+		created_at = NOW(),
+		expires_at = EXCLUDED.expires_at
+	WHERE idempotency_keys.expires_at <= NOW()
+		OR (idempotency_keys.status_code IS NULL AND idempotency_keys.locked_at < $5)
+	RETURNING tenant_id`
+	queryGet      = "SELECT " + idempotencyKeyColumns + " FROM idempotency_keys WHERE tenant_id = $1 AND key = $2"
+	queryComplete = `
+	UPDATE idempotency_keys SET status_code = $3, content_type = $4, body = $5
+	WHERE tenant_id = $1 AND key = $2 AND status_code IS NULL`
+	queryRelease       = "DELETE FROM idempotency_keys WHERE tenant_id = $1 AND key = $2 AND status_code IS NULL"
+	queryDeleteExpired = "DELETE FROM idempotency_keys WHERE expires_at <= NOW()"
+)
+
+type idempotencyRepository struct {
+	DB *sqlx.DB
+}
+
+func NewIdempotencyRepository(db *sqlx.DB) contracts.IdempotencyRepository {
+	return &idempotencyRepository{DB: db}
+}
+
+func (repo *idempotencyRepository) Reserve(
+	ctx context.Context,
+	key entity.IdempotencyKey,
+	staleBefore time.Time,
+) (*entity.IdempotencyKey, error) {
+	ctx, span := tracing.StartDB(ctx, "IdempotencyRepository.Reserve", queryReserve)
+	defer span.End()
+
+	var tenantID int
+	err := repo.DB.GetContext(ctx, &tenantID, queryReserve, key.TenantID, key.Key, key.Fingerprint, key.ExpiresAt, staleBefore)
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	// The conflict was not taken over, someone else holds the key
+	var existing entity.IdempotencyKey
+	if err := repo.DB.GetContext(ctx, &existing, queryGet, key.TenantID, key.Key); err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return &existing, nil
+}
+
+func (repo *idempotencyRepository) Complete(
+	ctx context.Context,
+	tenantID int,
+	key string,
+	statusCode int,
+	contentType string,
+	body []byte,
+) error {
+	ctx, span := tracing.StartDB(ctx, "IdempotencyRepository.Complete", queryComplete)
+	defer span.End()
+
+	_, err := repo.DB.ExecContext(ctx, queryComplete, tenantID, key, statusCode, contentType, body)
+	return tracing.RecordError(span, err)
+}
+
+func (repo *idempotencyRepository) Release(ctx context.Context, tenantID int, key string) error {
+	ctx, span := tracing.StartDB(ctx, "IdempotencyRepository.Release", queryRelease)
+	defer span.End()
+
+	_, err := repo.DB.ExecContext(ctx, queryRelease, tenantID, key)
+	return tracing.RecordError(span, err)
+}
+
+func (repo *idempotencyRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	ctx, span := tracing.StartDB(ctx, "IdempotencyRepository.DeleteExpired", queryDeleteExpired)
+	defer span.End()
+
+	result, err := repo.DB.ExecContext(ctx, queryDeleteExpired)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	deleted, err := result.RowsAffected()
+	return deleted, tracing.RecordError(span, err)
+}
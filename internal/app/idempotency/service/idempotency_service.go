@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	maxKeyLength = 255
+	// A reservation older than this belongs to a request that died without completing it
+	reservationLease = time.Minute
+	// Expired keys are swept once every sweepEvery requests
+	sweepEvery     = 1000
+	inProgressWait = time.Second
+)
+
+type Config struct {
+	// TTL is how long a response is replayed for
+	TTL time.Duration
+}
+
+type idempotencyService struct {
+	repo   contracts.IdempotencyRepository
+	config Config
+	begins atomic.Uint64
+}
+
+func NewIdempotencyService(repo contracts.IdempotencyRepository, config Config) contracts.IdempotencyService {
+	return &idempotencyService{
+		repo:   repo,
+		config: config,
+	}
+}
+
+func (s *idempotencyService) Begin(
+	ctx context.Context,
+	tenantID int,
+	key, fingerprint string,
+) (*dto.IdempotentResponse, error) {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Begin")
+	defer span.End()
+
+	if !validKey(key) {
+		return nil, domain.ErrInvalidIdempotencyKey
+	}
+
+	if s.begins.Add(1)%sweepEvery == 0 {
+		s.sweep(ctx)
+	}
+
+	now := time.Now()
+	existing, err := s.repo.Reserve(ctx, entity.IdempotencyKey{
+		TenantID:    tenantID,
+		Key:         key,
+		Fingerprint: fingerprint,
+		ExpiresAt:   now.Add(s.config.TTL),
+	}, now.Add(-reservationLease))
+	if err != nil {
+		// Released between the conflict and the lookup, the client can simply retry
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrIdempotencyKeyInProgress.WithRetryAfter(inProgressWait)
+		}
+		return nil, err
+	}
+
+	if existing == nil {
+		return nil, nil
+	}
+
+	if existing.Fingerprint != fingerprint {
+		return nil, domain.ErrIdempotencyKeyReused
+	}
+
+	if existing.StatusCode == nil {
+		return nil, domain.ErrIdempotencyKeyInProgress.WithRetryAfter(inProgressWait)
+	}
+
+	res := &dto.IdempotentResponse{
+		StatusCode: *existing.StatusCode,
+		Body:       existing.Body,
+	}
+	if existing.ContentType != nil {
+		res.ContentType = *existing.ContentType
+	}
+
+	return res, nil
+}
+
+func (s *idempotencyService) Complete(ctx context.Context, tenantID int, key string, res dto.IdempotentResponse) {
+	ctx, span := tracing.Start(ctx, "IdempotencyService.Complete")
+	defer span.End()
+
+	var err error
+	if res.StatusCode >= 500 {
+		err = s.repo.Release(ctx, tenantID, key)
+	} else {
+		err = s.repo.Complete(ctx, tenantID, key, res.StatusCode, res.ContentType, res.Body)
+	}
+
+	// The response is already made, the reservation just runs out after reservationLease
+	if err != nil {
+		tracing.RecordError(span, err)
+		log.ErrorCtx(ctx, log.LogInfo{
+			"status": res.StatusCode,
+			"error":  err.Error(),
+		}, "[IdempotencyService][Complete] failed to store response")
+	}
+}
+
+func (s *idempotencyService) sweep(ctx context.Context) {
+	deleted, err := s.repo.DeleteExpired(ctx)
+	if err != nil {
+		log.WarnCtx(ctx, log.LogInfo{
+			"error": err.Error(),
+		}, "[IdempotencyService][sweep] failed to delete expired keys")
+		return
+	}
+
+	log.DebugCtx(ctx, log.LogInfo{
+		"deleted": deleted,
+	}, "[IdempotencyService][sweep] deleted expired keys")
+}
+
+func validKey(key string) bool {
+	if key == "" || len(key) > maxKeyLength {
+		return false
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] < 0x20 || key[i] > 0x7e {
+			return false
+		}
+	}
+
+	return true
+}
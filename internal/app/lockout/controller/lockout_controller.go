@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
+)
+
+type lockoutController struct {
+	lockoutService contracts.LockoutService
+}
+
+func InitNewController(router fiber.Router, lockoutService contracts.LockoutService, middleware *middlewares.Middleware) {
+	controller := &lockoutController{
+		lockoutService: lockoutService,
+	}
+
+	route := router.Group("/v1/admin")
+	route.Post("/unlock", middleware.RequireAuth(), middleware.RequireOneOfRoles(enums.LeadAdmin), controller.unlock)
+}
+
+func (lc *lockoutController) unlock(ctx *fiber.Ctx) error {
+	var req dto.UnlockAccountRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	if err := lc.lockoutService.Unlock(ctx.UserContext(), req); err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, fiber.Map{
+		"message": "Account unlocked successfully",
+	})
+}
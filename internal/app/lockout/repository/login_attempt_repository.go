@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	// Failures older than the window ($3 seconds) start a new count
+	queryRecordFailure = `
+	INSERT INTO login_attempts (scope, subject, failures, last_failure_at)
+	VALUES ($1, $2, 1, NOW())
+	ON CONFLICT (scope, subject) DO UPDATE SET
+		failures = CASE
+			WHEN login_attempts.last_failure_at < NOW() - make_interval(secs => $3) THEN 1
+			ELSE login_attempts.failures + 1
+		END,
+		last_failure_at = NOW()
+	RETURNING failures`
+	queryLock      = "UPDATE login_attempts SET locked_until = NOW() + make_interval(secs => $3) WHERE scope = $1 AND subject = $2"
+	queryLockedFor = "SELECT EXTRACT(EPOCH FROM (locked_until - NOW()))::float8 FROM login_attempts WHERE scope = $1 AND subject = $2 AND locked_until > NOW()"
+	queryReset     = "DELETE FROM login_attempts WHERE scope = $1 AND subject = $2"
+)
+
+type loginAttemptRepository struct {
+	DB *sqlx.DB
+}
+
+func NewLoginAttemptRepository(db *sqlx.DB) contracts.LoginAttemptRepository {
+	return &loginAttemptRepository{DB: db}
+}
+
+func (repo *loginAttemptRepository) RecordFailure(ctx context.Context, scope, subject string, window time.Duration) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "LoginAttemptRepository.RecordFailure", queryRecordFailure)
+	defer span.End()
+
+	var failures int
+	err := repo.DB.QueryRowContext(ctx, queryRecordFailure, scope, subject, window.Seconds()).Scan(&failures)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	return failures, nil
+}
+
+func (repo *loginAttemptRepository) Lock(ctx context.Context, scope, subject string, duration time.Duration) error {
+	ctx, span := tracing.StartDB(ctx, "LoginAttemptRepository.Lock", queryLock)
+	defer span.End()
+
+	_, err := repo.DB.ExecContext(ctx, queryLock, scope, subject, duration.Seconds())
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return nil
+}
+
+func (repo *loginAttemptRepository) LockedFor(ctx context.Context, scope, subject string) (time.Duration, error) {
+	ctx, span := tracing.StartDB(ctx, "LoginAttemptRepository.LockedFor", queryLockedFor)
+	defer span.End()
+
+	var seconds []float64
+	err := repo.DB.SelectContext(ctx, &seconds, queryLockedFor, scope, subject)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	if len(seconds) == 0 {
+		return 0, nil
+	}
+
+	return time.Duration(seconds[0] * float64(time.Second)), nil
+}
+
+func (repo *loginAttemptRepository) Reset(ctx context.Context, scope, subject string) error {
+	ctx, span := tracing.StartDB(ctx, "LoginAttemptRepository.Reset", queryReset)
+	defer span.End()
+
+	_, err := repo.DB.ExecContext(ctx, queryReset, scope, subject)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return nil
+}
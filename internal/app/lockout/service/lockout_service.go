@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+const (
+	ScopeAccount = "account"
+	ScopeIP      = "ip"
+
+	KindManager = "manager"
+	KindUser    = "user"
+)
+
+// Policy controls when failed logins slow down and lock an account or IP.
+// Zero values fall back to the defaults below.
+type Policy struct {
+	MaxAttempts   int
+	IPMaxAttempts int
+	Window        time.Duration
+	Lockout       time.Duration
+	MaxDelay      time.Duration
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 5
+	}
+	if p.IPMaxAttempts <= 0 {
+		p.IPMaxAttempts = 20
+	}
+	if p.Window <= 0 {
+		p.Window = 15 * time.Minute
+	}
+	if p.Lockout <= 0 {
+		p.Lockout = 15 * time.Minute
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = 30 * time.Second
+	}
+
+	return p
+}
+
+// delay is how long the next attempt has to wait after the given number of failures.
+// The first two failures are free, then the wait doubles from one second up to MaxDelay.
+func (p Policy) delay(failures int) time.Duration {
+	if failures < 3 {
+		return 0
+	}
+
+	d := time.Second << min(failures-3, 16)
+	return min(d, p.MaxDelay)
+}
+
+// Account builds the subject tracked for a login identity, e.g. "manager:jane@example.com"
+func Account(kind, email string) string {
+	return kind + ":" + strings.ToLower(strings.TrimSpace(email))
+}
+
+type lockoutService struct {
+	repo      contracts.LoginAttemptRepository
+	validator validator.ValidatorInterface
+	policy    Policy
+}
+
+func NewLockoutService(repository contracts.LoginAttemptRepository, validator validator.ValidatorInterface, policy Policy) contracts.LockoutService {
+	return &lockoutService{
+		repo:      repository,
+		validator: validator,
+		policy:    policy.withDefaults(),
+	}
+}
+
+// Check rejects the attempt while the account or the client IP is locked or still has to wait
+func (s *lockoutService) Check(ctx context.Context, account string) error {
+	ctx, span := tracing.Start(ctx, "LockoutService.Check")
+	defer span.End()
+
+	wait, err := s.repo.LockedFor(ctx, ScopeAccount, account)
+	if err != nil {
+		return err
+	}
+
+	if ip := reqctx.ClientIP(ctx); ip != "" {
+		ipWait, err := s.repo.LockedFor(ctx, ScopeIP, ip)
+		if err != nil {
+			return err
+		}
+
+		wait = max(wait, ipWait)
+	}
+
+	if wait > 0 {
+		return domain.ErrTooManyLoginAttempts.WithRetryAfter(wait)
+	}
+
+	return nil
+}
+
+// Fail records a failed attempt for the account and the client IP and returns the error the
+// login should respond with. Unknown accounts are recorded too, so lockouts do not reveal
+// which emails exist.
+func (s *lockoutService) Fail(ctx context.Context, account string) error {
+	ctx, span := tracing.Start(ctx, "LockoutService.Fail")
+	defer span.End()
+
+	if err := s.fail(ctx, ScopeAccount, account, s.policy.MaxAttempts); err != nil {
+		return err
+	}
+
+	if ip := reqctx.ClientIP(ctx); ip != "" {
+		if err := s.fail(ctx, ScopeIP, ip, s.policy.IPMaxAttempts); err != nil {
+			return err
+		}
+	}
+
+	return domain.ErrCredentialsNotMatch
+}
+
+func (s *lockoutService) fail(ctx context.Context, scope, subject string, maxAttempts int) error {
+	failures, err := s.repo.RecordFailure(ctx, scope, subject, s.policy.Window)
+	if err != nil {
+		return err
+	}
+
+	wait := s.policy.delay(failures)
+	if failures >= maxAttempts {
+		wait = s.policy.Lockout
+
+		log.WarnCtx(ctx, log.LogInfo{
+			"scope":    scope,
+			"failures": failures,
+		}, "[LockoutService][Fail] login locked")
+	}
+
+	if wait == 0 {
+		return nil
+	}
+
+	return s.repo.Lock(ctx, scope, subject, wait)
+}
+
+// Succeed clears the failures of the account. The IP counter is kept, otherwise one valid
+// account would let an attacker reset it between guesses on others.
+func (s *lockoutService) Succeed(ctx context.Context, account string) error {
+	ctx, span := tracing.Start(ctx, "LockoutService.Succeed")
+	defer span.End()
+
+	return s.repo.Reset(ctx, ScopeAccount, account)
+}
+
+func (s *lockoutService) Unlock(ctx context.Context, req dto.UnlockAccountRequest) error {
+	ctx, span := tracing.Start(ctx, "LockoutService.Unlock")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
+	valErr := s.validator.Validate(req)
+	valSpan.End()
+	if valErr != nil {
+		return valErr
+	}
+
+	if err := s.repo.Reset(ctx, ScopeAccount, Account(req.Kind, req.Email)); err != nil {
+		return err
+	}
+
+	log.InfoCtx(ctx, log.LogInfo{
+		"kind": req.Kind,
+	}, "[LockoutService][Unlock] account unlocked")
+
+	return nil
+}
@@ -2,9 +2,13 @@ package controller
 
 import (
 	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/manager/service"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/etag"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
 )
 
@@ -12,7 +16,7 @@ type managerController struct {
 	managerService service.ManagerService
 }
 
-func InitManagerController(router fiber.Router, managerService service.ManagerService) {
+func InitManagerController(router fiber.Router, managerService service.ManagerService, middleware *middlewares.Middleware) {
 	controller := managerController{
 		managerService: managerService,
 	}
@@ -20,23 +24,18 @@ func InitManagerController(router fiber.Router, managerService service.ManagerSe
 	authGroup := router.Group("/v1/auth")
 	authGroup.Post("/", controller.handleAuth)
 
-	jwtManager := jwt.JwtManager
-	jwt := jwt.Jwt
-
-	middleware := middlewares.NewMiddleware(jwt, jwtManager)
-
 	managerRoute := router.Group("/v1/user")
-	managerRoute.Get("/", middleware.RequireAdmin(), controller.GetManagerById)
-	managerRoute.Patch("/", middleware.RequireAdmin(), controller.UpdateManagerById)
+	managerRoute.Get("/", middleware.RequireAdmin(enums.ScopeRead), controller.GetManagerById)
+	managerRoute.Patch("/", middleware.RequireAdmin(), middleware.Precondition(), controller.UpdateManagerById)
 }
 
 func (mc *managerController) handleAuth(ctx *fiber.Ctx) error {
 	var req dto.AuthRequest
 	if err := ctx.BodyParser(&req); err != nil {
-		return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		return domain.ErrInvalidRequestBody
 	}
 
-	res, err := mc.managerService.Authenticate(ctx.Context(), req)
+	res, err := mc.managerService.Authenticate(ctx.UserContext(), req)
 	if err != nil {
 		return err
 	}
@@ -45,34 +44,32 @@ func (mc *managerController) handleAuth(ctx *fiber.Ctx) error {
 	if req.Action == "create" {
 		status = fiber.StatusCreated
 	}
-	return ctx.Status(status).JSON(res)
+	return response.SendResponse(ctx, status, res)
 
 }
 
 func (mc *managerController) GetManagerById(ctx *fiber.Ctx) error {
 	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
 
-	res, err := mc.managerService.GetManagerById(ctx.Context(), managerID)
+	res, err := mc.managerService.GetManagerById(ctx.UserContext(), managerID)
 	if err != nil {
 		return err
 	}
 
-	return ctx.Status(fiber.StatusOK).JSON(res)
+	return etag.SendResponse(ctx, fiber.StatusOK, res.Version, res)
 }
 
 func (mc *managerController) UpdateManagerById(ctx *fiber.Ctx) error {
 	var requestBody dto.UpdateManagerRequest
 	if err := ctx.BodyParser(&requestBody); err != nil {
-		return ctx.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid request body",
-		})
+		return domain.ErrInvalidRequestBody
 	}
 
 	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
 
-	_, err := mc.managerService.UpdateManagerById(ctx.Context(), managerID, requestBody)
+	res, err := mc.managerService.UpdateManagerById(ctx.UserContext(), managerID, requestBody, ctx.Get(fiber.HeaderIfMatch))
 	if err != nil {
 		return err
 	}
-	return ctx.Status(fiber.StatusOK).JSON(requestBody)
+	return etag.SendResponse(ctx, fiber.StatusOK, res.Version, res)
 }
@@ -8,6 +8,19 @@ import (
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	queryEmailExists       = "SELECT EXISTS(SELECT 1 FROM managers WHERE email=$1)"
+	queryCreateManager     = "INSERT INTO managers (email, password) VALUES ($1, $2)"
+	queryUpdateManager     = "UPDATE managers SET name = $1, user_image_uri = $2, company_name = $3, company_image_uri = $4 WHERE email = $5"
+	queryGetManagerByEmail = "SELECT * FROM managers WHERE email=$1"
+	queryGetManagerByID    = "SELECT * FROM managers WHERE id=$1"
+	queryUpdateManagerByID = "UPDATE managers SET name = $1, user_image_uri = $2, company_name = $3, company_image_uri = $4 WHERE id = $5"
+	// Only replaces the hash it was computed from, so a concurrent password change wins
+	queryRehashPassword = "UPDATE managers SET password = $1 WHERE id = $2 AND password = $3"
 )
 
 type ManagerRepository interface {
@@ -16,7 +29,10 @@ type ManagerRepository interface {
 	GetManagerByEmail(ctx context.Context, email string) (entity.Manager, error)
 	GetManagerById(ctx context.Context, id int) (*entity.Manager, error)
 	UpdateManagerById(ctx context.Context, id int, email string, name string, userImageUri string, companyName string, companyImageUri string) (int, error)
-	UpdateManagerByIDSomeFields(ctx context.Context, id int, fields []string, args []interface{}) (int, error)
+	// UpdateManagerByIDSomeFields only applies to the row at version and returns the new version,
+	// sql.ErrNoRows means it changed since it was read
+	UpdateManagerByIDSomeFields(ctx context.Context, id, version int, fields []string, args []interface{}) (int, error)
+	RehashPassword(ctx context.Context, id int, oldHash, newHash string) error
 }
 
 type managerRepository struct {
@@ -30,73 +46,98 @@ func NewManagerRepository(db *sqlx.DB) ManagerRepository {
 }
 
 func (r *managerRepository) EmailExists(ctx context.Context, email string) (bool, error) {
+	ctx, span := tracing.StartDB(ctx, "ManagerRepository.EmailExists", queryEmailExists)
+	defer span.End()
+
 	var exists bool
-	err := r.db.Get(&exists, "SELECT EXISTS(SELECT 1 FROM managers WHERE email=$1)", email)
-	return exists, err
+	err := dbtx.From(ctx, r.db).GetContext(ctx, &exists, queryEmailExists, email)
+	return exists, tracing.RecordError(span, err)
 }
 
 func (r *managerRepository) CreateManager(ctx context.Context, req dto.AuthRequest) (entity.Manager, error) {
-	_, err := r.db.Exec("INSERT INTO managers (email, password) VALUES ($1, $2)", req.Email, req.Password)
+	ctx, span := tracing.StartDB(ctx, "ManagerRepository.CreateManager", queryCreateManager)
+	defer span.End()
+
+	_, err := dbtx.From(ctx, r.db).ExecContext(ctx, queryCreateManager, req.Email, req.Password)
 	if err != nil {
-		return entity.Manager{}, err
+		return entity.Manager{}, tracing.RecordError(span, err)
 	}
 
 	return r.GetManagerByEmail(ctx, req.Email)
 }
 
 func (r *managerRepository) UpdateManager(ctx context.Context, req dto.ManagerProfile) (entity.Manager, error) {
-	_, err := r.db.Exec("UPDATE managers SET name = $1, user_image_uri = $2, company_name = $3, company_image_uri = $4 WHERE email = $5", req.Name, req.UserImageUri, req.CompanyName, req.CompanyImageUri, req.Email)
+	ctx, span := tracing.StartDB(ctx, "ManagerRepository.UpdateManager", queryUpdateManager)
+	defer span.End()
+
+	_, err := dbtx.From(ctx, r.db).ExecContext(ctx, queryUpdateManager, req.Name, req.UserImageUri, req.CompanyName, req.CompanyImageUri, req.Email)
 	if err != nil {
-		return entity.Manager{}, err
+		return entity.Manager{}, tracing.RecordError(span, err)
 	}
 
 	return r.GetManagerByEmail(ctx, req.Email)
 }
 
 func (r *managerRepository) GetManagerByEmail(ctx context.Context, email string) (entity.Manager, error) {
+	ctx, span := tracing.StartDB(ctx, "ManagerRepository.GetManagerByEmail", queryGetManagerByEmail)
+	defer span.End()
+
 	var manager entity.Manager
-	err := r.db.Get(&manager, "SELECT * FROM managers WHERE email=$1", email)
-	return manager, err
+	err := dbtx.From(ctx, r.db).GetContext(ctx, &manager, queryGetManagerByEmail, email)
+	return manager, tracing.RecordError(span, err)
 }
 
 func (r *managerRepository) GetManagerById(ctx context.Context, id int) (*entity.Manager, error) {
+	ctx, span := tracing.StartDB(ctx, "ManagerRepository.GetManagerById", queryGetManagerByID)
+	defer span.End()
+
 	var manager entity.Manager
-	err := r.db.Get(&manager, "SELECT * FROM managers WHERE id=$1", id)
-	return &manager, err
+	err := dbtx.From(ctx, r.db).GetContext(ctx, &manager, queryGetManagerByID, id)
+	return &manager, tracing.RecordError(span, err)
 }
 
 func (r *managerRepository) UpdateManagerById(ctx context.Context, id int, email string, name string, userImageUri string, companyName string, companyImageUri string) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "ManagerRepository.UpdateManagerById", queryUpdateManagerByID)
+	defer span.End()
 
 	_, err := r.GetManagerByEmail(ctx, email)
 	if err == nil { // successfully found a manager with the same email
 		return 0, domain.ErrUserEmailAlreadyExists
 	}
-	result, err := r.db.ExecContext(ctx, "UPDATE managers SET name = $1, user_image_uri = $2, company_name = $3, company_image_uri = $4 WHERE id = $5", name, userImageUri, companyName, companyImageUri, id)
+	result, err := dbtx.From(ctx, r.db).ExecContext(ctx, queryUpdateManagerByID, name, userImageUri, companyName, companyImageUri, id)
 	if err != nil {
-		return 0, err
+		return 0, tracing.RecordError(span, err)
 	}
 
 	rowsAffected, _ := result.RowsAffected()
 	return int(rowsAffected), nil
 }
 
-func (r *managerRepository) UpdateManagerByIDSomeFields(ctx context.Context, id int, fields []string, args []interface{}) (int, error) {
+func (r *managerRepository) UpdateManagerByIDSomeFields(ctx context.Context, id, version int, fields []string, args []interface{}) (int, error) {
 	query := `UPDATE managers SET `
 	for i, field := range fields {
-		query += fmt.Sprintf("%s = $%d", field, i+1)
-		if i != len(fields)-1 {
-			query += ", "
-		}
+		query += fmt.Sprintf("%s = $%d, ", field, i+1)
 	}
-	query += fmt.Sprintf(" WHERE id = $%d", len(fields)+1)
+	query += fmt.Sprintf("version = version + 1, updated_at = NOW() WHERE id = $%d AND version = $%d RETURNING version", len(fields)+1, len(fields)+2)
 
-	args = append(args, id)
+	args = append(args, id, version)
 
-	result, err := r.db.ExecContext(ctx, query, args...)
+	ctx, span := tracing.StartDB(ctx, "ManagerRepository.UpdateManagerByIDSomeFields", query)
+	defer span.End()
+
+	var newVersion int
+	err := dbtx.From(ctx, r.db).GetContext(ctx, &newVersion, query, args...)
 	if err != nil {
-		return 0, err
+		return 0, tracing.RecordError(span, err)
 	}
 
-	rowsAffected, _ := result.RowsAffected()
-	return int(rowsAffected), nil
+	return newVersion, nil
+}
+
+func (r *managerRepository) RehashPassword(ctx context.Context, id int, oldHash, newHash string) error {
+	ctx, span := tracing.StartDB(ctx, "ManagerRepository.RehashPassword", queryRehashPassword)
+	defer span.End()
+
+	_, err := dbtx.From(ctx, r.db).ExecContext(ctx, queryRehashPassword, newHash, id, oldHash)
+	return tracing.RecordError(span, err)
 }
@@ -5,47 +5,76 @@ import (
 	"database/sql"
 	"errors"
 	"net/url"
+	"strconv"
 	"strings"
 
-	"github.com/gofiber/fiber/v2"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	lockoutSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/lockout/service"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/manager/repository"
-	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/bcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/hasher"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/etag"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
 )
 
 type ManagerService interface {
 	Authenticate(ctx context.Context, req dto.AuthRequest) (dto.AuthResponse, error)
 	GetManagerById(ctx context.Context, id int) (*dto.GetCurrentManagerResponse, error)
-	UpdateManagerById(ctx context.Context, id int, req dto.UpdateManagerRequest) (*dto.UpdateManagerResponse, error)
+	// UpdateManagerById takes the If-Match header of the request, empty when it had none
+	UpdateManagerById(ctx context.Context, id int, req dto.UpdateManagerRequest, ifMatch string) (*dto.UpdateManagerResponse, error)
 }
 
 type managerService struct {
 	repo      repository.ManagerRepository
 	jwt       jwt.JwtManagerInterface
-	bcrypt    bcrypt.BcryptInterface
+	hasher    hasher.PasswordHasher
 	validator validator.ValidatorInterface
+	lockout   contracts.LockoutService
+	account   contracts.AccountService
+	mfa       contracts.MFAService
+	audit     contracts.AuditService
+	tx        dbtx.TransactorInterface
 }
 
 func NewManagerService(
 	repo repository.ManagerRepository,
 	jwt jwt.JwtManagerInterface,
-	bcrypt bcrypt.BcryptInterface,
+	hasher hasher.PasswordHasher,
 	validator validator.ValidatorInterface,
+	lockout contracts.LockoutService,
+	account contracts.AccountService,
+	mfa contracts.MFAService,
+	audit contracts.AuditService,
+	tx dbtx.TransactorInterface,
 ) ManagerService {
 	return &managerService{
 		repo:      repo,
 		jwt:       jwt,
-		bcrypt:    bcrypt,
+		hasher:    hasher,
 		validator: validator,
+		lockout:   lockout,
+		account:   account,
+		mfa:       mfa,
+		audit:     audit,
+		tx:        tx,
 	}
 }
 
 func (s *managerService) Authenticate(ctx context.Context, req dto.AuthRequest) (dto.AuthResponse, error) {
+	ctx, span := tracing.Start(ctx, "ManagerService.Authenticate")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := s.validator.Validate(req)
+	valSpan.End()
 	if valErr != nil {
 		return dto.AuthResponse{}, valErr
 	}
@@ -58,16 +87,38 @@ func (s *managerService) Authenticate(ctx context.Context, req dto.AuthRequest)
 		}
 
 		if exists {
-			return dto.AuthResponse{}, fiber.NewError(fiber.StatusConflict, "email already exists")
+			return dto.AuthResponse{}, domain.ErrManagerEmailAlreadyExists
 		}
 
-		hashedPassword, err := s.bcrypt.Hash(req.Password)
+		hashedPassword, err := s.hasher.Hash(req.Password)
 		if err != nil {
 			return dto.AuthResponse{}, err
 		}
 
 		req.Password = hashedPassword
-		manager, err := s.repo.CreateManager(ctx, req)
+
+		// The audit record is written with the manager, neither exists without the other
+		var manager entity.Manager
+		err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+			var err error
+			manager, err = s.repo.CreateManager(ctx, req)
+			if err != nil {
+				return err
+			}
+
+			// Registration is anonymous, the new manager is its own actor
+			return s.audit.Record(ctx, dto.AuditRecord{
+				Action:     enums.AuditCreate,
+				EntityType: dto.AuditEntityManager,
+				EntityID:   strconv.Itoa(manager.ID),
+				After:      manager,
+				Actor: &reqctx.Principal{
+					TenantID: manager.ID,
+					UserID:   strconv.Itoa(manager.ID),
+					Kind:     reqctx.PrincipalManager,
+				},
+			})
+		})
 		if err != nil {
 			return dto.AuthResponse{}, err
 		}
@@ -77,17 +128,52 @@ func (s *managerService) Authenticate(ctx context.Context, req dto.AuthRequest)
 			return dto.AuthResponse{}, err
 		}
 
+		s.sendVerification(ctx, manager.ID)
+
 		return dto.AuthResponse{Email: req.Email, Token: token}, nil
 
 	case "login":
+		account := lockoutSvc.Account(lockoutSvc.KindManager, req.Email)
+		if err := s.lockout.Check(ctx, account); err != nil {
+			return dto.AuthResponse{}, err
+		}
+
 		manager, err := s.repo.GetManagerByEmail(ctx, req.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return dto.AuthResponse{}, err
+		}
+
+		// Unknown emails pay for a hash comparison too and get the same error as a wrong password
 		if err != nil {
-			return dto.AuthResponse{}, fiber.NewError(fiber.StatusNotFound, "manager not found")
+			s.hasher.CompareDummy(req.Password)
+			return dto.AuthResponse{}, s.lockout.Fail(ctx, account)
 		}
 
-		isValid := s.bcrypt.Compare(req.Password, manager.Password)
+		isValid, needsRehash := s.hasher.Verify(req.Password, manager.Password)
 		if !isValid {
-			return dto.AuthResponse{}, domain.ErrCredentialsNotMatch
+			return dto.AuthResponse{}, s.lockout.Fail(ctx, account)
+		}
+
+		if needsRehash {
+			s.rehash(ctx, manager.ID, manager.Password, req.Password)
+		}
+
+		// With 2FA the failures are only cleared once the second factor checks out
+		challenge, err := s.mfa.Challenge(ctx, dto.MFASubject{
+			Kind:  lockoutSvc.KindManager,
+			ID:    strconv.Itoa(manager.ID),
+			Email: manager.Email,
+		})
+		if err != nil {
+			return dto.AuthResponse{}, err
+		}
+
+		if challenge != nil {
+			return dto.AuthResponse{Email: req.Email, MFA: challenge}, nil
+		}
+
+		if err := s.lockout.Succeed(ctx, account); err != nil {
+			return dto.AuthResponse{}, err
 		}
 
 		token, err := s.jwt.CreateManager(manager.ID, req.Email)
@@ -98,114 +184,194 @@ func (s *managerService) Authenticate(ctx context.Context, req dto.AuthRequest)
 		return dto.AuthResponse{Email: req.Email, Token: token}, nil
 
 	default:
-		return dto.AuthResponse{}, errors.New("invalid action")
+		return dto.AuthResponse{}, domain.ErrInvalidAuthAction
 	}
 
 }
 
 func (s *managerService) GetManagerById(ctx context.Context, id int) (*dto.GetCurrentManagerResponse, error) {
+	ctx, span := tracing.Start(ctx, "ManagerService.GetManagerById")
+	defer span.End()
+
 	manager, err := s.repo.GetManagerById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 
-	ret := dto.GetCurrentManagerResponse{Email: manager.Email, Name: manager.Name, UserImageUri: manager.UserImageURI, CompanyName: manager.CompanyName, CompanyImageUri: manager.CompanyImageURI}
+	ret := dto.GetCurrentManagerResponse{Email: manager.Email, Name: manager.Name, UserImageUri: manager.UserImageURI, CompanyName: manager.CompanyName, CompanyImageUri: manager.CompanyImageURI, EmailVerified: manager.EmailVerifiedAt != nil, Version: manager.Version}
 	return &ret, nil
 }
 
-func (s *managerService) UpdateManagerById(ctx context.Context, id int, req dto.UpdateManagerRequest) (*dto.UpdateManagerResponse, error) {
+func (s *managerService) UpdateManagerById(ctx context.Context, id int, req dto.UpdateManagerRequest, ifMatch string) (*dto.UpdateManagerResponse, error) {
+	ctx, span := tracing.Start(ctx, "ManagerService.UpdateManagerById")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := s.validator.Validate(req)
+	valSpan.End()
 	if valErr != nil {
 		return nil, valErr
 	}
 
-	if req.Email != nil {
-		manager, err := s.repo.GetManagerByEmail(ctx, *req.Email)
-		if err == nil { // found a manager with the same email
-			if manager.ID != id {
-				log.Info(log.LogInfo{
-					"manager id": manager.ID,
-					"id":         id,
-				}, "[managerService.UpdateManagerById] id")
+	before, err := s.repo.GetManagerById(ctx, id)
+	if err != nil {
+		return nil, err
+	}
 
-				return nil, domain.ErrUserEmailAlreadyExists
-			}
-		}
+	if ifMatch != "" && !etag.Matches(ifMatch, before.Version) {
+		return nil, domain.ErrPreconditionFailed
+	}
 
-		if err != nil && !errors.Is(err, sql.ErrNoRows) { // some other error occurred
+	emailChanged := false
+	if email, ok := req.Email.Get(); ok {
+		manager, err := s.repo.GetManagerByEmail(ctx, email)
+		switch {
+		case errors.Is(err, sql.ErrNoRows): // nobody has the email, this manager included
+			emailChanged = true
+		case err != nil:
 			return nil, err
+		case manager.ID != id:
+			log.DebugCtx(ctx, log.LogInfo{
+				"conflicting_manager_id": manager.ID,
+			}, "[managerService.UpdateManagerById] email already taken")
+
+			return nil, domain.ErrUserEmailAlreadyExists
 		}
 	}
 
-	log.Info(log.LogInfo{
-		"is req user image uri nil": req.UserImageUri == nil,
-	}, "[managerService.UpdateManagerById] id")
-
-	if req.UserImageUri != nil {
-		u, err := url.ParseRequestURI(*req.UserImageUri)
+	// null clears an image, only a new value has to be checked
+	if userImageUri, ok := req.UserImageUri.Get(); ok && userImageUri != "" {
+		u, err := url.ParseRequestURI(userImageUri)
 		if err != nil {
-			return nil, fiber.NewError(fiber.StatusBadRequest, "invalid user image uri")
+			return nil, domain.ErrInvalidUserImageURI
 		}
 
 		if u.Scheme == "" || u.Host == "" {
-			return nil, fiber.NewError(fiber.StatusBadRequest, "invalid user image uri")
+			return nil, domain.ErrInvalidUserImageURI
 		}
 
 		// Additional validation: Check if the host contains a domain or is not empty
 		if !strings.Contains(u.Host, ".") {
-			return nil, fiber.NewError(fiber.StatusBadRequest, "invalid company image uri")
+			return nil, domain.ErrInvalidCompanyImageURI
 		}
 	}
 
-	if req.CompanyImageUri != nil {
-		u, err := url.ParseRequestURI(*req.CompanyImageUri)
+	if companyImageUri, ok := req.CompanyImageUri.Get(); ok && companyImageUri != "" {
+		u, err := url.ParseRequestURI(companyImageUri)
 		if err != nil {
-			return nil, fiber.NewError(fiber.StatusBadRequest, "invalid company image uri")
+			return nil, domain.ErrInvalidCompanyImageURI
 		}
 
-		log.Info(log.LogInfo{
-			"url": u,
-		}, "[managerService.UpdateManagerById] company image uri")
-
 		if u.Scheme == "" || u.Host == "" {
-			return nil, fiber.NewError(fiber.StatusBadRequest, "invalid company image uri")
+			return nil, domain.ErrInvalidCompanyImageURI
 		}
 
 		// Additional validation: Check if the host contains a domain or is not empty
 		if !strings.Contains(u.Host, ".") {
-			return nil, fiber.NewError(fiber.StatusBadRequest, "invalid company image uri")
+			return nil, domain.ErrInvalidCompanyImageURI
 		}
 	}
 
+	// The columns default to '', a cleared member is stored the same way
+	after := *before
 	fields := []string{}
 	args := []interface{}{}
-	if req.Email != nil {
+	if req.Email.Present() {
+		after.Email = req.Email.Apply(before.Email)
 		fields = append(fields, "email")
-		args = append(args, *req.Email)
+		args = append(args, after.Email)
+	}
+	if emailChanged {
+		// the new address has to be verified again
+		after.EmailVerifiedAt = nil
+		fields = append(fields, "email_verified_at")
+		args = append(args, nil)
 	}
-	if req.Name != nil {
+	if req.Name.Present() {
+		after.Name = req.Name.Apply(before.Name)
 		fields = append(fields, "name")
-		args = append(args, *req.Name)
+		args = append(args, after.Name)
 	}
-	if req.UserImageUri != nil {
+	if req.UserImageUri.Present() {
+		after.UserImageURI = req.UserImageUri.Apply(before.UserImageURI)
 		fields = append(fields, "user_image_uri")
-		args = append(args, *req.UserImageUri)
+		args = append(args, after.UserImageURI)
 	}
-	if req.CompanyName != nil {
+	if req.CompanyName.Present() {
+		after.CompanyName = req.CompanyName.Apply(before.CompanyName)
 		fields = append(fields, "company_name")
-		args = append(args, *req.CompanyName)
+		args = append(args, after.CompanyName)
 	}
-	if req.CompanyImageUri != nil {
+	if req.CompanyImageUri.Present() {
+		after.CompanyImageURI = req.CompanyImageUri.Apply(before.CompanyImageURI)
 		fields = append(fields, "company_image_uri")
-		args = append(args, *req.CompanyImageUri)
+		args = append(args, after.CompanyImageURI)
 	}
 
-	_, err := s.repo.UpdateManagerByIDSomeFields(ctx, id, fields, args)
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		after.Version, err = s.repo.UpdateManagerByIDSomeFields(ctx, id, before.Version, fields, args)
+		if err != nil {
+			return err
+		}
+
+		return s.audit.Record(ctx, dto.AuditRecord{
+			Action:     enums.AuditUpdate,
+			EntityType: dto.AuditEntityManager,
+			EntityID:   strconv.Itoa(id),
+			Before:     before,
+			After:      &after,
+		})
+	})
 	if err != nil {
+		// Without If-Match the update still only applies to the version read above
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrPreconditionFailed
+		}
 		return nil, err
 	}
 
-	ret := dto.UpdateManagerResponse{}
+	if emailChanged {
+		s.sendVerification(ctx, id)
+	}
+
+	// The update is versioned against before, so before with the patch applied is the stored row
+	ret := dto.UpdateManagerResponse{
+		Email:           after.Email,
+		Name:            after.Name,
+		UserImageUri:    after.UserImageURI,
+		CompanyName:     after.CompanyName,
+		CompanyImageUri: after.CompanyImageURI,
+		EmailVerified:   after.EmailVerifiedAt != nil,
+		Version:         after.Version,
+	}
 
 	return &ret, nil
 }
+
+// sendVerification emails a verification link without failing the request that triggered it,
+// the manager can ask for a new link from POST /v1/auth/verify/request
+func (s *managerService) sendVerification(ctx context.Context, managerID int) {
+	if err := s.account.RequestVerification(ctx, managerID); err != nil {
+		log.ErrorCtx(ctx, log.LogInfo{
+			"manager_id": managerID,
+			"error":      err.Error(),
+		}, "[managerService.sendVerification] failed to queue verification email")
+	}
+}
+
+// rehash upgrades a password hash made with an older algorithm or weaker costs. The login
+// goes on with the old hash if it fails.
+func (s *managerService) rehash(ctx context.Context, managerID int, oldHash, password string) {
+	newHash, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.repo.RehashPassword(ctx, managerID, oldHash, newHash)
+	}
+
+	if err != nil {
+		log.WarnCtx(ctx, log.LogInfo{
+			"manager_id": managerID,
+			"error":      err.Error(),
+		}, "[managerService.rehash] failed to upgrade password hash")
+	}
+}
@@ -0,0 +1,164 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	lockoutSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/lockout/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+)
+
+type mfaController struct {
+	mfaService contracts.MFAService
+}
+
+// InitNewController mounts the same 2FA handlers three times: for managers, for users, and
+// for logins that hold a challenge token. The handlers read the account from the claims.
+func InitNewController(router fiber.Router, mfaService contracts.MFAService, middleware *middlewares.Middleware) {
+	controller := &mfaController{
+		mfaService: mfaService,
+	}
+
+	managerRoute := router.Group("/v1/user/mfa", middleware.RequireAdmin())
+	controller.mountSettings(managerRoute)
+	managerRoute.Get("/policy", controller.getPolicy)
+	managerRoute.Put("/policy", controller.setPolicy)
+
+	userRoute := router.Group("/auth/mfa", middleware.RequireAuth())
+	controller.mountSettings(userRoute)
+
+	challengeRoute := router.Group("/v1/auth/mfa")
+	challengeRoute.Post("/verify", middleware.RequireMFAChallenge(jwt.ChallengeVerify), controller.verify)
+	challengeRoute.Post("/enroll", middleware.RequireMFAChallenge(jwt.ChallengeEnroll), controller.enroll)
+	challengeRoute.Post("/confirm", middleware.RequireMFAChallenge(jwt.ChallengeEnroll), controller.confirm)
+}
+
+func (mc *mfaController) mountSettings(route fiber.Router) {
+	route.Post("/enroll", mc.enroll)
+	route.Post("/confirm", mc.confirm)
+	route.Post("/disable", mc.disable)
+	route.Post("/recovery-codes", mc.regenerateRecoveryCodes)
+}
+
+func (mc *mfaController) verify(ctx *fiber.Ctx) error {
+	var req dto.MFACodeRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	res, err := mc.mfaService.Verify(ctx.UserContext(), subject(ctx), req)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+func (mc *mfaController) enroll(ctx *fiber.Ctx) error {
+	res, err := mc.mfaService.Enroll(ctx.UserContext(), subject(ctx))
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+func (mc *mfaController) confirm(ctx *fiber.Ctx) error {
+	var req dto.MFACodeRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	res, err := mc.mfaService.Confirm(ctx.UserContext(), subject(ctx), req)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+func (mc *mfaController) disable(ctx *fiber.Ctx) error {
+	var req dto.MFACodeRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	if err := mc.mfaService.Disable(ctx.UserContext(), subject(ctx), req); err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, fiber.Map{
+		"message": "Two-factor authentication disabled",
+	})
+}
+
+func (mc *mfaController) regenerateRecoveryCodes(ctx *fiber.Ctx) error {
+	var req dto.MFACodeRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	res, err := mc.mfaService.RegenerateRecoveryCodes(ctx.UserContext(), subject(ctx), req)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+func (mc *mfaController) getPolicy(ctx *fiber.Ctx) error {
+	res, err := mc.mfaService.GetPolicy(ctx.UserContext(), subject(ctx))
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+func (mc *mfaController) setPolicy(ctx *fiber.Ctx) error {
+	var req dto.MFAPolicyRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	res, err := mc.mfaService.SetPolicy(ctx.UserContext(), subject(ctx), req)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+// subject maps whichever claims the auth middleware stored to the account they belong to
+func subject(ctx *fiber.Ctx) dto.MFASubject {
+	switch claims := ctx.Locals("claims").(type) {
+	case jwt.ClaimsManager:
+		return dto.MFASubject{
+			Kind:  lockoutSvc.KindManager,
+			ID:    strconv.Itoa(claims.UserID),
+			Email: claims.Email,
+		}
+	case jwt.Claims:
+		return dto.MFASubject{
+			Kind:     lockoutSvc.KindUser,
+			ID:       claims.UserID.String(),
+			Email:    claims.Email,
+			RoleName: claims.RoleName,
+		}
+	case jwt.ClaimsChallenge:
+		return dto.MFASubject{
+			Kind:       claims.Kind,
+			ID:         claims.SubjectID,
+			Email:      claims.Email,
+			RoleName:   claims.RoleName,
+			Challenged: true,
+		}
+	}
+
+	return dto.MFASubject{}
+}
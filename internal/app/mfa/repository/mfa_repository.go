@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	queryGetFactor  = "SELECT * FROM mfa_factors WHERE subject_kind = $1 AND subject_id = $2"
+	querySaveFactor = `
+	INSERT INTO mfa_factors (subject_kind, subject_id, secret)
+	VALUES (:subject_kind, :subject_id, :secret)
+	ON CONFLICT (subject_kind, subject_id) DO UPDATE SET
+		secret = EXCLUDED.secret,
+		last_used_step = NULL,
+		created_at = NOW()
+	WHERE mfa_factors.confirmed_at IS NULL`
+	queryConfirmFactor = "UPDATE mfa_factors SET confirmed_at = NOW() WHERE subject_kind = $1 AND subject_id = $2 AND confirmed_at IS NULL"
+	queryDeleteFactor  = "DELETE FROM mfa_factors WHERE subject_kind = $1 AND subject_id = $2"
+	// The step only moves forward, so a code (or an older one) can't be replayed
+	queryUseStep = `
+	UPDATE mfa_factors SET last_used_step = $3
+	WHERE subject_kind = $1 AND subject_id = $2 AND (last_used_step IS NULL OR last_used_step < $3)`
+	queryUseRecoveryCode = `
+	UPDATE mfa_recovery_codes SET used_at = NOW()
+	WHERE subject_kind = $1 AND subject_id = $2 AND code_hash = $3 AND used_at IS NULL`
+	queryDeleteRecoveryCodes = "DELETE FROM mfa_recovery_codes WHERE subject_kind = $1 AND subject_id = $2"
+	queryInsertRecoveryCode  = "INSERT INTO mfa_recovery_codes (subject_kind, subject_id, code_hash) VALUES ($1, $2, $3)"
+	queryGetManagerRequired  = "SELECT mfa_required FROM managers WHERE id = $1"
+	querySetManagerRequired  = "UPDATE managers SET mfa_required = $2 WHERE id = $1"
+)
+
+type mfaRepository struct {
+	DB *sqlx.DB
+}
+
+func NewMFARepository(db *sqlx.DB) contracts.MFARepository {
+	return &mfaRepository{DB: db}
+}
+
+func (repo *mfaRepository) GetFactor(ctx context.Context, kind, subjectID string) (*entity.MFAFactor, error) {
+	ctx, span := tracing.StartDB(ctx, "MFARepository.GetFactor", queryGetFactor)
+	defer span.End()
+
+	var factor entity.MFAFactor
+	err := repo.DB.GetContext(ctx, &factor, queryGetFactor, kind, subjectID)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return &factor, nil
+}
+
+func (repo *mfaRepository) SaveFactor(ctx context.Context, factor entity.MFAFactor) error {
+	ctx, span := tracing.StartDB(ctx, "MFARepository.SaveFactor", querySaveFactor)
+	defer span.End()
+
+	_, err := repo.DB.NamedExecContext(ctx, querySaveFactor, factor)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return nil
+}
+
+// ConfirmFactor enables the factor and stores its first set of recovery codes in one transaction
+func (repo *mfaRepository) ConfirmFactor(ctx context.Context, kind, subjectID string, codeHashes []string) error {
+	ctx, span := tracing.StartDB(ctx, "MFARepository.ConfirmFactor", queryConfirmFactor)
+	defer span.End()
+
+	tx, err := repo.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, queryConfirmFactor, kind, subjectID); err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, kind, subjectID, codeHashes); err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return tracing.RecordError(span, tx.Commit())
+}
+
+func (repo *mfaRepository) DeleteFactor(ctx context.Context, kind, subjectID string) error {
+	ctx, span := tracing.StartDB(ctx, "MFARepository.DeleteFactor", queryDeleteFactor)
+	defer span.End()
+
+	_, err := repo.DB.ExecContext(ctx, queryDeleteFactor, kind, subjectID)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return nil
+}
+
+func (repo *mfaRepository) UseStep(ctx context.Context, kind, subjectID string, step int64) (bool, error) {
+	ctx, span := tracing.StartDB(ctx, "MFARepository.UseStep", queryUseStep)
+	defer span.End()
+
+	result, err := repo.DB.ExecContext(ctx, queryUseStep, kind, subjectID, step)
+	if err != nil {
+		return false, tracing.RecordError(span, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, tracing.RecordError(span, err)
+	}
+
+	return rows > 0, nil
+}
+
+func (repo *mfaRepository) UseRecoveryCode(ctx context.Context, kind, subjectID, codeHash string) (bool, error) {
+	ctx, span := tracing.StartDB(ctx, "MFARepository.UseRecoveryCode", queryUseRecoveryCode)
+	defer span.End()
+
+	result, err := repo.DB.ExecContext(ctx, queryUseRecoveryCode, kind, subjectID, codeHash)
+	if err != nil {
+		return false, tracing.RecordError(span, err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, tracing.RecordError(span, err)
+	}
+
+	return rows > 0, nil
+}
+
+func (repo *mfaRepository) ReplaceRecoveryCodes(ctx context.Context, kind, subjectID string, codeHashes []string) error {
+	ctx, span := tracing.StartDB(ctx, "MFARepository.ReplaceRecoveryCodes", queryInsertRecoveryCode)
+	defer span.End()
+
+	tx, err := repo.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(ctx, tx, kind, subjectID, codeHashes); err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return tracing.RecordError(span, tx.Commit())
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sqlx.Tx, kind, subjectID string, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, queryDeleteRecoveryCodes, kind, subjectID); err != nil {
+		return err
+	}
+
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, queryInsertRecoveryCode, kind, subjectID, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repo *mfaRepository) GetManagerRequired(ctx context.Context, managerID int) (bool, error) {
+	ctx, span := tracing.StartDB(ctx, "MFARepository.GetManagerRequired", queryGetManagerRequired)
+	defer span.End()
+
+	var required bool
+	err := repo.DB.GetContext(ctx, &required, queryGetManagerRequired, managerID)
+	if err != nil {
+		return false, tracing.RecordError(span, err)
+	}
+
+	return required, nil
+}
+
+func (repo *mfaRepository) SetManagerRequired(ctx context.Context, managerID int, required bool) error {
+	ctx, span := tracing.StartDB(ctx, "MFARepository.SetManagerRequired", querySetManagerRequired)
+	defer span.End()
+
+	_, err := repo.DB.ExecContext(ctx, querySetManagerRequired, managerID, required)
+	return tracing.RecordError(span, err)
+}
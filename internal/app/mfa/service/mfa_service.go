@@ -0,0 +1,517 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	lockoutSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/lockout/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/totp"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+const RecoveryCodeCount = 10
+
+// Policy configures how 2FA is presented and which users must use it. An empty RequiredRole
+// keeps 2FA optional for users; otherwise users with that role or above can't log in without
+// it. Managers, the tenants, make it mandatory for their own logins with SetPolicy.
+type Policy struct {
+	Issuer       string
+	RequiredRole enums.RoleEnum
+}
+
+type mfaService struct {
+	repo         contracts.MFARepository
+	lockout      contracts.LockoutService
+	totp         totp.TOTPInterface
+	jwt          jwt.JwtInterface
+	jwtManager   jwt.JwtManagerInterface
+	jwtChallenge jwt.JwtChallengeInterface
+	validator    validator.ValidatorInterface
+	policy       Policy
+}
+
+func NewMFAService(
+	repo contracts.MFARepository,
+	lockout contracts.LockoutService,
+	totp totp.TOTPInterface,
+	jwt jwt.JwtInterface,
+	jwtManager jwt.JwtManagerInterface,
+	jwtChallenge jwt.JwtChallengeInterface,
+	validator validator.ValidatorInterface,
+	policy Policy,
+) contracts.MFAService {
+	if policy.Issuer == "" {
+		policy.Issuer = "GoGoManager"
+	}
+
+	return &mfaService{
+		repo:         repo,
+		lockout:      lockout,
+		totp:         totp,
+		jwt:          jwt,
+		jwtManager:   jwtManager,
+		jwtChallenge: jwtChallenge,
+		validator:    validator,
+		policy:       policy,
+	}
+}
+
+func (s *mfaService) Challenge(ctx context.Context, subject dto.MFASubject) (*dto.MFAChallengeResponse, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.Challenge")
+	defer span.End()
+
+	factor, err := s.factor(ctx, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	purpose := jwt.ChallengeVerify
+	if !enabled(factor) {
+		required, err := s.required(ctx, subject)
+		if err != nil {
+			return nil, err
+		}
+
+		if !required {
+			return nil, nil
+		}
+
+		purpose = jwt.ChallengeEnroll
+	}
+
+	token, err := s.jwtChallenge.CreateChallenge(jwt.ClaimsChallenge{
+		Kind:      subject.Kind,
+		SubjectID: subject.ID,
+		Email:     subject.Email,
+		RoleName:  subject.RoleName,
+		Purpose:   purpose,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.MFAChallengeResponse{
+		MFAToken:           token,
+		EnrollmentRequired: purpose == jwt.ChallengeEnroll,
+	}, nil
+}
+
+func (s *mfaService) Verify(ctx context.Context, subject dto.MFASubject, req dto.MFACodeRequest) (dto.MFATokenResponse, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.Verify")
+	defer span.End()
+
+	if err := s.validate(ctx, req); err != nil {
+		return dto.MFATokenResponse{}, err
+	}
+
+	factor, err := s.factor(ctx, subject)
+	if err != nil {
+		return dto.MFATokenResponse{}, err
+	}
+
+	if !enabled(factor) {
+		return dto.MFATokenResponse{}, domain.ErrMFANotEnabled
+	}
+
+	if err := s.checkCode(ctx, subject, factor, req.Code, true); err != nil {
+		return dto.MFATokenResponse{}, err
+	}
+
+	token, err := s.accessToken(subject)
+	if err != nil {
+		return dto.MFATokenResponse{}, err
+	}
+
+	return dto.MFATokenResponse{Token: token}, nil
+}
+
+// Enroll generates a new secret. It only takes effect once Confirm proves the authenticator
+// app produces matching codes, so calling it again simply restarts the enrollment.
+func (s *mfaService) Enroll(ctx context.Context, subject dto.MFASubject) (dto.MFAEnrollResponse, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.Enroll")
+	defer span.End()
+
+	factor, err := s.factor(ctx, subject)
+	if err != nil {
+		return dto.MFAEnrollResponse{}, err
+	}
+
+	if enabled(factor) {
+		return dto.MFAEnrollResponse{}, domain.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := s.totp.GenerateSecret()
+	if err != nil {
+		return dto.MFAEnrollResponse{}, err
+	}
+
+	err = s.repo.SaveFactor(ctx, entity.MFAFactor{
+		SubjectKind: subject.Kind,
+		SubjectID:   subject.ID,
+		Secret:      secret,
+	})
+	if err != nil {
+		return dto.MFAEnrollResponse{}, err
+	}
+
+	return dto.MFAEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: s.totp.ProvisioningURI(s.policy.Issuer, subject.Email, secret),
+	}, nil
+}
+
+func (s *mfaService) Confirm(ctx context.Context, subject dto.MFASubject, req dto.MFACodeRequest) (dto.MFARecoveryCodesResponse, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.Confirm")
+	defer span.End()
+
+	if err := s.validate(ctx, req); err != nil {
+		return dto.MFARecoveryCodesResponse{}, err
+	}
+
+	factor, err := s.factor(ctx, subject)
+	if err != nil {
+		return dto.MFARecoveryCodesResponse{}, err
+	}
+
+	if factor == nil {
+		return dto.MFARecoveryCodesResponse{}, domain.ErrMFANotEnrolled
+	}
+
+	if enabled(factor) {
+		return dto.MFARecoveryCodesResponse{}, domain.ErrMFAAlreadyEnabled
+	}
+
+	if err := s.checkCode(ctx, subject, factor, req.Code, false); err != nil {
+		return dto.MFARecoveryCodesResponse{}, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return dto.MFARecoveryCodesResponse{}, err
+	}
+
+	if err := s.repo.ConfirmFactor(ctx, subject.Kind, subject.ID, hashes); err != nil {
+		return dto.MFARecoveryCodesResponse{}, err
+	}
+
+	log.InfoCtx(ctx, log.LogInfo{
+		"kind": subject.Kind,
+	}, "[MFAService][Confirm] two-factor authentication enabled")
+
+	res := dto.MFARecoveryCodesResponse{RecoveryCodes: codes}
+
+	// An enrollment forced at login finishes that login
+	if subject.Challenged {
+		res.Token, err = s.accessToken(subject)
+		if err != nil {
+			return dto.MFARecoveryCodesResponse{}, err
+		}
+	}
+
+	return res, nil
+}
+
+func (s *mfaService) Disable(ctx context.Context, subject dto.MFASubject, req dto.MFACodeRequest) error {
+	ctx, span := tracing.Start(ctx, "MFAService.Disable")
+	defer span.End()
+
+	if err := s.validate(ctx, req); err != nil {
+		return err
+	}
+
+	required, err := s.required(ctx, subject)
+	if err != nil {
+		return err
+	}
+
+	if required {
+		return domain.ErrMFARequired
+	}
+
+	factor, err := s.factor(ctx, subject)
+	if err != nil {
+		return err
+	}
+
+	if !enabled(factor) {
+		return domain.ErrMFANotEnabled
+	}
+
+	if err := s.checkCode(ctx, subject, factor, req.Code, true); err != nil {
+		return err
+	}
+
+	if err := s.repo.DeleteFactor(ctx, subject.Kind, subject.ID); err != nil {
+		return err
+	}
+
+	log.InfoCtx(ctx, log.LogInfo{
+		"kind": subject.Kind,
+	}, "[MFAService][Disable] two-factor authentication disabled")
+
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces every recovery code, used or not. It asks for a TOTP code
+// so a leaked recovery code can't be turned into a fresh set.
+func (s *mfaService) RegenerateRecoveryCodes(ctx context.Context, subject dto.MFASubject, req dto.MFACodeRequest) (dto.MFARecoveryCodesResponse, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.RegenerateRecoveryCodes")
+	defer span.End()
+
+	if err := s.validate(ctx, req); err != nil {
+		return dto.MFARecoveryCodesResponse{}, err
+	}
+
+	factor, err := s.factor(ctx, subject)
+	if err != nil {
+		return dto.MFARecoveryCodesResponse{}, err
+	}
+
+	if !enabled(factor) {
+		return dto.MFARecoveryCodesResponse{}, domain.ErrMFANotEnabled
+	}
+
+	if err := s.checkCode(ctx, subject, factor, req.Code, false); err != nil {
+		return dto.MFARecoveryCodesResponse{}, err
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return dto.MFARecoveryCodesResponse{}, err
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(ctx, subject.Kind, subject.ID, hashes); err != nil {
+		return dto.MFARecoveryCodesResponse{}, err
+	}
+
+	return dto.MFARecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+func (s *mfaService) GetPolicy(ctx context.Context, subject dto.MFASubject) (dto.MFAPolicy, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.GetPolicy")
+	defer span.End()
+
+	managerID, err := managerID(subject)
+	if err != nil {
+		return dto.MFAPolicy{}, err
+	}
+
+	required, err := s.repo.GetManagerRequired(ctx, managerID)
+	if err != nil {
+		return dto.MFAPolicy{}, err
+	}
+
+	return dto.MFAPolicy{Required: required}, nil
+}
+
+// SetPolicy changes whether the manager's logins need 2FA. Making it mandatory before enrolling
+// is allowed: the next login then has to enroll, as it does for users of the required role.
+func (s *mfaService) SetPolicy(ctx context.Context, subject dto.MFASubject, req dto.MFAPolicyRequest) (dto.MFAPolicy, error) {
+	ctx, span := tracing.Start(ctx, "MFAService.SetPolicy")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
+	valErr := s.validator.Validate(req)
+	valSpan.End()
+	if valErr != nil {
+		return dto.MFAPolicy{}, valErr
+	}
+
+	managerID, err := managerID(subject)
+	if err != nil {
+		return dto.MFAPolicy{}, err
+	}
+
+	if err := s.repo.SetManagerRequired(ctx, managerID, *req.Required); err != nil {
+		return dto.MFAPolicy{}, err
+	}
+
+	log.InfoCtx(ctx, log.LogInfo{
+		"required": *req.Required,
+	}, "[MFAService][SetPolicy] two-factor requirement changed")
+
+	return dto.MFAPolicy{Required: *req.Required}, nil
+}
+
+func (s *mfaService) validate(ctx context.Context, req dto.MFACodeRequest) error {
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
+	valErr := s.validator.Validate(req)
+	valSpan.End()
+	if valErr != nil {
+		return valErr
+	}
+
+	return nil
+}
+
+// factor returns the factor of the subject, or nil when it never started an enrollment
+func (s *mfaService) factor(ctx context.Context, subject dto.MFASubject) (*entity.MFAFactor, error) {
+	factor, err := s.repo.GetFactor(ctx, subject.Kind, subject.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return factor, nil
+}
+
+// required reports whether the account can't log in without 2FA: managers by their own
+// setting, users by the role the deployment requires it from
+func (s *mfaService) required(ctx context.Context, subject dto.MFASubject) (bool, error) {
+	if subject.Kind == lockoutSvc.KindManager {
+		managerID, err := managerID(subject)
+		if err != nil {
+			return false, err
+		}
+
+		return s.repo.GetManagerRequired(ctx, managerID)
+	}
+
+	if s.policy.RequiredRole == "" {
+		return false, nil
+	}
+
+	return enums.RoleEnum(subject.RoleName).AtLeast(s.policy.RequiredRole), nil
+}
+
+// checkCode shares the login lockout of the account, so the second factor can't be brute
+// forced either. A successful code also clears the failures left by the password step.
+func (s *mfaService) checkCode(ctx context.Context, subject dto.MFASubject, factor *entity.MFAFactor, code string, allowRecovery bool) error {
+	account := lockoutSvc.Account(subject.Kind, subject.Email)
+	if err := s.lockout.Check(ctx, account); err != nil {
+		return err
+	}
+
+	ok, err := s.matches(ctx, factor, code, allowRecovery)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		if err := s.lockout.Fail(ctx, account); !errors.Is(err, domain.ErrCredentialsNotMatch) {
+			return err
+		}
+
+		return domain.ErrInvalidMFACode
+	}
+
+	return s.lockout.Succeed(ctx, account)
+}
+
+func (s *mfaService) matches(ctx context.Context, factor *entity.MFAFactor, code string, allowRecovery bool) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if isTOTPCode(code) {
+		step, ok := s.totp.Validate(factor.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+
+		return s.repo.UseStep(ctx, factor.SubjectKind, factor.SubjectID, step)
+	}
+
+	if !allowRecovery || !enabled(factor) {
+		return false, nil
+	}
+
+	return s.repo.UseRecoveryCode(ctx, factor.SubjectKind, factor.SubjectID, hashRecoveryCode(code))
+}
+
+func (s *mfaService) accessToken(subject dto.MFASubject) (string, error) {
+	if subject.Kind == lockoutSvc.KindManager {
+		id, err := managerID(subject)
+		if err != nil {
+			return "", err
+		}
+
+		return s.jwtManager.CreateManager(id, subject.Email)
+	}
+
+	id, err := uuid.Parse(subject.ID)
+	if err != nil {
+		return "", domain.ErrInvalidMFAToken
+	}
+
+	return s.jwt.Create(id, subject.Email, subject.RoleName)
+}
+
+func managerID(subject dto.MFASubject) (int, error) {
+	if subject.Kind != lockoutSvc.KindManager {
+		return 0, domain.ErrInvalidMFAToken
+	}
+
+	id, err := strconv.Atoi(subject.ID)
+	if err != nil {
+		return 0, domain.ErrInvalidMFAToken
+	}
+
+	return id, nil
+}
+
+func enabled(factor *entity.MFAFactor) bool {
+	return factor != nil && factor.ConfirmedAt != nil
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+var recoveryEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// newRecoveryCodes returns codes like "k3jd7-x9q2m" (50 random bits each) and their hashes.
+// The codes are shown once; only the hashes are stored.
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, RecoveryCodeCount)
+	hashes := make([]string, RecoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+
+		raw := strings.ToLower(recoveryEncoding.EncodeToString(b))[:10]
+		codes[i] = raw[:5] + "-" + raw[5:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes so codes can be typed the way they were read
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	sum := sha256.Sum256([]byte(normalized))
+
+	return hex.EncodeToString(sum[:])
+}
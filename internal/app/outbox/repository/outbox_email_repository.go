@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	// Claimed rows are leased ($2 seconds) rather than locked, so a relay that dies mid-send
+	// only delays the email until the lease runs out. SKIP LOCKED lets several relays share the table.
+	queryClaim = `
+	UPDATE outbox_emails SET
+		status = 'sending',
+		attempts = attempts + 1,
+		next_attempt_at = NOW() + make_interval(secs => $2)
+	WHERE id IN (
+		SELECT id FROM outbox_emails
+		WHERE status IN ('pending', 'sending') AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING *`
+	// The body holds the raw token of the link it carries, it is cleared once nothing will send it again
+	queryMarkSent   = "UPDATE outbox_emails SET status = 'sent', sent_at = NOW(), last_error = '', body = '' WHERE id = $1"
+	queryMarkRetry  = "UPDATE outbox_emails SET status = 'pending', last_error = $2, next_attempt_at = NOW() + make_interval(secs => $3) WHERE id = $1"
+	queryMarkGaveUp = "UPDATE outbox_emails SET status = 'failed', last_error = $2, body = '' WHERE id = $1"
+)
+
+type outboxEmailRepository struct {
+	DB *sqlx.DB
+}
+
+func NewOutboxEmailRepository(db *sqlx.DB) contracts.OutboxEmailRepository {
+	return &outboxEmailRepository{DB: db}
+}
+
+func (repo *outboxEmailRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxEmail, error) {
+	ctx, span := tracing.StartDB(ctx, "OutboxEmailRepository.Claim", queryClaim)
+	defer span.End()
+
+	var emails []entity.OutboxEmail
+	err := repo.DB.SelectContext(ctx, &emails, queryClaim, limit, lease.Seconds())
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return emails, nil
+}
+
+func (repo *outboxEmailRepository) MarkSent(ctx context.Context, id int64) error {
+	ctx, span := tracing.StartDB(ctx, "OutboxEmailRepository.MarkSent", queryMarkSent)
+	defer span.End()
+
+	_, err := repo.DB.ExecContext(ctx, queryMarkSent, id)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return nil
+}
+
+func (repo *outboxEmailRepository) MarkFailed(ctx context.Context, id int64, lastError string, retryIn time.Duration, giveUp bool) error {
+	if giveUp {
+		ctx, span := tracing.StartDB(ctx, "OutboxEmailRepository.MarkFailed", queryMarkGaveUp)
+		defer span.End()
+
+		_, err := repo.DB.ExecContext(ctx, queryMarkGaveUp, id, lastError)
+		if err != nil {
+			return tracing.RecordError(span, err)
+		}
+
+		return nil
+	}
+
+	ctx, span := tracing.StartDB(ctx, "OutboxEmailRepository.MarkFailed", queryMarkRetry)
+	defer span.End()
+
+	_, err := repo.DB.ExecContext(ctx, queryMarkRetry, id, lastError, retryIn.Seconds())
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/fieldcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+// The field name is bound into the ciphertexts, renaming it makes existing rows unreadable
+const fieldPayload = "outbox_events.payload"
+
+const (
+	querySelectEventsForRekey = "SELECT " + outboxEventColumns + `
+	FROM outbox_events WHERE key_id IS DISTINCT FROM $1 ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED`
+	querySelectEventsForDecrypt = "SELECT " + outboxEventColumns + `
+	FROM outbox_events WHERE key_id IS NOT NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`
+	querySealEvent   = "UPDATE outbox_events SET payload = NULL, payload_enc = $1, data_key = $2, key_id = $3 WHERE id = $4"
+	queryUnsealEvent = "UPDATE outbox_events SET payload = $1, payload_enc = NULL, data_key = NULL, key_id = NULL WHERE id = $2"
+)
+
+// outboxEventRow is an event as stored: the payload is plaintext while key_id is NULL, sealed otherwise
+type outboxEventRow struct {
+	ID            int64          `db:"id"`
+	TenantID      int            `db:"tenant_id"`
+	AggregateType string         `db:"aggregate_type"`
+	AggregateID   string         `db:"aggregate_id"`
+	EventID       string         `db:"event_id"`
+	EventType     string         `db:"event_type"`
+	Payload       sql.NullString `db:"payload"`
+	PayloadEnc    []byte         `db:"payload_enc"`
+	DataKey       []byte         `db:"data_key"`
+	KeyID         sql.NullString `db:"key_id"`
+	Status        string         `db:"status"`
+	Attempts      int            `db:"attempts"`
+	LastError     string         `db:"last_error"`
+	NextAttemptAt time.Time      `db:"next_attempt_at"`
+	PublishedAt   *time.Time     `db:"published_at"`
+	CreatedAt     time.Time      `db:"created_at"`
+}
+
+func (repo *outboxEventRepository) open(row outboxEventRow) (entity.OutboxEvent, error) {
+	event := entity.OutboxEvent{
+		ID:            row.ID,
+		TenantID:      row.TenantID,
+		AggregateType: row.AggregateType,
+		AggregateID:   row.AggregateID,
+		EventID:       row.EventID,
+		EventType:     row.EventType,
+		Payload:       row.Payload.String,
+		Status:        row.Status,
+		Attempts:      row.Attempts,
+		LastError:     row.LastError,
+		NextAttemptAt: row.NextAttemptAt,
+		PublishedAt:   row.PublishedAt,
+		CreatedAt:     row.CreatedAt,
+	}
+
+	if !row.KeyID.Valid {
+		return event, nil
+	}
+
+	payload, err := fieldcrypt.Open(repo.crypt, fieldPayload, fieldcrypt.Sealed{
+		Ciphertext: row.PayloadEnc,
+		DataKey:    row.DataKey,
+		KeyID:      row.KeyID.String,
+	})
+	if err != nil {
+		return entity.OutboxEvent{}, err
+	}
+	event.Payload = payload
+
+	return event, nil
+}
+
+func (repo *outboxEventRepository) openAll(rows []outboxEventRow) ([]entity.OutboxEvent, error) {
+	events := make([]entity.OutboxEvent, 0, len(rows))
+	for _, row := range rows {
+		event, err := repo.open(row)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+// Reencrypt seals up to limit payloads that are still plaintext or use another key than the active one,
+// with a fresh data key each. Rows locked by a concurrent run are skipped.
+func (repo *outboxEventRepository) Reencrypt(ctx context.Context, limit int) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "OutboxEventRepository.Reencrypt", querySelectEventsForRekey)
+	defer span.End()
+
+	tx, err := repo.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+	defer tx.Rollback()
+
+	rows := []outboxEventRow{}
+	if err := tx.SelectContext(ctx, &rows, querySelectEventsForRekey, repo.crypt.ActiveKeyID(), limit); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	for _, row := range rows {
+		event, err := repo.open(row)
+		if err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+
+		sealed, err := fieldcrypt.Seal(repo.crypt, fieldPayload, event.Payload)
+		if err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+
+		_, err = tx.ExecContext(ctx, querySealEvent, sealed.Ciphertext, sealed.DataKey, sealed.KeyID, row.ID)
+		if err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+	}
+
+	return len(rows), tracing.RecordError(span, tx.Commit())
+}
+
+// Decrypt writes up to limit sealed payloads back in plaintext, to roll the encryption migration back
+func (repo *outboxEventRepository) Decrypt(ctx context.Context, limit int) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "OutboxEventRepository.Decrypt", querySelectEventsForDecrypt)
+	defer span.End()
+
+	tx, err := repo.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+	defer tx.Rollback()
+
+	rows := []outboxEventRow{}
+	if err := tx.SelectContext(ctx, &rows, querySelectEventsForDecrypt, limit); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	for _, row := range rows {
+		event, err := repo.open(row)
+		if err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, queryUnsealEvent, event.Payload, row.ID); err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+	}
+
+	return len(rows), tracing.RecordError(span, tx.Commit())
+}
@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/fieldcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+// tenantStreamLock is the advisory lock, taken per tenant, serializing appends until they commit. A
+// tenant's event ids then follow commit order and a stream reading past the last id it saw misses none.
+const tenantStreamLock = 0x6f757462
+
+const (
+	outboxEventColumns = `id, tenant_id, aggregate_type, aggregate_id, event_id, event_type, payload, payload_enc,
+		data_key, key_id, status, attempts, last_error, next_attempt_at, published_at, created_at`
+
+	queryAppendEvent = `
+	WITH tenant_lock AS (SELECT pg_advisory_xact_lock($1, $2))
+	INSERT INTO outbox_events (tenant_id, aggregate_type, aggregate_id, event_id, event_type, payload_enc, data_key, key_id)
+	SELECT $2, $3, $4, $5, $6, $7, $8, $9 FROM tenant_lock`
+	// Only the oldest unpublished event of an aggregate is claimable, so an aggregate has at most one
+	// event in flight and a failing one holds back the events behind it. Ids follow commit order within
+	// an aggregate because its writers serialize on the aggregate's row. Leasing works like the emails'.
+	queryClaimEvents = `
+	UPDATE outbox_events SET
+		status = 'publishing',
+		attempts = attempts + 1,
+		next_attempt_at = NOW() + make_interval(secs => $2)
+	WHERE id IN (
+		SELECT o.id FROM outbox_events o
+		WHERE o.status IN ('pending', 'publishing') AND o.next_attempt_at <= NOW()
+		AND NOT EXISTS (
+			SELECT 1 FROM outbox_events p
+			WHERE p.aggregate_type = o.aggregate_type AND p.aggregate_id = o.aggregate_id
+			AND p.id < o.id AND p.status <> 'published'
+		)
+		ORDER BY o.id
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	)
+	RETURNING ` + outboxEventColumns
+	queryListEventsAfter    = "SELECT " + outboxEventColumns + " FROM outbox_events WHERE tenant_id = $1 AND id > $2 ORDER BY id LIMIT $3"
+	queryLastEventID        = "SELECT COALESCE(MAX(id), 0) FROM outbox_events WHERE tenant_id = $1"
+	queryMarkEventPublished = "UPDATE outbox_events SET status = 'published', published_at = NOW(), last_error = '' WHERE id = $1"
+	queryMarkEventRetry     = "UPDATE outbox_events SET status = 'pending', last_error = $2, next_attempt_at = NOW() + make_interval(secs => $3) WHERE id = $1"
+	queryPruneEvents        = "DELETE FROM outbox_events WHERE status = 'published' AND published_at < $1"
+	// The envelope is kept so the event still goes out, or can still be listed, without its data
+	queryScrubEvents = `
+	UPDATE outbox_events SET
+		payload = json_build_object('id', event_id, 'type', event_type, 'occurredAt', created_at, 'data', NULL)::text,
+		payload_enc = NULL,
+		data_key = NULL,
+		key_id = NULL
+	WHERE aggregate_type = $1 AND aggregate_id = $2
+	RETURNING event_id`
+)
+
+type outboxEventRepository struct {
+	DB    *sqlx.DB
+	crypt fieldcrypt.FieldCryptInterface
+}
+
+func NewOutboxEventRepository(db *sqlx.DB, crypt fieldcrypt.FieldCryptInterface) contracts.OutboxEventRepository {
+	return &outboxEventRepository{DB: db, crypt: crypt}
+}
+
+func (repo *outboxEventRepository) Append(ctx context.Context, event entity.OutboxEvent) error {
+	ctx, span := tracing.StartDB(ctx, "OutboxEventRepository.Append", queryAppendEvent)
+	defer span.End()
+
+	sealed, err := fieldcrypt.Seal(repo.crypt, fieldPayload, event.Payload)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	_, err = dbtx.From(ctx, repo.DB).ExecContext(
+		ctx,
+		queryAppendEvent,
+		tenantStreamLock,
+		event.TenantID,
+		event.AggregateType,
+		event.AggregateID,
+		event.EventID,
+		event.EventType,
+		sealed.Ciphertext,
+		sealed.DataKey,
+		sealed.KeyID,
+	)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return nil
+}
+
+func (repo *outboxEventRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.OutboxEvent, error) {
+	ctx, span := tracing.StartDB(ctx, "OutboxEventRepository.Claim", queryClaimEvents)
+	defer span.End()
+
+	var rows []outboxEventRow
+	err := repo.DB.SelectContext(ctx, &rows, queryClaimEvents, limit, lease.Seconds())
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	events, err := repo.openAll(rows)
+	return events, tracing.RecordError(span, err)
+}
+
+func (repo *outboxEventRepository) MarkPublished(ctx context.Context, id int64) error {
+	ctx, span := tracing.StartDB(ctx, "OutboxEventRepository.MarkPublished", queryMarkEventPublished)
+	defer span.End()
+
+	_, err := repo.DB.ExecContext(ctx, queryMarkEventPublished, id)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return nil
+}
+
+func (repo *outboxEventRepository) MarkFailed(ctx context.Context, id int64, lastError string, retryIn time.Duration) error {
+	ctx, span := tracing.StartDB(ctx, "OutboxEventRepository.MarkFailed", queryMarkEventRetry)
+	defer span.End()
+
+	_, err := repo.DB.ExecContext(ctx, queryMarkEventRetry, id, lastError, retryIn.Seconds())
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	return nil
+}
+
+func (repo *outboxEventRepository) Prune(ctx context.Context, before time.Time) (int64, error) {
+	ctx, span := tracing.StartDB(ctx, "OutboxEventRepository.Prune", queryPruneEvents)
+	defer span.End()
+
+	result, err := repo.DB.ExecContext(ctx, queryPruneEvents, before)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	pruned, err := result.RowsAffected()
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	return pruned, nil
+}
+
+func (repo *outboxEventRepository) ListAfter(ctx context.Context, tenantID int, afterID int64, limit int) ([]entity.OutboxEvent, error) {
+	ctx, span := tracing.StartDB(ctx, "OutboxEventRepository.ListAfter", queryListEventsAfter)
+	defer span.End()
+
+	var rows []outboxEventRow
+	err := repo.DB.SelectContext(ctx, &rows, queryListEventsAfter, tenantID, afterID, limit)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	events, err := repo.openAll(rows)
+	return events, tracing.RecordError(span, err)
+}
+
+// Scrub replaces the payload of every event of the aggregate with its bare envelope, with the
+// transaction ctx carries if any, and returns their event ids
+func (repo *outboxEventRepository) Scrub(ctx context.Context, aggregateType, aggregateID string) ([]string, error) {
+	ctx, span := tracing.StartDB(ctx, "OutboxEventRepository.Scrub", queryScrubEvents)
+	defer span.End()
+
+	eventIDs := []string{}
+	err := dbtx.From(ctx, repo.DB).SelectContext(ctx, &eventIDs, queryScrubEvents, aggregateType, aggregateID)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return eventIDs, nil
+}
+
+func (repo *outboxEventRepository) LastID(ctx context.Context, tenantID int) (int64, error) {
+	ctx, span := tracing.StartDB(ctx, "OutboxEventRepository.LastID", queryLastEventID)
+	defer span.End()
+
+	var id int64
+	err := repo.DB.GetContext(ctx, &id, queryLastEventID, tenantID)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	return id, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/uuid"
+)
+
+type outboxEventService struct {
+	repo contracts.OutboxEventRepository
+	uuid uuid.UUIDInterface
+}
+
+func NewOutboxEventService(repo contracts.OutboxEventRepository, uuid uuid.UUIDInterface) contracts.OutboxEventService {
+	return &outboxEventService{repo: repo, uuid: uuid}
+}
+
+func (s *outboxEventService) Record(
+	ctx context.Context,
+	tenantID int,
+	aggregateID string,
+	event enums.WebhookEventEnum,
+	data, previous any,
+) error {
+	ctx, span := tracing.Start(ctx, "OutboxEventService.Record")
+	defer span.End()
+
+	id, err := s.uuid.NewV7()
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(dto.WebhookEvent{
+		ID:         id.String(),
+		Type:       event.String(),
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+		Previous:   previous,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.repo.Append(ctx, entity.OutboxEvent{
+		TenantID:      tenantID,
+		AggregateType: event.Aggregate(),
+		AggregateID:   aggregateID,
+		EventID:       id.String(),
+		EventType:     event.String(),
+		Payload:       string(payload),
+	})
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	EventBatchSize = 20
+	// EventLease is how long claimed events stay invisible to other relays. A batch stops once
+	// half of it is spent, the rest of the batch waits for the lease to run out.
+	EventLease = 2 * time.Minute
+	// EventMaxBackoff caps the exponential retry delay. An event is never given up on,
+	// skipping it would publish the events behind it out of order.
+	EventMaxBackoff = 10 * time.Minute
+	// eventPublishTimeout bounds a single sink's publish of a single event
+	eventPublishTimeout = 5 * time.Second
+	pruneInterval       = time.Hour
+)
+
+type RelayConfig struct {
+	Interval time.Duration
+	// Retention is how long published events are kept before they are pruned, zero keeps them
+	Retention time.Duration
+}
+
+type outboxRelay struct {
+	repo      contracts.OutboxEventRepository
+	sinks     []contracts.OutboxSink
+	interval  time.Duration
+	retention time.Duration
+}
+
+func NewOutboxRelay(
+	repo contracts.OutboxEventRepository,
+	sinks []contracts.OutboxSink,
+	config RelayConfig,
+) contracts.OutboxRelay {
+	if config.Interval <= 0 {
+		config.Interval = time.Second
+	}
+
+	return &outboxRelay{
+		repo:      repo,
+		sinks:     sinks,
+		interval:  config.Interval,
+		retention: config.Retention,
+	}
+}
+
+// Relay publishes one batch of due events to every sink and returns how many were published.
+// An event counts as published once all sinks took it; when one fails the event is retried
+// later on all of them.
+func (r *outboxRelay) Relay(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "OutboxRelay.Relay")
+	defer span.End()
+
+	deadline := time.Now().Add(EventLease / 2)
+	events, err := r.repo.Claim(ctx, EventBatchSize, EventLease)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, event := range events {
+		if time.Now().After(deadline) {
+			break
+		}
+
+		publishErr := r.publish(ctx, event)
+		if publishErr == nil {
+			if err := r.repo.MarkPublished(ctx, event.ID); err != nil {
+				return published, err
+			}
+
+			published++
+			continue
+		}
+
+		log.WarnCtx(ctx, log.LogInfo{
+			"id":         event.ID,
+			"event_type": event.EventType,
+			"attempts":   event.Attempts,
+			"error":      publishErr.Error(),
+		}, "[OutboxRelay][Relay] failed to publish event")
+
+		if err := r.repo.MarkFailed(ctx, event.ID, publishErr.Error(), eventBackoff(event.Attempts)); err != nil {
+			return published, err
+		}
+	}
+
+	return published, nil
+}
+
+// Run relays on every tick until ctx is cancelled. As long as a batch publishes anything the
+// next one follows immediately, it may hold events that were waiting behind this batch's.
+func (r *outboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	var lastPrune time.Time
+	for {
+		for {
+			published, err := r.Relay(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.ErrorCtx(ctx, log.LogInfo{
+						"error": err.Error(),
+					}, "[OutboxRelay][Run] failed to relay events")
+				}
+				break
+			}
+
+			if published == 0 {
+				break
+			}
+		}
+
+		if r.retention > 0 && time.Since(lastPrune) >= pruneInterval {
+			lastPrune = time.Now()
+			r.prune(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *outboxRelay) publish(ctx context.Context, event entity.OutboxEvent) error {
+	for _, sink := range r.sinks {
+		sinkCtx, cancel := context.WithTimeout(ctx, eventPublishTimeout)
+		err := sink.Publish(sinkCtx, event)
+		cancel()
+
+		if err != nil {
+			return fmt.Errorf("%s: %w", sink.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+func (r *outboxRelay) prune(ctx context.Context) {
+	pruned, err := r.repo.Prune(ctx, time.Now().Add(-r.retention))
+	if err != nil {
+		if ctx.Err() == nil {
+			log.ErrorCtx(ctx, log.LogInfo{
+				"error": err.Error(),
+			}, "[OutboxRelay][prune] failed to prune published events")
+		}
+		return
+	}
+
+	if pruned > 0 {
+		log.InfoCtx(ctx, log.LogInfo{
+			"count": pruned,
+		}, "[OutboxRelay][prune] published events pruned")
+	}
+}
+
+func eventBackoff(attempts int) time.Duration {
+	delay := time.Second << max(attempts-1, 0)
+	if delay <= 0 || delay > EventMaxBackoff {
+		return EventMaxBackoff
+	}
+
+	return delay
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/mailer"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	BatchSize   = 20
+	MaxAttempts = 8
+	// Lease is how long a claimed email stays invisible to other relays while it is being sent
+	Lease = time.Minute
+	// MaxBackoff caps the exponential retry delay; 8 attempts span roughly an hour
+	MaxBackoff = 30 * time.Minute
+)
+
+type outboxService struct {
+	repo     contracts.OutboxEmailRepository
+	mailer   mailer.MailerInterface
+	interval time.Duration
+}
+
+func NewOutboxService(
+	repo contracts.OutboxEmailRepository,
+	mailer mailer.MailerInterface,
+	interval time.Duration,
+) contracts.OutboxService {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	return &outboxService{
+		repo:     repo,
+		mailer:   mailer,
+		interval: interval,
+	}
+}
+
+// Dispatch sends one batch of due emails and returns how many were delivered
+func (s *outboxService) Dispatch(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "OutboxService.Dispatch")
+	defer span.End()
+
+	emails, err := s.repo.Claim(ctx, BatchSize, Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, email := range emails {
+		sendErr := s.mailer.Send(ctx, mailer.Message{
+			To:      email.Recipient,
+			Subject: email.Subject,
+			Body:    email.Body,
+		})
+		if sendErr == nil {
+			if err := s.repo.MarkSent(ctx, email.ID); err != nil {
+				return sent, err
+			}
+
+			sent++
+			continue
+		}
+
+		giveUp := email.Attempts >= MaxAttempts
+		log.WarnCtx(ctx, log.LogInfo{
+			"id":       email.ID,
+			"attempts": email.Attempts,
+			"give_up":  giveUp,
+			"error":    sendErr.Error(),
+		}, "[OutboxService][Dispatch] failed to send email")
+
+		if err := s.repo.MarkFailed(ctx, email.ID, sendErr.Error(), backoff(email.Attempts), giveUp); err != nil {
+			return sent, err
+		}
+	}
+
+	return sent, nil
+}
+
+// Run dispatches on every tick until ctx is cancelled. A full batch is followed
+// immediately by the next one so a backlog drains without waiting for the ticker.
+func (s *outboxService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			sent, err := s.Dispatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.ErrorCtx(ctx, log.LogInfo{
+						"error": err.Error(),
+					}, "[OutboxService][Run] failed to dispatch emails")
+				}
+				break
+			}
+
+			if sent < BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func backoff(attempts int) time.Duration {
+	delay := 30 * time.Second << max(attempts-1, 0)
+	if delay <= 0 || delay > MaxBackoff {
+		return MaxBackoff
+	}
+
+	return delay
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/broker"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+)
+
+const (
+	SinkWebhook = "webhook"
+	SinkLog     = "log"
+	SinkNATS    = "nats"
+	SinkKafka   = "kafka"
+)
+
+type SinkConfig struct {
+	// Sinks is a comma separated list of the sinks events are published to, in order
+	Sinks             string
+	NATSURL           string
+	NATSSubjectPrefix string
+	// KafkaBrokers is a comma separated list of host:port
+	KafkaBrokers string
+	KafkaTopic   string
+}
+
+// NewOutboxSinks builds the configured sinks, webhooks alone when none are configured
+func NewOutboxSinks(config SinkConfig, webhooks contracts.WebhookRepository) ([]contracts.OutboxSink, error) {
+	if strings.TrimSpace(config.Sinks) == "" {
+		config.Sinks = SinkWebhook
+	}
+
+	var sinks []contracts.OutboxSink
+	for _, name := range strings.Split(config.Sinks, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case SinkWebhook:
+			sinks = append(sinks, NewWebhookSink(webhooks))
+		case SinkLog:
+			sinks = append(sinks, NewLogSink())
+		case SinkNATS:
+			publisher, err := broker.NewNATSPublisher(broker.NATSConfig{
+				URL:           config.NATSURL,
+				SubjectPrefix: config.NATSSubjectPrefix,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to connect to nats: %w", err)
+			}
+
+			sinks = append(sinks, NewBrokerSink(SinkNATS, publisher))
+		case SinkKafka:
+			sinks = append(sinks, NewBrokerSink(SinkKafka, broker.NewKafkaPublisher(broker.KafkaConfig{
+				Brokers: strings.Split(config.KafkaBrokers, ","),
+				Topic:   config.KafkaTopic,
+			})))
+		default:
+			return nil, fmt.Errorf("unknown outbox sink %q", name)
+		}
+	}
+
+	return sinks, nil
+}
+
+// webhookSink queues the event for the tenant's subscribed endpoints, the webhook dispatcher
+// delivers it from there
+type webhookSink struct {
+	repo contracts.WebhookRepository
+}
+
+func NewWebhookSink(repo contracts.WebhookRepository) contracts.OutboxSink {
+	return &webhookSink{repo: repo}
+}
+
+func (s *webhookSink) Name() string {
+	return SinkWebhook
+}
+
+func (s *webhookSink) Publish(ctx context.Context, event entity.OutboxEvent) error {
+	_, err := s.repo.Enqueue(ctx, event.TenantID, entity.WebhookDelivery{
+		EventID:   event.EventID,
+		EventType: event.EventType,
+		Payload:   event.Payload,
+	})
+
+	return err
+}
+
+// logSink writes events to the log, for development
+type logSink struct{}
+
+func NewLogSink() contracts.OutboxSink {
+	return &logSink{}
+}
+
+func (s *logSink) Name() string {
+	return SinkLog
+}
+
+func (s *logSink) Publish(ctx context.Context, event entity.OutboxEvent) error {
+	// The payload stays out of the log, employee events carry personal data
+	log.InfoCtx(ctx, log.LogInfo{
+		"event_id":       event.EventID,
+		"event_type":     event.EventType,
+		"tenant_id":      event.TenantID,
+		"aggregate_type": event.AggregateType,
+		"aggregate_id":   event.AggregateID,
+	}, "[LogSink][Publish] domain event published")
+
+	return nil
+}
+
+// brokerSink publishes events to a message broker, keyed by aggregate so a partitioned
+// broker keeps each aggregate's events in order
+type brokerSink struct {
+	name      string
+	publisher broker.PublisherInterface
+}
+
+func NewBrokerSink(name string, publisher broker.PublisherInterface) contracts.OutboxSink {
+	return &brokerSink{name: name, publisher: publisher}
+}
+
+func (s *brokerSink) Name() string {
+	return s.name
+}
+
+func (s *brokerSink) Publish(ctx context.Context, event entity.OutboxEvent) error {
+	return s.publisher.Publish(ctx, broker.Message{
+		Subject: event.EventType,
+		Key:     event.AggregateType + ":" + event.AggregateID,
+		ID:      event.EventID,
+		Body:    []byte(event.Payload),
+	})
+}
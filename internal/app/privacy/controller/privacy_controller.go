@@ -0,0 +1,61 @@
+package controller
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+)
+
+type privacyController struct {
+	privacyService contracts.PrivacyService
+}
+
+func InitNewController(router fiber.Router, privacyService contracts.PrivacyService, middleware *middlewares.Middleware) {
+	controller := &privacyController{
+		privacyService: privacyService,
+	}
+
+	route := router.Group("/v1/employee")
+	route.Get("/:identityNumber/export", middleware.RequireAdmin(enums.ScopeRead), controller.export)
+	route.Post("/:identityNumber/erase", middleware.RequireAdmin(enums.ScopeEmployeeWrite), controller.erase)
+}
+
+func (c *privacyController) export(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	identityNumber := ctx.Params("identityNumber")
+	if identityNumber == "" {
+		return domain.ErrIdentityNumberRequired
+	}
+
+	res, err := c.privacyService.Export(ctx.UserContext(), managerID, identityNumber)
+	if err != nil {
+		return err
+	}
+
+	ctx.Attachment(res.FileName)
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+
+	return ctx.Status(fiber.StatusOK).Send(res.Archive)
+}
+
+func (c *privacyController) erase(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	identityNumber := ctx.Params("identityNumber")
+	if identityNumber == "" {
+		return domain.ErrIdentityNumberRequired
+	}
+
+	if err := c.privacyService.Erase(ctx.UserContext(), managerID, identityNumber); err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, fiber.Map{
+		"message": "Employee erased successfully",
+	})
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strconv"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/s3"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const auditPageSize = 100
+
+type privacyService struct {
+	employees   contracts.EmployeeRepository
+	departments contracts.DepartmentRepository
+	audit       contracts.AuditService
+	storage     s3.S3Interface
+	tx          dbtx.TransactorInterface
+	outbox      contracts.OutboxEventRepository
+	webhooks    contracts.WebhookRepository
+	events      contracts.OutboxEventService
+}
+
+func NewPrivacyService(
+	employees contracts.EmployeeRepository,
+	departments contracts.DepartmentRepository,
+	audit contracts.AuditService,
+	storage s3.S3Interface,
+	tx dbtx.TransactorInterface,
+	outbox contracts.OutboxEventRepository,
+	webhooks contracts.WebhookRepository,
+	events contracts.OutboxEventService,
+) contracts.PrivacyService {
+	return &privacyService{
+		employees:   employees,
+		departments: departments,
+		audit:       audit,
+		storage:     storage,
+		tx:          tx,
+		outbox:      outbox,
+		webhooks:    webhooks,
+		events:      events,
+	}
+}
+
+func (s *privacyService) Export(ctx context.Context, managerID int, identityNumber string) (*dto.PrivacyExport, error) {
+	ctx, span := tracing.Start(ctx, "PrivacyService.Export")
+	defer span.End()
+
+	employee, department, err := s.find(ctx, managerID, identityNumber)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	events, err := s.auditEvents(ctx, managerID, employee.ID)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	files := []struct {
+		name string
+		data any
+	}{
+		{"employee.json", dto.EmployeeExport{
+			ID:               employee.ID,
+			IdentityNumber:   employee.IdentityNumber,
+			Name:             employee.Name,
+			Gender:           employee.Gender,
+			DepartmentID:     strconv.Itoa(employee.DepartmentID),
+			EmployeeImageURI: employee.EmployeeImageURI,
+			CreatedAt:        employee.CreatedAt,
+		}},
+		{"department.json", dto.DepartmentRes{
+			ID:   strconv.Itoa(department.ID),
+			Name: department.Name,
+		}},
+		{"audit_events.json", events},
+	}
+
+	for _, file := range files {
+		if err := writeJSON(archive, file.name, file.data); err != nil {
+			return nil, tracing.RecordError(span, err)
+		}
+	}
+
+	if err := s.writeImage(ctx, archive, employee.EmployeeImageURI); err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return &dto.PrivacyExport{
+		// The identity number stays out of the file name, it ends up in headers and download folders
+		FileName: fmt.Sprintf("employee-%d.zip", employee.ID),
+		Archive:  buf.Bytes(),
+	}, nil
+}
+
+func (s *privacyService) Erase(ctx context.Context, managerID int, identityNumber string) error {
+	ctx, span := tracing.Start(ctx, "PrivacyService.Erase")
+	defer span.End()
+
+	employee, _, err := s.find(ctx, managerID, identityNumber)
+	if err != nil {
+		return tracing.RecordError(span, err)
+	}
+
+	// The image goes first: if the row were erased first, a failed delete would leave an
+	// object nothing points to anymore
+	if employee.EmployeeImageURI != "" {
+		err := s.storage.Delete(ctx, employee.EmployeeImageURI)
+		if errors.Is(err, s3.ErrForeignURI) {
+			log.InfoCtx(ctx, log.LogInfo{
+				"employee_id": employee.ID,
+			}, "[PrivacyService][Erase] image is hosted elsewhere, only the reference is removed")
+		} else if err != nil {
+			return tracing.RecordError(span, fmt.Errorf("failed to delete employee image: %w", err))
+		}
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.employees.Erase(ctx, employee.ID); err != nil {
+			return err
+		}
+
+		// Earlier events carry the employee's data, they keep only their envelope. The erasure
+		// event is recorded afterwards so it isn't scrubbed with them.
+		aggregateID := strconv.Itoa(employee.ID)
+		eventIDs, err := s.outbox.Scrub(ctx, enums.WebhookEmployeeErased.Aggregate(), aggregateID)
+		if err != nil {
+			return err
+		}
+
+		if _, err := s.webhooks.ScrubEvents(ctx, eventIDs); err != nil {
+			return err
+		}
+
+		err = s.events.Record(ctx, managerID, aggregateID, enums.WebhookEmployeeErased, dto.EmployeeErasedRes{
+			EmployeeID:   aggregateID,
+			DepartmentID: strconv.Itoa(employee.DepartmentID),
+		}, nil)
+		if err != nil {
+			return err
+		}
+
+		// Nothing about the employee goes into the log, only that the erasure happened
+		return s.audit.Record(ctx, dto.AuditRecord{
+			Action:     enums.AuditErase,
+			EntityType: dto.AuditEntityEmployee,
+			EntityID:   aggregateID,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrEmployeeNotFound
+		}
+		return tracing.RecordError(span, err)
+	}
+
+	return nil
+}
+
+// find looks the employee up and makes sure it belongs to one of the manager's departments
+func (s *privacyService) find(
+	ctx context.Context,
+	managerID int,
+	identityNumber string,
+) (*entity.Employee, *entity.Department, error) {
+	employee, err := s.employees.FindByIdentityNumber(ctx, identityNumber)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, domain.ErrEmployeeNotFound
+		}
+		return nil, nil, err
+	}
+
+	department, err := s.departments.FindByID(ctx, employee.DepartmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, domain.ErrEmployeeNotFound
+		}
+		return nil, nil, err
+	}
+
+	if department.ManagerID != managerID {
+		return nil, nil, domain.ErrEmployeeNotFound
+	}
+
+	return employee, department, nil
+}
+
+func (s *privacyService) auditEvents(ctx context.Context, managerID, employeeID int) ([]dto.AuditEventRes, error) {
+	events := []dto.AuditEventRes{}
+
+	for offset := 0; ; offset += auditPageSize {
+		page, err := s.audit.List(ctx, managerID, dto.AuditQuery{
+			EntityType: dto.AuditEntityEmployee,
+			EntityID:   strconv.Itoa(employeeID),
+			Limit:      auditPageSize,
+			Offset:     offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, page...)
+		if len(page) < auditPageSize {
+			return events, nil
+		}
+	}
+}
+
+// writeImage adds the stored image under images/. Images outside the bucket are never fetched,
+// employee.json already carries their URI.
+func (s *privacyService) writeImage(ctx context.Context, archive *zip.Writer, uri string) error {
+	if uri == "" {
+		return nil
+	}
+
+	object, err := s.storage.Download(ctx, uri)
+	if errors.Is(err, s3.ErrForeignURI) {
+		return nil
+	}
+	if errors.Is(err, s3.ErrNotFound) {
+		log.WarnCtx(ctx, log.LogInfo{}, "[PrivacyService][Export] employee image is missing from the bucket")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download employee image: %w", err)
+	}
+
+	w, err := archive.Create("images/" + path.Base(object.Key))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(object.Body)
+	return err
+}
+
+func writeJSON(archive *zip.Writer, name string, data any) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
@@ -0,0 +1,328 @@
+package controller
+
+import (
+	"errors"
+	"math"
+	"net/url"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/metrics"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+const (
+	Prefix          = "/scim/v2"
+	ContentTypeSCIM = "application/scim+json"
+)
+
+// scimTypes refines the status of the errors SCIM has an error type for, by RequestError code
+var scimTypes = map[string]string{
+	domain.ErrInvalidRequestBody.Code:      "invalidSyntax",
+	domain.ErrInvalidQueryParam.Code:       "invalidValue",
+	domain.ErrInvalidSCIMFilter.Code:       "invalidFilter",
+	domain.ErrInvalidSCIMPatch.Code:        "invalidPath",
+	domain.ErrInvalidEmployeeImageURI.Code: "invalidValue",
+	domain.ErrSCIMUnknownGroup.Code:        "invalidValue",
+	domain.ErrSCIMUnknownMember.Code:       "invalidValue",
+	domain.ErrSCIMMemberRemoval.Code:       "mutability",
+	domain.ErrSCIMDeactivation.Code:        "mutability",
+	domain.ErrEmployeeAlreadyExists.Code:   "uniqueness",
+}
+
+type scimController struct {
+	scimService contracts.SCIMService
+	metrics     metrics.MetricsInterface
+}
+
+// InitNewController mounts the SCIM 2.0 endpoints identity providers provision employees and
+// departments through. They authenticate with an API key holding the scim scope, sent as a
+// bearer token, and every error, authentication included, is answered in the SCIM format.
+func InitNewController(
+	router fiber.Router,
+	scimService contracts.SCIMService,
+	middleware *middlewares.Middleware,
+	metrics metrics.MetricsInterface,
+) {
+	controller := &scimController{
+		scimService: scimService,
+		metrics:     metrics,
+	}
+
+	route := router.Group(Prefix, controller.handleErrors, middleware.RequireAPIKey(enums.ScopeSCIM))
+
+	route.Get("/ServiceProviderConfig", controller.serviceProviderConfig)
+
+	route.Post("/Users", controller.createUser)
+	route.Get("/Users", controller.listUsers)
+	route.Get("/Users/:id", controller.getUser)
+	route.Put("/Users/:id", controller.replaceUser)
+	route.Patch("/Users/:id", controller.patchUser)
+	route.Delete("/Users/:id", controller.deleteUser)
+
+	route.Post("/Groups", controller.createGroup)
+	route.Get("/Groups", controller.listGroups)
+	route.Get("/Groups/:id", controller.getGroup)
+	route.Put("/Groups/:id", controller.replaceGroup)
+	route.Patch("/Groups/:id", controller.patchGroup)
+	route.Delete("/Groups/:id", controller.deleteGroup)
+}
+
+func (c *scimController) serviceProviderConfig(ctx *fiber.Ctx) error {
+	return send(ctx, fiber.StatusOK, c.scimService.ServiceProviderConfig())
+}
+
+func (c *scimController) createUser(ctx *fiber.Ctx) error {
+	var req dto.SCIMUser
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	user, err := c.scimService.CreateUser(ctx.UserContext(), req)
+	if err != nil {
+		return err
+	}
+
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+	c.metrics.EmployeeCreated(strconv.Itoa(managerID))
+
+	return sendUser(ctx, fiber.StatusCreated, user)
+}
+
+func (c *scimController) listUsers(ctx *fiber.Ctx) error {
+	var query dto.SCIMQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		return domain.ErrInvalidQueryParam
+	}
+
+	list, err := c.scimService.ListUsers(ctx.UserContext(), query)
+	if err != nil {
+		return err
+	}
+
+	for i := range list.Resources {
+		list.Resources[i].Meta.Location = location(ctx, "Users", list.Resources[i].ID)
+	}
+
+	return send(ctx, fiber.StatusOK, list)
+}
+
+func (c *scimController) getUser(ctx *fiber.Ctx) error {
+	user, err := c.scimService.GetUser(ctx.UserContext(), ctx.Params("id"))
+	if err != nil {
+		return err
+	}
+
+	return sendUser(ctx, fiber.StatusOK, user)
+}
+
+func (c *scimController) replaceUser(ctx *fiber.Ctx) error {
+	var req dto.SCIMUser
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	user, err := c.scimService.ReplaceUser(ctx.UserContext(), ctx.Params("id"), req, ctx.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		return err
+	}
+
+	return sendUser(ctx, fiber.StatusOK, user)
+}
+
+func (c *scimController) patchUser(ctx *fiber.Ctx) error {
+	var req dto.SCIMPatchRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	user, err := c.scimService.PatchUser(ctx.UserContext(), ctx.Params("id"), req, ctx.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		return err
+	}
+
+	return sendUser(ctx, fiber.StatusOK, user)
+}
+
+func (c *scimController) deleteUser(ctx *fiber.Ctx) error {
+	err := c.scimService.DeleteUser(ctx.UserContext(), ctx.Params("id"), ctx.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		return err
+	}
+
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+	c.metrics.EmployeeDeleted(strconv.Itoa(managerID))
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+func (c *scimController) createGroup(ctx *fiber.Ctx) error {
+	var req dto.SCIMGroup
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	group, err := c.scimService.CreateGroup(ctx.UserContext(), managerID, req)
+	if err != nil {
+		return err
+	}
+
+	c.metrics.DepartmentCreated(strconv.Itoa(managerID))
+
+	return sendGroup(ctx, fiber.StatusCreated, group)
+}
+
+func (c *scimController) listGroups(ctx *fiber.Ctx) error {
+	var query dto.SCIMQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		return domain.ErrInvalidQueryParam
+	}
+
+	list, err := c.scimService.ListGroups(ctx.UserContext(), query)
+	if err != nil {
+		return err
+	}
+
+	for i := range list.Resources {
+		list.Resources[i].Meta.Location = location(ctx, "Groups", list.Resources[i].ID)
+	}
+
+	return send(ctx, fiber.StatusOK, list)
+}
+
+func (c *scimController) getGroup(ctx *fiber.Ctx) error {
+	group, err := c.scimService.GetGroup(ctx.UserContext(), ctx.Params("id"), ctx.Query("excludedAttributes"))
+	if err != nil {
+		return err
+	}
+
+	return sendGroup(ctx, fiber.StatusOK, group)
+}
+
+func (c *scimController) replaceGroup(ctx *fiber.Ctx) error {
+	var req dto.SCIMGroup
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	group, err := c.scimService.ReplaceGroup(ctx.UserContext(), ctx.Params("id"), req, ctx.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		return err
+	}
+
+	return sendGroup(ctx, fiber.StatusOK, group)
+}
+
+func (c *scimController) patchGroup(ctx *fiber.Ctx) error {
+	var req dto.SCIMPatchRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	group, err := c.scimService.PatchGroup(ctx.UserContext(), ctx.Params("id"), req, ctx.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		return err
+	}
+
+	return sendGroup(ctx, fiber.StatusOK, group)
+}
+
+func (c *scimController) deleteGroup(ctx *fiber.Ctx) error {
+	err := c.scimService.DeleteGroup(ctx.UserContext(), ctx.Params("id"), ctx.Get(fiber.HeaderIfMatch))
+	if err != nil {
+		return err
+	}
+
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+	c.metrics.DepartmentDeleted(strconv.Itoa(managerID))
+
+	return ctx.SendStatus(fiber.StatusNoContent)
+}
+
+// handleErrors answers the errors of the SCIM routes as SCIM errors instead of problems
+func (c *scimController) handleErrors(ctx *fiber.Ctx) error {
+	err := ctx.Next()
+	if err == nil {
+		return nil
+	}
+
+	res := dto.SCIMError{
+		Schemas: []string{dto.SCIMMessageError},
+	}
+	status := fiber.StatusInternalServerError
+
+	var valErr validator.ValidationErrors
+	var reqErr *domain.RequestError
+	var fiberErr *fiber.Error
+
+	switch {
+	case errors.As(err, &valErr):
+		status = fiber.StatusBadRequest
+		res.SCIMType = "invalidValue"
+		res.Detail = valErr.Error()
+	case errors.As(err, &reqErr):
+		status = reqErr.StatusCode
+		res.SCIMType = scimTypes[reqErr.Code]
+		res.Detail = reqErr.Error()
+
+		if reqErr.RetryAfter > 0 {
+			ctx.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(reqErr.RetryAfter.Seconds()))))
+		}
+	case errors.As(err, &fiberErr):
+		status = fiberErr.Code
+		res.Detail = fiberErr.Message
+	default:
+		log.ErrorCtx(ctx.UserContext(), log.LogInfo{
+			"error": err.Error(),
+		}, "[SCIMController][handleErrors] unhandled error")
+
+		res.Detail = "internal server error"
+	}
+
+	res.Status = strconv.Itoa(status)
+
+	return send(ctx, status, res)
+}
+
+func sendUser(ctx *fiber.Ctx, status int, user *dto.SCIMUser) error {
+	user.Meta.Location = location(ctx, "Users", user.ID)
+	return sendResource(ctx, status, user.Meta, user)
+}
+
+func sendGroup(ctx *fiber.Ctx, status int, group *dto.SCIMGroup) error {
+	group.Meta.Location = location(ctx, "Groups", group.ID)
+	return sendResource(ctx, status, group.Meta, group)
+}
+
+// sendResource sends a single resource with its ETag, and its location when it was created
+func sendResource(ctx *fiber.Ctx, status int, meta *dto.SCIMMeta, resource interface{}) error {
+	ctx.Set(fiber.HeaderETag, meta.Version)
+	if status == fiber.StatusCreated {
+		ctx.Set(fiber.HeaderLocation, meta.Location)
+	}
+
+	return send(ctx, status, resource)
+}
+
+// send writes body as is, SCIM responses are not wrapped in the payload envelope
+func send(ctx *fiber.Ctx, status int, body interface{}) error {
+	if err := ctx.Status(status).JSON(body); err != nil {
+		return err
+	}
+
+	ctx.Set(fiber.HeaderContentType, ContentTypeSCIM)
+	return nil
+}
+
+func location(ctx *fiber.Ctx, resourceType, id string) string {
+	return ctx.BaseURL() + Prefix + "/" + resourceType + "/" + url.PathEscape(id)
+}
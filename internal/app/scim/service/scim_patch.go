@@ -0,0 +1,352 @@
+package service
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+)
+
+const (
+	opAdd     = "add"
+	opReplace = "replace"
+	opRemove  = "remove"
+)
+
+// employeeExtension prefixes the paths of extension attributes, compared lower-cased like every path
+var employeeExtension = strings.ToLower(dto.SCIMSchemaEmployee)
+
+// filterEq is the only filter supported: one attribute compared to a string with eq
+var filterEq = regexp.MustCompile(`(?i)^\s*([a-z0-9:._-]+)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+// parseFilter returns the lower-cased attribute, without its schema, and the value of an eq filter
+func parseFilter(filter, schema string) (string, string, error) {
+	match := filterEq.FindStringSubmatch(filter)
+	if match == nil {
+		return "", "", domain.ErrInvalidSCIMFilter
+	}
+
+	var value string
+	if err := json.Unmarshal([]byte(match[2]), &value); err != nil {
+		return "", "", domain.ErrInvalidSCIMFilter
+	}
+
+	return normalizePath(match[1], schema), value, nil
+}
+
+// normalizePath lower-cases path, attribute names are case-insensitive, and strips the core schema
+func normalizePath(path, schema string) string {
+	path = strings.ToLower(strings.TrimSpace(path))
+	return strings.TrimPrefix(path, strings.ToLower(schema)+":")
+}
+
+// applyUser sets the attributes user carries on employee, leaving the others as they are
+func applyUser(employee *dto.EmployeeDataRes, user dto.SCIMUser) error {
+	if user.Active != nil && !*user.Active {
+		return domain.ErrSCIMDeactivation
+	}
+
+	if user.UserName != "" {
+		employee.IdentityNumber = user.UserName
+	}
+
+	switch {
+	case user.DisplayName != "":
+		employee.Name = user.DisplayName
+	case user.Name != nil && user.Name.Formatted != "":
+		employee.Name = user.Name.Formatted
+	case user.Name != nil && (user.Name.GivenName != "" || user.Name.FamilyName != ""):
+		employee.Name = joinName(user.Name.GivenName, user.Name.FamilyName)
+	}
+
+	if user.Photos != nil {
+		employee.EmployeeImageURI = primaryValue(user.Photos)
+	}
+
+	if user.Employee != nil {
+		if user.Employee.Gender != "" {
+			employee.Gender = user.Employee.Gender
+		}
+		if user.Employee.DepartmentID != "" {
+			employee.DepartmentID = user.Employee.DepartmentID
+		}
+	}
+
+	return nil
+}
+
+// userPatch is an employee being patched. The name parts are kept apart until the end, where
+// they only make the name when no operation set the whole of it.
+type userPatch struct {
+	employee   dto.EmployeeDataRes
+	givenName  string
+	familyName string
+	named      bool
+	partNamed  bool
+}
+
+// patchUser applies the operations to employee and returns the result
+func patchUser(employee dto.EmployeeDataRes, operations []dto.SCIMPatchOperation) (dto.EmployeeDataRes, error) {
+	p := &userPatch{employee: employee}
+	p.givenName, p.familyName, _ = strings.Cut(employee.Name, " ")
+
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != opAdd && op != opReplace && op != opRemove {
+			return employee, domain.ErrInvalidSCIMPatch
+		}
+
+		if err := p.apply(op, normalizePath(operation.Path, dto.SCIMSchemaUser), operation.Value); err != nil {
+			return employee, err
+		}
+	}
+
+	if p.partNamed && !p.named {
+		p.employee.Name = joinName(p.givenName, p.familyName)
+	}
+
+	return p.employee, nil
+}
+
+func (p *userPatch) apply(op, path string, value json.RawMessage) error {
+	if op == opRemove {
+		switch {
+		case strings.HasPrefix(path, "photos"):
+			p.employee.EmployeeImageURI = ""
+			return nil
+		case path == "externalid":
+			return nil
+		default:
+			// Every other attribute is required
+			return domain.ErrInvalidSCIMPatch
+		}
+	}
+
+	switch {
+	case path == "":
+		// Without a path the value is an object of attributes, each one applied as if it was the path
+		var attributes map[string]json.RawMessage
+		if err := json.Unmarshal(value, &attributes); err != nil {
+			return domain.ErrInvalidSCIMPatch
+		}
+
+		for attribute, value := range attributes {
+			if err := p.apply(op, normalizePath(attribute, dto.SCIMSchemaUser), value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case path == "username":
+		return stringValue(value, &p.employee.IdentityNumber)
+	case path == "displayname", path == "name.formatted":
+		p.named = true
+		return stringValue(value, &p.employee.Name)
+	case path == "name.givenname":
+		p.partNamed = true
+		return stringValue(value, &p.givenName)
+	case path == "name.familyname":
+		p.partNamed = true
+		return stringValue(value, &p.familyName)
+	case path == "name":
+		var name dto.SCIMName
+		if err := json.Unmarshal(value, &name); err != nil {
+			return domain.ErrInvalidSCIMPatch
+		}
+
+		if name.Formatted != "" {
+			p.employee.Name, p.named = name.Formatted, true
+		}
+		if name.GivenName != "" {
+			p.givenName, p.partNamed = name.GivenName, true
+		}
+		if name.FamilyName != "" {
+			p.familyName, p.partNamed = name.FamilyName, true
+		}
+
+		return nil
+	case strings.HasPrefix(path, "photos"):
+		return photoValue(value, &p.employee.EmployeeImageURI)
+	case path == "active":
+		active, err := boolValue(value)
+		if err != nil {
+			return err
+		}
+		if !active {
+			return domain.ErrSCIMDeactivation
+		}
+
+		return nil
+	case path == "externalid":
+		// Not stored, identity providers find users again by userName
+		return nil
+	case path == employeeExtension:
+		var extension dto.SCIMEmployeeExtension
+		if err := json.Unmarshal(value, &extension); err != nil {
+			return domain.ErrInvalidSCIMPatch
+		}
+
+		if extension.Gender != "" {
+			p.employee.Gender = extension.Gender
+		}
+		if extension.DepartmentID != "" {
+			p.employee.DepartmentID = extension.DepartmentID
+		}
+
+		return nil
+	case path == employeeExtension+":gender":
+		return stringValue(value, &p.employee.Gender)
+	case path == employeeExtension+":departmentid":
+		return stringValue(value, &p.employee.DepartmentID)
+	default:
+		return domain.ErrInvalidSCIMPatch
+	}
+}
+
+// groupChange is what a replacement or patch does to a group. Members are moved in, replacing
+// members only differs from adding them in that the group must not lose any.
+type groupChange struct {
+	name    string
+	members *[]dto.SCIMValue
+	added   []dto.SCIMValue
+}
+
+func patchGroup(operations []dto.SCIMPatchOperation) (groupChange, error) {
+	var change groupChange
+
+	for _, operation := range operations {
+		op := strings.ToLower(operation.Op)
+		if op != opAdd && op != opReplace && op != opRemove {
+			return change, domain.ErrInvalidSCIMPatch
+		}
+
+		if err := change.apply(op, normalizePath(operation.Path, dto.SCIMSchemaGroup), operation.Value); err != nil {
+			return change, err
+		}
+	}
+
+	return change, nil
+}
+
+func (c *groupChange) apply(op, path string, value json.RawMessage) error {
+	if op == opRemove {
+		switch {
+		case strings.HasPrefix(path, "members"):
+			return domain.ErrSCIMMemberRemoval
+		case path == "externalid":
+			return nil
+		default:
+			return domain.ErrInvalidSCIMPatch
+		}
+	}
+
+	switch path {
+	case "":
+		var attributes map[string]json.RawMessage
+		if err := json.Unmarshal(value, &attributes); err != nil {
+			return domain.ErrInvalidSCIMPatch
+		}
+
+		for attribute, value := range attributes {
+			if err := c.apply(op, normalizePath(attribute, dto.SCIMSchemaGroup), value); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case "displayname":
+		return stringValue(value, &c.name)
+	case "members":
+		var members []dto.SCIMValue
+		if err := json.Unmarshal(value, &members); err != nil {
+			return domain.ErrInvalidSCIMPatch
+		}
+
+		if op == opReplace {
+			c.members, c.added = &members, nil
+			return nil
+		}
+
+		c.added = append(c.added, members...)
+		return nil
+	case "externalid":
+		return nil
+	default:
+		return domain.ErrInvalidSCIMPatch
+	}
+}
+
+// stringValue reads a string into target. Numbers are taken as well, some clients send ids as such.
+func stringValue(value json.RawMessage, target *string) error {
+	if err := json.Unmarshal(value, target); err == nil {
+		return nil
+	}
+
+	var number json.Number
+	if err := json.Unmarshal(value, &number); err != nil {
+		return domain.ErrInvalidSCIMPatch
+	}
+
+	*target = number.String()
+	return nil
+}
+
+// boolValue reads a boolean, also sent as "True" or "False" by some clients
+func boolValue(value json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(value, &b); err == nil {
+		return b, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(value, &s); err != nil {
+		return false, domain.ErrInvalidSCIMPatch
+	}
+
+	switch strings.ToLower(s) {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	default:
+		return false, domain.ErrInvalidSCIMPatch
+	}
+}
+
+// photoValue reads the image from the photos attribute, its value or one of its elements
+func photoValue(value json.RawMessage, target *string) error {
+	var photos []dto.SCIMValue
+	if err := json.Unmarshal(value, &photos); err == nil {
+		*target = primaryValue(photos)
+		return nil
+	}
+
+	var photo dto.SCIMValue
+	if err := json.Unmarshal(value, &photo); err == nil && photo.Value != "" {
+		*target = photo.Value
+		return nil
+	}
+
+	return stringValue(value, target)
+}
+
+// primaryValue returns the primary value of a multi-valued attribute, the first one when none is
+func primaryValue(values []dto.SCIMValue) string {
+	for _, value := range values {
+		if value.Primary {
+			return value.Value
+		}
+	}
+
+	if len(values) > 0 {
+		return values[0].Value
+	}
+
+	return ""
+}
+
+func joinName(givenName, familyName string) string {
+	return strings.TrimSpace(givenName + " " + familyName)
+}
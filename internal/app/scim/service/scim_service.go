@@ -0,0 +1,714 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/etag"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/patch"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	ResourceUser  = "User"
+	ResourceGroup = "Group"
+	// MaxResults caps the count of a page, it is also the count of pages requested without one
+	MaxResults = 100
+	// memberBatchSize is how many members of a group are read at once
+	memberBatchSize = 100
+)
+
+// scimService provisions the employees and departments of the tenant the API key belongs to.
+// Users and groups of other tenants are answered as if they didn't exist.
+type scimService struct {
+	employees      contracts.EmployeeService
+	departments    contracts.DepartmentService
+	departmentRepo contracts.DepartmentRepository
+	tx             dbtx.TransactorInterface
+}
+
+func NewSCIMService(
+	employees contracts.EmployeeService,
+	departments contracts.DepartmentService,
+	departmentRepo contracts.DepartmentRepository,
+	tx dbtx.TransactorInterface,
+) contracts.SCIMService {
+	return &scimService{
+		employees:      employees,
+		departments:    departments,
+		departmentRepo: departmentRepo,
+		tx:             tx,
+	}
+}
+
+func (s *scimService) ServiceProviderConfig() dto.SCIMServiceProviderConfig {
+	return dto.SCIMServiceProviderConfig{
+		Schemas: []string{dto.SCIMSchemaServiceConfig},
+		Patch:   dto.SCIMSupported{Supported: true},
+		Bulk:    dto.SCIMBulk{Supported: false},
+		Filter:  dto.SCIMFilter{Supported: true, MaxResults: MaxResults},
+		ETag:    dto.SCIMSupported{Supported: true},
+		AuthenticationSchemes: []dto.SCIMAuthenticationScheme{{
+			Type:        "oauthbearertoken",
+			Name:        "API key",
+			Description: "An API key with the scim scope, sent as a bearer token",
+			Primary:     true,
+		}},
+	}
+}
+
+func (s *scimService) CreateUser(ctx context.Context, user dto.SCIMUser) (*dto.SCIMUser, error) {
+	ctx, span := tracing.Start(ctx, "SCIMService.CreateUser")
+	defer span.End()
+
+	var employee dto.EmployeeDataRes
+	if err := applyUser(&employee, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkUnique(ctx, employee.IdentityNumber); err != nil {
+		return nil, err
+	}
+
+	if err := s.checkDepartment(ctx, employee.DepartmentID); err != nil {
+		return nil, err
+	}
+
+	created, err := s.employees.Create(ctx, dto.EmployeeCreateReq{
+		IdentityNumber:   employee.IdentityNumber,
+		Name:             employee.Name,
+		EmployeeImageURI: employee.EmployeeImageURI,
+		Gender:           employee.Gender,
+		DepartmentID:     employee.DepartmentID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := toUser(*created)
+	return &res, nil
+}
+
+func (s *scimService) GetUser(ctx context.Context, id string) (*dto.SCIMUser, error) {
+	ctx, span := tracing.Start(ctx, "SCIMService.GetUser")
+	defer span.End()
+
+	employee, err := s.user(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	res := toUser(*employee)
+	return &res, nil
+}
+
+func (s *scimService) ListUsers(ctx context.Context, query dto.SCIMQuery) (*dto.SCIMUserList, error) {
+	ctx, span := tracing.Start(ctx, "SCIMService.ListUsers")
+	defer span.End()
+
+	startIndex, count := pageOf(query)
+
+	if query.Filter != "" {
+		attribute, value, err := parseFilter(query.Filter, dto.SCIMSchemaUser)
+		if err != nil {
+			return nil, err
+		}
+
+		if attribute != "username" {
+			return nil, domain.ErrInvalidSCIMFilter
+		}
+
+		// Identity numbers are unique, a filter on them matches one user at most
+		users := []dto.SCIMUser{}
+		employee, err := s.user(ctx, value)
+		switch {
+		case err == nil:
+			users = append(users, toUser(*employee))
+		case !errors.Is(err, domain.ErrEmployeeNotFound):
+			return nil, err
+		}
+
+		total := len(users)
+		users = slicePage(users, startIndex, count)
+		return &dto.SCIMUserList{
+			SCIMListResponse: listResponse(total, startIndex, len(users)),
+			Resources:        users,
+		}, nil
+	}
+
+	employees, total, err := s.employees.FindByManager(ctx, reqctx.TenantID(ctx), count, startIndex-1)
+	if err != nil {
+		return nil, err
+	}
+
+	users := make([]dto.SCIMUser, 0, len(employees))
+	for _, employee := range employees {
+		users = append(users, toUser(*employee))
+	}
+
+	return &dto.SCIMUserList{
+		SCIMListResponse: listResponse(total, startIndex, len(users)),
+		Resources:        users,
+	}, nil
+}
+
+func (s *scimService) ReplaceUser(ctx context.Context, id string, user dto.SCIMUser, ifMatch string) (*dto.SCIMUser, error) {
+	ctx, span := tracing.Start(ctx, "SCIMService.ReplaceUser")
+	defer span.End()
+
+	current, err := s.currentUser(ctx, id, ifMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Attributes left out of the replacement are kept, identity providers only send those they map
+	updated := *current
+	if err := applyUser(&updated, user); err != nil {
+		return nil, err
+	}
+
+	return s.updateUser(ctx, *current, updated)
+}
+
+func (s *scimService) PatchUser(ctx context.Context, id string, req dto.SCIMPatchRequest, ifMatch string) (*dto.SCIMUser, error) {
+	ctx, span := tracing.Start(ctx, "SCIMService.PatchUser")
+	defer span.End()
+
+	current, err := s.currentUser(ctx, id, ifMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	updated, err := patchUser(*current, req.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.updateUser(ctx, *current, updated)
+}
+
+func (s *scimService) DeleteUser(ctx context.Context, id, ifMatch string) error {
+	ctx, span := tracing.Start(ctx, "SCIMService.DeleteUser")
+	defer span.End()
+
+	return s.employees.Delete(ctx, reqctx.TenantID(ctx), id, ifMatch)
+}
+
+// currentUser reads the user a change applies to, checking ifMatch against its version
+func (s *scimService) currentUser(ctx context.Context, id, ifMatch string) (*dto.EmployeeDataRes, error) {
+	current, err := s.user(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if ifMatch != "" && !etag.Matches(ifMatch, current.Version) {
+		return nil, domain.ErrPreconditionFailed
+	}
+
+	return current, nil
+}
+
+// updateUser writes the attributes that differ between current and updated. The update only
+// applies to the version read, a concurrent change fails it instead of being overwritten.
+func (s *scimService) updateUser(ctx context.Context, current, updated dto.EmployeeDataRes) (*dto.SCIMUser, error) {
+	var req dto.EmployeeUpdateReq
+	changed := false
+
+	if updated.IdentityNumber != current.IdentityNumber {
+		if err := s.checkUnique(ctx, updated.IdentityNumber); err != nil {
+			return nil, err
+		}
+
+		req.IdentityNumber, changed = patch.Value(updated.IdentityNumber), true
+	}
+
+	if updated.Name != current.Name {
+		req.Name, changed = patch.Value(updated.Name), true
+	}
+
+	if updated.EmployeeImageURI != current.EmployeeImageURI {
+		req.EmployeeImageURI, changed = patch.Value(updated.EmployeeImageURI), true
+		if updated.EmployeeImageURI == "" {
+			req.EmployeeImageURI = patch.Null[string]()
+		}
+	}
+
+	if updated.Gender != current.Gender {
+		req.Gender, changed = patch.Value(updated.Gender), true
+	}
+
+	if updated.DepartmentID != current.DepartmentID {
+		if err := s.checkDepartment(ctx, updated.DepartmentID); err != nil {
+			return nil, err
+		}
+
+		req.DepartmentID, changed = patch.Value(updated.DepartmentID), true
+	}
+
+	// Nothing to write, a version bump would only make clients refetch
+	if !changed {
+		res := toUser(current)
+		return &res, nil
+	}
+
+	employee, err := s.employees.Update(ctx, reqctx.TenantID(ctx), req, current.IdentityNumber, etag.Format(current.Version))
+	if err != nil {
+		return nil, err
+	}
+
+	res := toUser(*employee)
+	return &res, nil
+}
+
+func (s *scimService) checkUnique(ctx context.Context, identityNumber string) error {
+	if identityNumber == "" {
+		return nil
+	}
+
+	// Identity numbers are unique across tenants, whoever's employee holds it
+	_, err := s.employees.FindByIdentityNumber(ctx, 0, identityNumber)
+	switch {
+	case err == nil:
+		return domain.ErrEmployeeAlreadyExists
+	case errors.Is(err, domain.ErrEmployeeNotFound):
+		return nil
+	default:
+		return err
+	}
+}
+
+// checkDepartment makes sure a user is put in a group of the tenant. Empty ids are left to validation.
+func (s *scimService) checkDepartment(ctx context.Context, departmentID string) error {
+	if departmentID == "" {
+		return nil
+	}
+
+	_, err := s.ownedDepartment(ctx, departmentID)
+	if errors.Is(err, domain.ErrDepartmentNotFound) {
+		return domain.ErrSCIMUnknownGroup
+	}
+
+	return err
+}
+
+// user looks the employee up, not found unless it is in a department of the tenant
+func (s *scimService) user(ctx context.Context, identityNumber string) (*dto.EmployeeDataRes, error) {
+	return s.employees.FindByIdentityNumber(ctx, reqctx.TenantID(ctx), identityNumber)
+}
+
+// ownedDepartment looks the department up, not found unless the tenant manages it
+func (s *scimService) ownedDepartment(ctx context.Context, id string) (*entity.Department, error) {
+	departmentID, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, domain.ErrDepartmentNotFound
+	}
+
+	department, err := s.departmentRepo.FindByID(ctx, departmentID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrDepartmentNotFound
+		}
+		return nil, err
+	}
+
+	if department.ManagerID != reqctx.TenantID(ctx) {
+		return nil, domain.ErrDepartmentNotFound
+	}
+
+	return department, nil
+}
+
+func (s *scimService) CreateGroup(ctx context.Context, managerID int, group dto.SCIMGroup) (*dto.SCIMGroup, error) {
+	ctx, span := tracing.Start(ctx, "SCIMService.CreateGroup")
+	defer span.End()
+
+	var res *dto.SCIMGroup
+	// The group is only created along with its members
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		department, err := s.departments.Create(ctx, managerID, group.DisplayName)
+		if err != nil {
+			return err
+		}
+
+		id, _ := strconv.Atoi(department.ID)
+		if err := s.addMembers(ctx, id, group.Members); err != nil {
+			return err
+		}
+
+		res, err = s.group(ctx, *department, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (s *scimService) GetGroup(ctx context.Context, id, excludedAttributes string) (*dto.SCIMGroup, error) {
+	ctx, span := tracing.Start(ctx, "SCIMService.GetGroup")
+	defer span.End()
+
+	department, err := s.department(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.group(ctx, *department, withMembers(excludedAttributes))
+}
+
+func (s *scimService) ListGroups(ctx context.Context, query dto.SCIMQuery) (*dto.SCIMGroupList, error) {
+	ctx, span := tracing.Start(ctx, "SCIMService.ListGroups")
+	defer span.End()
+
+	startIndex, count := pageOf(query)
+	members := withMembers(query.ExcludedAttributes)
+
+	if query.Filter != "" {
+		attribute, value, err := parseFilter(query.Filter, dto.SCIMSchemaGroup)
+		if err != nil {
+			return nil, err
+		}
+
+		if attribute != "displayname" {
+			return nil, domain.ErrInvalidSCIMFilter
+		}
+
+		managerID := reqctx.TenantID(ctx)
+		named, err := s.departmentRepo.FindByManagerAndName(ctx, managerID, value, count, startIndex-1)
+		if err != nil {
+			return nil, err
+		}
+
+		total, err := s.departmentRepo.CountByManagerAndName(ctx, managerID, value)
+		if err != nil {
+			return nil, err
+		}
+
+		groups, err := s.groups(ctx, toDepartments(named), members)
+		if err != nil {
+			return nil, err
+		}
+
+		return &dto.SCIMGroupList{
+			SCIMListResponse: listResponse(total, startIndex, len(groups)),
+			Resources:        groups,
+		}, nil
+	}
+
+	managerID := reqctx.TenantID(ctx)
+	departments, err := s.departmentRepo.FindByManager(ctx, managerID, count, startIndex-1)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := s.departmentRepo.CountByManager(ctx, managerID)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, err := s.groups(ctx, toDepartments(departments), members)
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.SCIMGroupList{
+		SCIMListResponse: listResponse(total, startIndex, len(groups)),
+		Resources:        groups,
+	}, nil
+}
+
+func (s *scimService) ReplaceGroup(ctx context.Context, id string, group dto.SCIMGroup, ifMatch string) (*dto.SCIMGroup, error) {
+	ctx, span := tracing.Start(ctx, "SCIMService.ReplaceGroup")
+	defer span.End()
+
+	var members *[]dto.SCIMValue
+	if group.Members != nil {
+		members = &group.Members
+	}
+
+	return s.changeGroup(ctx, id, ifMatch, groupChange{
+		name:    group.DisplayName,
+		members: members,
+	})
+}
+
+func (s *scimService) PatchGroup(ctx context.Context, id string, req dto.SCIMPatchRequest, ifMatch string) (*dto.SCIMGroup, error) {
+	ctx, span := tracing.Start(ctx, "SCIMService.PatchGroup")
+	defer span.End()
+
+	change, err := patchGroup(req.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.changeGroup(ctx, id, ifMatch, change)
+}
+
+func (s *scimService) DeleteGroup(ctx context.Context, id, ifMatch string) error {
+	ctx, span := tracing.Start(ctx, "SCIMService.DeleteGroup")
+	defer span.End()
+
+	department, err := s.ownedDepartment(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.departments.Delete(ctx, reqctx.TenantID(ctx), department.ID, ifMatch)
+}
+
+// changeGroup renames the group and moves members into it, all or nothing
+func (s *scimService) changeGroup(ctx context.Context, id, ifMatch string, change groupChange) (*dto.SCIMGroup, error) {
+	department, err := s.department(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if ifMatch != "" && !etag.Matches(ifMatch, department.Version) {
+		return nil, domain.ErrPreconditionFailed
+	}
+
+	departmentID, _ := strconv.Atoi(department.ID)
+
+	var res *dto.SCIMGroup
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if change.name != "" && change.name != department.Name {
+			renamed, err := s.departments.Update(ctx, reqctx.TenantID(ctx), departmentID, change.name, etag.Format(department.Version))
+			if err != nil {
+				return err
+			}
+
+			department = renamed
+		}
+
+		if change.members != nil {
+			if err := s.replaceMembers(ctx, departmentID, *change.members); err != nil {
+				return err
+			}
+		}
+
+		if err := s.addMembers(ctx, departmentID, change.added); err != nil {
+			return err
+		}
+
+		var err error
+		res, err = s.group(ctx, *department, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (s *scimService) department(ctx context.Context, id string) (*dto.DepartmentRes, error) {
+	department, err := s.ownedDepartment(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return toDepartments([]*entity.Department{department})[0], nil
+}
+
+// addMembers moves the employees into the department
+func (s *scimService) addMembers(ctx context.Context, departmentID int, members []dto.SCIMValue) error {
+	target := strconv.Itoa(departmentID)
+
+	for _, member := range members {
+		employee, err := s.user(ctx, member.Value)
+		if err != nil {
+			if errors.Is(err, domain.ErrEmployeeNotFound) {
+				return domain.ErrSCIMUnknownMember
+			}
+			return err
+		}
+
+		if employee.DepartmentID == target {
+			continue
+		}
+
+		_, err = s.employees.Update(ctx, reqctx.TenantID(ctx), dto.EmployeeUpdateReq{
+			DepartmentID: patch.Value(target),
+		}, employee.IdentityNumber, etag.Format(employee.Version))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// replaceMembers moves the employees into the department. Employees cannot be without one, so
+// the members it has already must all be kept.
+func (s *scimService) replaceMembers(ctx context.Context, departmentID int, members []dto.SCIMValue) error {
+	current, err := s.members(ctx, departmentID)
+	if err != nil {
+		return err
+	}
+
+	kept := make(map[string]bool, len(members))
+	for _, member := range members {
+		kept[member.Value] = true
+	}
+
+	for _, member := range current {
+		if !kept[member.Value] {
+			return domain.ErrSCIMMemberRemoval
+		}
+	}
+
+	return s.addMembers(ctx, departmentID, members)
+}
+
+func (s *scimService) members(ctx context.Context, departmentID int) ([]dto.SCIMValue, error) {
+	members := []dto.SCIMValue{}
+	for offset := 0; ; offset += memberBatchSize {
+		employees, err := s.employees.Find(ctx, reqctx.TenantID(ctx), "", "", "", departmentID, memberBatchSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, employee := range employees {
+			members = append(members, dto.SCIMValue{
+				Value:   employee.IdentityNumber,
+				Display: employee.Name,
+			})
+		}
+
+		if len(employees) < memberBatchSize {
+			return members, nil
+		}
+	}
+}
+
+func (s *scimService) group(ctx context.Context, department dto.DepartmentRes, withMembers bool) (*dto.SCIMGroup, error) {
+	group := dto.SCIMGroup{
+		Schemas:     []string{dto.SCIMSchemaGroup},
+		ID:          department.ID,
+		DisplayName: department.Name,
+		Meta: &dto.SCIMMeta{
+			ResourceType: ResourceGroup,
+			Version:      etag.Format(department.Version),
+		},
+	}
+
+	if withMembers {
+		departmentID, _ := strconv.Atoi(department.ID)
+
+		members, err := s.members(ctx, departmentID)
+		if err != nil {
+			return nil, err
+		}
+
+		group.Members = members
+	}
+
+	return &group, nil
+}
+
+func (s *scimService) groups(ctx context.Context, departments []*dto.DepartmentRes, withMembers bool) ([]dto.SCIMGroup, error) {
+	groups := make([]dto.SCIMGroup, 0, len(departments))
+	for _, department := range departments {
+		group, err := s.group(ctx, *department, withMembers)
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, *group)
+	}
+
+	return groups, nil
+}
+
+func toUser(employee dto.EmployeeDataRes) dto.SCIMUser {
+	active := true
+	givenName, familyName, _ := strings.Cut(employee.Name, " ")
+
+	user := dto.SCIMUser{
+		Schemas:     []string{dto.SCIMSchemaUser, dto.SCIMSchemaEmployee},
+		ID:          employee.IdentityNumber,
+		UserName:    employee.IdentityNumber,
+		DisplayName: employee.Name,
+		Name: &dto.SCIMName{
+			Formatted:  employee.Name,
+			GivenName:  givenName,
+			FamilyName: familyName,
+		},
+		Active: &active,
+		Groups: []dto.SCIMValue{{Value: employee.DepartmentID}},
+		Employee: &dto.SCIMEmployeeExtension{
+			Gender:       employee.Gender,
+			DepartmentID: employee.DepartmentID,
+		},
+		Meta: &dto.SCIMMeta{
+			ResourceType: ResourceUser,
+			Version:      etag.Format(employee.Version),
+		},
+	}
+
+	if employee.EmployeeImageURI != "" {
+		user.Photos = []dto.SCIMValue{{Value: employee.EmployeeImageURI, Type: "photo", Primary: true}}
+	}
+
+	return user
+}
+
+// pageOf returns the one-based start index and the count of the page a query asks for
+func pageOf(query dto.SCIMQuery) (int, int) {
+	count := MaxResults
+	if query.Count != nil {
+		count = min(max(*query.Count, 0), MaxResults)
+	}
+
+	return max(query.StartIndex, 1), count
+}
+
+// slicePage returns the page of items starting at the one-based startIndex
+func slicePage[T any](items []T, startIndex, count int) []T {
+	start := min(startIndex-1, len(items))
+	return items[start:min(start+count, len(items))]
+}
+
+func toDepartments(departments []*entity.Department) []*dto.DepartmentRes {
+	res := make([]*dto.DepartmentRes, 0, len(departments))
+	for _, department := range departments {
+		res = append(res, &dto.DepartmentRes{
+			ID:      strconv.Itoa(department.ID),
+			Name:    department.Name,
+			Version: department.Version,
+		})
+	}
+
+	return res
+}
+
+func listResponse(total, startIndex, returned int) dto.SCIMListResponse {
+	return dto.SCIMListResponse{
+		Schemas:      []string{dto.SCIMMessageList},
+		TotalResults: total,
+		StartIndex:   startIndex,
+		ItemsPerPage: returned,
+	}
+}
+
+// withMembers reports whether excludedAttributes, a comma separated list, keeps the members
+func withMembers(excludedAttributes string) bool {
+	for _, attribute := range strings.Split(excludedAttributes, ",") {
+		attribute = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(attribute)), strings.ToLower(dto.SCIMSchemaGroup)+":")
+		if attribute == "members" {
+			return false
+		}
+	}
+
+	return true
+}
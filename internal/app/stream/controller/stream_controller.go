@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+)
+
+const (
+	HeaderLastEventID = "Last-Event-ID"
+	// heartbeatInterval keeps idle streams from being cut by proxies and finds disconnected clients
+	heartbeatInterval = 15 * time.Second
+	// maxStreamDuration ends streams so clients reconnect, and re-authenticate, now and then
+	maxStreamDuration = 30 * time.Minute
+	retryMillis       = 3000
+)
+
+type streamController struct {
+	streamService contracts.StreamService
+}
+
+// InitNewController mounts the change stream. EventSource cannot send an Authorization header,
+// browsers need a fetch based client or an API key proxy.
+func InitNewController(router fiber.Router, streamService contracts.StreamService, middleware *middlewares.Middleware) {
+	controller := &streamController{
+		streamService: streamService,
+	}
+
+	router.Get("/v1/events", middleware.RequireAdmin(enums.ScopeRead), controller.stream)
+}
+
+func (c *streamController) stream(ctx *fiber.Ctx) error {
+	userCtx := ctx.UserContext()
+	tenantID := reqctx.TenantID(userCtx)
+
+	var query dto.StreamQuery
+	if err := ctx.QueryParser(&query); err != nil {
+		return domain.ErrInvalidLastEventID
+	}
+
+	lastEventID := ctx.Get(HeaderLastEventID)
+	if lastEventID == "" {
+		lastEventID = query.LastEventID
+	}
+
+	// Subscribed before the cursor is read, an event committed in between still wakes the stream
+	wake, unsubscribe := c.streamService.Subscribe(tenantID)
+
+	cursor, err := c.streamService.Cursor(userCtx, tenantID, lastEventID)
+	if err != nil {
+		unsubscribe()
+		return err
+	}
+
+	ctx.Set(fiber.HeaderContentType, "text/event-stream")
+	ctx.Set(fiber.HeaderCacheControl, "no-store")
+	ctx.Set(fiber.HeaderConnection, "keep-alive")
+	// Stops nginx from buffering the stream
+	ctx.Set("X-Accel-Buffering", "no")
+
+	// The writer runs after the handler returned, it must not touch ctx
+	ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer unsubscribe()
+
+		streamCtx, cancel := context.WithTimeout(userCtx, maxStreamDuration)
+		defer cancel()
+
+		c.write(streamCtx, w, tenantID, cursor, wake)
+	})
+
+	return nil
+}
+
+// write sends the events after the cursor as they come until the client is gone or the stream expires
+func (c *streamController) write(ctx context.Context, w *bufio.Writer, tenantID int, cursor int64, wake <-chan struct{}) {
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	fmt.Fprintf(w, "retry: %d\n\n", retryMillis)
+	if err := w.Flush(); err != nil {
+		return
+	}
+
+	for {
+		events, err := c.streamService.Next(ctx, tenantID, cursor)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.ErrorCtx(ctx, log.LogInfo{
+					"error": err.Error(),
+				}, "[StreamController][write] failed to read events")
+			}
+			return
+		}
+
+		for _, event := range events {
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+			cursor = event.ID
+		}
+
+		// Read on until the backlog is drained, a batch may have more behind it
+		if len(events) > 0 {
+			if err := w.Flush(); err != nil {
+				return
+			}
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-wake:
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/pgnotify"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	// Channel is notified by the outbox_events trigger with the tenant id as payload
+	Channel = "outbox_events"
+	// BatchSize is how many events a stream reads at once, a longer backlog is read batch after batch
+	BatchSize = 100
+)
+
+type streamService struct {
+	repo     contracts.OutboxEventRepository
+	listener pgnotify.ListenerInterface
+
+	mu          sync.Mutex
+	subscribers map[int]map[chan struct{}]struct{}
+}
+
+func NewStreamService(repo contracts.OutboxEventRepository, listener pgnotify.ListenerInterface) contracts.StreamService {
+	return &streamService{
+		repo:        repo,
+		listener:    listener,
+		subscribers: map[int]map[chan struct{}]struct{}{},
+	}
+}
+
+func (s *streamService) Cursor(ctx context.Context, tenantID int, lastEventID string) (int64, error) {
+	ctx, span := tracing.Start(ctx, "StreamService.Cursor")
+	defer span.End()
+
+	if lastEventID == "" {
+		return s.repo.LastID(ctx, tenantID)
+	}
+
+	// Events older than the outbox retention are gone, a stream resumed that late starts at the oldest one left
+	cursor, err := strconv.ParseInt(lastEventID, 10, 64)
+	if err != nil || cursor < 0 {
+		return 0, domain.ErrInvalidLastEventID
+	}
+
+	return cursor, nil
+}
+
+func (s *streamService) Next(ctx context.Context, tenantID int, after int64) ([]dto.StreamEvent, error) {
+	ctx, span := tracing.Start(ctx, "StreamService.Next")
+	defer span.End()
+
+	events, err := s.repo.ListAfter(ctx, tenantID, after, BatchSize)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.StreamEvent, 0, len(events))
+	for _, event := range events {
+		res = append(res, dto.StreamEvent{
+			ID:   event.ID,
+			Type: event.EventType,
+			Data: json.RawMessage(event.Payload),
+		})
+	}
+
+	return res, nil
+}
+
+func (s *streamService) Subscribe(tenantID int) (<-chan struct{}, func()) {
+	// A pending signal already covers every event behind it, so one slot is enough
+	wake := make(chan struct{}, 1)
+
+	s.mu.Lock()
+	if s.subscribers[tenantID] == nil {
+		s.subscribers[tenantID] = map[chan struct{}]struct{}{}
+	}
+	s.subscribers[tenantID][wake] = struct{}{}
+	s.mu.Unlock()
+
+	return wake, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		delete(s.subscribers[tenantID], wake)
+		if len(s.subscribers[tenantID]) == 0 {
+			delete(s.subscribers, tenantID)
+		}
+	}
+}
+
+func (s *streamService) Listen(ctx context.Context) {
+	s.listener.Listen(ctx, Channel, s.wakeAll, func(payload string) {
+		tenantID, err := strconv.Atoi(payload)
+		if err != nil {
+			log.WarnCtx(ctx, log.LogInfo{
+				"payload": payload,
+			}, "[StreamService][Listen] ignoring malformed notification")
+			return
+		}
+
+		s.wake(tenantID)
+	})
+}
+
+func (s *streamService) wake(tenantID int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for wake := range s.subscribers[tenantID] {
+		signal(wake)
+	}
+}
+
+// wakeAll catches every stream up after the listener (re)connected, it may have missed notifications
+func (s *streamService) wakeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, subscribers := range s.subscribers {
+		for wake := range subscribers {
+			signal(wake)
+		}
+	}
+}
+
+func signal(wake chan struct{}) {
+	select {
+	case wake <- struct{}{}:
+	default:
+	}
+}
@@ -33,7 +33,7 @@ func (uc *userController) getUsers(ctx *fiber.Ctx) error {
 		return err
 	}
 
-	res, err := uc.userService.GetUsers(ctx.Context(), query)
+	res, err := uc.userService.GetUsers(ctx.UserContext(), query)
 	if err != nil {
 		return err
 	}
@@ -42,7 +42,7 @@ func (uc *userController) getUsers(ctx *fiber.Ctx) error {
 }
 
 func (uc *userController) getUsersStats(ctx *fiber.Ctx) error {
-	res, err := uc.userService.GetUsersStats(ctx.Context())
+	res, err := uc.userService.GetUsersStats(ctx.UserContext())
 	if err != nil {
 		return err
 	}
@@ -56,7 +56,7 @@ func (uc *userController) getUserByID(ctx *fiber.Ctx) error {
 		return err
 	}
 
-	res, err := uc.userService.GetUserByID(ctx.Context(), req)
+	res, err := uc.userService.GetUserByID(ctx.UserContext(), req)
 	if err != nil {
 		return err
 	}
@@ -70,7 +70,7 @@ func (uc *userController) createUser(ctx *fiber.Ctx) error {
 		return err
 	}
 
-	res, err := uc.userService.CreateUser(ctx.Context(), req)
+	res, err := uc.userService.CreateUser(ctx.UserContext(), req)
 	if err != nil {
 		return err
 	}
@@ -88,7 +88,7 @@ func (uc *userController) updateUser(ctx *fiber.Ctx) error {
 		return err
 	}
 
-	res, err := uc.userService.UpdateUser(ctx.Context(), req)
+	res, err := uc.userService.UpdateUser(ctx.UserContext(), req)
 	if err != nil {
 		return err
 	}
@@ -102,7 +102,7 @@ func (uc *userController) softDeleteUser(ctx *fiber.Ctx) error {
 		return err
 	}
 
-	res, err := uc.userService.SoftDeleteUser(ctx.Context(), req)
+	res, err := uc.userService.SoftDeleteUser(ctx.UserContext(), req)
 	if err != nil {
 		return err
 	}
@@ -116,7 +116,7 @@ func (uc *userController) restoreUser(ctx *fiber.Ctx) error {
 		return err
 	}
 
-	res, err := uc.userService.RestoreUser(ctx.Context(), req)
+	res, err := uc.userService.RestoreUser(ctx.UserContext(), req)
 	if err != nil {
 		return err
 	}
@@ -130,7 +130,7 @@ func (uc *userController) deleteUser(ctx *fiber.Ctx) error {
 		return err
 	}
 
-	res, err := uc.userService.DeleteUser(ctx.Context(), req)
+	res, err := uc.userService.DeleteUser(ctx.UserContext(), req)
 	if err != nil {
 		return err
 	}
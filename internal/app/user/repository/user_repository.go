@@ -8,6 +8,16 @@ import (
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	queryCreateUser     = `INSERT INTO users (id, name, password, email) VALUES (:id, :name, :password, :email)`
+	queryUpdateUser     = `UPDATE users SET name = :name, password = :password, email = :email, updated_at = NOW() WHERE id = :id`
+	querySoftDeleteUser = `UPDATE users SET deleted_at = NOW() WHERE id = $1`
+	queryDeleteUser     = `DELETE FROM users WHERE id = $1`
+	queryRestoreUser    = `UPDATE users SET deleted_at = NULL WHERE id = $1`
 )
 
 type userRepository struct {
@@ -90,10 +100,13 @@ func (r *userRepository) GetUsers(ctx context.Context, query dto.GetUsersQuery)
 
 	finalQuery = r.conn.Rebind(finalQuery)
 
+	ctx, span := tracing.StartDB(ctx, "UserRepository.GetUsers", finalQuery)
+	defer span.End()
+
 	users := make([]entity.User, 0)
-	err = r.conn.SelectContext(ctx, &users, finalQuery, finalArgs...)
+	err = dbtx.From(ctx, r.conn).SelectContext(ctx, &users, finalQuery, finalArgs...)
 	if err != nil {
-		return nil, err
+		return nil, tracing.RecordError(span, err)
 	}
 
 	return users, nil
@@ -119,62 +132,80 @@ func (r *userRepository) GetUserByField(ctx context.Context, field, value string
 		AND deleted_at IS NULL
 		`
 
-	err := r.conn.GetContext(ctx, &user, statement, value)
+	ctx, span := tracing.StartDB(ctx, "UserRepository.GetUserByField", statement)
+	defer span.End()
+
+	err := dbtx.From(ctx, r.conn).GetContext(ctx, &user, statement, value)
 	if err != nil {
-		return nil, err
+		return nil, tracing.RecordError(span, err)
 	}
 
 	return &user, nil
 }
 
 func (r *userRepository) CreateUser(ctx context.Context, user *entity.User) (uuid.UUID, error) {
-	_, err := r.conn.NamedExecContext(
+	ctx, span := tracing.StartDB(ctx, "UserRepository.CreateUser", queryCreateUser)
+	defer span.End()
+
+	_, err := dbtx.From(ctx, r.conn).NamedExecContext(
 		ctx,
-		`INSERT INTO users (id, name, password, email) VALUES (:id, :name, :password, :email)`,
+		queryCreateUser,
 		user,
 	)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, tracing.RecordError(span, err)
 	}
 
 	return user.ID, nil
 }
 
 func (r *userRepository) UpdateUser(ctx context.Context, user *entity.User) (uuid.UUID, error) {
-	_, err := r.conn.NamedExecContext(
+	ctx, span := tracing.StartDB(ctx, "UserRepository.UpdateUser", queryUpdateUser)
+	defer span.End()
+
+	_, err := dbtx.From(ctx, r.conn).NamedExecContext(
 		ctx,
-		`UPDATE users SET name = :name, password = :password, email = :email, updated_at = NOW() WHERE id = :id`,
+		queryUpdateUser,
 		user,
 	)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, tracing.RecordError(span, err)
 	}
 
 	return user.ID, nil
 }
 
 func (r *userRepository) SoftDeleteUser(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
-	_, err := r.conn.ExecContext(ctx, `UPDATE users SET deleted_at = NOW() WHERE id = $1`, id)
+	ctx, span := tracing.StartDB(ctx, "UserRepository.SoftDeleteUser", querySoftDeleteUser)
+	defer span.End()
+
+	_, err := dbtx.From(ctx, r.conn).ExecContext(ctx, querySoftDeleteUser, id)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, tracing.RecordError(span, err)
 	}
 
 	return id, nil
 }
 
 func (r *userRepository) DeleteUser(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
-	_, err := r.conn.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
+	ctx, span := tracing.StartDB(ctx, "UserRepository.DeleteUser", queryDeleteUser)
+	defer span.End()
+
+	_, err := dbtx.From(ctx, r.conn).ExecContext(ctx, queryDeleteUser, id)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, tracing.RecordError(span, err)
 	}
 
 	return id, nil
 }
 
 func (r *userRepository) RestoreUser(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
-	_, err := r.conn.ExecContext(ctx, `UPDATE users SET deleted_at = NULL WHERE id = $1`, id)
+	ctx, span := tracing.StartDB(ctx, "UserRepository.RestoreUser", queryRestoreUser)
+	defer span.End()
+
+	_, err := dbtx.From(ctx, r.conn).ExecContext(ctx, queryRestoreUser, id)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, tracing.RecordError(span, err)
 	}
 
 	return id, nil
@@ -200,10 +231,13 @@ func (r *userRepository) CountUsers(ctx context.Context, query dto.GetUsersStats
 
 	finalQuery = r.conn.Rebind(finalQuery)
 
+	ctx, span := tracing.StartDB(ctx, "UserRepository.CountUsers", finalQuery)
+	defer span.End()
+
 	var count int64
-	err = r.conn.GetContext(ctx, &count, finalQuery, finalArgs...)
+	err = dbtx.From(ctx, r.conn).GetContext(ctx, &count, finalQuery, finalArgs...)
 	if err != nil {
-		return 0, err
+		return 0, tracing.RecordError(span, err)
 	}
 
 	return count, nil
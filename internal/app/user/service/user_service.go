@@ -9,7 +9,10 @@ import (
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
-	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/bcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/hasher"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/uuid"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
 )
@@ -18,25 +21,36 @@ type userService struct {
 	userRepo  contracts.UserRepository
 	validator validator.ValidatorInterface
 	uuid      uuid.UUIDInterface
-	bcrypt    bcrypt.BcryptInterface
+	hasher    hasher.PasswordHasher
+	audit     contracts.AuditService
+	tx        dbtx.TransactorInterface
 }
 
 func NewUserService(
 	userRepo contracts.UserRepository,
 	validator validator.ValidatorInterface,
 	uuid uuid.UUIDInterface,
-	bcrypt bcrypt.BcryptInterface,
+	hasher hasher.PasswordHasher,
+	audit contracts.AuditService,
+	tx dbtx.TransactorInterface,
 ) contracts.UserService {
 	return &userService{
 		userRepo:  userRepo,
 		validator: validator,
 		uuid:      uuid,
-		bcrypt:    bcrypt,
+		hasher:    hasher,
+		audit:     audit,
+		tx:        tx,
 	}
 }
 
 func (s *userService) GetUsers(ctx context.Context, query dto.GetUsersQuery) (dto.GetUsersResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUsers")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := s.validator.Validate(query)
+	valSpan.End()
 	if valErr != nil {
 		return dto.GetUsersResponse{}, valErr
 	}
@@ -68,7 +82,12 @@ func (s *userService) GetUsers(ctx context.Context, query dto.GetUsersQuery) (dt
 }
 
 func (s *userService) GetUserByID(ctx context.Context, req dto.GetUserByIDRequest) (dto.GetUserByIDResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUserByID")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := s.validator.Validate(req)
+	valSpan.End()
 	if valErr != nil {
 		return dto.GetUserByIDResponse{}, valErr
 	}
@@ -88,6 +107,9 @@ func (s *userService) GetUserByID(ctx context.Context, req dto.GetUserByIDReques
 }
 
 func (s *userService) GetUsersStats(ctx context.Context) (dto.GetUsersStatsResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.GetUsersStats")
+	defer span.End()
+
 	resultCh := make(chan int64, 2)
 	errCh := make(chan error, 2)
 
@@ -137,7 +159,12 @@ func (s *userService) GetUsersStats(ctx context.Context) (dto.GetUsersStatsRespo
 }
 
 func (s *userService) CreateUser(ctx context.Context, req dto.CreateUserRequest) (dto.CreateUserResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.CreateUser")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := s.validator.Validate(req)
+	valSpan.End()
 	if valErr != nil {
 		return dto.CreateUserResponse{}, valErr
 	}
@@ -147,7 +174,7 @@ func (s *userService) CreateUser(ctx context.Context, req dto.CreateUserRequest)
 		return dto.CreateUserResponse{}, err
 	}
 
-	hashedPassword, err := s.bcrypt.Hash(req.Password)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return dto.CreateUserResponse{}, err
 	}
@@ -168,18 +195,36 @@ func (s *userService) CreateUser(ctx context.Context, req dto.CreateUserRequest)
 		return dto.CreateUserResponse{}, err
 	}
 
-	id, err := s.userRepo.CreateUser(ctx, user)
+	// The audit record is written with the user, neither exists without the other
+	var res dto.CreateUserResponse
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		id, err := s.userRepo.CreateUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		res.ID = id
+
+		return s.audit.Record(ctx, dto.AuditRecord{
+			Action:     enums.AuditCreate,
+			EntityType: dto.AuditEntityUser,
+			EntityID:   id.String(),
+			After:      user,
+		})
+	})
 	if err != nil {
 		return dto.CreateUserResponse{}, err
 	}
 
-	return dto.CreateUserResponse{
-		ID: id,
-	}, nil
+	return res, nil
 }
 
 func (s *userService) UpdateUser(ctx context.Context, req dto.UpdateUserRequest) (dto.UpdateUserResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.UpdateUser")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := s.validator.Validate(req)
+	valSpan.End()
 	if valErr != nil {
 		return dto.UpdateUserResponse{}, valErr
 	}
@@ -191,7 +236,7 @@ func (s *userService) UpdateUser(ctx context.Context, req dto.UpdateUserRequest)
 		Email:    req.Email,
 	}
 
-	_, err := s.userRepo.GetUserByField(ctx, "id", user.ID.String())
+	before, err := s.userRepo.GetUserByField(ctx, "id", user.ID.String())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return dto.UpdateUserResponse{}, domain.ErrUserNotFound
@@ -209,30 +254,65 @@ func (s *userService) UpdateUser(ctx context.Context, req dto.UpdateUserRequest)
 		return dto.UpdateUserResponse{}, err
 	}
 
-	hashedPassword, err := s.bcrypt.Hash(req.Password)
+	hashedPassword, err := s.hasher.Hash(req.Password)
 	if err != nil {
 		return dto.UpdateUserResponse{}, err
 	}
 
 	user.Password = hashedPassword
 
-	id, err := s.userRepo.UpdateUser(ctx, user)
+	after := *before
+	after.Name = user.Name
+	after.Email = user.Email
+
+	var res dto.UpdateUserResponse
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		id, err := s.userRepo.UpdateUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		res.ID = id
+
+		return s.audit.Record(ctx, dto.AuditRecord{
+			Action:     enums.AuditUpdate,
+			EntityType: dto.AuditEntityUser,
+			EntityID:   id.String(),
+			Before:     before,
+			After:      after,
+		})
+	})
 	if err != nil {
 		return dto.UpdateUserResponse{}, err
 	}
 
-	return dto.UpdateUserResponse{
-		ID: id,
-	}, nil
+	return res, nil
 }
 
 func (s *userService) SoftDeleteUser(ctx context.Context, req dto.SoftDeleteUserRequest) (dto.SoftDeleteUserResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.SoftDeleteUser")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := s.validator.Validate(req)
+	valSpan.End()
 	if valErr != nil {
 		return dto.SoftDeleteUserResponse{}, valErr
 	}
 
-	id, err := s.userRepo.SoftDeleteUser(ctx, req.ID)
+	var res dto.SoftDeleteUserResponse
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		id, err := s.userRepo.SoftDeleteUser(ctx, req.ID)
+		if err != nil {
+			return err
+		}
+		res.ID = id
+
+		return s.audit.Record(ctx, dto.AuditRecord{
+			Action:     enums.AuditSoftDelete,
+			EntityType: dto.AuditEntityUser,
+			EntityID:   id.String(),
+		})
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return dto.SoftDeleteUserResponse{}, domain.ErrUserNotFound
@@ -241,18 +321,21 @@ func (s *userService) SoftDeleteUser(ctx context.Context, req dto.SoftDeleteUser
 		return dto.SoftDeleteUserResponse{}, err
 	}
 
-	return dto.SoftDeleteUserResponse{
-		ID: id,
-	}, nil
+	return res, nil
 }
 
 func (s *userService) DeleteUser(ctx context.Context, req dto.DeleteUserRequest) (dto.DeleteUserResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.DeleteUser")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := s.validator.Validate(req)
+	valSpan.End()
 	if valErr != nil {
 		return dto.DeleteUserResponse{}, valErr
 	}
 
-	id, err := s.userRepo.DeleteUser(ctx, req.ID)
+	before, err := s.userRepo.GetUserByField(ctx, "id", req.ID.String())
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return dto.DeleteUserResponse{}, domain.ErrUserNotFound
@@ -261,18 +344,57 @@ func (s *userService) DeleteUser(ctx context.Context, req dto.DeleteUserRequest)
 		return dto.DeleteUserResponse{}, err
 	}
 
-	return dto.DeleteUserResponse{
-		ID: id,
-	}, nil
+	var res dto.DeleteUserResponse
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		id, err := s.userRepo.DeleteUser(ctx, req.ID)
+		if err != nil {
+			return err
+		}
+		res.ID = id
+
+		return s.audit.Record(ctx, dto.AuditRecord{
+			Action:     enums.AuditDelete,
+			EntityType: dto.AuditEntityUser,
+			EntityID:   id.String(),
+			Before:     before,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return dto.DeleteUserResponse{}, domain.ErrUserNotFound
+		}
+
+		return dto.DeleteUserResponse{}, err
+	}
+
+	return res, nil
 }
 
 func (s *userService) RestoreUser(ctx context.Context, req dto.RestoreUserRequest) (dto.RestoreUserResponse, error) {
+	ctx, span := tracing.Start(ctx, "UserService.RestoreUser")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
 	valErr := s.validator.Validate(req)
+	valSpan.End()
 	if valErr != nil {
 		return dto.RestoreUserResponse{}, valErr
 	}
 
-	id, err := s.userRepo.RestoreUser(ctx, req.ID)
+	var res dto.RestoreUserResponse
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		id, err := s.userRepo.RestoreUser(ctx, req.ID)
+		if err != nil {
+			return err
+		}
+		res.ID = id
+
+		return s.audit.Record(ctx, dto.AuditRecord{
+			Action:     enums.AuditRestore,
+			EntityType: dto.AuditEntityUser,
+			EntityID:   id.String(),
+		})
+	})
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return dto.RestoreUserResponse{}, domain.ErrUserNotFound
@@ -281,7 +403,5 @@ func (s *userService) RestoreUser(ctx context.Context, req dto.RestoreUserReques
 		return dto.RestoreUserResponse{}, err
 	}
 
-	return dto.RestoreUserResponse{
-		ID: id,
-	}, nil
+	return res, nil
 }
@@ -0,0 +1,244 @@
+package controller
+
+import (
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+)
+
+type webhookController struct {
+	webhookService contracts.WebhookService
+}
+
+// InitNewController mounts webhook management. Like API keys these endpoints need a manager
+// token: the responses carry signing secrets and deliveries carry employee data.
+func InitNewController(router fiber.Router, webhookService contracts.WebhookService, middleware *middlewares.Middleware) {
+	controller := &webhookController{
+		webhookService: webhookService,
+	}
+
+	route := router.Group("/v1/webhooks")
+	route.Post("/", middleware.RequireAdmin(), controller.create)
+	route.Get("/", middleware.RequireAdmin(), controller.list)
+	route.Get("/:id", middleware.RequireAdmin(), controller.get)
+	route.Patch("/:id", middleware.RequireAdmin(), controller.update)
+	route.Delete("/:id", middleware.RequireAdmin(), controller.delete)
+	route.Post("/:id/rotate-secret", middleware.RequireAdmin(), controller.rotateSecret)
+	route.Post("/:id/ping", middleware.RequireAdmin(), controller.ping)
+	route.Get("/:id/deliveries", middleware.RequireAdmin(), controller.listDeliveries)
+	route.Post("/:id/deliveries/redeliver-dead", middleware.RequireAdmin(), controller.redeliverDead)
+	route.Get("/:id/deliveries/:deliveryId", middleware.RequireAdmin(), controller.getDelivery)
+	route.Post("/:id/deliveries/:deliveryId/redeliver", middleware.RequireAdmin(), controller.redeliver)
+}
+
+func (c *webhookController) create(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	var req dto.CreateWebhookRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	res, err := c.webhookService.Create(ctx.UserContext(), managerID, req)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusCreated, res)
+}
+
+func (c *webhookController) list(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	res, err := c.webhookService.List(ctx.UserContext(), managerID)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+func (c *webhookController) get(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	id, err := webhookID(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.webhookService.Get(ctx.UserContext(), managerID, id)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+func (c *webhookController) update(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	id, err := webhookID(ctx)
+	if err != nil {
+		return err
+	}
+
+	var req dto.UpdateWebhookRequest
+	if err := ctx.BodyParser(&req); err != nil {
+		return domain.ErrInvalidRequestBody
+	}
+
+	res, err := c.webhookService.Update(ctx.UserContext(), managerID, id, req)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+func (c *webhookController) delete(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	id, err := webhookID(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.webhookService.Delete(ctx.UserContext(), managerID, id); err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, fiber.Map{
+		"message": "Webhook deleted",
+	})
+}
+
+func (c *webhookController) rotateSecret(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	id, err := webhookID(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.webhookService.RotateSecret(ctx.UserContext(), managerID, id)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+func (c *webhookController) ping(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	id, err := webhookID(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.webhookService.Ping(ctx.UserContext(), managerID, id)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusAccepted, res)
+}
+
+func (c *webhookController) listDeliveries(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	id, err := webhookID(ctx)
+	if err != nil {
+		return err
+	}
+
+	query := dto.WebhookDeliveryQuery{Limit: 20}
+	if err := ctx.QueryParser(&query); err != nil {
+		return domain.ErrInvalidQueryParam
+	}
+
+	res, err := c.webhookService.ListDeliveries(ctx.UserContext(), managerID, id, query)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+func (c *webhookController) getDelivery(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	id, deliveryID, err := deliveryIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.webhookService.GetDelivery(ctx.UserContext(), managerID, id, deliveryID)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusOK, res)
+}
+
+func (c *webhookController) redeliver(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	id, deliveryID, err := deliveryIDs(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := c.webhookService.Redeliver(ctx.UserContext(), managerID, id, deliveryID); err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusAccepted, fiber.Map{
+		"message": "Delivery queued",
+	})
+}
+
+func (c *webhookController) redeliverDead(ctx *fiber.Ctx) error {
+	managerID := ctx.Locals("claims").(jwt.ClaimsManager).UserID
+
+	id, err := webhookID(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := c.webhookService.RedeliverDead(ctx.UserContext(), managerID, id)
+	if err != nil {
+		return err
+	}
+
+	return response.SendResponse(ctx, fiber.StatusAccepted, res)
+}
+
+func webhookID(ctx *fiber.Ctx) (int, error) {
+	id, err := ctx.ParamsInt("id")
+	if err != nil || id <= 0 {
+		return 0, domain.ErrWebhookNotFound
+	}
+
+	return id, nil
+}
+
+func deliveryIDs(ctx *fiber.Ctx) (int, int64, error) {
+	id, err := webhookID(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	deliveryID, err := strconv.ParseInt(ctx.Params("deliveryId"), 10, 64)
+	if err != nil || deliveryID <= 0 {
+		return 0, 0, domain.ErrWebhookDeliveryNotFound
+	}
+
+	return id, deliveryID, nil
+}
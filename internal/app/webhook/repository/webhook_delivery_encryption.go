@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/fieldcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+// The field name is bound into the ciphertexts, renaming it makes existing rows unreadable
+const fieldPayload = "webhook_deliveries.payload"
+
+const (
+	querySelectDeliveriesForRekey = "SELECT " + deliveryColumns + `
+	FROM webhook_deliveries WHERE key_id IS DISTINCT FROM $1 ORDER BY id LIMIT $2 FOR UPDATE SKIP LOCKED`
+	querySelectDeliveriesForDecrypt = "SELECT " + deliveryColumns + `
+	FROM webhook_deliveries WHERE key_id IS NOT NULL ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED`
+	querySealDelivery   = "UPDATE webhook_deliveries SET payload = NULL, payload_enc = $1, data_key = $2, key_id = $3 WHERE id = $4"
+	queryUnsealDelivery = "UPDATE webhook_deliveries SET payload = $1, payload_enc = NULL, data_key = NULL, key_id = NULL WHERE id = $2"
+)
+
+// deliveryRow is a delivery as stored: the payload is plaintext while key_id is NULL, sealed otherwise
+type deliveryRow struct {
+	ID             int64          `db:"id"`
+	EndpointID     int            `db:"endpoint_id"`
+	EventID        string         `db:"event_id"`
+	EventType      string         `db:"event_type"`
+	Payload        sql.NullString `db:"payload"`
+	PayloadEnc     []byte         `db:"payload_enc"`
+	DataKey        []byte         `db:"data_key"`
+	KeyID          sql.NullString `db:"key_id"`
+	Status         string         `db:"status"`
+	Attempts       int            `db:"attempts"`
+	NextAttemptAt  time.Time      `db:"next_attempt_at"`
+	LastStatusCode *int           `db:"last_status_code"`
+	LastError      string         `db:"last_error"`
+	DeliveredAt    *time.Time     `db:"delivered_at"`
+	CreatedAt      time.Time      `db:"created_at"`
+	URL            string         `db:"url"`
+	Secret         string         `db:"secret"`
+}
+
+func (repo *webhookRepository) open(row deliveryRow) (entity.WebhookDelivery, error) {
+	delivery := entity.WebhookDelivery{
+		ID:             row.ID,
+		EndpointID:     row.EndpointID,
+		EventID:        row.EventID,
+		EventType:      row.EventType,
+		Payload:        row.Payload.String,
+		Status:         row.Status,
+		Attempts:       row.Attempts,
+		NextAttemptAt:  row.NextAttemptAt,
+		LastStatusCode: row.LastStatusCode,
+		LastError:      row.LastError,
+		DeliveredAt:    row.DeliveredAt,
+		CreatedAt:      row.CreatedAt,
+		URL:            row.URL,
+		Secret:         row.Secret,
+	}
+
+	if !row.KeyID.Valid {
+		return delivery, nil
+	}
+
+	payload, err := fieldcrypt.Open(repo.crypt, fieldPayload, fieldcrypt.Sealed{
+		Ciphertext: row.PayloadEnc,
+		DataKey:    row.DataKey,
+		KeyID:      row.KeyID.String,
+	})
+	if err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+	delivery.Payload = payload
+
+	return delivery, nil
+}
+
+func (repo *webhookRepository) openAll(rows []deliveryRow) ([]entity.WebhookDelivery, error) {
+	deliveries := make([]entity.WebhookDelivery, 0, len(rows))
+	for _, row := range rows {
+		delivery, err := repo.open(row)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+
+	return deliveries, nil
+}
+
+// Reencrypt seals up to limit payloads that are still plaintext or use another key than the active one,
+// with a fresh data key each. Rows locked by a concurrent run are skipped.
+func (repo *webhookRepository) Reencrypt(ctx context.Context, limit int) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.Reencrypt", querySelectDeliveriesForRekey)
+	defer span.End()
+
+	tx, err := repo.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+	defer tx.Rollback()
+
+	rows := []deliveryRow{}
+	if err := tx.SelectContext(ctx, &rows, querySelectDeliveriesForRekey, repo.crypt.ActiveKeyID(), limit); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	for _, row := range rows {
+		delivery, err := repo.open(row)
+		if err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+
+		sealed, err := fieldcrypt.Seal(repo.crypt, fieldPayload, delivery.Payload)
+		if err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+
+		_, err = tx.ExecContext(ctx, querySealDelivery, sealed.Ciphertext, sealed.DataKey, sealed.KeyID, row.ID)
+		if err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+	}
+
+	return len(rows), tracing.RecordError(span, tx.Commit())
+}
+
+// Decrypt writes up to limit sealed payloads back in plaintext, to roll the encryption migration back
+func (repo *webhookRepository) Decrypt(ctx context.Context, limit int) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.Decrypt", querySelectDeliveriesForDecrypt)
+	defer span.End()
+
+	tx, err := repo.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+	defer tx.Rollback()
+
+	rows := []deliveryRow{}
+	if err := tx.SelectContext(ctx, &rows, querySelectDeliveriesForDecrypt, limit); err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	for _, row := range rows {
+		delivery, err := repo.open(row)
+		if err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+
+		if _, err := tx.ExecContext(ctx, queryUnsealDelivery, delivery.Payload, row.ID); err != nil {
+			return 0, tracing.RecordError(span, err)
+		}
+	}
+
+	return len(rows), tracing.RecordError(span, tx.Commit())
+}
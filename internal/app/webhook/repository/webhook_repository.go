@@ -0,0 +1,394 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/fieldcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	endpointColumns = "id, tenant_id, url, description, events, secret, active, created_at, updated_at"
+	deliveryColumns = `id, endpoint_id, event_id, event_type, payload, payload_enc, data_key, key_id, status, attempts,
+		next_attempt_at, last_status_code, last_error, delivered_at, created_at`
+
+	queryCreate = `
+	INSERT INTO webhook_endpoints (tenant_id, url, description, events, secret)
+	VALUES (:tenant_id, :url, :description, :events, :secret)
+	RETURNING ` + endpointColumns
+	queryListByTenant = "SELECT " + endpointColumns + " FROM webhook_endpoints WHERE tenant_id = $1 ORDER BY id"
+	queryFindByID     = "SELECT " + endpointColumns + " FROM webhook_endpoints WHERE id = $1 AND tenant_id = $2"
+	queryUpdate       = `
+	UPDATE webhook_endpoints SET
+		url = :url,
+		description = :description,
+		events = :events,
+		secret = :secret,
+		active = :active,
+		updated_at = NOW()
+	WHERE id = :id AND tenant_id = :tenant_id
+	RETURNING ` + endpointColumns
+	queryDelete = "DELETE FROM webhook_endpoints WHERE id = $1 AND tenant_id = $2"
+
+	// One delivery per subscribed endpoint, in a single statement so an event reaches all of them or none
+	queryEnqueue = `
+	INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload_enc, data_key, key_id)
+	SELECT id, $2, $3::text, $4, $5, $6 FROM webhook_endpoints
+	WHERE tenant_id = $1 AND active AND (events = '' OR $3::text = ANY(string_to_array(events, ',')))
+	ON CONFLICT (endpoint_id, event_id) DO NOTHING`
+	queryEnqueueTo = `
+	INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload_enc, data_key, key_id)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING ` + deliveryColumns
+	// Like the email outbox, claimed rows are leased ($2 seconds) rather than locked. Deliveries of
+	// paused endpoints are skipped and wait for the endpoint to be resumed.
+	queryClaim = `
+	UPDATE webhook_deliveries d SET
+		status = 'delivering',
+		attempts = d.attempts + 1,
+		next_attempt_at = NOW() + make_interval(secs => $2)
+	FROM webhook_endpoints e
+	WHERE e.id = d.endpoint_id AND d.id IN (
+		SELECT wd.id FROM webhook_deliveries wd
+		JOIN webhook_endpoints we ON we.id = wd.endpoint_id
+		WHERE we.active AND wd.status IN ('pending', 'delivering') AND wd.next_attempt_at <= NOW()
+		ORDER BY wd.next_attempt_at
+		LIMIT $1
+		FOR UPDATE OF wd SKIP LOCKED
+	)
+	RETURNING d.id, d.endpoint_id, d.event_id, d.event_type, d.payload, d.payload_enc, d.data_key, d.key_id, d.status,
+		d.attempts, d.next_attempt_at, d.last_status_code, d.last_error, d.delivered_at, d.created_at, e.url, e.secret`
+	queryInsertAttempt = `
+	INSERT INTO webhook_delivery_attempts (delivery_id, status_code, error, response_body, duration_ms)
+	VALUES (:delivery_id, :status_code, :error, :response_body, :duration_ms)`
+	queryMarkDelivered = `
+	UPDATE webhook_deliveries SET status = 'delivered', delivered_at = NOW(), last_status_code = $2, last_error = ''
+	WHERE id = $1`
+	queryMarkRetry = `
+	UPDATE webhook_deliveries SET
+		status = 'pending',
+		last_status_code = $2,
+		last_error = $3,
+		next_attempt_at = NOW() + make_interval(secs => $4)
+	WHERE id = $1`
+	queryMarkDead = "UPDATE webhook_deliveries SET status = 'dead', last_status_code = $2, last_error = $3 WHERE id = $1"
+
+	queryListDeliveries = `
+	SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+	WHERE endpoint_id = $1 AND ($2 = '' OR status = $2)
+	ORDER BY id DESC LIMIT $3 OFFSET $4`
+	queryFindDelivery = "SELECT " + deliveryColumns + " FROM webhook_deliveries WHERE id = $1 AND endpoint_id = $2"
+	queryListAttempts = `
+	SELECT id, delivery_id, status_code, error, response_body, duration_ms, created_at
+	FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY id`
+	queryRedeliver = `
+	UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = NOW(), delivered_at = NULL
+	WHERE id = $1 AND endpoint_id = $2 AND status <> 'delivering'`
+	queryRedeliverDead = `
+	UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+	WHERE endpoint_id = $1 AND status = 'dead'`
+	// Like the outbox, deliveries of the events keep their envelope, pending ones still go out without the data
+	queryScrubDeliveries = `
+	UPDATE webhook_deliveries SET
+		payload = json_build_object('id', event_id, 'type', event_type, 'occurredAt', created_at, 'data', NULL)::text,
+		payload_enc = NULL,
+		data_key = NULL,
+		key_id = NULL
+	WHERE event_id = ANY($1)`
+)
+
+type webhookRepository struct {
+	DB    *sqlx.DB
+	crypt fieldcrypt.FieldCryptInterface
+}
+
+func NewWebhookRepository(db *sqlx.DB, crypt fieldcrypt.FieldCryptInterface) contracts.WebhookRepository {
+	return &webhookRepository{DB: db, crypt: crypt}
+}
+
+func (repo *webhookRepository) Create(ctx context.Context, endpoint entity.WebhookEndpoint) (entity.WebhookEndpoint, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.Create", queryCreate)
+	defer span.End()
+
+	created, err := repo.namedGet(ctx, queryCreate, endpoint)
+	return created, tracing.RecordError(span, err)
+}
+
+func (repo *webhookRepository) ListByTenant(ctx context.Context, tenantID int) ([]entity.WebhookEndpoint, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.ListByTenant", queryListByTenant)
+	defer span.End()
+
+	endpoints := []entity.WebhookEndpoint{}
+	if err := repo.DB.SelectContext(ctx, &endpoints, queryListByTenant, tenantID); err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return endpoints, nil
+}
+
+func (repo *webhookRepository) FindByID(ctx context.Context, tenantID, id int) (*entity.WebhookEndpoint, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.FindByID", queryFindByID)
+	defer span.End()
+
+	var endpoint entity.WebhookEndpoint
+	if err := repo.DB.GetContext(ctx, &endpoint, queryFindByID, id, tenantID); err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return &endpoint, nil
+}
+
+// Update returns sql.ErrNoRows when the tenant has no such endpoint
+func (repo *webhookRepository) Update(ctx context.Context, endpoint entity.WebhookEndpoint) (entity.WebhookEndpoint, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.Update", queryUpdate)
+	defer span.End()
+
+	updated, err := repo.namedGet(ctx, queryUpdate, endpoint)
+	return updated, tracing.RecordError(span, err)
+}
+
+func (repo *webhookRepository) Delete(ctx context.Context, tenantID, id int) (bool, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.Delete", queryDelete)
+	defer span.End()
+
+	result, err := repo.DB.ExecContext(ctx, queryDelete, id, tenantID)
+	if err != nil {
+		return false, tracing.RecordError(span, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, tracing.RecordError(span, err)
+	}
+
+	return affected > 0, nil
+}
+
+func (repo *webhookRepository) Enqueue(ctx context.Context, tenantID int, event entity.WebhookDelivery) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.Enqueue", queryEnqueue)
+	defer span.End()
+
+	sealed, err := fieldcrypt.Seal(repo.crypt, fieldPayload, event.Payload)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	result, err := repo.DB.ExecContext(ctx, queryEnqueue,
+		tenantID, event.EventID, event.EventType, sealed.Ciphertext, sealed.DataKey, sealed.KeyID)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	return int(affected), nil
+}
+
+func (repo *webhookRepository) EnqueueTo(ctx context.Context, endpointID int, event entity.WebhookDelivery) (entity.WebhookDelivery, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.EnqueueTo", queryEnqueueTo)
+	defer span.End()
+
+	sealed, err := fieldcrypt.Seal(repo.crypt, fieldPayload, event.Payload)
+	if err != nil {
+		return entity.WebhookDelivery{}, tracing.RecordError(span, err)
+	}
+
+	var row deliveryRow
+	err = repo.DB.GetContext(ctx, &row, queryEnqueueTo,
+		endpointID, event.EventID, event.EventType, sealed.Ciphertext, sealed.DataKey, sealed.KeyID)
+	if err != nil {
+		return entity.WebhookDelivery{}, tracing.RecordError(span, err)
+	}
+
+	delivery, err := repo.open(row)
+	return delivery, tracing.RecordError(span, err)
+}
+
+func (repo *webhookRepository) Claim(ctx context.Context, limit int, lease time.Duration) ([]entity.WebhookDelivery, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.Claim", queryClaim)
+	defer span.End()
+
+	var rows []deliveryRow
+	if err := repo.DB.SelectContext(ctx, &rows, queryClaim, limit, lease.Seconds()); err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	deliveries, err := repo.openAll(rows)
+	return deliveries, tracing.RecordError(span, err)
+}
+
+func (repo *webhookRepository) MarkDelivered(ctx context.Context, attempt entity.WebhookAttempt) error {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.MarkDelivered", queryMarkDelivered)
+	defer span.End()
+
+	return tracing.RecordError(span, repo.recordAttempt(ctx, attempt, queryMarkDelivered, attempt.DeliveryID, attempt.StatusCode))
+}
+
+func (repo *webhookRepository) MarkFailed(ctx context.Context, attempt entity.WebhookAttempt, retryIn time.Duration, giveUp bool) error {
+	if giveUp {
+		ctx, span := tracing.StartDB(ctx, "WebhookRepository.MarkFailed", queryMarkDead)
+		defer span.End()
+
+		return tracing.RecordError(span, repo.recordAttempt(ctx, attempt, queryMarkDead,
+			attempt.DeliveryID, attempt.StatusCode, attempt.Error))
+	}
+
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.MarkFailed", queryMarkRetry)
+	defer span.End()
+
+	return tracing.RecordError(span, repo.recordAttempt(ctx, attempt, queryMarkRetry,
+		attempt.DeliveryID, attempt.StatusCode, attempt.Error, retryIn.Seconds()))
+}
+
+func (repo *webhookRepository) ListDeliveries(ctx context.Context, endpointID int, query dto.WebhookDeliveryQuery) ([]entity.WebhookDelivery, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.ListDeliveries", queryListDeliveries)
+	defer span.End()
+
+	rows := []deliveryRow{}
+	err := repo.DB.SelectContext(ctx, &rows, queryListDeliveries, endpointID, query.Status, query.Limit, query.Offset)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	deliveries, err := repo.openAll(rows)
+	return deliveries, tracing.RecordError(span, err)
+}
+
+func (repo *webhookRepository) FindDelivery(ctx context.Context, endpointID int, id int64) (*entity.WebhookDelivery, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.FindDelivery", queryFindDelivery)
+	defer span.End()
+
+	var row deliveryRow
+	if err := repo.DB.GetContext(ctx, &row, queryFindDelivery, id, endpointID); err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	delivery, err := repo.open(row)
+	if err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return &delivery, nil
+}
+
+func (repo *webhookRepository) ListAttempts(ctx context.Context, deliveryID int64) ([]entity.WebhookAttempt, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.ListAttempts", queryListAttempts)
+	defer span.End()
+
+	attempts := []entity.WebhookAttempt{}
+	if err := repo.DB.SelectContext(ctx, &attempts, queryListAttempts, deliveryID); err != nil {
+		return nil, tracing.RecordError(span, err)
+	}
+
+	return attempts, nil
+}
+
+func (repo *webhookRepository) Redeliver(ctx context.Context, endpointID int, id int64) (bool, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.Redeliver", queryRedeliver)
+	defer span.End()
+
+	result, err := repo.DB.ExecContext(ctx, queryRedeliver, id, endpointID)
+	if err != nil {
+		return false, tracing.RecordError(span, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, tracing.RecordError(span, err)
+	}
+
+	return affected > 0, nil
+}
+
+func (repo *webhookRepository) RedeliverDead(ctx context.Context, endpointID int) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.RedeliverDead", queryRedeliverDead)
+	defer span.End()
+
+	result, err := repo.DB.ExecContext(ctx, queryRedeliverDead, endpointID)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	return int(affected), nil
+}
+
+// ScrubEvents replaces the payload of every delivery of the events with its bare envelope, with the
+// transaction ctx carries if any, and returns how many
+func (repo *webhookRepository) ScrubEvents(ctx context.Context, eventIDs []string) (int, error) {
+	ctx, span := tracing.StartDB(ctx, "WebhookRepository.ScrubEvents", queryScrubDeliveries)
+	defer span.End()
+
+	if len(eventIDs) == 0 {
+		return 0, nil
+	}
+
+	result, err := dbtx.From(ctx, repo.DB).ExecContext(ctx, queryScrubDeliveries, eventIDs)
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, tracing.RecordError(span, err)
+	}
+
+	return int(affected), nil
+}
+
+// recordAttempt logs the attempt and moves its delivery to the next state in one transaction
+func (repo *webhookRepository) recordAttempt(ctx context.Context, attempt entity.WebhookAttempt, query string, args ...interface{}) error {
+	tx, err := repo.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.NamedExecContext(ctx, queryInsertAttempt, attempt); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// namedGet runs a named query returning a single endpoint, sql.ErrNoRows when it returns none
+func (repo *webhookRepository) namedGet(ctx context.Context, query string, arg interface{}) (entity.WebhookEndpoint, error) {
+	rows, err := repo.DB.NamedQueryContext(ctx, query, arg)
+	if err != nil {
+		return entity.WebhookEndpoint{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return entity.WebhookEndpoint{}, err
+		}
+
+		return entity.WebhookEndpoint{}, sql.ErrNoRows
+	}
+
+	var endpoint entity.WebhookEndpoint
+	if err := rows.StructScan(&endpoint); err != nil {
+		return entity.WebhookEndpoint{}, err
+	}
+
+	return endpoint, nil
+}
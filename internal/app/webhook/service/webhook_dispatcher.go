@@ -0,0 +1,209 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/egress"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/signature"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+)
+
+const (
+	BatchSize   = 20
+	MaxAttempts = 10
+	// Lease is how long a claimed delivery stays invisible to other dispatchers. A batch is sent
+	// concurrently, so it has to outlast one request timeout.
+	Lease = 2 * time.Minute
+	// MaxBackoff caps the exponential retry delay; 10 attempts span roughly 4 hours
+	MaxBackoff = 3 * time.Hour
+	// maxResponseBody is how much of a response the delivery log keeps
+	maxResponseBody = 1024
+	userAgent       = "GoGoManager-Webhooks/1.0"
+)
+
+type DispatcherConfig struct {
+	Interval time.Duration
+	// Timeout bounds a whole request, receivers should answer quickly and process asynchronously
+	Timeout time.Duration
+	// Guard is checked on every dial, a registered host may resolve elsewhere by the time it is sent to
+	Guard egress.Guard
+}
+
+type webhookDispatcher struct {
+	repo     contracts.WebhookRepository
+	client   *http.Client
+	interval time.Duration
+}
+
+func NewWebhookDispatcher(repo contracts.WebhookRepository, config DispatcherConfig) contracts.WebhookDispatcher {
+	if config.Interval <= 0 {
+		config.Interval = 5 * time.Second
+	}
+	if config.Timeout <= 0 || config.Timeout > Lease/2 {
+		config.Timeout = 10 * time.Second
+	}
+
+	return &webhookDispatcher{
+		repo: repo,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: config.Guard.Transport(),
+			// A redirect is a failed delivery, receivers have to register the final url
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		interval: config.Interval,
+	}
+}
+
+// Dispatch sends one batch of due deliveries concurrently and returns how many were claimed
+func (d *webhookDispatcher) Dispatch(ctx context.Context) (int, error) {
+	ctx, span := tracing.Start(ctx, "WebhookDispatcher.Dispatch")
+	defer span.End()
+
+	deliveries, err := d.repo.Claim(ctx, BatchSize, Lease)
+	if err != nil {
+		return 0, err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery entity.WebhookDelivery) {
+			defer wg.Done()
+			d.deliver(ctx, delivery)
+		}(delivery)
+	}
+	wg.Wait()
+
+	return len(deliveries), nil
+}
+
+// Run dispatches on every tick until ctx is cancelled, draining a backlog batch after batch
+func (d *webhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			claimed, err := d.Dispatch(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					log.ErrorCtx(ctx, log.LogInfo{
+						"error": err.Error(),
+					}, "[WebhookDispatcher][Run] failed to dispatch webhooks")
+				}
+				break
+			}
+
+			if claimed < BatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver makes one attempt and records its outcome. A failure to record it is only logged:
+// the lease runs out and the delivery is attempted again, receivers dedupe on Webhook-Id.
+func (d *webhookDispatcher) deliver(ctx context.Context, delivery entity.WebhookDelivery) {
+	ctx, span := tracing.Start(ctx, "WebhookDispatcher.deliver")
+	defer span.End()
+
+	attempt := d.send(ctx, delivery)
+
+	var err error
+	if attempt.Error == "" {
+		err = d.repo.MarkDelivered(ctx, attempt)
+	} else {
+		giveUp := delivery.Attempts >= MaxAttempts
+		log.WarnCtx(ctx, log.LogInfo{
+			"delivery_id": delivery.ID,
+			"endpoint_id": delivery.EndpointID,
+			"attempts":    delivery.Attempts,
+			"give_up":     giveUp,
+			"error":       attempt.Error,
+		}, "[WebhookDispatcher][deliver] failed to deliver webhook")
+
+		err = d.repo.MarkFailed(ctx, attempt, backoff(delivery.Attempts), giveUp)
+	}
+
+	if err != nil {
+		tracing.RecordError(span, err)
+		log.ErrorCtx(ctx, log.LogInfo{
+			"delivery_id": delivery.ID,
+			"error":       err.Error(),
+		}, "[WebhookDispatcher][deliver] failed to record webhook attempt")
+	}
+}
+
+// send POSTs the payload signed with the endpoint's current secret. Only a 2xx answer counts as delivered.
+func (d *webhookDispatcher) send(ctx context.Context, delivery entity.WebhookDelivery) entity.WebhookAttempt {
+	attempt := entity.WebhookAttempt{DeliveryID: delivery.ID}
+	body := []byte(delivery.Payload)
+
+	now := time.Now()
+	sig, err := signature.Sign(delivery.Secret, delivery.EventID, now, body)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(signature.HeaderID, delivery.EventID)
+	req.Header.Set(signature.HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(signature.HeaderSignature, sig)
+
+	res, err := d.client.Do(req)
+	attempt.DurationMs = int(time.Since(now).Milliseconds())
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer res.Body.Close()
+
+	statusCode := res.StatusCode
+	attempt.StatusCode = &statusCode
+
+	responseBody, _ := io.ReadAll(io.LimitReader(res.Body, maxResponseBody))
+	// Postgres text takes neither invalid UTF-8 nor NUL bytes
+	attempt.ResponseBody = string(bytes.ReplaceAll(bytes.ToValidUTF8(responseBody, nil), []byte{0}, nil))
+
+	if statusCode < 200 || statusCode > 299 {
+		attempt.Error = fmt.Sprintf("endpoint answered %d %s", statusCode, http.StatusText(statusCode))
+	}
+
+	return attempt
+}
+
+func backoff(attempts int) time.Duration {
+	delay := 30 * time.Second << max(attempts-1, 0)
+	if delay <= 0 || delay > MaxBackoff {
+		return MaxBackoff
+	}
+
+	return delay
+}
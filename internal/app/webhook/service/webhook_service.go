@@ -0,0 +1,444 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/egress"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/signature"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/uuid"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+type webhookService struct {
+	repo      contracts.WebhookRepository
+	validator validator.ValidatorInterface
+	uuid      uuid.UUIDInterface
+	guard     egress.Guard
+}
+
+func NewWebhookService(
+	repo contracts.WebhookRepository,
+	validator validator.ValidatorInterface,
+	uuid uuid.UUIDInterface,
+	guard egress.Guard,
+) contracts.WebhookService {
+	return &webhookService{
+		repo:      repo,
+		validator: validator,
+		uuid:      uuid,
+		guard:     guard,
+	}
+}
+
+func (s *webhookService) Create(ctx context.Context, managerID int, req dto.CreateWebhookRequest) (dto.WebhookSecretRes, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.Create")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
+	valErr := s.validator.Validate(req)
+	valSpan.End()
+	if valErr != nil {
+		return dto.WebhookSecretRes{}, valErr
+	}
+
+	if err := s.validateURL(ctx, req.URL); err != nil {
+		return dto.WebhookSecretRes{}, err
+	}
+
+	secret, err := signature.NewSecret()
+	if err != nil {
+		return dto.WebhookSecretRes{}, err
+	}
+
+	created, err := s.repo.Create(ctx, entity.WebhookEndpoint{
+		TenantID:    managerID,
+		URL:         req.URL,
+		Description: req.Description,
+		Events:      strings.Join(req.Events, ","),
+		Secret:      secret,
+	})
+	if err != nil {
+		return dto.WebhookSecretRes{}, err
+	}
+
+	log.InfoCtx(ctx, log.LogInfo{
+		"webhook_id": created.ID,
+		"events":     created.Events,
+	}, "[WebhookService][Create] webhook created")
+
+	return dto.WebhookSecretRes{
+		WebhookRes: toWebhookRes(created),
+		Secret:     secret,
+	}, nil
+}
+
+func (s *webhookService) List(ctx context.Context, managerID int) ([]dto.WebhookRes, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.List")
+	defer span.End()
+
+	endpoints, err := s.repo.ListByTenant(ctx, managerID)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.WebhookRes, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		res = append(res, toWebhookRes(endpoint))
+	}
+
+	return res, nil
+}
+
+func (s *webhookService) Get(ctx context.Context, managerID, id int) (dto.WebhookRes, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.Get")
+	defer span.End()
+
+	endpoint, err := s.find(ctx, managerID, id)
+	if err != nil {
+		return dto.WebhookRes{}, err
+	}
+
+	return toWebhookRes(*endpoint), nil
+}
+
+func (s *webhookService) Update(ctx context.Context, managerID, id int, req dto.UpdateWebhookRequest) (dto.WebhookRes, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.Update")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
+	valErr := s.validator.Validate(req)
+	valSpan.End()
+	if valErr != nil {
+		return dto.WebhookRes{}, valErr
+	}
+
+	if rawURL, ok := req.URL.Get(); ok {
+		if err := s.validateURL(ctx, rawURL); err != nil {
+			return dto.WebhookRes{}, err
+		}
+	}
+
+	endpoint, err := s.find(ctx, managerID, id)
+	if err != nil {
+		return dto.WebhookRes{}, err
+	}
+
+	endpoint.URL = req.URL.Apply(endpoint.URL)
+	endpoint.Description = req.Description.Apply(endpoint.Description)
+	endpoint.Events = strings.Join(req.Events.Apply(endpoint.EventList()), ",")
+	endpoint.Active = req.Active.Apply(endpoint.Active)
+
+	updated, err := s.update(ctx, *endpoint)
+	if err != nil {
+		return dto.WebhookRes{}, err
+	}
+
+	return toWebhookRes(updated), nil
+}
+
+// RotateSecret replaces the signing secret. Retries of earlier events are signed with the new one.
+func (s *webhookService) RotateSecret(ctx context.Context, managerID, id int) (dto.WebhookSecretRes, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.RotateSecret")
+	defer span.End()
+
+	endpoint, err := s.find(ctx, managerID, id)
+	if err != nil {
+		return dto.WebhookSecretRes{}, err
+	}
+
+	endpoint.Secret, err = signature.NewSecret()
+	if err != nil {
+		return dto.WebhookSecretRes{}, err
+	}
+
+	updated, err := s.update(ctx, *endpoint)
+	if err != nil {
+		return dto.WebhookSecretRes{}, err
+	}
+
+	log.InfoCtx(ctx, log.LogInfo{
+		"webhook_id": id,
+	}, "[WebhookService][RotateSecret] webhook secret rotated")
+
+	return dto.WebhookSecretRes{
+		WebhookRes: toWebhookRes(updated),
+		Secret:     updated.Secret,
+	}, nil
+}
+
+func (s *webhookService) Delete(ctx context.Context, managerID, id int) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.Delete")
+	defer span.End()
+
+	deleted, err := s.repo.Delete(ctx, managerID, id)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return domain.ErrWebhookNotFound
+	}
+
+	log.InfoCtx(ctx, log.LogInfo{
+		"webhook_id": id,
+	}, "[WebhookService][Delete] webhook deleted")
+
+	return nil
+}
+
+func (s *webhookService) Ping(ctx context.Context, managerID, id int) (dto.WebhookDeliveryRes, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.Ping")
+	defer span.End()
+
+	if _, err := s.find(ctx, managerID, id); err != nil {
+		return dto.WebhookDeliveryRes{}, err
+	}
+
+	event, err := s.newEvent(enums.WebhookPing, map[string]int{"webhookId": id}, nil)
+	if err != nil {
+		return dto.WebhookDeliveryRes{}, err
+	}
+
+	delivery, err := s.repo.EnqueueTo(ctx, id, event)
+	if err != nil {
+		return dto.WebhookDeliveryRes{}, err
+	}
+
+	return toDeliveryRes(delivery), nil
+}
+
+func (s *webhookService) ListDeliveries(ctx context.Context, managerID, id int, query dto.WebhookDeliveryQuery) ([]dto.WebhookDeliveryRes, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.ListDeliveries")
+	defer span.End()
+
+	_, valSpan := tracing.Start(ctx, "Validator.Validate")
+	valErr := s.validator.Validate(query)
+	valSpan.End()
+	if valErr != nil {
+		return nil, valErr
+	}
+
+	if _, err := s.find(ctx, managerID, id); err != nil {
+		return nil, err
+	}
+
+	deliveries, err := s.repo.ListDeliveries(ctx, id, query)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]dto.WebhookDeliveryRes, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		res = append(res, toDeliveryRes(delivery))
+	}
+
+	return res, nil
+}
+
+func (s *webhookService) GetDelivery(ctx context.Context, managerID, id int, deliveryID int64) (dto.WebhookDeliveryDetailRes, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.GetDelivery")
+	defer span.End()
+
+	delivery, err := s.findDelivery(ctx, managerID, id, deliveryID)
+	if err != nil {
+		return dto.WebhookDeliveryDetailRes{}, err
+	}
+
+	attempts, err := s.repo.ListAttempts(ctx, deliveryID)
+	if err != nil {
+		return dto.WebhookDeliveryDetailRes{}, err
+	}
+
+	history := make([]dto.WebhookAttemptRes, 0, len(attempts))
+	for _, attempt := range attempts {
+		history = append(history, dto.WebhookAttemptRes{
+			StatusCode:   attempt.StatusCode,
+			Error:        attempt.Error,
+			ResponseBody: attempt.ResponseBody,
+			DurationMs:   attempt.DurationMs,
+			CreatedAt:    attempt.CreatedAt,
+		})
+	}
+
+	return dto.WebhookDeliveryDetailRes{
+		WebhookDeliveryRes: toDeliveryRes(*delivery),
+		Payload:            json.RawMessage(delivery.Payload),
+		History:            history,
+	}, nil
+}
+
+// Redeliver sends a delivery again, whatever its state, with a fresh set of attempts
+func (s *webhookService) Redeliver(ctx context.Context, managerID, id int, deliveryID int64) error {
+	ctx, span := tracing.Start(ctx, "WebhookService.Redeliver")
+	defer span.End()
+
+	if _, err := s.findDelivery(ctx, managerID, id, deliveryID); err != nil {
+		return err
+	}
+
+	queued, err := s.repo.Redeliver(ctx, id, deliveryID)
+	if err != nil {
+		return err
+	}
+
+	if !queued {
+		return domain.ErrWebhookDeliveryInProgress
+	}
+
+	return nil
+}
+
+func (s *webhookService) RedeliverDead(ctx context.Context, managerID, id int) (dto.WebhookRedeliverRes, error) {
+	ctx, span := tracing.Start(ctx, "WebhookService.RedeliverDead")
+	defer span.End()
+
+	if _, err := s.find(ctx, managerID, id); err != nil {
+		return dto.WebhookRedeliverRes{}, err
+	}
+
+	count, err := s.repo.RedeliverDead(ctx, id)
+	if err != nil {
+		return dto.WebhookRedeliverRes{}, err
+	}
+
+	log.InfoCtx(ctx, log.LogInfo{
+		"webhook_id": id,
+		"count":      count,
+	}, "[WebhookService][RedeliverDead] dead deliveries queued again")
+
+	return dto.WebhookRedeliverRes{Count: count}, nil
+}
+
+func (s *webhookService) newEvent(event enums.WebhookEventEnum, data, previous any) (entity.WebhookDelivery, error) {
+	id, err := s.uuid.NewV7()
+	if err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+
+	payload, err := json.Marshal(dto.WebhookEvent{
+		ID:         id.String(),
+		Type:       event.String(),
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+		Previous:   previous,
+	})
+	if err != nil {
+		return entity.WebhookDelivery{}, err
+	}
+
+	return entity.WebhookDelivery{
+		EventID:   id.String(),
+		EventType: event.String(),
+		Payload:   string(payload),
+	}, nil
+}
+
+func (s *webhookService) find(ctx context.Context, managerID, id int) (*entity.WebhookEndpoint, error) {
+	endpoint, err := s.repo.FindByID(ctx, managerID, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrWebhookNotFound
+		}
+
+		return nil, err
+	}
+
+	return endpoint, nil
+}
+
+func (s *webhookService) findDelivery(ctx context.Context, managerID, id int, deliveryID int64) (*entity.WebhookDelivery, error) {
+	if _, err := s.find(ctx, managerID, id); err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.repo.FindDelivery(ctx, id, deliveryID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, domain.ErrWebhookDeliveryNotFound
+		}
+
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+func (s *webhookService) update(ctx context.Context, endpoint entity.WebhookEndpoint) (entity.WebhookEndpoint, error) {
+	updated, err := s.repo.Update(ctx, endpoint)
+	if err != nil {
+		// Deleted since it was read
+		if errors.Is(err, sql.ErrNoRows) {
+			return entity.WebhookEndpoint{}, domain.ErrWebhookNotFound
+		}
+
+		return entity.WebhookEndpoint{}, err
+	}
+
+	return updated, nil
+}
+
+// validateURL only lets deliveries go out over http(s), the validator's url rule takes any scheme,
+// and to hosts resolving to public addresses
+func (s *webhookService) validateURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return domain.ErrInvalidWebhookURL
+	}
+
+	if err := s.guard.CheckHost(ctx, u.Hostname()); err != nil {
+		log.InfoCtx(ctx, log.LogInfo{
+			"host":  u.Hostname(),
+			"error": err.Error(),
+		}, "[WebhookService][validateURL] refused webhook host")
+
+		return domain.ErrInvalidWebhookURL
+	}
+
+	return nil
+}
+
+func toWebhookRes(endpoint entity.WebhookEndpoint) dto.WebhookRes {
+	return dto.WebhookRes{
+		ID:          endpoint.ID,
+		URL:         endpoint.URL,
+		Description: endpoint.Description,
+		Events:      endpoint.EventList(),
+		Active:      endpoint.Active,
+		CreatedAt:   endpoint.CreatedAt,
+		UpdatedAt:   endpoint.UpdatedAt,
+	}
+}
+
+func toDeliveryRes(delivery entity.WebhookDelivery) dto.WebhookDeliveryRes {
+	res := dto.WebhookDeliveryRes{
+		ID:             delivery.ID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		DeliveredAt:    delivery.DeliveredAt,
+		CreatedAt:      delivery.CreatedAt,
+	}
+
+	// Finished deliveries have no next attempt
+	if delivery.Status == entity.WebhookDeliveryPending || delivery.Status == entity.WebhookDeliveryDelivering {
+		res.NextAttemptAt = &delivery.NextAttemptAt
+	}
+
+	return res
+}
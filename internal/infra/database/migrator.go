@@ -0,0 +1,221 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"io/fs"
+	"os"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratepgx "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/database/migrations"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+)
+
+type MigrationStatus struct {
+	// Current is the version recorded in schema_migrations, 0 when nothing was applied yet
+	Current uint
+	// Latest is the highest version embedded in this binary
+	Latest  uint
+	Dirty   bool
+	Pending []uint
+}
+
+func (s MigrationStatus) UpToDate() bool {
+	return !s.Dirty && s.Current >= s.Latest
+}
+
+type Migrator interface {
+	Up() error
+	Steps(n int) error
+	Down(steps int) error
+	Force(version int) error
+	Status() (MigrationStatus, error)
+	Close() error
+}
+
+type migrator struct {
+	migrate  *migrate.Migrate
+	versions []uint
+}
+
+// NewMigrator uses its own connection because closing golang-migrate also closes the *sql.DB it was given.
+// The migrations are read from the files embedded in the binary and tracked in schema_migrations,
+// the same table used by the migrate CLI, so existing databases are picked up as they are.
+func NewMigrator() (Migrator, error) {
+	db, err := sql.Open("pgx", DataSourceName())
+	if err != nil {
+		return nil, err
+	}
+
+	driver, err := migratepgx.WithInstance(db, &migratepgx.Config{})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	versions, err := embeddedVersions()
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	m, err := migrate.NewWithInstance("iofs", src, "pgx", driver)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	m.Log = migrateLogger{}
+
+	return &migrator{
+		migrate:  m,
+		versions: versions,
+	}, nil
+}
+
+func embeddedVersions() ([]uint, error) {
+	src, err := iofs.New(migrations.FS, ".")
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := []uint{version}
+	for {
+		version, err = src.Next(version)
+		if errors.Is(err, os.ErrNotExist) || errors.Is(err, fs.ErrNotExist) {
+			return versions, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		versions = append(versions, version)
+	}
+}
+
+func (m *migrator) Up() error {
+	return ignoreNoChange(m.migrate.Up())
+}
+
+func (m *migrator) Steps(n int) error {
+	return ignoreNoChange(m.migrate.Steps(n))
+}
+
+func (m *migrator) Down(steps int) error {
+	return ignoreNoChange(m.migrate.Steps(-steps))
+}
+
+func (m *migrator) Force(version int) error {
+	return m.migrate.Force(version)
+}
+
+func (m *migrator) Status() (MigrationStatus, error) {
+	status := MigrationStatus{
+		Latest: m.versions[len(m.versions)-1],
+	}
+
+	current, dirty, err := m.migrate.Version()
+	if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+		return status, err
+	}
+
+	status.Current = current
+	status.Dirty = dirty
+
+	for _, version := range m.versions {
+		if version > current {
+			status.Pending = append(status.Pending, version)
+		}
+	}
+
+	return status, nil
+}
+
+func (m *migrator) Close() error {
+	sourceErr, dbErr := m.migrate.Close()
+	if sourceErr != nil {
+		return sourceErr
+	}
+
+	return dbErr
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+
+	return err
+}
+
+// EnsureSchema optionally applies pending migrations and refuses to continue when the database
+// schema is dirty or older than the migrations embedded in this binary.
+func EnsureSchema(autoMigrate bool) {
+	m, err := NewMigrator()
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[DB][EnsureSchema] failed to initialize migrator")
+	}
+	defer func() {
+		if err := m.Close(); err != nil {
+			log.Warn(log.LogInfo{
+				"error": err.Error(),
+			}, "[DB][EnsureSchema] failed to close migrator")
+		}
+	}()
+
+	if autoMigrate {
+		if err := m.Up(); err != nil {
+			log.Fatal(log.LogInfo{
+				"error": err.Error(),
+			}, "[DB][EnsureSchema] failed to apply migrations")
+		}
+	}
+
+	status, err := m.Status()
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[DB][EnsureSchema] failed to read schema version")
+	}
+
+	if !status.UpToDate() {
+		log.Fatal(log.LogInfo{
+			"current": status.Current,
+			"latest":  status.Latest,
+			"dirty":   status.Dirty,
+			"pending": status.Pending,
+		}, "[DB][EnsureSchema] database schema is dirty or behind this binary, run the migrate command first")
+	}
+
+	if status.Current > status.Latest {
+		log.Warn(log.LogInfo{
+			"current": status.Current,
+			"latest":  status.Latest,
+		}, "[DB][EnsureSchema] database schema is newer than this binary")
+	}
+}
+
+type migrateLogger struct{}
+
+func (migrateLogger) Printf(format string, v ...interface{}) {
+	log.GetLogger().Info().Msgf("[MIGRATE] "+format, v...)
+}
+
+func (migrateLogger) Verbose() bool {
+	return true
+}
@@ -12,8 +12,8 @@ import (
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
 )
 
-func NewPgsqlConn() *sqlx.DB {
-	dataSourceName := fmt.Sprintf(
+func DataSourceName() string {
+	return fmt.Sprintf(
 		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable ",
 		env.AppEnv.DBHost,
 		env.AppEnv.DBPort,
@@ -21,8 +21,10 @@ func NewPgsqlConn() *sqlx.DB {
 		env.AppEnv.DBPass,
 		env.AppEnv.DBName,
 	)
+}
 
-	db, err := sqlx.Connect("pgx", dataSourceName)
+func NewPgsqlConn() *sqlx.DB {
+	db, err := sqlx.Connect("pgx", DataSourceName())
 	if err != nil {
 		log.Panic(log.LogInfo{
 			"error": err.Error(),
@@ -1,6 +1,7 @@
 package env
 
 import (
+	"os"
 	"time"
 
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
@@ -8,20 +9,78 @@ import (
 )
 
 type Env struct {
-	AppEnv             string        `mapstructure:"APP_ENV"`
-	AppPort            string        `mapstructure:"APP_PORT"`
-	ApiKey             string        `mapstructure:"API_KEY"`
-	DBHost             string        `mapstructure:"DB_HOST"`
-	DBPort             string        `mapstructure:"DB_PORT"`
-	DBUser             string        `mapstructure:"DB_USER"`
-	DBPass             string        `mapstructure:"DB_PASS"`
-	DBName             string        `mapstructure:"DB_NAME"`
-	JwtSecretKey       string        `mapstructure:"JWT_SECRET_KEY"`
-	JwtExpTime         time.Duration `mapstructure:"JWT_EXP_TIME"`
-	AWSAccessKeyID     string        `mapstructure:"AWS_ACCESS_KEY_ID"`
-	AWSSecretAccessKey string        `mapstructure:"AWS_SECRET_ACCESS_KEY"`
-	AWSS3BucketName    string        `mapstructure:"AWS_S3_BUCKET_NAME"`
-	AWSRegion          string        `mapstructure:"AWS_REGION"`
+	AppEnv                    string        `mapstructure:"APP_ENV"`
+	AppPort                   string        `mapstructure:"APP_PORT"`
+	LogLevel                  string        `mapstructure:"LOG_LEVEL"`
+	LogFormat                 string        `mapstructure:"LOG_FORMAT"`
+	TrustedProxies            string        `mapstructure:"TRUSTED_PROXIES"`
+	ProxyHeader               string        `mapstructure:"PROXY_HEADER"`
+	DBHost                    string        `mapstructure:"DB_HOST"`
+	DBPort                    string        `mapstructure:"DB_PORT"`
+	DBUser                    string        `mapstructure:"DB_USER"`
+	DBPass                    string        `mapstructure:"DB_PASS"`
+	DBName                    string        `mapstructure:"DB_NAME"`
+	DBAutoMigrate             bool          `mapstructure:"DB_AUTO_MIGRATE"`
+	JwtSecretKey              string        `mapstructure:"JWT_SECRET_KEY"`
+	JwtExpTime                time.Duration `mapstructure:"JWT_EXP_TIME"`
+	PasswordHashAlgorithm     string        `mapstructure:"PASSWORD_HASH_ALGORITHM"`
+	PasswordArgon2Memory      uint32        `mapstructure:"PASSWORD_ARGON2_MEMORY"`
+	PasswordArgon2Iterations  uint32        `mapstructure:"PASSWORD_ARGON2_ITERATIONS"`
+	PasswordArgon2Parallelism uint8         `mapstructure:"PASSWORD_ARGON2_PARALLELISM"`
+	PasswordBcryptCost        int           `mapstructure:"PASSWORD_BCRYPT_COST"`
+	LoginMaxAttempts          int           `mapstructure:"LOGIN_MAX_ATTEMPTS"`
+	LoginIPMaxAttempts        int           `mapstructure:"LOGIN_IP_MAX_ATTEMPTS"`
+	LoginAttemptWindow        time.Duration `mapstructure:"LOGIN_ATTEMPT_WINDOW"`
+	LoginLockout              time.Duration `mapstructure:"LOGIN_LOCKOUT"`
+	LoginMaxDelay             time.Duration `mapstructure:"LOGIN_MAX_DELAY"`
+	MFAIssuer                 string        `mapstructure:"MFA_ISSUER"`
+	MFAChallengeTTL           time.Duration `mapstructure:"MFA_CHALLENGE_TTL"`
+	MFARequiredRole           string        `mapstructure:"MFA_REQUIRED_ROLE"`
+	AppWebURL                 string        `mapstructure:"APP_WEB_URL"`
+	MailDriver                string        `mapstructure:"MAIL_DRIVER"`
+	MailFrom                  string        `mapstructure:"MAIL_FROM"`
+	MailOutboxInterval        time.Duration `mapstructure:"MAIL_OUTBOX_INTERVAL"`
+	SMTPHost                  string        `mapstructure:"SMTP_HOST"`
+	SMTPPort                  string        `mapstructure:"SMTP_PORT"`
+	SMTPUsername              string        `mapstructure:"SMTP_USERNAME"`
+	SMTPPassword              string        `mapstructure:"SMTP_PASSWORD"`
+	PasswordResetTTL          time.Duration `mapstructure:"PASSWORD_RESET_TTL"`
+	EmailVerifyTTL            time.Duration `mapstructure:"EMAIL_VERIFY_TTL"`
+	RateLimitStore            string        `mapstructure:"RATE_LIMIT_STORE"`
+	RateLimitRedisURL         string        `mapstructure:"RATE_LIMIT_REDIS_URL"`
+	RateLimitAuth             string        `mapstructure:"RATE_LIMIT_AUTH"`
+	RateLimitUpload           string        `mapstructure:"RATE_LIMIT_UPLOAD"`
+	RateLimitRead             string        `mapstructure:"RATE_LIMIT_READ"`
+	RateLimitWrite            string        `mapstructure:"RATE_LIMIT_WRITE"`
+	RateLimitTenantUpload     string        `mapstructure:"RATE_LIMIT_TENANT_UPLOAD"`
+	RateLimitTenantRead       string        `mapstructure:"RATE_LIMIT_TENANT_READ"`
+	RateLimitTenantWrite      string        `mapstructure:"RATE_LIMIT_TENANT_WRITE"`
+	RateLimitFailClosed       bool          `mapstructure:"RATE_LIMIT_FAIL_CLOSED"`
+	FieldEncryptionKeys       string        `mapstructure:"FIELD_ENCRYPTION_KEYS"`
+	FieldEncryptionActiveKey  string        `mapstructure:"FIELD_ENCRYPTION_ACTIVE_KEY"`
+	FieldBlindIndexKey        string        `mapstructure:"FIELD_BLIND_INDEX_KEY"`
+	IdempotencyKeyTTL         time.Duration `mapstructure:"IDEMPOTENCY_KEY_TTL"`
+	IfMatchRequired           bool          `mapstructure:"IF_MATCH_REQUIRED"`
+	WebhookDispatchInterval   time.Duration `mapstructure:"WEBHOOK_DISPATCH_INTERVAL"`
+	WebhookTimeout            time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookAllowPrivate       bool          `mapstructure:"WEBHOOK_ALLOW_PRIVATE_NETWORKS"`
+	OutboxRelayInterval       time.Duration `mapstructure:"OUTBOX_RELAY_INTERVAL"`
+	OutboxRetention           time.Duration `mapstructure:"OUTBOX_RETENTION"`
+	OutboxSinks               string        `mapstructure:"OUTBOX_SINKS"`
+	NATSURL                   string        `mapstructure:"NATS_URL"`
+	NATSSubjectPrefix         string        `mapstructure:"NATS_SUBJECT_PREFIX"`
+	KafkaBrokers              string        `mapstructure:"KAFKA_BROKERS"`
+	KafkaTopic                string        `mapstructure:"KAFKA_TOPIC"`
+	AWSAccessKeyID            string        `mapstructure:"AWS_ACCESS_KEY_ID"`
+	AWSSecretAccessKey        string        `mapstructure:"AWS_SECRET_ACCESS_KEY"`
+	AWSS3BucketName           string        `mapstructure:"AWS_S3_BUCKET_NAME"`
+	AWSRegion                 string        `mapstructure:"AWS_REGION"`
+	MetricsPort               string        `mapstructure:"METRICS_PORT"`
+	MetricsToken              string        `mapstructure:"METRICS_TOKEN"`
+	GRPCPort                  string        `mapstructure:"GRPC_PORT"`
+	TracingExporter           string        `mapstructure:"TRACING_EXPORTER"`
+	TracingFilePath           string        `mapstructure:"TRACING_FILE_PATH"`
+	TracingSampleRatio        float64       `mapstructure:"TRACING_SAMPLE_RATIO"`
 }
 
 var AppEnv = getEnv()
@@ -29,7 +88,15 @@ var AppEnv = getEnv()
 func getEnv() *Env {
 	env := &Env{}
 
-	viper.SetConfigFile("./config/.env")
+	// CONFIG_FILE points elsewhere when the working directory isn't the repository root, tests
+	// run from their own package directory
+	configFile := os.Getenv("CONFIG_FILE")
+	if configFile == "" {
+		configFile = "./config/.env"
+	}
+
+	viper.SetConfigFile(configFile)
+	viper.SetConfigType("env")
 
 	if err := viper.ReadInConfig(); err != nil {
 		log.Fatal(log.LogInfo{
@@ -43,6 +110,8 @@ func getEnv() *Env {
 		}, "[ENV][getEnv] failed to unmarshal to struct")
 	}
 
+	log.Configure(env.LogLevel, env.LogFormat)
+
 	switch env.AppEnv {
 	case "development":
 		log.Info(nil, "Application is running on development mode")
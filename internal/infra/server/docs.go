@@ -0,0 +1,714 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	graphQLCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/graphql/controller"
+	scimCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/scim/controller"
+	streamCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/stream/controller"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/openapi"
+)
+
+// undocumentedRoutes are registered on purpose but left out of the specification
+var undocumentedRoutes = map[string]bool{
+	"GET /":                  true,
+	"GET /api/v1":            true,
+	"GET /docs":              true,
+	"GET /docs/openapi.json": true,
+	"PATCH /v1/department":   true,
+	"DELETE /v1/department":  true,
+	"PATCH /v1/employee":     true,
+	"DELETE /v1/employee":    true,
+}
+
+// apiSpec documents every route mounted in MountRoutes. Schemas are generated from the DTOs,
+// so new fields and validation rules show up without touching this file, but a new route
+// has to be added here or the server refuses to start in development.
+func apiSpec() *openapi.Spec {
+	spec := openapi.New("GoGoManager API", "1.0.0", response.Problem{})
+
+	// Managers
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/auth",
+		Tag:      "Manager",
+		Summary:  "Register (action=create, 201) or log in (action=login, 200) a manager",
+		Body:     dto.AuthRequest{},
+		Response: dto.AuthResponse{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         "/v1/user",
+		Tag:          "Manager",
+		Summary:      "Get the profile of the authenticated manager, with its ETag",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeRead.String()},
+		Headers:      []string{fiber.HeaderIfNoneMatch},
+		Response:     dto.GetCurrentManagerResponse{},
+	})
+	spec.Add(openapi.Route{
+		Method:     http.MethodPatch,
+		Path:       "/v1/user",
+		Tag:        "Manager",
+		Summary:    "Merge patch the profile of the authenticated manager; a stale If-Match fails with 412",
+		Secured:    true,
+		Headers:    []string{fiber.HeaderIfMatch},
+		Body:       dto.UpdateManagerRequest{},
+		MergePatch: true,
+		Response:   dto.UpdateManagerResponse{},
+	})
+
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/auth/password/forgot",
+		Tag:      "Manager",
+		Summary:  "Email a password reset link; answers 202 whether or not the email is registered",
+		Body:     dto.ForgotPasswordRequest{},
+		Status:   http.StatusAccepted,
+		Response: fiber.Map{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/auth/password/reset",
+		Tag:      "Manager",
+		Summary:  "Set a new password with a reset token",
+		Body:     dto.ResetPasswordRequest{},
+		Response: fiber.Map{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/auth/verify/request",
+		Tag:      "Manager",
+		Summary:  "Send a new email verification link to the authenticated manager",
+		Secured:  true,
+		Status:   http.StatusAccepted,
+		Response: fiber.Map{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/auth/verify",
+		Tag:      "Manager",
+		Summary:  "Verify the email of a manager with a verification token",
+		Body:     dto.VerifyEmailRequest{},
+		Response: fiber.Map{},
+	})
+
+	// Users
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/auth/register",
+		Tag:      "Auth",
+		Summary:  "Register a user",
+		Body:     dto.RegisterRequest{},
+		Response: dto.RegisterResponse{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/auth/login",
+		Tag:      "Auth",
+		Summary:  "Log in a user",
+		Body:     dto.LoginRequest{},
+		Response: dto.LoginResponse{},
+	})
+
+	// Two-factor authentication
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/auth/mfa/verify",
+		Tag:      "MFA",
+		Summary:  "Finish a login with a TOTP or recovery code, using the mfaToken of the login as bearer token",
+		Secured:  true,
+		Body:     dto.MFACodeRequest{},
+		Response: dto.MFATokenResponse{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/auth/mfa/enroll",
+		Tag:      "MFA",
+		Summary:  "Start the enrollment a login requires (enrollmentRequired), using its mfaToken as bearer token",
+		Secured:  true,
+		Response: dto.MFAEnrollResponse{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/auth/mfa/confirm",
+		Tag:      "MFA",
+		Summary:  "Confirm the enrollment a login requires; returns the recovery codes and the access token",
+		Secured:  true,
+		Body:     dto.MFACodeRequest{},
+		Response: dto.MFARecoveryCodesResponse{},
+	})
+	for _, account := range []struct{ prefix, who string }{
+		{"/v1/user/mfa", "the authenticated manager"},
+		{"/auth/mfa", "the authenticated user"},
+	} {
+		spec.Add(openapi.Route{
+			Method:   http.MethodPost,
+			Path:     account.prefix + "/enroll",
+			Tag:      "MFA",
+			Summary:  "Generate a TOTP secret and provisioning URI for " + account.who,
+			Secured:  true,
+			Response: dto.MFAEnrollResponse{},
+		})
+		spec.Add(openapi.Route{
+			Method:   http.MethodPost,
+			Path:     account.prefix + "/confirm",
+			Tag:      "MFA",
+			Summary:  "Enable 2FA for " + account.who + " with a first TOTP code; returns the recovery codes",
+			Secured:  true,
+			Body:     dto.MFACodeRequest{},
+			Response: dto.MFARecoveryCodesResponse{},
+		})
+		spec.Add(openapi.Route{
+			Method:   http.MethodPost,
+			Path:     account.prefix + "/disable",
+			Tag:      "MFA",
+			Summary:  "Disable 2FA for " + account.who + " with a TOTP or recovery code",
+			Secured:  true,
+			Body:     dto.MFACodeRequest{},
+			Response: fiber.Map{},
+		})
+		spec.Add(openapi.Route{
+			Method:   http.MethodPost,
+			Path:     account.prefix + "/recovery-codes",
+			Tag:      "MFA",
+			Summary:  "Replace the recovery codes of " + account.who + " with a TOTP code",
+			Secured:  true,
+			Body:     dto.MFACodeRequest{},
+			Response: dto.MFARecoveryCodesResponse{},
+		})
+	}
+	spec.Add(openapi.Route{
+		Method:   http.MethodGet,
+		Path:     "/v1/user/mfa/policy",
+		Tag:      "MFA",
+		Summary:  "Tell whether the logins of the authenticated manager need 2FA",
+		Secured:  true,
+		Response: dto.MFAPolicy{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodPut,
+		Path:     "/v1/user/mfa/policy",
+		Tag:      "MFA",
+		Summary:  "Make 2FA mandatory or optional for the logins of the authenticated manager; a login without it enrolls first",
+		Secured:  true,
+		Body:     dto.MFAPolicyRequest{},
+		Response: dto.MFAPolicy{},
+	})
+
+	// Departments
+	spec.Add(openapi.Route{
+		Method:       http.MethodPost,
+		Path:         "/v1/department",
+		Tag:          "Department",
+		Summary:      "Create a department; retries sent with the same Idempotency-Key replay the first response",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeDepartmentWrite.String()},
+		Headers:      []string{middlewares.HeaderIdempotencyKey},
+		Body:         dto.DepartmentReq{},
+		Status:       http.StatusCreated,
+		Response:     dto.DepartmentRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         "/v1/department",
+		Tag:          "Department",
+		Summary:      "List departments, optionally filtered by name",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeRead.String()},
+		Query:        dto.DepartmentQuery{},
+		Response:     []dto.DepartmentRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         "/v1/department/{departmentid}",
+		Tag:          "Department",
+		Summary:      "Get a department, with its ETag",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeRead.String()},
+		Headers:      []string{fiber.HeaderIfNoneMatch},
+		Response:     dto.DepartmentRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodPatch,
+		Path:         "/v1/department/{departmentid}",
+		Tag:          "Department",
+		Summary:      "Rename a department; a stale If-Match fails with 412",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeDepartmentWrite.String()},
+		Headers:      []string{fiber.HeaderIfMatch},
+		Body:         dto.DepartmentReq{},
+		Response:     dto.DepartmentRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodDelete,
+		Path:         "/v1/department/{departmentid}",
+		Tag:          "Department",
+		Summary:      "Delete a department; a stale If-Match fails with 412",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeDepartmentWrite.String()},
+		Headers:      []string{fiber.HeaderIfMatch},
+		Response:     fiber.Map{},
+	})
+
+	// Employees
+	spec.Add(openapi.Route{
+		Method:       http.MethodPost,
+		Path:         "/v1/employee",
+		Tag:          "Employee",
+		Summary:      "Create an employee; retries sent with the same Idempotency-Key replay the first response",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeEmployeeWrite.String()},
+		Headers:      []string{middlewares.HeaderIdempotencyKey},
+		Body:         dto.EmployeeCreateReq{},
+		Status:       http.StatusCreated,
+		Response:     dto.EmployeeDataRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         "/v1/employee",
+		Tag:          "Employee",
+		Summary:      "List employees",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeRead.String()},
+		Query:        dto.EmployeeQuery{},
+		Response:     []dto.EmployeeDataRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         "/v1/employee/{identityNumber}",
+		Tag:          "Employee",
+		Summary:      "Get an employee, with its ETag",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeRead.String()},
+		Headers:      []string{fiber.HeaderIfNoneMatch},
+		Response:     dto.EmployeeDataRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodPatch,
+		Path:         "/v1/employee/{identityNumber}",
+		Tag:          "Employee",
+		Summary:      "Merge patch an employee; a stale If-Match fails with 412",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeEmployeeWrite.String()},
+		Headers:      []string{fiber.HeaderIfMatch},
+		Body:         dto.EmployeeUpdateReq{},
+		MergePatch:   true,
+		Response:     dto.EmployeeDataRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodDelete,
+		Path:         "/v1/employee/{identityNumber}",
+		Tag:          "Employee",
+		Summary:      "Delete an employee; a stale If-Match fails with 412",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeEmployeeWrite.String()},
+		Headers:      []string{fiber.HeaderIfMatch},
+		Response:     fiber.Map{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         "/v1/employee/{identityNumber}/export",
+		Tag:          "Employee",
+		Summary:      "Download everything stored about an employee: the record, its department, its audit events and its image",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeRead.String()},
+		Download:     openapi.ContentTypeZip,
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodPost,
+		Path:         "/v1/employee/{identityNumber}/erase",
+		Tag:          "Employee",
+		Summary:      "Erase an employee's personal data, image and the data of its past events; the anonymised record is kept and employee.erased is sent",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeEmployeeWrite.String()},
+		Response:     fiber.Map{},
+	})
+
+	// Administration
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/admin/unlock",
+		Tag:      "Admin",
+		Summary:  "Clear failed logins and the lockout of an account (Lead Admin and above)",
+		Secured:  true,
+		Body:     dto.UnlockAccountRequest{},
+		Response: fiber.Map{},
+	})
+
+	// API keys
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/api-keys",
+		Tag:      "API key",
+		Summary:  "Create an API key acting as the authenticated manager; the key is only shown in this response",
+		Secured:  true,
+		Body:     dto.CreateAPIKeyRequest{},
+		Status:   http.StatusCreated,
+		Response: dto.CreateAPIKeyRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodGet,
+		Path:     "/v1/api-keys",
+		Tag:      "API key",
+		Summary:  "List the API keys of the authenticated manager, including revoked and expired ones",
+		Secured:  true,
+		Response: []dto.APIKeyRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodDelete,
+		Path:     "/v1/api-keys/{id}",
+		Tag:      "API key",
+		Summary:  "Revoke an API key",
+		Secured:  true,
+		Response: fiber.Map{},
+	})
+
+	// Audit
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         "/v1/audit",
+		Tag:          "Audit",
+		Summary:      "List the audit events of the manager's data, newest first",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeAuditRead.String()},
+		Query:        dto.AuditQuery{},
+		Response:     []dto.AuditEventRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         "/v1/audit/verify",
+		Tag:          "Audit",
+		Summary:      "Check the hash chain links of a page of your audit events, following next until it is null",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeAuditRead.String()},
+		Query:        dto.AuditVerifyQuery{},
+		Response:     dto.AuditVerifyRes{},
+	})
+
+	// Webhooks
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/webhooks",
+		Tag:      "Webhook",
+		Summary:  "Register an endpoint receiving the subscribed events as signed POSTs of a WebhookEvent; the secret is only shown in this response",
+		Secured:  true,
+		Body:     dto.CreateWebhookRequest{},
+		Status:   http.StatusCreated,
+		Response: dto.WebhookSecretRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodGet,
+		Path:     "/v1/webhooks",
+		Tag:      "Webhook",
+		Summary:  "List the webhook endpoints of the authenticated manager",
+		Secured:  true,
+		Response: []dto.WebhookRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodGet,
+		Path:     "/v1/webhooks/{id}",
+		Tag:      "Webhook",
+		Summary:  "Get a webhook endpoint",
+		Secured:  true,
+		Response: dto.WebhookRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:     http.MethodPatch,
+		Path:       "/v1/webhooks/{id}",
+		Tag:        "Webhook",
+		Summary:    "Merge patch a webhook endpoint; a paused endpoint keeps its queued deliveries until resumed",
+		Secured:    true,
+		Body:       dto.UpdateWebhookRequest{},
+		MergePatch: true,
+		Response:   dto.WebhookRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodDelete,
+		Path:     "/v1/webhooks/{id}",
+		Tag:      "Webhook",
+		Summary:  "Delete a webhook endpoint along with its deliveries",
+		Secured:  true,
+		Response: fiber.Map{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/webhooks/{id}/rotate-secret",
+		Tag:      "Webhook",
+		Summary:  "Replace the signing secret; queued and retried deliveries are signed with the new one",
+		Secured:  true,
+		Response: dto.WebhookSecretRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/webhooks/{id}/ping",
+		Tag:      "Webhook",
+		Summary:  "Queue a webhook.ping event for the endpoint, to check it receives and verifies deliveries",
+		Secured:  true,
+		Status:   http.StatusAccepted,
+		Response: dto.WebhookDeliveryRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodGet,
+		Path:     "/v1/webhooks/{id}/deliveries",
+		Tag:      "Webhook",
+		Summary:  "List the deliveries made to an endpoint, newest first",
+		Secured:  true,
+		Query:    dto.WebhookDeliveryQuery{},
+		Response: []dto.WebhookDeliveryRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodGet,
+		Path:     "/v1/webhooks/{id}/deliveries/{deliveryId}",
+		Tag:      "Webhook",
+		Summary:  "Get a delivery with its payload and every attempt made",
+		Secured:  true,
+		Response: dto.WebhookDeliveryDetailRes{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/webhooks/{id}/deliveries/{deliveryId}/redeliver",
+		Tag:      "Webhook",
+		Summary:  "Send a delivery again with a fresh set of attempts, whether it succeeded or died",
+		Secured:  true,
+		Status:   http.StatusAccepted,
+		Response: fiber.Map{},
+	})
+	spec.Add(openapi.Route{
+		Method:   http.MethodPost,
+		Path:     "/v1/webhooks/{id}/deliveries/redeliver-dead",
+		Tag:      "Webhook",
+		Summary:  "Send every dead delivery of the endpoint again",
+		Secured:  true,
+		Status:   http.StatusAccepted,
+		Response: dto.WebhookRedeliverRes{},
+	})
+
+	// Change stream
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         "/v1/events",
+		Tag:          "Events",
+		Summary:      "Stream employee and department changes of the tenant as server-sent events, resumable with Last-Event-ID",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeRead.String()},
+		Headers:      []string{streamCtr.HeaderLastEventID},
+		Query:        dto.StreamQuery{},
+		Events:       dto.StreamEvent{},
+	})
+
+	// SCIM provisioning
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         scimCtr.Prefix + "/ServiceProviderConfig",
+		Tag:          "SCIM",
+		Summary:      "Describe the SCIM features this server supports",
+		SCIM:         true,
+		APIKeyScopes: []string{enums.ScopeSCIM.String()},
+		Response:     dto.SCIMServiceProviderConfig{},
+		Error:        dto.SCIMError{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodPost,
+		Path:         scimCtr.Prefix + "/Users",
+		Tag:          "SCIM",
+		Summary:      "Provision an employee as a SCIM user; userName is the identity number",
+		SCIM:         true,
+		APIKeyScopes: []string{enums.ScopeSCIM.String()},
+		Body:         dto.SCIMUser{},
+		Status:       http.StatusCreated,
+		Response:     dto.SCIMUser{},
+		Error:        dto.SCIMError{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         scimCtr.Prefix + "/Users",
+		Tag:          "SCIM",
+		Summary:      "List SCIM users, filtered with userName eq \"...\"",
+		SCIM:         true,
+		APIKeyScopes: []string{enums.ScopeSCIM.String()},
+		Query:        dto.SCIMQuery{},
+		Response:     dto.SCIMUserList{},
+		Error:        dto.SCIMError{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         scimCtr.Prefix + "/Users/{id}",
+		Tag:          "SCIM",
+		Summary:      "Get a SCIM user by identity number",
+		SCIM:         true,
+		APIKeyScopes: []string{enums.ScopeSCIM.String()},
+		Response:     dto.SCIMUser{},
+		Error:        dto.SCIMError{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodPut,
+		Path:         scimCtr.Prefix + "/Users/{id}",
+		Tag:          "SCIM",
+		Summary:      "Replace the attributes a SCIM user is sent with, the others are kept",
+		SCIM:         true,
+		APIKeyScopes: []string{enums.ScopeSCIM.String()},
+		Headers:      []string{fiber.HeaderIfMatch},
+		Body:         dto.SCIMUser{},
+		Response:     dto.SCIMUser{},
+		Error:        dto.SCIMError{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodPatch,
+		Path:         scimCtr.Prefix + "/Users/{id}",
+		Tag:          "SCIM",
+		Summary:      "Apply SCIM patch operations to a user",
+		SCIM:         true,
+		APIKeyScopes: []string{enums.ScopeSCIM.String()},
+		Headers:      []string{fiber.HeaderIfMatch},
+		Body:         dto.SCIMPatchRequest{},
+		Response:     dto.SCIMUser{},
+		Error:        dto.SCIMError{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodDelete,
+		Path:         scimCtr.Prefix + "/Users/{id}",
+		Tag:          "SCIM",
+		Summary:      "Deprovision a SCIM user by deleting the employee",
+		SCIM:         true,
+		APIKeyScopes: []string{enums.ScopeSCIM.String()},
+		Headers:      []string{fiber.HeaderIfMatch},
+		Status:       http.StatusNoContent,
+		Error:        dto.SCIMError{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodPost,
+		Path:         scimCtr.Prefix + "/Groups",
+		Tag:          "SCIM",
+		Summary:      "Create a department as a SCIM group, moving its members into it",
+		SCIM:         true,
+		APIKeyScopes: []string{enums.ScopeSCIM.String()},
+		Body:         dto.SCIMGroup{},
+		Status:       http.StatusCreated,
+		Response:     dto.SCIMGroup{},
+		Error:        dto.SCIMError{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         scimCtr.Prefix + "/Groups",
+		Tag:          "SCIM",
+		Summary:      "List SCIM groups, filtered with displayName eq \"...\"",
+		SCIM:         true,
+		APIKeyScopes: []string{enums.ScopeSCIM.String()},
+		Query:        dto.SCIMQuery{},
+		Response:     dto.SCIMGroupList{},
+		Error:        dto.SCIMError{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodGet,
+		Path:         scimCtr.Prefix + "/Groups/{id}",
+		Tag:          "SCIM",
+		Summary:      "Get a SCIM group by department id",
+		SCIM:         true,
+		APIKeyScopes: []string{enums.ScopeSCIM.String()},
+		Response:     dto.SCIMGroup{},
+		Error:        dto.SCIMError{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodPut,
+		Path:         scimCtr.Prefix + "/Groups/{id}",
+		Tag:          "SCIM",
+		Summary:      "Rename a SCIM group and move the members it is sent with into it",
+		SCIM:         true,
+		APIKeyScopes: []string{enums.ScopeSCIM.String()},
+		Headers:      []string{fiber.HeaderIfMatch},
+		Body:         dto.SCIMGroup{},
+		Response:     dto.SCIMGroup{},
+		Error:        dto.SCIMError{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodPatch,
+		Path:         scimCtr.Prefix + "/Groups/{id}",
+		Tag:          "SCIM",
+		Summary:      "Apply SCIM patch operations to a group; members can be added but not removed",
+		SCIM:         true,
+		APIKeyScopes: []string{enums.ScopeSCIM.String()},
+		Headers:      []string{fiber.HeaderIfMatch},
+		Body:         dto.SCIMPatchRequest{},
+		Response:     dto.SCIMGroup{},
+		Error:        dto.SCIMError{},
+	})
+	spec.Add(openapi.Route{
+		Method:       http.MethodDelete,
+		Path:         scimCtr.Prefix + "/Groups/{id}",
+		Tag:          "SCIM",
+		Summary:      "Delete a SCIM group",
+		SCIM:         true,
+		APIKeyScopes: []string{enums.ScopeSCIM.String()},
+		Headers:      []string{fiber.HeaderIfMatch},
+		Status:       http.StatusNoContent,
+		Error:        dto.SCIMError{},
+	})
+
+	// GraphQL
+	spec.Add(openapi.Route{
+		Method:  http.MethodPost,
+		Path:    graphQLCtr.Path,
+		Tag:     "GraphQL",
+		Summary: "Query the manager, departments with their employees and employees with their department, or change them; operations nesting too deeply or asking for too much are rejected before anything is resolved",
+		Secured: true,
+		APIKeyScopes: []string{
+			enums.ScopeRead.String(),
+			enums.ScopeDepartmentWrite.String(),
+			enums.ScopeEmployeeWrite.String(),
+		},
+		Body:      dto.GraphQLRequest{},
+		Response:  dto.GraphQLResponse{},
+		Unwrapped: true,
+	})
+
+	// Files
+	spec.Add(openapi.Route{
+		Method:       http.MethodPost,
+		Path:         "/v1/file",
+		Tag:          "File",
+		Summary:      "Upload a jpg, jpeg or png image of at most 100 KiB",
+		Secured:      true,
+		APIKeyScopes: []string{enums.ScopeFileWrite.String()},
+		Multipart:    []string{"file"},
+		Response:     dto.FileUploadRes{},
+	})
+
+	return spec
+}
+
+func (s *httpServer) mountDocs(spec *openapi.Spec) {
+	doc, err := s.app.Config().JSONEncoder(spec.Document())
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[SERVER][mountDocs] failed to encode OpenAPI document")
+	}
+
+	s.app.Get("/docs", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+		return c.Send(openapi.DocsPage)
+	})
+
+	s.app.Get("/docs/openapi.json", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		return c.Send(doc)
+	})
+}
+
+// Undocumented lists, as "METHOD path", the routes mounted on app that apiSpec leaves out.
+// tests/openapi_test.go fails on any of them.
+func Undocumented(app *fiber.App) []string {
+	var routes [][2]string
+	for _, route := range app.GetRoutes(true) {
+		routes = append(routes, [2]string{route.Method, route.Path})
+	}
+
+	return apiSpec().Undocumented(routes, undocumentedRoutes)
+}
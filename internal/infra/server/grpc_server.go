@@ -0,0 +1,72 @@
+package server
+
+import (
+	"maps"
+	"net"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	errorhandler "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/grpc/error_handler"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"google.golang.org/grpc"
+)
+
+type GrpcServer interface {
+	grpc.ServiceRegistrar
+	Start(port string)
+}
+
+type grpcServer struct {
+	*grpc.Server
+}
+
+// NewGrpcServer serves the gRPC services on their own listener, next to the HTTP server. Calls
+// authenticate and are rate limited like requests are, API keys with the scopes the services list
+// for each method.
+func NewGrpcServer(middleware *middlewares.Middleware, scopes ...middlewares.GRPCScopes) GrpcServer {
+	methodScopes := middlewares.GRPCScopes{}
+	for _, s := range scopes {
+		maps.Copy(methodScopes, s)
+	}
+
+	// Panics are recovered around everything else. Errors are turned into statuses last,
+	// authentication and rate limit errors included, and calls are limited once authenticated.
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			errorhandler.UnaryRecoveryInterceptor(),
+			errorhandler.UnaryInterceptor(),
+			middleware.UnaryGRPCAuth(methodScopes),
+			middleware.UnaryGRPCRateLimit(),
+		),
+		grpc.ChainStreamInterceptor(
+			errorhandler.StreamRecoveryInterceptor(),
+			errorhandler.StreamInterceptor(),
+			middleware.StreamGRPCAuth(methodScopes),
+			middleware.StreamGRPCRateLimit(),
+		),
+	)
+
+	return &grpcServer{Server: server}
+}
+
+func (s *grpcServer) Start(port string) {
+	if port[0] != ':' {
+		port = ":" + port
+	}
+
+	listener, err := net.Listen("tcp", port)
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[GRPC SERVER][Start] failed to listen")
+	}
+
+	log.Info(log.LogInfo{
+		"port": port,
+	}, "[GRPC SERVER][Start] serving gRPC")
+
+	if err := s.Serve(listener); err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[GRPC SERVER][Start] failed to start gRPC server")
+	}
+}
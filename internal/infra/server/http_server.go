@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"path/filepath"
 	"strings"
 
@@ -8,27 +9,68 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/jmoiron/sqlx"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	accountCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/account/controller"
+	accountRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/account/repository"
+	accountSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/account/service"
+	apiKeyCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/apikey/controller"
+	apiKeyRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/apikey/repository"
+	apiKeySvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/apikey/service"
+	auditCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/audit/controller"
+	auditRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/audit/repository"
+	auditSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/audit/service"
 	authCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/auth/controller"
 	authRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/auth/repository"
 	authSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/auth/service"
 	deptCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/department/controller"
 	deptRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/department/repository"
+	deptRpc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/department/rpc"
 	deptSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/department/service"
 	employeeCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/employee/controller"
 	employeeRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/employee/repository"
+	employeeRpc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/employee/rpc"
 	employeeSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/employee/service"
+	graphQLCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/graphql/controller"
+	graphQLSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/graphql/service"
+	idempotencyRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/idempotency/repository"
+	idempotencySvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/idempotency/service"
+	lockoutCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/lockout/controller"
+	lockoutRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/lockout/repository"
+	lockoutSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/lockout/service"
 	managerCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/manager/controller"
 	managerRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/manager/repository"
 	managerSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/manager/service"
+	mfaCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/mfa/controller"
+	mfaRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/mfa/repository"
+	mfaSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/mfa/service"
+	outboxRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/outbox/repository"
+	outboxSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/outbox/service"
+	privacyCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/privacy/controller"
+	privacySvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/privacy/service"
+	scimCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/scim/controller"
+	scimSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/scim/service"
+	streamCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/stream/controller"
+	streamSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/stream/service"
+	webhookCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/webhook/controller"
+	webhookRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/webhook/repository"
+	webhookSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/webhook/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/database"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/env"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
-	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/bcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/dbtx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/egress"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/fieldcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/hasher"
 	errorhandler "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/error_handler"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/metrics"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/pgnotify"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/s3"
-	timePkg "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/time"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/totp"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/uuid"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
 )
@@ -38,13 +80,25 @@ type HttpServer interface {
 	MountMiddlewares()
 	MountRoutes(db *sqlx.DB)
 	GetApp() *fiber.App
+	// GetGrpcServer returns the gRPC server MountRoutes registered its services on
+	GetGrpcServer() GrpcServer
 }
 
 type httpServer struct {
-	app *fiber.App
+	app  *fiber.App
+	grpc GrpcServer
 }
 
-func NewHttpServer() HttpServer {
+// ProxyConfig tells the server which reverse proxies it sits behind
+type ProxyConfig struct {
+	// TrustedProxies is a comma separated list of the proxies' addresses or CIDR ranges
+	TrustedProxies string
+	// Header is where the proxies put the client IP, it has to be one they overwrite rather than
+	// append to, or clients could pick their own address
+	Header string
+}
+
+func NewHttpServer(proxy ProxyConfig) HttpServer {
 	config := fiber.Config{
 		CaseSensitive: true,
 		AppName:       "GoGo Manager",
@@ -54,6 +108,19 @@ func NewHttpServer() HttpServer {
 		ErrorHandler:  errorhandler.ErrorHandler,
 	}
 
+	// The client IP keys login lockouts and rate limits and is audited, so the proxy header is only
+	// believed on connections from a trusted proxy. Everyone else is known by their own address.
+	for _, trusted := range strings.Split(proxy.TrustedProxies, ",") {
+		if trusted = strings.TrimSpace(trusted); trusted != "" {
+			config.TrustedProxies = append(config.TrustedProxies, trusted)
+		}
+	}
+	if len(config.TrustedProxies) > 0 {
+		config.ProxyHeader = proxy.Header
+		config.EnableTrustedProxyCheck = true
+		config.EnableIPValidation = true
+	}
+
 	app := fiber.New(config)
 
 	return &httpServer{
@@ -65,6 +132,10 @@ func (s *httpServer) GetApp() *fiber.App {
 	return s.app
 }
 
+func (s *httpServer) GetGrpcServer() GrpcServer {
+	return s.grpc
+}
+
 func (s *httpServer) Start(port string) {
 	if port[0] != ':' {
 		port = ":" + port
@@ -80,26 +151,30 @@ func (s *httpServer) Start(port string) {
 }
 
 func (s *httpServer) MountMiddlewares() {
+	s.app.Use(middlewares.Tracing())
+	s.app.Use(middlewares.RequestID())
+	s.app.Use(middlewares.Metrics(metrics.Metrics))
 	s.app.Use(middlewares.LoggerConfig())
 	s.app.Use(middlewares.Helmet())
 	s.app.Use(middlewares.Compress())
 	s.app.Use(middlewares.Cors())
-	if env.AppEnv.AppEnv != "development" {
-		s.app.Use(middlewares.ApiKey())
-	}
 	s.app.Use(middlewares.RecoverConfig())
 }
 
 func (s *httpServer) MountRoutes(db *sqlx.DB) {
-	bcrypt := bcrypt.Bcrypt
-	_ = timePkg.Time
+	hasher := hasher.Hasher
 	uuid := uuid.UUID
 	validator := validator.Validator
 	jwtManager := jwt.JwtManager
+	jwtChallenge := jwt.JwtChallenge
 	jwt := jwt.Jwt
 	s3 := s3.S3
+	appMetrics := metrics.Metrics
+
+	appMetrics.RegisterDB("postgres", db.DB)
 
-	middleware := middlewares.NewMiddleware(jwt, jwtManager)
+	limiter := newRateLimiter(db)
+	s.app.Use(middlewares.RateLimit(limiter))
 
 	s.app.Get("/", func(c *fiber.Ctx) error {
 		return response.SendResponse(c, fiber.StatusOK, "GoGoManager API")
@@ -116,23 +191,91 @@ func (s *httpServer) MountRoutes(db *sqlx.DB) {
 	managerRepo := managerRepo.NewManagerRepository(db)
 	authRepository := authRepo.NewAuthRepository(db)
 	departmentRepository := deptRepo.NewDepartmentRepository(db)
-	employeeRepository := employeeRepo.NewEmployeeRepository(db)
+	employeeRepository := employeeRepo.NewEmployeeRepository(db, fieldcrypt.FieldCrypt)
+	loginAttemptRepository := lockoutRepo.NewLoginAttemptRepository(db)
+	accountRepository := accountRepo.NewAccountRepository(db)
+	mfaRepository := mfaRepo.NewMFARepository(db)
+	apiKeyRepository := apiKeyRepo.NewAPIKeyRepository(db)
+	auditRepository := auditRepo.NewAuditRepository(db)
+	idempotencyRepository := idempotencyRepo.NewIdempotencyRepository(db)
+	webhookRepository := webhookRepo.NewWebhookRepository(db, fieldcrypt.FieldCrypt)
+	outboxEventRepository := outboxRepo.NewOutboxEventRepository(db, fieldcrypt.FieldCrypt)
+	transactor := dbtx.NewTransactor(db)
 
 	// Initialize services
-	managerService := managerSvc.NewManagerService(managerRepo, jwtManager, bcrypt, validator)
-	authService := authSvc.NewAuthService(authRepository, validator, uuid, jwt, bcrypt)
-	departmentService := deptSvc.NewDepartmentService(departmentRepository, validator)
-	employeeService := employeeSvc.NewEmployeeService(employeeRepository, validator)
+	auditService := auditSvc.NewAuditService(auditRepository, validator)
+	apiKeyService := apiKeySvc.NewAPIKeyService(apiKeyRepository, validator)
+	webhookService := webhookSvc.NewWebhookService(webhookRepository, validator, uuid, egress.Guard{
+		AllowPrivate: env.AppEnv.WebhookAllowPrivate,
+	})
+	outboxEventService := outboxSvc.NewOutboxEventService(outboxEventRepository, uuid)
+	streamService := streamSvc.NewStreamService(outboxEventRepository, pgnotify.NewListener(database.DataSourceName()))
+	idempotencyService := idempotencySvc.NewIdempotencyService(idempotencyRepository, idempotencySvc.Config{
+		TTL: env.AppEnv.IdempotencyKeyTTL,
+	})
+	lockoutService := lockoutSvc.NewLockoutService(loginAttemptRepository, validator, lockoutSvc.Policy{
+		MaxAttempts:   env.AppEnv.LoginMaxAttempts,
+		IPMaxAttempts: env.AppEnv.LoginIPMaxAttempts,
+		Window:        env.AppEnv.LoginAttemptWindow,
+		Lockout:       env.AppEnv.LoginLockout,
+		MaxDelay:      env.AppEnv.LoginMaxDelay,
+	})
+	accountService := accountSvc.NewAccountService(accountRepository, lockoutService, hasher, validator, accountSvc.Config{
+		WebURL:    env.AppEnv.AppWebURL,
+		ResetTTL:  env.AppEnv.PasswordResetTTL,
+		VerifyTTL: env.AppEnv.EmailVerifyTTL,
+	})
+	mfaService := mfaSvc.NewMFAService(mfaRepository, lockoutService, totp.TOTP, jwt, jwtManager, jwtChallenge, validator, mfaSvc.Policy{
+		Issuer:       env.AppEnv.MFAIssuer,
+		RequiredRole: enums.RoleEnum(env.AppEnv.MFARequiredRole),
+	})
+	managerService := managerSvc.NewManagerService(managerRepo, jwtManager, hasher, validator, lockoutService, accountService, mfaService, auditService, transactor)
+	authService := authSvc.NewAuthService(authRepository, validator, uuid, jwt, hasher, lockoutService, mfaService)
+	departmentService := deptSvc.NewDepartmentService(departmentRepository, validator, auditService, outboxEventService, transactor)
+	employeeService := employeeSvc.NewEmployeeService(employeeRepository, departmentRepository, validator, auditService, outboxEventService, transactor)
+	privacyService := privacySvc.NewPrivacyService(
+		employeeRepository,
+		departmentRepository,
+		auditService,
+		s3,
+		transactor,
+		outboxEventRepository,
+		webhookRepository,
+		outboxEventService,
+	)
+	scimService := scimSvc.NewSCIMService(employeeService, departmentService, departmentRepository, transactor)
+	graphQLService := graphQLSvc.NewGraphQLService(managerService, departmentService, employeeService, appMetrics, env.AppEnv.IfMatchRequired)
+
+	middleware := middlewares.NewMiddleware(jwt, jwtManager, jwtChallenge, apiKeyService, limiter, idempotencyService, env.AppEnv.IfMatchRequired)
 
 	// Initialize controllers
-	managerCtr.InitManagerController(s.app, managerService)
+	managerCtr.InitManagerController(s.app, managerService, middleware)
 	authCtr.InitAuthController(s.app, authService)
-	deptCtr.InitNewController(s.app, departmentService, middleware)
-	employeeCtr.InitNewController(s.app, employeeService, middleware)
+	deptCtr.InitNewController(s.app, departmentService, middleware, appMetrics)
+	employeeCtr.InitNewController(s.app, employeeService, middleware, appMetrics)
+	lockoutCtr.InitNewController(s.app, lockoutService, middleware)
+	accountCtr.InitNewController(s.app, accountService, middleware)
+	mfaCtr.InitNewController(s.app, mfaService, middleware)
+	apiKeyCtr.InitNewController(s.app, apiKeyService, middleware)
+	auditCtr.InitNewController(s.app, auditService, middleware)
+	privacyCtr.InitNewController(s.app, privacyService, middleware)
+	webhookCtr.InitNewController(s.app, webhookService, middleware)
+	streamCtr.InitNewController(s.app, streamService, middleware)
+	scimCtr.InitNewController(s.app, scimService, middleware, appMetrics)
+	graphQLCtr.InitNewController(s.app, graphQLService, middleware)
+
+	// The gRPC services run over the same services and authenticate the same way
+	s.grpc = NewGrpcServer(middleware, employeeRpc.Scopes, deptRpc.Scopes)
+	employeeRpc.Register(s.grpc, employeeService, departmentService, appMetrics, env.AppEnv.IfMatchRequired)
+	deptRpc.Register(s.grpc, departmentService, appMetrics, env.AppEnv.IfMatchRequired)
+
+	// Streams are woken by the notifications of every instance for as long as the server runs
+	go streamService.Listen(context.Background())
 
-	s.app.Post("/v1/file", middleware.RequireAdmin(), func(c *fiber.Ctx) error {
+	s.app.Post("/v1/file", middleware.RequireAdmin(enums.ScopeFileWrite), func(c *fiber.Ctx) error {
 		file, err := c.FormFile("file")
 		if err != nil {
+			appMetrics.ObserveUpload(0, metrics.UploadResultMissingFile)
 			return domain.ErrFileNotFound
 		}
 
@@ -149,24 +292,34 @@ func (s *httpServer) MountRoutes(db *sqlx.DB) {
 		}
 
 		if !validExt {
+			appMetrics.ObserveUpload(file.Size, metrics.UploadResultInvalidExtension)
 			return domain.ErrInvalidFileExtension
 		}
 
 		// check file size
 		if file.Size > int64(maxSize) {
+			appMetrics.ObserveUpload(file.Size, metrics.UploadResultTooLarge)
 			return domain.ErrFileSizeLimitExceeded
 		}
 
+		_, span := tracing.Start(c.UserContext(), "S3.Upload")
 		uri, err := s3.Upload(file)
+		span.End()
 		if err != nil {
+			appMetrics.ObserveUpload(file.Size, metrics.UploadResultFailed)
 			return err
 		}
 
-		return c.Status(fiber.StatusOK).JSON(fiber.Map{
-			"uri": uri,
+		appMetrics.ObserveUpload(file.Size, metrics.UploadResultSuccess)
+
+		return response.SendResponse(c, fiber.StatusOK, dto.FileUploadRes{
+			URI: uri,
 		})
 	})
 
+	spec := apiSpec()
+	s.mountDocs(spec)
+
 	s.app.Use(func(c *fiber.Ctx) error {
 		return c.SendFile("./web/not-found.html")
 	})
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/metrics"
+)
+
+type MetricsServer interface {
+	Start(port string)
+}
+
+type metricsServer struct {
+	handler http.Handler
+	token   string
+}
+
+// NewMetricsServer serves /metrics on its own listener so it is never exposed through the public API port.
+// When token is not empty, scrapes must send it as a bearer token.
+func NewMetricsServer(m metrics.MetricsInterface, token string) MetricsServer {
+	return &metricsServer{
+		handler: m.Handler(),
+		token:   token,
+	}
+}
+
+func (s *metricsServer) Start(port string) {
+	if port[0] != ':' {
+		port = ":" + port
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.requireToken(s.handler))
+
+	server := &http.Server{
+		Addr:              port,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	log.Info(log.LogInfo{
+		"port": port,
+	}, "[METRICS SERVER][Start] serving metrics")
+
+	err := server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[METRICS SERVER][Start] failed to start metrics server")
+	}
+}
+
+func (s *metricsServer) requireToken(next http.Handler) http.Handler {
+	if s.token == "" {
+		return next
+	}
+
+	expected := []byte("Bearer " + s.token)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := []byte(r.Header.Get("Authorization"))
+		if subtle.ConstantTimeCompare(header, expected) != 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/env"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/ratelimit"
+)
+
+func newRateLimiter(db *sqlx.DB) *ratelimit.Limiter {
+	limits := map[string]ratelimit.Limit{}
+	for class, raw := range map[string]string{
+		ratelimit.ClassAuth:   env.AppEnv.RateLimitAuth,
+		ratelimit.ClassUpload: env.AppEnv.RateLimitUpload,
+		ratelimit.ClassRead:   env.AppEnv.RateLimitRead,
+		ratelimit.ClassWrite:  env.AppEnv.RateLimitWrite,
+
+		ratelimit.TenantClass(ratelimit.ClassUpload): env.AppEnv.RateLimitTenantUpload,
+		ratelimit.TenantClass(ratelimit.ClassRead):   env.AppEnv.RateLimitTenantRead,
+		ratelimit.TenantClass(ratelimit.ClassWrite):  env.AppEnv.RateLimitTenantWrite,
+	} {
+		limit, err := ratelimit.ParseLimit(raw)
+		if err != nil {
+			log.Fatal(log.LogInfo{
+				"error": err.Error(),
+				"class": class,
+			}, "[SERVER][newRateLimiter] invalid rate limit")
+		}
+
+		limits[class] = limit
+	}
+
+	var store ratelimit.Store
+	switch env.AppEnv.RateLimitStore {
+	case ratelimit.StoreMemory, "":
+		store = ratelimit.NewMemoryStore()
+	case ratelimit.StorePostgres:
+		store = ratelimit.NewPostgresStore(db.DB)
+	case ratelimit.StoreRedis:
+		redis, err := ratelimit.NewRedisStore(env.AppEnv.RateLimitRedisURL)
+		if err != nil {
+			log.Fatal(log.LogInfo{
+				"error": err.Error(),
+			}, "[SERVER][newRateLimiter] invalid redis url")
+		}
+
+		store = redis
+	default:
+		log.Fatal(log.LogInfo{
+			"store": env.AppEnv.RateLimitStore,
+		}, "[SERVER][newRateLimiter] unknown rate limit store")
+	}
+
+	return ratelimit.New(store, limits, env.AppEnv.RateLimitFailClosed)
+}
@@ -1,15 +1,23 @@
 package middlewares
 
 import (
+	"context"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
 )
 
+const HeaderAPIKey = "X-API-Key"
+
 func (m *Middleware) RequireAuth() fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		header := ctx.Get("Authorization")
@@ -18,7 +26,7 @@ func (m *Middleware) RequireAuth() fiber.Handler {
 		}
 
 		headerSlice := strings.Split(header, " ")
-		if len(headerSlice) != 2 && headerSlice[0] != "Bearer" {
+		if len(headerSlice) != 2 || headerSlice[0] != "Bearer" {
 			return domain.ErrInvalidBearerToken
 		}
 
@@ -48,8 +56,12 @@ func (m *Middleware) RequireAuth() fiber.Handler {
 		}
 
 		ctx.Locals("claims", claims)
+		setPrincipal(ctx, reqctx.Principal{
+			UserID: claims.UserID.String(),
+			Kind:   reqctx.PrincipalUser,
+		})
 
-		return ctx.Next()
+		return m.limit(ctx)
 	}
 }
 
@@ -74,45 +86,177 @@ func (m *Middleware) RequireOneOfRoles(roles ...enums.RoleEnum) fiber.Handler {
 	}
 }
 
-func (m *Middleware) RequireAdmin() fiber.Handler {
+// RequireAdmin authenticates a manager by bearer token or, on endpoints that list the scopes
+// they accept, by an API key in the X-API-Key header. A key acts as the manager owning it and
+// needs one of the scopes; endpoints without scopes can't be called with a key at all.
+func (m *Middleware) RequireAdmin(scopes ...enums.ScopeEnum) fiber.Handler {
 	return func(ctx *fiber.Ctx) error {
 		header := ctx.Get("Authorization")
 		if header == "" {
+			if apiKey := ctx.Get(HeaderAPIKey); apiKey != "" {
+				return m.requireAPIKey(ctx, apiKey, scopes)
+			}
+
 			return domain.ErrNoBearerToken
 		}
 
 		headerSlice := strings.Split(header, " ")
-		if len(headerSlice) != 2 && headerSlice[0] != "Bearer" {
+		if len(headerSlice) != 2 || headerSlice[0] != "Bearer" {
 			return domain.ErrInvalidBearerToken
 		}
 
-		token := headerSlice[1]
-		var claims jwt.ClaimsManager
-		err := m.jwtManager.DecodeManager(token, &claims)
+		claims, err := m.decodeManagerToken(headerSlice[1])
 		if err != nil {
-			return domain.ErrInvalidBearerToken
+			return err
 		}
 
-		notBefore, err := claims.GetNotBefore()
-		if err != nil {
-			return domain.ErrInvalidBearerToken
+		ctx.Locals("claims", claims)
+		setPrincipal(ctx, reqctx.Principal{
+			TenantID: claims.UserID,
+			UserID:   strconv.Itoa(claims.UserID),
+			Kind:     reqctx.PrincipalManager,
+		})
+
+		return m.limit(ctx)
+	}
+}
+
+// decodeManagerToken returns the claims of a manager access token that is currently valid
+func (m *Middleware) decodeManagerToken(token string) (jwt.ClaimsManager, error) {
+	var claims jwt.ClaimsManager
+	err := m.jwtManager.DecodeManager(token, &claims)
+	if err != nil {
+		return claims, domain.ErrInvalidBearerToken
+	}
+
+	notBefore, err := claims.GetNotBefore()
+	if err != nil {
+		return claims, domain.ErrInvalidBearerToken
+	}
+
+	if notBefore.After(time.Now()) {
+		return claims, domain.ErrBearerTokenNotActive
+	}
+
+	expirationTime, err := claims.GetExpirationTime()
+	if err != nil {
+		return claims, domain.ErrInvalidBearerToken
+	}
+
+	if expirationTime.Before(time.Now()) {
+		return claims, domain.ErrExpiredBearerToken
+	}
+
+	return claims, nil
+}
+
+// RequireAPIKey authenticates a manager by API key only, sent in the X-API-Key header or as a
+// bearer token the way SCIM clients send theirs. The key needs one of the scopes.
+func (m *Middleware) RequireAPIKey(scopes ...enums.ScopeEnum) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if apiKey := ctx.Get(HeaderAPIKey); apiKey != "" {
+			return m.requireAPIKey(ctx, apiKey, scopes)
 		}
 
-		if notBefore.After(time.Now()) {
-			return domain.ErrBearerTokenNotActive
+		header := ctx.Get("Authorization")
+		if header == "" {
+			return domain.ErrNoAPIKey
 		}
 
-		expirationTime, err := claims.GetExpirationTime()
-		if err != nil {
+		scheme, apiKey, ok := strings.Cut(header, " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") || apiKey == "" {
+			return domain.ErrInvalidAPIKey
+		}
+
+		return m.requireAPIKey(ctx, apiKey, scopes)
+	}
+}
+
+func (m *Middleware) requireAPIKey(ctx *fiber.Ctx, rawKey string, scopes []enums.ScopeEnum) error {
+	key, err := m.authenticateAPIKey(ctx.UserContext(), rawKey, scopes)
+	if err != nil {
+		return err
+	}
+
+	// Handlers read the manager from the claims whichever way it authenticated
+	ctx.Locals("claims", jwt.ClaimsManager{
+		UserID: key.ManagerID,
+		Email:  key.ManagerEmail,
+	})
+	ctx.Locals("api_key_id", key.ID)
+	setPrincipal(ctx, reqctx.Principal{
+		TenantID: key.ManagerID,
+		UserID:   strconv.Itoa(key.ManagerID),
+		Kind:     reqctx.PrincipalManager,
+		APIKeyID: key.ID,
+		Scopes:   key.ScopeList(),
+	})
+	ctx.SetUserContext(log.WithContext(ctx.UserContext(), log.LogInfo{
+		"api_key_id": key.ID,
+	}))
+
+	return m.limit(ctx)
+}
+
+// authenticateAPIKey returns the key rawKey is when it holds one of the scopes
+func (m *Middleware) authenticateAPIKey(ctx context.Context, rawKey string, scopes []enums.ScopeEnum) (*entity.APIKey, error) {
+	if len(scopes) == 0 {
+		return nil, domain.ErrAPIKeyScopeDenied
+	}
+
+	key, err := m.apiKeys.Authenticate(ctx, rawKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if !slices.ContainsFunc(scopes, func(scope enums.ScopeEnum) bool {
+		return slices.Contains(key.ScopeList(), scope.String())
+	}) {
+		return nil, domain.ErrAPIKeyScopeDenied
+	}
+
+	return key, nil
+}
+
+// RequireMFAChallenge accepts the challenge token a login hands out before the second factor,
+// for the given purpose only. Access tokens are rejected here and challenge tokens everywhere else.
+func (m *Middleware) RequireMFAChallenge(purpose string) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		header := ctx.Get("Authorization")
+		if header == "" {
+			return domain.ErrNoBearerToken
+		}
+
+		headerSlice := strings.Split(header, " ")
+		if len(headerSlice) != 2 || headerSlice[0] != "Bearer" {
 			return domain.ErrInvalidBearerToken
 		}
 
-		if expirationTime.Before(time.Now()) {
-			return domain.ErrExpiredBearerToken
+		var claims jwt.ClaimsChallenge
+		if err := m.jwtChallenge.DecodeChallenge(headerSlice[1], &claims); err != nil {
+			return domain.ErrInvalidMFAToken
+		}
+
+		if claims.Purpose != purpose {
+			return domain.ErrInvalidMFAToken
 		}
 
 		ctx.Locals("claims", claims)
+		setPrincipal(ctx, reqctx.Principal{
+			UserID: claims.SubjectID,
+			Kind:   claims.Kind,
+		})
 
 		return ctx.Next()
 	}
 }
+
+// setPrincipal stores the authenticated principal in the user context and tags the request logger with it.
+func setPrincipal(ctx *fiber.Ctx, principal reqctx.Principal) {
+	userCtx := reqctx.WithPrincipal(ctx.UserContext(), principal)
+	userCtx = log.WithContext(userCtx, log.LogInfo{
+		"tenant_id": principal.TenantID,
+		"user_id":   principal.UserID,
+	})
+	ctx.SetUserContext(userCtx)
+}
@@ -7,9 +7,12 @@ import (
 
 func Compress() fiber.Handler {
 	config := compress.Config{
-		Next:  nil,
-    Level: compress.LevelDefault,
+		// Server-sent events are flushed one by one, compressing them only adds latency
+		Next: func(c *fiber.Ctx) bool {
+			return c.Get(fiber.HeaderAccept) == "text/event-stream"
+		},
+		Level: compress.LevelDefault,
 	}
 
 	return compress.New(config)
-}
\ No newline at end of file
+}
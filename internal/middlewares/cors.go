@@ -7,9 +7,9 @@ import (
 
 func Cors() fiber.Handler {
 	config := cors.Config{
-		AllowMethods:     "GET,POST,PUT,DELETE,PATCH,OPTIONS,HEAD",
-		AllowHeaders:     "Content-Type,Authorization,X-API-Key,Accept,Origin,X-Requested-With,X-XSRF-Token,X-Cursor,Token-Type",
-		ExposeHeaders:    "Content-Length",
+		AllowMethods:  "GET,POST,PUT,DELETE,PATCH,OPTIONS,HEAD",
+		AllowHeaders:  "Content-Type,Authorization,X-API-Key,Accept,Origin,X-Requested-With,X-XSRF-Token,X-Cursor,Token-Type,Traceparent,Tracestate,X-Request-ID,Idempotency-Key,If-Match,If-None-Match",
+		ExposeHeaders: "Content-Length,ETag,X-Request-ID,Retry-After,RateLimit-Limit,RateLimit-Remaining,RateLimit-Reset,RateLimit-Policy,Idempotent-Replayed",
 	}
 
 	return cors.New(config)
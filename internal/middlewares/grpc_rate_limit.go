@@ -0,0 +1,96 @@
+package middlewares
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/ratelimit"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryGRPCRateLimit limits unary calls the way Middleware.limit does requests. It runs after
+// UnaryGRPCAuth, which puts the principal the buckets are keyed by in the context.
+func (m *Middleware) UnaryGRPCRateLimit() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		header, err := m.limitGRPC(ctx, info.FullMethod)
+		if header != nil {
+			_ = grpc.SetHeader(ctx, header)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamGRPCRateLimit limits streaming calls the way Middleware.limit does requests, a stream
+// taking a single token however many messages it carries
+func (m *Middleware) StreamGRPCRateLimit() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		header, err := m.limitGRPC(stream.Context(), info.FullMethod)
+		if header != nil {
+			_ = stream.SetHeader(header)
+		}
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, stream)
+	}
+}
+
+// limitGRPC takes a token from every bucket of the principal calling method. The metadata carries
+// the RateLimit headers of the bucket closest to running out, nil when no bucket is limited.
+func (m *Middleware) limitGRPC(ctx context.Context, method string) (metadata.MD, error) {
+	principal, _ := reqctx.GetPrincipal(ctx)
+
+	var closest *ratelimit.Result
+	for _, bucket := range buckets(grpcClassOf(method), principal) {
+		res, ok, err := takeToken(ctx, m.limiter, bucket.class, bucket.key)
+		if !ok && err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		if closest == nil || !res.Allowed || res.Remaining < closest.Remaining {
+			closest = &res
+		}
+		if err != nil {
+			return rateLimitMetadata(*closest), err
+		}
+	}
+
+	if closest == nil {
+		return nil, nil
+	}
+
+	return rateLimitMetadata(*closest), nil
+}
+
+// grpcClassOf classes methods reading like GET requests, by the verb their name starts with
+func grpcClassOf(fullMethod string) string {
+	method := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	for _, verb := range []string{"Get", "List", "Stream"} {
+		if strings.HasPrefix(method, verb) {
+			return ratelimit.ClassRead
+		}
+	}
+
+	return ratelimit.ClassWrite
+}
+
+// rateLimitMetadata holds the RateLimit headers of res, metadata keys are lower-case
+func rateLimitMetadata(res ratelimit.Result) metadata.MD {
+	return metadata.Pairs(
+		strings.ToLower(HeaderRateLimitLimit), strconv.Itoa(res.Limit.Requests),
+		strings.ToLower(HeaderRateLimitRemaining), strconv.Itoa(res.Remaining),
+		strings.ToLower(HeaderRateLimitReset), strconv.Itoa(ceilSeconds(res.Reset.Seconds())),
+		strings.ToLower(HeaderRateLimitPolicy), policyOf(res),
+	)
+}
@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+)
+
+const (
+	HeaderIdempotencyKey     = "Idempotency-Key"
+	HeaderIdempotentReplayed = "Idempotent-Replayed"
+)
+
+// Idempotent replays the first response to a request carrying an Idempotency-Key header, so a
+// client retrying a create doesn't create twice. Keys are per tenant; reusing one for a different
+// request is rejected. It has to run after authentication.
+func (m *Middleware) Idempotent() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		key := ctx.Get(HeaderIdempotencyKey)
+		tenantID := reqctx.TenantID(ctx.UserContext())
+		if key == "" || tenantID == 0 {
+			return ctx.Next()
+		}
+
+		stored, err := m.idempotency.Begin(ctx.UserContext(), tenantID, key, fingerprint(ctx))
+		if err != nil {
+			return err
+		}
+
+		if stored != nil {
+			ctx.Set(HeaderIdempotentReplayed, "true")
+			if stored.ContentType != "" {
+				ctx.Set(fiber.HeaderContentType, stored.ContentType)
+			}
+			return ctx.Status(stored.StatusCode).Send(stored.Body)
+		}
+
+		// Errors are rendered here rather than by the app so the problem response can be stored too
+		if err := ctx.Next(); err != nil {
+			if err := ctx.App().Config().ErrorHandler(ctx, err); err != nil {
+				m.idempotency.Complete(ctx.UserContext(), tenantID, key, dto.IdempotentResponse{
+					StatusCode: fiber.StatusInternalServerError,
+				})
+				return err
+			}
+		}
+
+		m.idempotency.Complete(ctx.UserContext(), tenantID, key, dto.IdempotentResponse{
+			StatusCode:  ctx.Response().StatusCode(),
+			ContentType: string(ctx.Response().Header.ContentType()),
+			Body:        bytes.Clone(ctx.Response().Body()),
+		})
+
+		return nil
+	}
+}
+
+// fingerprint identifies the request a key is used with: the same key on another route or with
+// another body is a different request
+func fingerprint(ctx *fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(ctx.Method() + "\n" + ctx.Path() + "\n"))
+	hash.Write(ctx.Body())
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
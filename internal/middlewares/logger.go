@@ -4,18 +4,24 @@ import (
 	"github.com/gofiber/contrib/fiberzerolog"
 	"github.com/gofiber/fiber/v2"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/rs/zerolog"
 )
 
 func LoggerConfig() fiber.Handler {
 	logger := log.GetLogger()
 	config := fiberzerolog.Config{
-		Logger:          logger,
+		Logger: logger,
+		// Use the request-scoped logger so access logs carry request, tenant and user ids
+		GetLogger: func(c *fiber.Ctx) zerolog.Logger {
+			return *log.FromContext(c.UserContext())
+		},
 		FieldsSnakeCase: true,
+		// The route template stands in for the path, which can hold an employee identity number
 		Fields: []string{
 			"referer",
 			"ip",
 			"host",
-			"url",
+			"route",
 			"ua",
 			"latency",
 			"status",
@@ -0,0 +1,26 @@
+package middlewares
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/metrics"
+)
+
+func Metrics(m metrics.MetricsInterface) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		start := time.Now()
+
+		// Resolve the error here so the recorded status matches what the client receives
+		if err := ctx.Next(); err != nil {
+			if handlerErr := ctx.App().Config().ErrorHandler(ctx, err); handlerErr != nil {
+				_ = ctx.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		// Use the route template (e.g. /v1/employee/:identityNumber) to keep label cardinality bounded
+		m.ObserveRequest(ctx.Method(), ctx.Route().Path, ctx.Response().StatusCode(), time.Since(start))
+
+		return nil
+	}
+}
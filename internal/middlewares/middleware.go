@@ -1,18 +1,38 @@
 package middlewares
 
-import "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+import (
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/ratelimit"
+)
 
 type Middleware struct {
-	jwt jwt.JwtInterface
-	jwtManager jwt.JwtManagerInterface
+	jwt          jwt.JwtInterface
+	jwtManager   jwt.JwtManagerInterface
+	jwtChallenge jwt.JwtChallengeInterface
+	apiKeys      contracts.APIKeyService
+	limiter      *ratelimit.Limiter
+	idempotency  contracts.IdempotencyService
+	// requireIfMatch rejects changes to versioned resources sent without If-Match
+	requireIfMatch bool
 }
 
 func NewMiddleware(
 	jwt jwt.JwtInterface,
 	jwtManager jwt.JwtManagerInterface,
+	jwtChallenge jwt.JwtChallengeInterface,
+	apiKeys contracts.APIKeyService,
+	limiter *ratelimit.Limiter,
+	idempotency contracts.IdempotencyService,
+	requireIfMatch bool,
 ) *Middleware {
 	return &Middleware{
-		jwt: jwt,
-		jwtManager: jwtManager,
+		jwt:            jwt,
+		jwtManager:     jwtManager,
+		jwtChallenge:   jwtChallenge,
+		apiKeys:        apiKeys,
+		limiter:        limiter,
+		idempotency:    idempotency,
+		requireIfMatch: requireIfMatch,
 	}
 }
@@ -0,0 +1,18 @@
+package middlewares
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+)
+
+// Precondition guards a route changing a versioned resource. The service compares If-Match with the
+// current version; this only rejects requests without one when the deployment requires it.
+func (m *Middleware) Precondition() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if m.requireIfMatch && ctx.Get(fiber.HeaderIfMatch) == "" {
+			return domain.ErrPreconditionRequired
+		}
+
+		return ctx.Next()
+	}
+}
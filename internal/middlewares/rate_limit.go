@@ -0,0 +1,175 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/ratelimit"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+)
+
+const (
+	HeaderRateLimitLimit     = "RateLimit-Limit"
+	HeaderRateLimitRemaining = "RateLimit-Remaining"
+	HeaderRateLimitReset     = "RateLimit-Reset"
+	HeaderRateLimitPolicy    = "RateLimit-Policy"
+)
+
+// RateLimit limits the authentication endpoints per client IP, before any credentials are checked.
+// Behind trusted proxies that is the IP they forward, see RequestID, not the proxy's own.
+// Every other endpoint is limited per API key or manager once authenticated, see Middleware.limit.
+//
+// When the limiter's store fails, requests are let through without limits unless the limiter
+// fails closed, in which case they are refused with 503.
+func RateLimit(limiter *ratelimit.Limiter) fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		if !isAuthPath(ctx.Path()) {
+			return ctx.Next()
+		}
+
+		ip := reqctx.ClientIP(ctx.UserContext())
+		if ip == "" {
+			ip = ctx.IP()
+		}
+
+		if err := take(ctx, limiter, ratelimit.ClassAuth, "ip:"+ip); err != nil {
+			return err
+		}
+
+		return ctx.Next()
+	}
+}
+
+// limit takes a token from every bucket of the authenticated principal, see buckets, and
+// continues the chain when each had one left.
+func (m *Middleware) limit(ctx *fiber.Ctx) error {
+	// Already limited per IP by RateLimit
+	if isAuthPath(ctx.Path()) {
+		return ctx.Next()
+	}
+
+	principal, _ := reqctx.GetPrincipal(ctx.UserContext())
+	for _, bucket := range buckets(classOf(ctx), principal) {
+		if err := take(ctx, m.limiter, bucket.class, bucket.key); err != nil {
+			return err
+		}
+	}
+
+	return ctx.Next()
+}
+
+type bucket struct {
+	class string
+	key   string
+}
+
+// buckets are those a request of principal takes a token from: the API key's when the request
+// used one, so a busy integration can't starve its owner, or else the principal's own; then the
+// tenant's, which its manager and all of its keys share so that more keys don't mean more requests.
+func buckets(class string, principal reqctx.Principal) []bucket {
+	key := principal.Kind + ":" + principal.UserID
+	if principal.APIKeyID != 0 {
+		key = "key:" + strconv.Itoa(principal.APIKeyID)
+	}
+
+	limited := []bucket{{class: class, key: key}}
+	if principal.TenantID != 0 {
+		limited = append(limited, bucket{
+			class: ratelimit.TenantClass(class),
+			key:   "tenant:" + strconv.Itoa(principal.TenantID),
+		})
+	}
+
+	return limited
+}
+
+func take(ctx *fiber.Ctx, limiter *ratelimit.Limiter, class, key string) error {
+	res, ok, err := takeToken(ctx.UserContext(), limiter, class, key)
+	if !ok {
+		return err
+	}
+
+	// With several buckets the headers describe the one closest to running out
+	if remaining := ctx.GetRespHeader(HeaderRateLimitRemaining); remaining != "" && res.Allowed {
+		if current, err := strconv.Atoi(remaining); err == nil && current <= res.Remaining {
+			return nil
+		}
+	}
+
+	ctx.Set(HeaderRateLimitLimit, strconv.Itoa(res.Limit.Requests))
+	ctx.Set(HeaderRateLimitRemaining, strconv.Itoa(res.Remaining))
+	ctx.Set(HeaderRateLimitReset, strconv.Itoa(ceilSeconds(res.Reset.Seconds())))
+	ctx.Set(HeaderRateLimitPolicy, policyOf(res))
+
+	return err
+}
+
+// takeToken takes a token from the bucket of key in class, err is set when there was none left or,
+// for a limiter failing closed, when the store failed. ok is false when there is nothing to tell
+// the client, the class being unlimited or the store failing.
+func takeToken(ctx context.Context, limiter *ratelimit.Limiter, class, key string) (res ratelimit.Result, ok bool, err error) {
+	res, ok, err = limiter.Allow(ctx, class, key)
+	if err != nil && limiter.FailsClosed() {
+		log.ErrorCtx(ctx, log.LogInfo{
+			"error": err.Error(),
+			"class": class,
+		}, "[RateLimit] failed to take a token, refusing the request")
+
+		return ratelimit.Result{}, false, domain.ErrRateLimitUnavailable
+	}
+	if err != nil {
+		// Rather serve without limits than fail every request while the store is down
+		log.WarnCtx(ctx, log.LogInfo{
+			"error": err.Error(),
+			"class": class,
+		}, "[RateLimit] failed to take a token, letting the request through")
+
+		return ratelimit.Result{}, false, nil
+	}
+
+	if !ok || res.Allowed {
+		return res, ok, nil
+	}
+
+	log.InfoCtx(ctx, log.LogInfo{
+		"class": class,
+		"key":   key,
+	}, "[RateLimit] request refused")
+
+	return res, true, domain.ErrTooManyRequests.WithRetryAfter(res.RetryAfter)
+}
+
+func policyOf(res ratelimit.Result) string {
+	return fmt.Sprintf("%d;w=%d", res.Limit.Requests, ceilSeconds(res.Limit.Period.Seconds()))
+}
+
+func classOf(ctx *fiber.Ctx) string {
+	switch {
+	case ctx.Path() == "/v1/file":
+		return ratelimit.ClassUpload
+	case ctx.Method() == fiber.MethodGet || ctx.Method() == fiber.MethodHead:
+		return ratelimit.ClassRead
+	default:
+		return ratelimit.ClassWrite
+	}
+}
+
+func isAuthPath(path string) bool {
+	for _, prefix := range []string{"/v1/auth", "/auth"} {
+		if path == prefix || strings.HasPrefix(path, prefix+"/") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ceilSeconds(s float64) int {
+	return int(math.Ceil(s))
+}
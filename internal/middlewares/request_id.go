@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"regexp"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Incoming ids are only trusted when they are short and cannot break log lines
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// RequestID assigns every request an id (reusing a sane X-Request-ID from the client), echoes it
+// back in the response and stores it, together with a request-scoped logger, in the user context.
+func RequestID() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		requestID := ctx.Get(fiber.HeaderXRequestID)
+		if !validRequestID.MatchString(requestID) {
+			requestID = uuid.NewString()
+		}
+
+		ctx.Set(fiber.HeaderXRequestID, requestID)
+		ctx.Locals("requestid", requestID)
+
+		fields := log.LogInfo{
+			"request_id": requestID,
+		}
+
+		userCtx := ctx.UserContext()
+		if spanCtx := trace.SpanContextFromContext(userCtx); spanCtx.IsValid() {
+			fields["trace_id"] = spanCtx.TraceID().String()
+		}
+
+		userCtx = reqctx.WithRequestID(userCtx, requestID)
+		userCtx = reqctx.WithClientIP(userCtx, ctx.IP())
+		userCtx = log.WithContext(userCtx, fields)
+		ctx.SetUserContext(userCtx)
+
+		return ctx.Next()
+	}
+}
@@ -0,0 +1,58 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a server span for every request, continuing the trace from an incoming
+// W3C traceparent header, and stores it in the user context handed to services. Only the route
+// template is recorded, paths carry identifiers like employee identity numbers.
+func Tracing() fiber.Handler {
+	return func(ctx *fiber.Ctx) error {
+		header := http.Header{}
+		ctx.Request().Header.VisitAll(func(key, value []byte) {
+			header.Add(string(key), string(value))
+		})
+
+		parent := otel.GetTextMapPropagator().Extract(ctx.UserContext(), propagation.HeaderCarrier(header))
+
+		spanCtx, span := tracing.Tracer().Start(parent, ctx.Method(),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(ctx.Method()),
+				semconv.ClientAddress(ctx.IP()),
+				semconv.UserAgentOriginal(ctx.Get(fiber.HeaderUserAgent)),
+			),
+		)
+		defer span.End()
+
+		ctx.SetUserContext(spanCtx)
+
+		err := ctx.Next()
+
+		route := ctx.Route().Path
+		status := ctx.Response().StatusCode()
+
+		span.SetName(ctx.Method() + " " + route)
+		span.SetAttributes(
+			semconv.HTTPRoute(route),
+			semconv.HTTPResponseStatusCode(status),
+		)
+
+		// The error itself is recorded by the error handler, middlewares further in resolve it
+		// into the response before it gets here
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+
+		return err
+	}
+}
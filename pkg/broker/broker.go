@@ -0,0 +1,20 @@
+// Package broker publishes messages to a message broker. The adapters wait for the broker to
+// acknowledge each message, so a nil error means it is stored.
+package broker
+
+import "context"
+
+type Message struct {
+	// Subject is the message type, adapters map it to a subject or a header
+	Subject string
+	// Key groups messages that have to stay in order
+	Key string
+	// ID identifies the message for brokers and consumers that dedupe
+	ID   string
+	Body []byte
+}
+
+type PublisherInterface interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
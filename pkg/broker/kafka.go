@@ -0,0 +1,58 @@
+package broker
+
+import (
+	"context"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+)
+
+type KafkaConfig struct {
+	Brokers []string
+	Topic   string
+}
+
+// KafkaWriter is the part of *kafka.Writer the publisher uses
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+type kafkaPublisher struct {
+	writer KafkaWriter
+}
+
+// NewKafkaPublisher publishes to a single topic. Messages are partitioned by key, which keeps
+// the messages of a key in order, and the subject and id travel as the "type" and "id" headers.
+func NewKafkaPublisher(config KafkaConfig) PublisherInterface {
+	return NewKafkaWriterPublisher(&kafka.Writer{
+		Addr:         kafka.TCP(config.Brokers...),
+		Topic:        config.Topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		// Messages are written one at a time, there is no batch worth waiting for
+		BatchTimeout: 10 * time.Millisecond,
+		// The caller retries failed messages itself
+		MaxAttempts: 3,
+	})
+}
+
+// NewKafkaWriterPublisher publishes through a writer configured elsewhere, e.g. with TLS or SASL
+func NewKafkaWriterPublisher(writer KafkaWriter) PublisherInterface {
+	return &kafkaPublisher{writer: writer}
+}
+
+func (p *kafkaPublisher) Publish(ctx context.Context, msg Message) error {
+	return p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(msg.Key),
+		Value: msg.Body,
+		Headers: []kafka.Header{
+			{Key: "id", Value: []byte(msg.ID)},
+			{Key: "type", Value: []byte(msg.Subject)},
+		},
+	})
+}
+
+func (p *kafkaPublisher) Close() error {
+	return p.writer.Close()
+}
@@ -0,0 +1,58 @@
+package broker
+
+import (
+	"context"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// HeaderKey carries Message.Key on NATS, where ordering comes from the stream instead
+const HeaderKey = "Gogomanager-Key"
+
+type NATSConfig struct {
+	URL string
+	// SubjectPrefix is prepended to the message subject, a JetStream stream has to capture "<prefix>.>"
+	SubjectPrefix string
+}
+
+type natsPublisher struct {
+	conn   *nats.Conn
+	js     jetstream.JetStream
+	prefix string
+}
+
+// NewNATSPublisher publishes to JetStream. The message id is sent as Nats-Msg-Id, so a message
+// published again within the stream's duplicate window is stored once.
+func NewNATSPublisher(config NATSConfig) (PublisherInterface, error) {
+	conn, err := nats.Connect(config.URL, nats.Name("gogomanager-api"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, err
+	}
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &natsPublisher{conn: conn, js: js, prefix: config.SubjectPrefix}, nil
+}
+
+func (p *natsPublisher) Publish(ctx context.Context, msg Message) error {
+	subject := msg.Subject
+	if p.prefix != "" {
+		subject = p.prefix + "." + subject
+	}
+
+	m := nats.NewMsg(subject)
+	m.Data = msg.Body
+	m.Header.Set(HeaderKey, msg.Key)
+
+	_, err := p.js.PublishMsg(ctx, m, jetstream.WithMsgID(msg.ID))
+	return err
+}
+
+func (p *natsPublisher) Close() error {
+	return p.conn.Drain()
+}
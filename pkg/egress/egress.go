@@ -0,0 +1,83 @@
+// Package egress keeps requests made on behalf of tenants, like webhook deliveries, out of the
+// network the service runs in. Loopback, private, link-local and unspecified addresses are
+// refused, both when a url is registered and again when a connection is dialed, since a host
+// can resolve to something else by then.
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+var ErrForbiddenAddress = errors.New("address is not publicly routable")
+
+type Guard struct {
+	// AllowPrivate turns the checks off, for development against receivers on the same machine
+	AllowPrivate bool
+}
+
+// CheckIP refuses addresses that aren't publicly routable
+func (g Guard) CheckIP(ip netip.Addr) error {
+	if g.AllowPrivate {
+		return nil
+	}
+
+	// ::ffff:127.0.0.1 is loopback too
+	ip = ip.Unmap()
+	if !ip.IsValid() || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, ip)
+	}
+
+	return nil
+}
+
+// CheckHost resolves the host and refuses it when any of its addresses is refused
+func (g Guard) CheckHost(ctx context.Context, host string) error {
+	if g.AllowPrivate {
+		return nil
+	}
+
+	ips, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return err
+	}
+
+	for _, ip := range ips {
+		if err := g.CheckIP(ip); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Control is a net.Dialer Control function checking the address actually being dialed
+func (g Guard) Control(_ string, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrForbiddenAddress, address)
+	}
+
+	return g.CheckIP(addrPort.Addr())
+}
+
+// Transport is http.DefaultTransport dialing through the guard. It never uses a proxy: the
+// guard would only see the proxy's address.
+func (g Guard) Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = (&net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   g.Control,
+	}).DialContext
+
+	return transport
+}
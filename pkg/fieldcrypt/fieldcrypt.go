@@ -0,0 +1,287 @@
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/env"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+)
+
+// KeySize is the size of every key: AES-256 for the key encryption and data keys, HMAC-SHA256 for the blind index
+const KeySize = 32
+
+var (
+	ErrInvalidKeyring = errors.New("fieldcrypt: invalid keyring")
+	ErrUnknownKey     = errors.New("fieldcrypt: unknown key id")
+	ErrDecrypt        = errors.New("fieldcrypt: ciphertext could not be decrypted")
+)
+
+// FieldCryptInterface does envelope encryption: every row gets its own data key, stored next to
+// the row wrapped by a key of the keyring, so retiring a key only means re-encrypting the rows
+// that name it.
+type FieldCryptInterface interface {
+	// NewDataKey creates a data key wrapped with the active key
+	NewDataKey() (*DataKey, error)
+	// OpenDataKey unwraps a stored data key with the keyring key it names
+	OpenDataKey(keyID string, wrapped []byte) (*DataKey, error)
+	ActiveKeyID() string
+	// BlindIndex is a keyed hash of value that allows exact-match lookups without decrypting.
+	// The field is mixed in so equal values in different columns don't match.
+	BlindIndex(field, value string) string
+}
+
+type Config struct {
+	// Keys maps key ids to key encryption keys, retired keys stay until no row names them
+	Keys          map[string][]byte
+	ActiveKeyID   string
+	BlindIndexKey []byte
+}
+
+type FieldCryptStruct struct {
+	keys          map[string]cipher.AEAD
+	activeKeyID   string
+	blindIndexKey []byte
+}
+
+var FieldCrypt = getFieldCrypt()
+
+func getFieldCrypt() FieldCryptInterface {
+	keys, err := ParseKeys(env.AppEnv.FieldEncryptionKeys)
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[FIELDCRYPT][getFieldCrypt] failed to parse FIELD_ENCRYPTION_KEYS")
+	}
+
+	blindIndexKey, err := base64.StdEncoding.DecodeString(env.AppEnv.FieldBlindIndexKey)
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[FIELDCRYPT][getFieldCrypt] failed to decode FIELD_BLIND_INDEX_KEY")
+	}
+
+	f, err := New(Config{
+		Keys:          keys,
+		ActiveKeyID:   env.AppEnv.FieldEncryptionActiveKey,
+		BlindIndexKey: blindIndexKey,
+	})
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[FIELDCRYPT][getFieldCrypt] failed to load keyring")
+	}
+
+	return f
+}
+
+// ParseKeys reads a keyring written as "<id>:<base64 key>,<id>:<base64 key>"
+func ParseKeys(s string) (map[string][]byte, error) {
+	keys := map[string][]byte{}
+
+	for i, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		id, encoded, found := strings.Cut(entry, ":")
+		if !found || id == "" {
+			// The entry may be a bare key, keep it out of the error
+			return nil, fmt.Errorf("%w: entry %d is not <id>:<base64 key>", ErrInvalidKeyring, i+1)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %q is not valid base64", ErrInvalidKeyring, id)
+		}
+
+		if _, ok := keys[id]; ok {
+			return nil, fmt.Errorf("%w: key %q is listed twice", ErrInvalidKeyring, id)
+		}
+
+		keys[id] = key
+	}
+
+	return keys, nil
+}
+
+func New(config Config) (*FieldCryptStruct, error) {
+	if _, ok := config.Keys[config.ActiveKeyID]; !ok {
+		return nil, fmt.Errorf("%w: active key %q is not in the keyring", ErrInvalidKeyring, config.ActiveKeyID)
+	}
+
+	if len(config.BlindIndexKey) != KeySize {
+		return nil, fmt.Errorf("%w: blind index key must be %d bytes", ErrInvalidKeyring, KeySize)
+	}
+
+	f := &FieldCryptStruct{
+		keys:          map[string]cipher.AEAD{},
+		activeKeyID:   config.ActiveKeyID,
+		blindIndexKey: config.BlindIndexKey,
+	}
+
+	for id, key := range config.Keys {
+		if len(key) != KeySize {
+			return nil, fmt.Errorf("%w: key %q must be %d bytes", ErrInvalidKeyring, id, KeySize)
+		}
+
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+
+		f.keys[id] = aead
+	}
+
+	return f, nil
+}
+
+func (f *FieldCryptStruct) ActiveKeyID() string {
+	return f.activeKeyID
+}
+
+func (f *FieldCryptStruct) NewDataKey() (*DataKey, error) {
+	plain := make([]byte, KeySize)
+	if _, err := rand.Read(plain); err != nil {
+		return nil, err
+	}
+
+	wrapped, err := seal(f.keys[f.activeKeyID], plain, wrapAAD(f.activeKeyID))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataKey{KeyID: f.activeKeyID, Wrapped: wrapped, aead: aead}, nil
+}
+
+func (f *FieldCryptStruct) OpenDataKey(keyID string, wrapped []byte) (*DataKey, error) {
+	kek, ok := f.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, keyID)
+	}
+
+	plain, err := open(kek, wrapped, wrapAAD(keyID))
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(plain)
+	if err != nil {
+		return nil, err
+	}
+
+	return &DataKey{KeyID: keyID, Wrapped: wrapped, aead: aead}, nil
+}
+
+func (f *FieldCryptStruct) BlindIndex(field, value string) string {
+	mac := hmac.New(sha256.New, f.blindIndexKey)
+	mac.Write([]byte(field))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// DataKey encrypts the fields of one row
+type DataKey struct {
+	KeyID   string
+	Wrapped []byte
+	aead    cipher.AEAD
+}
+
+// Encrypt binds the ciphertext to its field, so it can't be moved to another column unnoticed
+func (k *DataKey) Encrypt(field, value string) ([]byte, error) {
+	return seal(k.aead, []byte(value), []byte(field))
+}
+
+func (k *DataKey) Decrypt(field string, ciphertext []byte) (string, error) {
+	plain, err := open(k.aead, ciphertext, []byte(field))
+	if err != nil {
+		return "", err
+	}
+
+	return string(plain), nil
+}
+
+// Sealed is a single value encrypted under a data key of its own, for rows with one encrypted column
+type Sealed struct {
+	Ciphertext []byte
+	DataKey    []byte
+	KeyID      string
+}
+
+// Seal encrypts value for field under a fresh data key wrapped with the active key
+func Seal(crypt FieldCryptInterface, field, value string) (Sealed, error) {
+	dataKey, err := crypt.NewDataKey()
+	if err != nil {
+		return Sealed{}, err
+	}
+
+	ciphertext, err := dataKey.Encrypt(field, value)
+	if err != nil {
+		return Sealed{}, err
+	}
+
+	return Sealed{Ciphertext: ciphertext, DataKey: dataKey.Wrapped, KeyID: dataKey.KeyID}, nil
+}
+
+// Open decrypts a value sealed by Seal
+func Open(crypt FieldCryptInterface, field string, sealed Sealed) (string, error) {
+	dataKey, err := crypt.OpenDataKey(sealed.KeyID, sealed.DataKey)
+	if err != nil {
+		return "", err
+	}
+
+	return dataKey.Decrypt(field, sealed.Ciphertext)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+// seal returns the random nonce followed by the ciphertext
+func seal(aead cipher.AEAD, plain, aad []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plain)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plain, aad), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, aad []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+
+	nonce, body := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plain, err := aead.Open(nil, nonce, body, aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	return plain, nil
+}
+
+func wrapAAD(keyID string) []byte {
+	return []byte("data-key:" + keyID)
+}
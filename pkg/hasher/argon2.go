@@ -0,0 +1,85 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	saltLength = 16
+	keyLength  = 32
+)
+
+const argon2idParams = "m=%d,t=%d,p=%d"
+
+var phcEncoding = base64.RawStdEncoding
+
+// hashArgon2id encodes the hash in the PHC string format, e.g.
+// $argon2id$v=19$m=19456,t=2,p=1$<salt>$<hash>, so the parameters travel with every hash
+func (h *HasherStruct) hashArgon2id(plain string) (string, error) {
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(plain), salt, h.config.Argon2Iterations, h.config.Argon2Memory, h.config.Argon2Parallelism, keyLength)
+
+	return fmt.Sprintf("$%s$v=%d$"+argon2idParams+"$%s$%s",
+		AlgorithmArgon2id, argon2.Version,
+		h.config.Argon2Memory, h.config.Argon2Iterations, h.config.Argon2Parallelism,
+		phcEncoding.EncodeToString(salt), phcEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *HasherStruct) verifyArgon2id(password, hashed string) (bool, bool) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, hash
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 6 {
+		return false, false
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return false, false
+	}
+
+	var (
+		memory, iterations uint32
+		parallelism        uint8
+	)
+	if _, err := fmt.Sscanf(parts[3], argon2idParams, &memory, &iterations, &parallelism); err != nil {
+		return false, false
+	}
+
+	if memory == 0 || iterations == 0 || parallelism == 0 {
+		return false, false
+	}
+
+	salt, err := phcEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false
+	}
+
+	key, err := phcEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, candidate) != 1 {
+		return false, false
+	}
+
+	needsRehash := h.config.Algorithm != AlgorithmArgon2id ||
+		memory < h.config.Argon2Memory ||
+		iterations < h.config.Argon2Iterations ||
+		parallelism != h.config.Argon2Parallelism ||
+		len(key) != keyLength
+
+	return true, needsRehash
+}
@@ -0,0 +1,46 @@
+package hasher
+
+import (
+	"strings"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// bcryptMaxLength is where bcrypt stops reading the password. Longer passwords are refused
+// rather than silently truncated.
+const bcryptMaxLength = 72
+
+func isBcrypt(hashed string) bool {
+	return strings.HasPrefix(hashed, "$2a$") || strings.HasPrefix(hashed, "$2b$") || strings.HasPrefix(hashed, "$2y$")
+}
+
+func (h *HasherStruct) hashBcrypt(plain string) (string, error) {
+	if len(plain) > bcryptMaxLength {
+		return "", domain.ErrPasswordTooLong
+	}
+
+	bytes, err := bcrypt.GenerateFromPassword([]byte(plain), h.config.BcryptCost)
+	if err != nil {
+		return "", err
+	}
+
+	return string(bytes), nil
+}
+
+func (h *HasherStruct) verifyBcrypt(password, hashed string) (bool, bool) {
+	if len(password) > bcryptMaxLength {
+		return false, false
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) != nil {
+		return false, false
+	}
+
+	cost, err := bcrypt.Cost([]byte(hashed))
+	if err != nil {
+		return true, true
+	}
+
+	return true, h.config.Algorithm != AlgorithmBcrypt || cost < h.config.BcryptCost
+}
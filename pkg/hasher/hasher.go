@@ -0,0 +1,165 @@
+package hasher
+
+import (
+	"crypto/rand"
+	"errors"
+	"strings"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/env"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	AlgorithmArgon2id = "argon2id"
+	AlgorithmBcrypt   = "bcrypt"
+)
+
+var (
+	ErrInvalidAlgorithm  = errors.New("hasher: unknown algorithm")
+	ErrInvalidParameters = errors.New("hasher: invalid cost parameters")
+)
+
+type PasswordHasher interface {
+	// Hash encodes the password with the configured algorithm and parameters
+	Hash(plain string) (string, error)
+	// Verify checks password against hashed, whatever algorithm produced it. needsRehash is true
+	// when the password matched but the hash uses another algorithm or weaker parameters.
+	Verify(password, hashed string) (ok bool, needsRehash bool)
+	// CompareDummy spends as long as Verify does, for callers that have no hash to check against.
+	// It never matches.
+	CompareDummy(password string) bool
+}
+
+// Config selects the algorithm new hashes use. Zero parameters fall back to the defaults,
+// which follow the OWASP recommendations for argon2id.
+type Config struct {
+	Algorithm         string
+	Argon2Memory      uint32 // KiB
+	Argon2Iterations  uint32
+	Argon2Parallelism uint8
+	BcryptCost        int
+}
+
+type HasherStruct struct {
+	config    Config
+	dummyHash string
+}
+
+var Hasher = getHasher()
+
+func getHasher() PasswordHasher {
+	h, err := New(Config{
+		Algorithm:         env.AppEnv.PasswordHashAlgorithm,
+		Argon2Memory:      env.AppEnv.PasswordArgon2Memory,
+		Argon2Iterations:  env.AppEnv.PasswordArgon2Iterations,
+		Argon2Parallelism: env.AppEnv.PasswordArgon2Parallelism,
+		BcryptCost:        env.AppEnv.PasswordBcryptCost,
+	})
+	if err != nil {
+		log.Fatal(log.LogInfo{
+			"error": err.Error(),
+		}, "[HASHER][getHasher] failed to create password hasher")
+	}
+
+	return h
+}
+
+func New(config Config) (*HasherStruct, error) {
+	if config.Algorithm == "" {
+		config.Algorithm = AlgorithmArgon2id
+	}
+	if config.Argon2Memory == 0 {
+		config.Argon2Memory = 19 * 1024
+	}
+	if config.Argon2Iterations == 0 {
+		config.Argon2Iterations = 2
+	}
+	if config.Argon2Parallelism == 0 {
+		config.Argon2Parallelism = 1
+	}
+	if config.BcryptCost == 0 {
+		config.BcryptCost = 12
+	}
+
+	h := &HasherStruct{config: config}
+
+	if err := h.validate(); err != nil {
+		return nil, err
+	}
+
+	// dummyHash is verified against when an account does not exist, so the response time
+	// does not tell whether an email is registered. Its password is random, no one can log in with it.
+	dummy := make([]byte, saltLength)
+	if _, err := rand.Read(dummy); err != nil {
+		return nil, err
+	}
+
+	dummyHash, err := h.Hash(phcEncoding.EncodeToString(dummy))
+	if err != nil {
+		return nil, err
+	}
+	h.dummyHash = dummyHash
+
+	return h, nil
+}
+
+func (h *HasherStruct) Hash(plain string) (string, error) {
+	var (
+		hashed string
+		err    error
+	)
+
+	switch h.config.Algorithm {
+	case AlgorithmBcrypt:
+		hashed, err = h.hashBcrypt(plain)
+	default:
+		hashed, err = h.hashArgon2id(plain)
+	}
+
+	if err != nil {
+		log.Error(log.LogInfo{
+			"algorithm": h.config.Algorithm,
+			"error":     err.Error(),
+		}, "[HASHER][Hash] failed to hash password")
+
+		return "", err
+	}
+
+	return hashed, nil
+}
+
+func (h *HasherStruct) Verify(password, hashed string) (bool, bool) {
+	switch {
+	case strings.HasPrefix(hashed, "$"+AlgorithmArgon2id+"$"):
+		return h.verifyArgon2id(password, hashed)
+	case isBcrypt(hashed):
+		return h.verifyBcrypt(password, hashed)
+	default:
+		return false, false
+	}
+}
+
+func (h *HasherStruct) CompareDummy(password string) bool {
+	ok, _ := h.Verify(password, h.dummyHash)
+	return ok
+}
+
+func (h *HasherStruct) validate() error {
+	switch h.config.Algorithm {
+	case AlgorithmArgon2id, AlgorithmBcrypt:
+	default:
+		return ErrInvalidAlgorithm
+	}
+
+	if h.config.BcryptCost < bcrypt.MinCost || h.config.BcryptCost > bcrypt.MaxCost {
+		return ErrInvalidParameters
+	}
+
+	// argon2 needs at least 8 KiB of memory per lane
+	if h.config.Argon2Memory < 8*uint32(h.config.Argon2Parallelism) {
+		return ErrInvalidParameters
+	}
+
+	return nil
+}
@@ -5,15 +5,36 @@ import (
 )
 
 type Flag struct {
+	// Entity selects which seeder to run: users, managers, departments, employees or all
+	Entity string
+	// Fake generates synthetic data instead of loading the CSV seeders
+	Fake bool
+	// Seed makes the fake data reproducible, the same seed always generates the same rows
+	Seed uint64
+	// Managers is the number of fake managers to generate
+	Managers int
+	// Departments is the number of fake departments per manager
+	Departments int
+	// Employees is the number of fake employees per department
+	Employees int
+	// BatchSize is the number of rows sent per INSERT statement
+	BatchSize int
 }
 
 var FlagVars = getFlags()
 
 func getFlags() *Flag {
+	flags := &Flag{}
 
-	flag.Parse()
+	flag.StringVar(&flags.Entity, "entity", "all", "entity to seed: users, managers, departments, employees or all")
+	flag.BoolVar(&flags.Fake, "fake", false, "generate synthetic data instead of loading the CSV seeders")
+	flag.Uint64Var(&flags.Seed, "seed", 1, "seed for the synthetic data generator")
+	flag.IntVar(&flags.Managers, "managers", 5, "number of synthetic managers")
+	flag.IntVar(&flags.Departments, "departments", 20, "number of synthetic departments per manager")
+	flag.IntVar(&flags.Employees, "employees", 100, "number of synthetic employees per department")
+	flag.IntVar(&flags.BatchSize, "batch-size", 1000, "rows per INSERT statement")
 
-	flag := &Flag{}
+	flag.Parse()
 
-	return flag
+	return flags
 }
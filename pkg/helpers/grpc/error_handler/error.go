@@ -0,0 +1,139 @@
+// Package errorhandler turns the errors of gRPC handlers into statuses, the way the HTTP error
+// handler turns them into problems.
+package errorhandler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	httpErrorHandler "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/error_handler"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain is the domain of the ErrorInfo detail attached to every status, its reason is the
+// code REST answers the same error with
+const ErrorDomain = "gogomanager"
+
+var codesByStatus = map[int]codes.Code{
+	http.StatusBadRequest:           codes.InvalidArgument,
+	http.StatusUnprocessableEntity:  codes.InvalidArgument,
+	http.StatusUnauthorized:         codes.Unauthenticated,
+	http.StatusForbidden:            codes.PermissionDenied,
+	http.StatusNotFound:             codes.NotFound,
+	http.StatusConflict:             codes.AlreadyExists,
+	http.StatusPreconditionFailed:   codes.FailedPrecondition,
+	http.StatusPreconditionRequired: codes.FailedPrecondition,
+	http.StatusTooManyRequests:      codes.ResourceExhausted,
+	http.StatusServiceUnavailable:   codes.Unavailable,
+}
+
+func UnaryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		res, err := handler(ctx, req)
+		if err != nil {
+			return nil, ToStatus(ctx, err)
+		}
+
+		return res, nil
+	}
+}
+
+func StreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := handler(srv, stream); err != nil {
+			return ToStatus(stream.Context(), err)
+		}
+
+		return nil
+	}
+}
+
+// UnaryRecoveryInterceptor answers a unary call with an internal error when the handler or an
+// interceptor after it panics, the way the recover middleware does requests
+func UnaryRecoveryInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (res interface{}, err error) {
+		defer recoverPanic(ctx, info.FullMethod, &err)
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecoveryInterceptor answers a streaming call with an internal error when the handler or
+// an interceptor after it panics, the way the recover middleware does requests
+func StreamRecoveryInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer recoverPanic(stream.Context(), info.FullMethod, &err)
+
+		return handler(srv, stream)
+	}
+}
+
+// ToStatus returns the status err is answered with. Errors that already are statuses, such as
+// those of a cancelled stream, are returned as they are.
+func ToStatus(ctx context.Context, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var valErr validator.ValidationErrors
+	var reqErr *domain.RequestError
+
+	switch {
+	case errors.As(err, &valErr):
+		return withReason(codes.InvalidArgument, valErr.Error(), httpErrorHandler.CodeValidationFailed)
+	case errors.As(err, &reqErr):
+		code, ok := codesByStatus[reqErr.StatusCode]
+		if !ok {
+			code = codes.Unknown
+		}
+
+		return withReason(code, reqErr.Error(), reqErr.Code)
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	default:
+		log.ErrorCtx(ctx, log.LogInfo{
+			"error": err.Error(),
+		}, "[GRPCErrorHandler] unhandled error")
+
+		return withReason(codes.Internal, "internal server error", httpErrorHandler.CodeInternalError)
+	}
+}
+
+func withReason(code codes.Code, message, reason string) error {
+	st := status.New(code, message)
+
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: ErrorDomain,
+	})
+	if err != nil {
+		return st.Err()
+	}
+
+	return detailed.Err()
+}
+
+// recoverPanic answers a call that panicked with an internal error instead of bringing the
+// server down
+func recoverPanic(ctx context.Context, method string, err *error) {
+	if r := recover(); r != nil {
+		log.ErrorCtx(ctx, log.LogInfo{
+			"panic":  fmt.Sprint(r),
+			"method": method,
+			"stack":  string(debug.Stack()),
+		}, "[GRPCErrorHandler] handler panicked")
+
+		*err = withReason(codes.Internal, "internal server error", httpErrorHandler.CodeInternalError)
+	}
+}
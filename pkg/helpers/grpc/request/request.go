@@ -0,0 +1,51 @@
+// Package request reads the paging and versioning fields gRPC requests share.
+package request
+
+import (
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/etag"
+)
+
+const (
+	// DefaultLimit is the size of pages asked for without a limit, as on REST
+	DefaultLimit = 5
+	MaxLimit     = 100
+	// DefaultPageSize and MaxPageSize bound how many rows a streaming call reads at a time
+	DefaultPageSize = 100
+	MaxPageSize     = 1000
+)
+
+// Page returns the limit and offset of a list, defaulting the limit when it is zero
+func Page(limit, offset int32) (int, int, error) {
+	if limit == 0 {
+		limit = DefaultLimit
+	}
+
+	if limit < 0 || limit > MaxLimit || offset < 0 {
+		return 0, 0, domain.ErrInvalidPagination
+	}
+
+	return int(limit), int(offset), nil
+}
+
+// PageSize returns how many rows a streaming call reads at a time
+func PageSize(size int32) int {
+	if size <= 0 {
+		return DefaultPageSize
+	}
+
+	return int(min(size, MaxPageSize))
+}
+
+// IfMatch returns the If-Match of version, empty when the request has none. When required,
+// requests without one are rejected as the Precondition middleware rejects them on REST.
+func IfMatch(version *int32, required bool) (string, error) {
+	if version == nil {
+		if required {
+			return "", domain.ErrVersionRequired
+		}
+		return "", nil
+	}
+
+	return etag.Format(int(*version)), nil
+}
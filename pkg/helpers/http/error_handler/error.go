@@ -2,28 +2,77 @@ package errorhandler
 
 import (
 	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+	"go.opentelemetry.io/otel/trace"
 )
 
+const (
+	CodeValidationFailed = "validation_failed"
+	CodeInternalError    = "internal_error"
+)
+
+// ErrorHandler renders err as a problem response. It is also where errors are recorded on the
+// request span: the metrics and access log middlewares resolve errors themselves, so the tracing
+// middleware only ever sees the status.
 func ErrorHandler(c *fiber.Ctx, err error) error {
-	var valErr validator.ValidationErrors
-	if errors.As(err, &valErr) {
-		return response.SendResponse(c, fiber.StatusBadRequest, valErr)
+	trace.SpanFromContext(c.UserContext()).RecordError(err)
+
+	problem := response.Problem{
+		Instance:  c.Path(),
+		RequestID: reqctx.RequestID(c.UserContext()),
 	}
 
+	var valErr validator.ValidationErrors
 	var reqErr *domain.RequestError
-	if errors.As(err, &reqErr) {
-		return response.SendResponse(c, reqErr.StatusCode, reqErr)
+	var fiberErr *fiber.Error
+
+	switch {
+	case errors.As(err, &valErr):
+		problem.Status = fiber.StatusBadRequest
+		problem.Code = CodeValidationFailed
+		problem.Detail = valErr.Error()
+		problem.Errors = valErr
+	case errors.As(err, &reqErr):
+		problem.Status = reqErr.StatusCode
+		problem.Code = reqErr.Code
+		problem.Detail = reqErr.Error()
+
+		if reqErr.RetryAfter > 0 {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(reqErr.RetryAfter.Seconds()))))
+		}
+	case errors.As(err, &fiberErr):
+		problem.Status = fiberErr.Code
+		problem.Code = codeFromStatus(fiberErr.Code)
+		problem.Detail = fiberErr.Message
+	default:
+		log.ErrorCtx(c.UserContext(), log.LogInfo{
+			"error": err.Error(),
+		}, "[ErrorHandler] unhandled error")
+
+		problem.Status = fiber.StatusInternalServerError
+		problem.Code = CodeInternalError
+		problem.Detail = "internal server error"
 	}
 
-	var fiberErr *fiber.Error
-	if errors.As(err, &fiberErr) {
-		return response.SendResponse(c, fiberErr.Code, fiberErr)
+	return response.SendProblem(c, problem)
+}
+
+// codeFromStatus derives a code for errors raised by fiber itself, e.g. 405 -> method_not_allowed
+func codeFromStatus(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return CodeInternalError
 	}
 
-	return response.SendResponse(c, fiber.StatusInternalServerError, err)
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
 }
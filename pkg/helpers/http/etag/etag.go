@@ -0,0 +1,47 @@
+package etag
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/response"
+)
+
+// Format returns the strong entity tag of a resource at version
+func Format(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// Matches reports whether an If-Match header lets a request change a resource at version.
+// If-Match uses the strong comparison, weak tags never match.
+func Matches(ifMatch string, version int) bool {
+	tag := Format(version)
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SendResponse sends payload with the ETag of version, or 304 Not Modified when the client's
+// If-None-Match already names it
+func SendResponse(ctx *fiber.Ctx, code int, version int, payload interface{}) error {
+	tag := Format(version)
+	ctx.Set(fiber.HeaderETag, tag)
+
+	if ifNoneMatch := ctx.Get(fiber.HeaderIfNoneMatch); ifNoneMatch != "" && ctx.Method() == fiber.MethodGet {
+		for _, candidate := range strings.Split(ifNoneMatch, ",") {
+			// If-None-Match uses the weak comparison
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == tag {
+				return ctx.SendStatus(fiber.StatusNotModified)
+			}
+		}
+	}
+
+	return response.SendResponse(ctx, code, payload)
+}
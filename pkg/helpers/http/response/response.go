@@ -1,32 +1,52 @@
 package response
 
 import (
+	"net/http"
+
 	"github.com/gofiber/fiber/v2"
-	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
 )
 
+const ContentTypeProblemJSON = "application/problem+json"
+
+// Response is the envelope of every successful response
 type Response struct {
 	Payload interface{} `json:"payload"`
 }
 
+// Problem is an RFC 7807 problem details body, extended with a stable error code,
+// the request id and, for validation failures, the offending fields.
+type Problem struct {
+	Type      string                     `json:"type"`
+	Title     string                     `json:"title"`
+	Status    int                        `json:"status"`
+	Detail    string                     `json:"detail,omitempty"`
+	Instance  string                     `json:"instance,omitempty"`
+	Code      string                     `json:"code"`
+	RequestID string                     `json:"request_id,omitempty"`
+	Errors    validator.ValidationErrors `json:"errors,omitempty"`
+}
+
 func SendResponse(
 	ctx *fiber.Ctx,
 	code int,
 	payload interface{},
 ) error {
-	if code >= 400 {
-		if err, ok := payload.(error); ok {
-			var errPayload any = err
-			if _, ok := err.(domain.SerializableError); !ok {
-				errPayload = err.Error()
-			}
-			payload = fiber.Map{"error": errPayload}
-		}
-	}
-
 	return ctx.Status(code).JSON(
 		Response{
 			Payload: payload,
 		},
 	)
 }
+
+func SendProblem(ctx *fiber.Ctx, problem Problem) error {
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+
+	return ctx.Status(problem.Status).JSON(problem, ContentTypeProblemJSON)
+}
@@ -10,7 +10,7 @@ import (
 )
 
 type JwtInterface interface {
-	Create(userID uuid.UUID, roleName string) (string, error)
+	Create(userID uuid.UUID, email, roleName string) (string, error)
 	Decode(tokenString string, claims *Claims) error
 }
 
@@ -32,6 +32,33 @@ type ClaimsManager struct {
 	Email  string `json:"email"`
 }
 
+// JwtChallengeInterface issues the short-lived token handed out between the password and the
+// second factor of a login. It carries its own audience so it is never accepted as an access token.
+type JwtChallengeInterface interface {
+	CreateChallenge(claims ClaimsChallenge) (string, error)
+	DecodeChallenge(tokenString string, claims *ClaimsChallenge) error
+}
+
+const (
+	ChallengeVerify = "verify"
+	ChallengeEnroll = "enroll"
+)
+
+type ClaimsChallenge struct {
+	jwt.RegisteredClaims
+	Kind      string `json:"kind"`
+	SubjectID string `json:"subject_id"`
+	Email     string `json:"email"`
+	RoleName  string `json:"role_name,omitempty"`
+	// Purpose is ChallengeVerify, or ChallengeEnroll when the account has to set up 2FA first
+	Purpose string `json:"purpose"`
+}
+
+const (
+	audience          = "gogo-manager"
+	challengeAudience = "gogo-manager-mfa"
+)
+
 type JwtStruct struct {
 	SecretKey   string
 	ExpiredTime time.Duration
@@ -55,12 +82,26 @@ func getManagerJwt() JwtManagerInterface {
 	}
 }
 
-func (j *JwtStruct) Create(userID uuid.UUID, roleName string) (string, error) {
+var JwtChallenge = getChallengeJwt()
+
+func getChallengeJwt() JwtChallengeInterface {
+	ttl := env.AppEnv.MFAChallengeTTL
+	if ttl <= 0 {
+		ttl = 5 * time.Minute
+	}
+
+	return &JwtStruct{
+		SecretKey:   env.AppEnv.JwtSecretKey,
+		ExpiredTime: ttl,
+	}
+}
+
+func (j *JwtStruct) Create(userID uuid.UUID, email, roleName string) (string, error) {
 	claims := Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "gogo-manager",
 			Subject:   userID.String(),
-			Audience:  jwt.ClaimStrings{"gogo-manager"},
+			Audience:  jwt.ClaimStrings{audience},
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.ExpiredTime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
@@ -68,6 +109,7 @@ func (j *JwtStruct) Create(userID uuid.UUID, roleName string) (string, error) {
 		},
 		UserID:   userID,
 		RoleName: roleName,
+		Email:    email,
 	}
 
 	unsignedJWT := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -82,7 +124,7 @@ func (j *JwtStruct) Create(userID uuid.UUID, roleName string) (string, error) {
 func (j *JwtStruct) Decode(tokenString string, claims *Claims) error {
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(_ *jwt.Token) (any, error) {
 		return []byte(j.SecretKey), nil
-	})
+	}, jwt.WithAudience(audience))
 
 	if err != nil {
 		return err
@@ -100,14 +142,14 @@ func (j *JwtStruct) CreateManager(userID int, email string) (string, error) {
 		RegisteredClaims: jwt.RegisteredClaims{
 			Issuer:    "gogo-manager",
 			Subject:   email,
-			Audience:  jwt.ClaimStrings{"gogo-manager"},
+			Audience:  jwt.ClaimStrings{audience},
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.ExpiredTime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			ID:        strconv.Itoa(userID),
 		},
 		UserID: userID,
-		Email: email,
+		Email:  email,
 	}
 
 	unsignedJWT := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -122,7 +164,43 @@ func (j *JwtStruct) CreateManager(userID int, email string) (string, error) {
 func (j *JwtStruct) DecodeManager(tokenString string, claims *ClaimsManager) error {
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(_ *jwt.Token) (any, error) {
 		return []byte(j.SecretKey), nil
-	})
+	}, jwt.WithAudience(audience))
+
+	if err != nil {
+		return err
+	}
+
+	if !token.Valid {
+		return jwt.ErrSignatureInvalid
+	}
+
+	return nil
+}
+
+func (j *JwtStruct) CreateChallenge(claims ClaimsChallenge) (string, error) {
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    "gogo-manager",
+		Subject:   claims.Kind + ":" + claims.SubjectID,
+		Audience:  jwt.ClaimStrings{challengeAudience},
+		ExpiresAt: jwt.NewNumericDate(now.Add(j.ExpiredTime)),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+	}
+
+	unsignedJWT := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signedJWT, err := unsignedJWT.SignedString([]byte(j.SecretKey))
+	if err != nil {
+		return "", err
+	}
+
+	return signedJWT, nil
+}
+
+func (j *JwtStruct) DecodeChallenge(tokenString string, claims *ClaimsChallenge) error {
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(_ *jwt.Token) (any, error) {
+		return []byte(j.SecretKey), nil
+	}, jwt.WithAudience(challengeAudience))
 
 	if err != nil {
 		return err
@@ -1,8 +1,11 @@
 package log
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -11,99 +14,120 @@ import (
 
 type LogInfo map[string]interface{}
 
-var logger zerolog.Logger
+var (
+	logger zerolog.Logger
+	// fileWriter is opened once and shared by every logger Configure builds
+	fileWriter *lumberjack.Logger
+)
 
 func GetLogger() *zerolog.Logger {
 	return &logger
 }
 
 func init() {
-	consoleWriter := zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}
+	Configure("debug", "console")
+}
+
+// Configure rebuilds the global logger. format is either "json" or "console";
+// the rotating log file under ./data/logs always receives JSON. It runs at init and again once
+// the env is read, the log file is kept open across both.
+func Configure(level, format string) {
+	lvl, err := zerolog.ParseLevel(strings.ToLower(level))
+	if err != nil || level == "" {
+		lvl = zerolog.DebugLevel
+	}
+
+	var stdout io.Writer = zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}
+	if format == "json" {
+		stdout = os.Stdout
+	}
 
-	logFileName := fmt.Sprintf("./data/logs/app-%s.log", time.Now().Format("2006-01-02"))
-	fileWriter := &lumberjack.Logger{
-		Filename:  logFileName,
-		LocalTime: true,
-		Compress:  true,
+	if fileWriter == nil {
+		fileWriter = &lumberjack.Logger{
+			Filename:  fmt.Sprintf("./data/logs/app-%s.log", time.Now().Format("2006-01-02")),
+			LocalTime: true,
+			Compress:  true,
+		}
 	}
 
-	multi := zerolog.MultiLevelWriter(consoleWriter, fileWriter)
+	multi := zerolog.MultiLevelWriter(stdout, fileWriter)
 
-	logger = zerolog.New(multi).With().Timestamp().Logger()
+	logger = zerolog.New(multi).Level(lvl).With().Timestamp().Logger()
 }
 
-func UpdateContext(key, value string) {
-	logger.UpdateContext(func(c zerolog.Context) zerolog.Context {
-		return c.Str(key, value)
-	})
+// WithContext returns a copy of ctx carrying a logger enriched with fields. The logger is
+// derived from the one already in ctx, or from the global logger when there is none.
+func WithContext(ctx context.Context, fields LogInfo) context.Context {
+	child := FromContext(ctx).With()
+	for key, value := range fields {
+		child = child.Interface(key, redact(key, value))
+	}
+
+	l := child.Logger()
+	return l.WithContext(ctx)
 }
 
-func Trace(fields LogInfo, msg string) {
-	var convFields map[string]interface{} = fields
+// FromContext returns the request-scoped logger stored in ctx, falling back to the global logger.
+func FromContext(ctx context.Context) *zerolog.Logger {
+	if ctx == nil {
+		return &logger
+	}
 
-	event := logger.Trace()
-	for key, value := range convFields {
-		event = event.Interface(key, value)
+	l := zerolog.Ctx(ctx)
+	if l.GetLevel() == zerolog.Disabled {
+		return &logger
 	}
-	event.Msg(msg)
-}
 
-func Debug(fields LogInfo, msg string) {
-	var convFields map[string]interface{} = fields
+	return l
+}
 
-	event := logger.Debug()
-	for key, value := range convFields {
-		event = event.Interface(key, value)
+func write(event *zerolog.Event, fields LogInfo, msg string) {
+	for key, value := range fields {
+		event = event.Interface(key, redact(key, value))
 	}
 	event.Msg(msg)
 }
 
-func Info(fields LogInfo, msg string) {
-	var convFields map[string]interface{} = fields
+func Trace(fields LogInfo, msg string) {
+	write(logger.Trace(), fields, msg)
+}
 
-	event := logger.Info()
-	for key, value := range convFields {
-		event = event.Interface(key, value)
-	}
-	event.Msg(msg)
+func Debug(fields LogInfo, msg string) {
+	write(logger.Debug(), fields, msg)
 }
 
-func Warn(fields LogInfo, msg string) {
-	var convFields map[string]interface{} = fields
+func Info(fields LogInfo, msg string) {
+	write(logger.Info(), fields, msg)
+}
 
-	event := logger.Warn()
-	for key, value := range convFields {
-		event = event.Interface(key, value)
-	}
-	event.Msg(msg)
+func Warn(fields LogInfo, msg string) {
+	write(logger.Warn(), fields, msg)
 }
 
 func Error(fields LogInfo, msg string) {
-	var convFields map[string]interface{} = fields
-
-	event := logger.Error()
-	for key, value := range convFields {
-		event = event.Interface(key, value)
-	}
-	event.Msg(msg)
+	write(logger.Error(), fields, msg)
 }
 
 func Fatal(fields LogInfo, msg string) {
-	var convFields map[string]interface{} = fields
-
-	event := logger.Fatal()
-	for key, value := range convFields {
-		event = event.Interface(key, value)
-	}
-	event.Msg(msg)
+	write(logger.Fatal(), fields, msg)
 }
 
 func Panic(fields LogInfo, msg string) {
-	var convFields map[string]interface{} = fields
+	write(logger.Panic(), fields, msg)
+}
 
-	event := logger.Panic()
-	for key, value := range convFields {
-		event = event.Interface(key, value)
-	}
-	event.Msg(msg)
+func DebugCtx(ctx context.Context, fields LogInfo, msg string) {
+	write(FromContext(ctx).Debug(), fields, msg)
+}
+
+func InfoCtx(ctx context.Context, fields LogInfo, msg string) {
+	write(FromContext(ctx).Info(), fields, msg)
+}
+
+func WarnCtx(ctx context.Context, fields LogInfo, msg string) {
+	write(FromContext(ctx).Warn(), fields, msg)
+}
+
+func ErrorCtx(ctx context.Context, fields LogInfo, msg string) {
+	write(FromContext(ctx).Error(), fields, msg)
 }
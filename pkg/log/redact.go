@@ -0,0 +1,105 @@
+package log
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// sensitiveKeys are compared after lower-casing and stripping "_" and "-",
+// so identityNumber, identity_number and identity-number all match.
+var sensitiveKeys = map[string]func(string) string{
+	"password":       func(string) string { return redacted },
+	"newpassword":    func(string) string { return redacted },
+	"token":          func(string) string { return redacted },
+	"identitynumber": maskIdentityNumber,
+	"email":          maskEmail,
+}
+
+func normalizeKey(key string) string {
+	key = strings.ToLower(key)
+	key = strings.ReplaceAll(key, "_", "")
+	return strings.ReplaceAll(key, "-", "")
+}
+
+// redact masks value when key names a sensitive field. Structs, maps and slices are
+// walked through their JSON representation so nested DTO fields are masked as well.
+func redact(key string, value interface{}) interface{} {
+	if mask, ok := sensitiveKeys[normalizeKey(key)]; ok {
+		if value == nil {
+			return nil
+		}
+
+		if s, ok := value.(string); ok {
+			return mask(s)
+		}
+
+		return redacted
+	}
+
+	if value == nil {
+		return nil
+	}
+
+	switch reflect.Indirect(reflect.ValueOf(value)).Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+	default:
+		return value
+	}
+
+	if _, ok := value.(error); ok {
+		return value
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return value
+	}
+
+	return redactTree(decoded)
+}
+
+func redactTree(node interface{}) interface{} {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		for key, child := range v {
+			if _, ok := sensitiveKeys[normalizeKey(key)]; ok {
+				v[key] = redact(key, child)
+				continue
+			}
+			v[key] = redactTree(child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = redactTree(child)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func maskEmail(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at <= 0 {
+		return redacted
+	}
+
+	return email[:1] + "***" + email[at:]
+}
+
+func maskIdentityNumber(identityNumber string) string {
+	if len(identityNumber) <= 4 {
+		return "****"
+	}
+
+	return "****" + identityNumber[len(identityNumber)-4:]
+}
@@ -0,0 +1,25 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+)
+
+// logMailer writes emails to the log instead of sending them, for development
+type logMailer struct{}
+
+func NewLogMailer() MailerInterface {
+	return &logMailer{}
+}
+
+func (m *logMailer) Send(ctx context.Context, msg Message) error {
+	// The body carries one-time tokens; it is only ever logged by this development driver
+	log.InfoCtx(ctx, log.LogInfo{
+		"email":   msg.To,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	}, "[MAILER][Send] email not sent, log driver in use")
+
+	return nil
+}
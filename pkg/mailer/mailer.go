@@ -0,0 +1,45 @@
+package mailer
+
+import (
+	"context"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/env"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type MailerInterface interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+const (
+	DriverLog  = "log"
+	DriverSMTP = "smtp"
+)
+
+var Mailer = getMailer()
+
+func getMailer() MailerInterface {
+	switch env.AppEnv.MailDriver {
+	case DriverSMTP:
+		return NewSMTPMailer(SMTPConfig{
+			Host:     env.AppEnv.SMTPHost,
+			Port:     env.AppEnv.SMTPPort,
+			Username: env.AppEnv.SMTPUsername,
+			Password: env.AppEnv.SMTPPassword,
+			From:     env.AppEnv.MailFrom,
+		})
+	case DriverLog, "":
+		return NewLogMailer()
+	default:
+		log.Fatal(log.LogInfo{
+			"driver": env.AppEnv.MailDriver,
+		}, "[MAILER][getMailer] unknown mail driver")
+		return nil
+	}
+}
@@ -0,0 +1,168 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gogomanager"
+
+type MetricsInterface interface {
+	ObserveRequest(method, route string, status int, duration time.Duration)
+	ObserveUpload(size int64, result string)
+	EmployeeCreated(tenant string)
+	EmployeeDeleted(tenant string)
+	DepartmentCreated(tenant string)
+	DepartmentDeleted(tenant string)
+	RegisterDB(name string, db *sql.DB)
+	Handler() http.Handler
+}
+
+type MetricsStruct struct {
+	registry           *prometheus.Registry
+	requestsTotal      *prometheus.CounterVec
+	requestDuration    *prometheus.HistogramVec
+	uploadsTotal       *prometheus.CounterVec
+	uploadSizeBytes    prometheus.Histogram
+	employeesCreated   *prometheus.CounterVec
+	employeesDeleted   *prometheus.CounterVec
+	departmentsCreated *prometheus.CounterVec
+	departmentsDeleted *prometheus.CounterVec
+}
+
+var Metrics = getMetrics()
+
+func getMetrics() MetricsInterface {
+	registry := prometheus.NewRegistry()
+
+	m := &MetricsStruct{
+		registry: registry,
+		requestsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "requests_total",
+			Help:      "Total number of HTTP requests by method, route template and status code.",
+		}, []string{"method", "route", "status"}),
+		requestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "http",
+			Name:      "request_duration_seconds",
+			Help:      "HTTP request latency by method, route template and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		uploadsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "upload",
+			Name:      "files_total",
+			Help:      "Total number of file uploads by result.",
+		}, []string{"result"}),
+		uploadSizeBytes: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "upload",
+			Name:      "file_size_bytes",
+			Help:      "Size of successfully uploaded files.",
+			Buckets:   prometheus.ExponentialBuckets(1024, 2, 8), // 1 KiB .. 128 KiB
+		}),
+		employeesCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "employee",
+			Name:      "created_total",
+			Help:      "Total number of employees created per tenant.",
+		}, []string{"tenant"}),
+		employeesDeleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "employee",
+			Name:      "deleted_total",
+			Help:      "Total number of employees deleted per tenant.",
+		}, []string{"tenant"}),
+		departmentsCreated: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "department",
+			Name:      "created_total",
+			Help:      "Total number of departments created per tenant.",
+		}, []string{"tenant"}),
+		departmentsDeleted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "department",
+			Name:      "deleted_total",
+			Help:      "Total number of departments deleted per tenant.",
+		}, []string{"tenant"}),
+	}
+
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.requestsTotal,
+		m.requestDuration,
+		m.uploadsTotal,
+		m.uploadSizeBytes,
+		m.employeesCreated,
+		m.employeesDeleted,
+		m.departmentsCreated,
+		m.departmentsDeleted,
+	)
+
+	return m
+}
+
+func (m *MetricsStruct) ObserveRequest(method, route string, status int, duration time.Duration) {
+	statusStr := strconv.Itoa(status)
+
+	m.requestsTotal.WithLabelValues(method, route, statusStr).Inc()
+	m.requestDuration.WithLabelValues(method, route, statusStr).Observe(duration.Seconds())
+}
+
+func (m *MetricsStruct) ObserveUpload(size int64, result string) {
+	m.uploadsTotal.WithLabelValues(result).Inc()
+
+	if result == UploadResultSuccess {
+		m.uploadSizeBytes.Observe(float64(size))
+	}
+}
+
+func (m *MetricsStruct) EmployeeCreated(tenant string) {
+	m.employeesCreated.WithLabelValues(tenant).Inc()
+}
+
+func (m *MetricsStruct) EmployeeDeleted(tenant string) {
+	m.employeesDeleted.WithLabelValues(tenant).Inc()
+}
+
+func (m *MetricsStruct) DepartmentCreated(tenant string) {
+	m.departmentsCreated.WithLabelValues(tenant).Inc()
+}
+
+func (m *MetricsStruct) DepartmentDeleted(tenant string) {
+	m.departmentsDeleted.WithLabelValues(tenant).Inc()
+}
+
+// RegisterDB exposes the sql.DB pool statistics (open, in use, idle, wait count, ...) as gauges.
+func (m *MetricsStruct) RegisterDB(name string, db *sql.DB) {
+	err := m.registry.Register(collectors.NewDBStatsCollector(db, name))
+	if err != nil {
+		log.Error(log.LogInfo{
+			"error": err.Error(),
+		}, "[METRICS][RegisterDB] failed to register db stats collector")
+	}
+}
+
+func (m *MetricsStruct) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		Registry: m.registry,
+	})
+}
+
+const (
+	UploadResultSuccess          = "success"
+	UploadResultMissingFile      = "missing_file"
+	UploadResultInvalidExtension = "invalid_extension"
+	UploadResultTooLarge         = "too_large"
+	UploadResultFailed           = "failed"
+)
@@ -0,0 +1,9 @@
+package openapi
+
+import _ "embed"
+
+// DocsPage renders /docs/openapi.json without loading anything from a CDN,
+// so it keeps working under the Helmet cross-origin policies
+//
+//go:embed docs.html
+var DocsPage []byte
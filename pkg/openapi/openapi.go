@@ -0,0 +1,383 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	ContentTypeJSON      = "application/json"
+	ContentTypeProblem   = "application/problem+json"
+	ContentTypeMultipart = "multipart/form-data"
+	ContentTypeZip       = "application/zip"
+	ContentTypeMerge     = "application/merge-patch+json"
+	ContentTypeEvents    = "text/event-stream"
+	ContentTypeSCIM      = "application/scim+json"
+)
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+// PathItem maps a lower-case HTTP method to its operation
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Tags        []string              `json:"tags,omitempty"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// Route documents one registered route. Query, Body and Response are zero values of the
+// DTOs the handler binds and returns; their schemas are generated from the struct tags.
+type Route struct {
+	Method  string
+	Path    string
+	Tag     string
+	Summary string
+	// Secured routes require a bearer token
+	Secured bool
+	// APIKeyScopes lists the scopes that let an API key call a secured route instead of a token
+	APIKeyScopes []string
+	// Headers lists optional request headers the route understands
+	Headers []string
+	Query   interface{}
+	Body    interface{}
+	// MergePatch marks Body as a JSON merge patch, sent as application/merge-patch+json or plain JSON
+	MergePatch bool
+	// Multipart lists the file fields of a multipart/form-data body, used instead of Body
+	Multipart []string
+	Status    int
+	Response  interface{}
+	// Download is the media type of a file sent as is, used instead of Response
+	Download string
+	// Events is one of the server-sent events the route streams, used instead of Response
+	Events interface{}
+	// SCIM routes take and return application/scim+json bodies without the payload envelope.
+	// They are called with an API key holding one of APIKeyScopes, sent as a bearer token.
+	SCIM bool
+	// Unwrapped responses are sent as is, without the payload envelope
+	Unwrapped bool
+	// Error is the body of failed responses, used instead of the problem
+	Error interface{}
+}
+
+type Spec struct {
+	doc *Document
+	gen *generator
+}
+
+const (
+	BearerAuth = "bearerAuth"
+	APIKeyAuth = "apiKeyAuth"
+	// APIKeyBearerAuth is an API key sent as a bearer token
+	APIKeyBearerAuth = "apiKeyBearerAuth"
+)
+
+func New(title, version string, problem interface{}) *Spec {
+	gen := newGenerator()
+	gen.schemaFor(reflect.TypeOf(problem))
+
+	return &Spec{
+		doc: &Document{
+			OpenAPI: "3.0.3",
+			Info: Info{
+				Title:   title,
+				Version: version,
+			},
+			Paths: map[string]*PathItem{},
+			Components: Components{
+				Schemas: gen.schemas,
+				SecuritySchemes: map[string]SecurityScheme{
+					BearerAuth: {
+						Type:         "http",
+						Scheme:       "bearer",
+						BearerFormat: "JWT",
+					},
+					APIKeyAuth: {
+						Type: "apiKey",
+						In:   "header",
+						Name: "X-API-Key",
+					},
+					APIKeyBearerAuth: {
+						Type:         "http",
+						Scheme:       "bearer",
+						BearerFormat: "API key",
+					},
+				},
+			},
+		},
+		gen: gen,
+	}
+}
+
+var pathParam = regexp.MustCompile(`\{([^}]+)\}`)
+
+func (s *Spec) Add(r Route) {
+	item, ok := s.doc.Paths[r.Path]
+	if !ok {
+		item = &PathItem{}
+		s.doc.Paths[r.Path] = item
+	}
+
+	op := &Operation{
+		Summary:     r.Summary,
+		OperationID: operationID(r.Method, r.Path),
+		Responses:   map[string]Response{},
+	}
+
+	if r.Tag != "" {
+		op.Tags = []string{r.Tag}
+	}
+
+	if r.Secured {
+		op.Security = []map[string][]string{{BearerAuth: {}}}
+	}
+
+	if r.Secured && len(r.APIKeyScopes) > 0 {
+		op.Security = append(op.Security, map[string][]string{APIKeyAuth: {}})
+		op.Description = "API keys need one of the scopes: " + strings.Join(r.APIKeyScopes, ", ")
+	}
+
+	if r.SCIM {
+		op.Security = []map[string][]string{{APIKeyBearerAuth: {}}, {APIKeyAuth: {}}}
+		op.Description = "API keys need one of the scopes: " + strings.Join(r.APIKeyScopes, ", ")
+	}
+
+	for _, match := range pathParam.FindAllStringSubmatch(r.Path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+
+	for _, header := range r.Headers {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:   header,
+			In:     "header",
+			Schema: &Schema{Type: "string"},
+		})
+	}
+
+	if r.Query != nil {
+		op.Parameters = append(op.Parameters, s.gen.parameters(reflect.TypeOf(r.Query), "query")...)
+	}
+
+	if r.Body != nil {
+		body := MediaType{Schema: s.gen.schemaFor(reflect.TypeOf(r.Body))}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]MediaType{ContentTypeJSON: body},
+		}
+		if r.MergePatch {
+			op.RequestBody.Content[ContentTypeMerge] = body
+		}
+		if r.SCIM {
+			op.RequestBody.Content[ContentTypeSCIM] = body
+		}
+	}
+
+	if len(r.Multipart) > 0 {
+		form := &Schema{
+			Type:       "object",
+			Properties: map[string]*Schema{},
+			Required:   r.Multipart,
+		}
+		for _, field := range r.Multipart {
+			form.Properties[field] = &Schema{Type: "string", Format: "binary"}
+		}
+
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content: map[string]MediaType{
+				ContentTypeMultipart: {Schema: form},
+			},
+		}
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	// Successful bodies are wrapped in the {"payload": ...} envelope
+	envelope := &Schema{
+		Type: "object",
+		Properties: map[string]*Schema{
+			"payload": {},
+		},
+	}
+	if r.Response != nil {
+		envelope.Properties["payload"] = s.gen.schemaFor(reflect.TypeOf(r.Response))
+	}
+
+	content := map[string]MediaType{
+		ContentTypeJSON: {Schema: envelope},
+	}
+	if r.Unwrapped && r.Response != nil {
+		content = map[string]MediaType{
+			ContentTypeJSON: {Schema: s.gen.schemaFor(reflect.TypeOf(r.Response))},
+		}
+	}
+	if r.Download != "" {
+		content = map[string]MediaType{
+			r.Download: {Schema: &Schema{Type: "string", Format: "binary"}},
+		}
+	}
+	if r.Events != nil {
+		content = map[string]MediaType{
+			ContentTypeEvents: {Schema: s.gen.schemaFor(reflect.TypeOf(r.Events))},
+		}
+	}
+	if r.SCIM {
+		content = map[string]MediaType{}
+		if r.Response != nil {
+			content[ContentTypeSCIM] = MediaType{Schema: s.gen.schemaFor(reflect.TypeOf(r.Response))}
+		}
+	}
+
+	op.Responses[fmt.Sprint(status)] = Response{
+		Description: http.StatusText(status),
+		Content:     content,
+	}
+
+	errorContent := map[string]MediaType{
+		ContentTypeProblem: {Schema: &Schema{Ref: refPrefix + "Problem"}},
+	}
+	if r.Error != nil {
+		errorType := ContentTypeJSON
+		if r.SCIM {
+			errorType = ContentTypeSCIM
+		}
+
+		errorContent = map[string]MediaType{
+			errorType: {Schema: s.gen.schemaFor(reflect.TypeOf(r.Error))},
+		}
+	}
+
+	op.Responses["default"] = Response{
+		Description: "Error",
+		Content:     errorContent,
+	}
+
+	(*item)[strings.ToLower(r.Method)] = op
+}
+
+func (s *Spec) Document() *Document {
+	return s.doc
+}
+
+// Has reports whether method and path, written in the OpenAPI {param} form, are documented
+func (s *Spec) Has(method, path string) bool {
+	item, ok := s.doc.Paths[path]
+	if !ok {
+		return false
+	}
+
+	_, ok = (*item)[strings.ToLower(method)]
+	return ok
+}
+
+func operationID(method, path string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+	for _, segment := range strings.Split(path, "/") {
+		segment = strings.Trim(segment, "{}")
+		if segment == "" {
+			continue
+		}
+		b.WriteString(strings.ToUpper(segment[:1]) + segment[1:])
+	}
+
+	return b.String()
+}
+
+var fiberParam = regexp.MustCompile(`:([A-Za-z0-9_]+)\??`)
+
+// NormalizePath converts a fiber route path such as /v1/employee/:identityNumber/ to the
+// OpenAPI form /v1/employee/{identityNumber}
+func NormalizePath(path string) string {
+	if len(path) > 1 {
+		path = strings.TrimSuffix(path, "/")
+	}
+
+	return fiberParam.ReplaceAllString(path, "{$1}")
+}
+
+// Undocumented returns "METHOD path" for every registered route missing from the spec.
+// routes holds method and fiber path pairs; HEAD routes fiber adds for GET handlers are skipped.
+func (s *Spec) Undocumented(routes [][2]string, ignore map[string]bool) []string {
+	seen := map[string]bool{}
+	var missing []string
+
+	for _, route := range routes {
+		method, path := route[0], NormalizePath(route[1])
+		key := method + " " + path
+
+		if method == http.MethodHead || seen[key] || ignore[key] {
+			continue
+		}
+		seen[key] = true
+
+		if !s.Has(method, path) {
+			missing = append(missing, key)
+		}
+	}
+
+	sort.Strings(missing)
+
+	return missing
+}
@@ -0,0 +1,282 @@
+package openapi
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const refPrefix = "#/components/schemas/"
+
+type Schema struct {
+	Ref         string             `json:"$ref,omitempty"`
+	Type        string             `json:"type,omitempty"`
+	Format      string             `json:"format,omitempty"`
+	Pattern     string             `json:"pattern,omitempty"`
+	Enum        []string           `json:"enum,omitempty"`
+	MinLength   *int               `json:"minLength,omitempty"`
+	MaxLength   *int               `json:"maxLength,omitempty"`
+	Minimum     *float64           `json:"minimum,omitempty"`
+	Maximum     *float64           `json:"maximum,omitempty"`
+	MinItems    *int               `json:"minItems,omitempty"`
+	MaxItems    *int               `json:"maxItems,omitempty"`
+	Nullable    bool               `json:"nullable,omitempty"`
+	Items       *Schema            `json:"items,omitempty"`
+	Properties  map[string]*Schema `json:"properties,omitempty"`
+	Required    []string           `json:"required,omitempty"`
+	Description string             `json:"description,omitempty"`
+}
+
+type generator struct {
+	schemas map[string]*Schema
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: map[string]*Schema{},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// wrapper is implemented by field types standing for another type, like merge patch members
+type wrapper interface {
+	ElemType() reflect.Type
+}
+
+// unwrap returns the type a wrapper stands for
+func unwrap(t reflect.Type) (reflect.Type, bool) {
+	if !t.Implements(reflect.TypeOf((*wrapper)(nil)).Elem()) {
+		return t, false
+	}
+
+	return reflect.Zero(t).Interface().(wrapper).ElemType(), true
+}
+
+// schemaFor returns the schema of t. Named structs are stored once under components and
+// referenced, so a DTO used by several routes is described in a single place.
+func (g *generator) schemaFor(t reflect.Type) *Schema {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if elem, ok := unwrap(t); ok {
+		return g.schemaFor(elem)
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Name() == "UUID" && strings.HasSuffix(t.PkgPath(), "google/uuid"):
+		return &Schema{Type: "string", Format: "uuid"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: g.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object"}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+
+		if _, ok := g.schemas[t.Name()]; !ok {
+			// Reserve the name first so self-referencing types terminate
+			g.schemas[t.Name()] = &Schema{}
+			*g.schemas[t.Name()] = *g.structSchema(t)
+		}
+
+		return &Schema{Ref: refPrefix + t.Name()}
+	default:
+		return &Schema{}
+	}
+}
+
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{
+		Type:       "object",
+		Properties: map[string]*Schema{},
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			embedded := g.structSchema(field.Type)
+			for name, prop := range embedded.Properties {
+				schema.Properties[name] = prop
+			}
+			schema.Required = append(schema.Required, embedded.Required...)
+			continue
+		}
+
+		name, ok := fieldName(field, "json")
+		if !ok {
+			continue
+		}
+
+		prop := g.schemaFor(field.Type)
+		if field.Type.Kind() == reflect.Ptr && prop.Ref == "" {
+			prop.Nullable = true
+		}
+
+		required := applyValidation(prop, field)
+		if _, ok := unwrap(field.Type); ok {
+			// A merge patch member can always be left out, "required" only forbids null
+			prop.Nullable = !required
+			required = false
+		}
+
+		if required {
+			schema.Required = append(schema.Required, name)
+		}
+
+		schema.Properties[name] = prop
+	}
+
+	return schema
+}
+
+// parameters describes the fields of a struct bound with ctx.QueryParser as parameters in the given location
+func (g *generator) parameters(t reflect.Type, in string) []Parameter {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var params []Parameter
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		name, ok := fieldName(field, in)
+		if !ok || !field.IsExported() {
+			continue
+		}
+
+		schema := g.schemaFor(field.Type)
+		required := applyValidation(schema, field)
+
+		params = append(params, Parameter{
+			Name:     name,
+			In:       in,
+			Required: required,
+			Schema:   schema,
+		})
+	}
+
+	return params
+}
+
+func fieldName(field reflect.StructField, tag string) (string, bool) {
+	value, ok := field.Tag.Lookup(tag)
+	if !ok {
+		if tag == "json" {
+			return field.Name, true
+		}
+		return "", false
+	}
+
+	name := strings.Split(value, ",")[0]
+	if name == "-" {
+		return "", false
+	}
+	if name == "" {
+		name = field.Name
+	}
+
+	return name, true
+}
+
+// applyValidation translates the go-playground validate tag of field into schema constraints
+// and reports whether the field is required. Rules after "dive" apply to slice elements and
+// are ignored.
+func applyValidation(schema *Schema, field reflect.StructField) bool {
+	tag, ok := field.Tag.Lookup("validate")
+	if !ok || schema.Ref != "" {
+		return strings.Contains(tag, "required")
+	}
+
+	fieldType, _ := unwrap(field.Type)
+	kind := fieldType.Kind()
+	if kind == reflect.Ptr {
+		kind = fieldType.Elem().Kind()
+	}
+
+	required := false
+	for _, rule := range strings.Split(tag, ",") {
+		name, param, _ := strings.Cut(rule, "=")
+
+		switch name {
+		case "dive":
+			return required
+		case "required":
+			required = true
+		case "email":
+			schema.Format = "email"
+		case "url", "uri":
+			schema.Format = "uri"
+		case "uuid", "uuid4", "uuid7":
+			schema.Format = "uuid"
+		case "ascii":
+			schema.Pattern = `^[\x00-\x7F]*$`
+		case "numeric", "number":
+			if kind == reflect.String {
+				schema.Pattern = `^[0-9]+$`
+			}
+		case "oneof":
+			schema.Enum = strings.Fields(param)
+		case "min", "gte":
+			setBound(schema, kind, param, true)
+		case "max", "lte":
+			setBound(schema, kind, param, false)
+		case "len":
+			setBound(schema, kind, param, true)
+			setBound(schema, kind, param, false)
+		}
+	}
+
+	return required
+}
+
+func setBound(schema *Schema, kind reflect.Kind, param string, lower bool) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return
+	}
+
+	switch kind {
+	case reflect.String:
+		i := int(n)
+		if lower {
+			schema.MinLength = &i
+		} else {
+			schema.MaxLength = &i
+		}
+	case reflect.Slice, reflect.Array:
+		i := int(n)
+		if lower {
+			schema.MinItems = &i
+		} else {
+			schema.MaxItems = &i
+		}
+	default:
+		if lower {
+			schema.Minimum = &n
+		} else {
+			schema.Maximum = &n
+		}
+	}
+}
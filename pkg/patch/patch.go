@@ -0,0 +1,79 @@
+// Package patch implements the members of JSON merge patch (RFC 7396) request bodies.
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+)
+
+// Field is a member of a merge patch document. Unlike a pointer it tells a member that was left
+// out, which keeps the current value, from an explicit null, which clears it.
+type Field[T any] struct {
+	value   T
+	present bool
+	null    bool
+}
+
+// Value returns a field setting v
+func Value[T any](v T) Field[T] {
+	return Field[T]{value: v, present: true}
+}
+
+// Null returns a field clearing the member
+func Null[T any]() Field[T] {
+	return Field[T]{present: true, null: true}
+}
+
+func (f *Field[T]) UnmarshalJSON(data []byte) error {
+	f.present = true
+	f.null = bytes.Equal(bytes.TrimSpace(data), []byte("null"))
+
+	var zero T
+	f.value = zero
+	if f.null {
+		return nil
+	}
+
+	return json.Unmarshal(data, &f.value)
+}
+
+// Present reports whether the document has the member, null or not
+func (f Field[T]) Present() bool {
+	return f.present
+}
+
+// IsNull reports whether the member is an explicit null
+func (f Field[T]) IsNull() bool {
+	return f.present && f.null
+}
+
+// Get returns the value the member sets, ok is false when it is absent or null
+func (f Field[T]) Get() (value T, ok bool) {
+	return f.value, f.present && !f.null
+}
+
+// Apply returns current patched by the member: unchanged when absent, the zero value when null
+func (f Field[T]) Apply(current T) T {
+	if !f.present {
+		return current
+	}
+
+	return f.value
+}
+
+// ValidationValue is what the validator checks in place of the field: a nil pointer when absent,
+// so `omitnil` skips it, and the zero value for null, so `omitempty` lets a nullable member be
+// cleared while `required` forbids it
+func (f Field[T]) ValidationValue() any {
+	if !f.present {
+		return (*T)(nil)
+	}
+
+	return f.value
+}
+
+// ElemType is the type of the member's value, for schema generation
+func (f Field[T]) ElemType() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
@@ -0,0 +1,71 @@
+// Package pgnotify receives Postgres notifications on a connection of its own, the pool's
+// connections are handed out per query and cannot stay subscribed to a channel.
+package pgnotify
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+)
+
+const reconnectDelay = 5 * time.Second
+
+type ListenerInterface interface {
+	// Listen calls handle with the payload of every notification on channel until ctx is cancelled.
+	// Notifications sent while the connection is down are lost, connected is called on every
+	// (re)connection so the caller can catch up.
+	Listen(ctx context.Context, channel string, connected func(), handle func(payload string))
+}
+
+type listener struct {
+	dsn string
+}
+
+func NewListener(dsn string) ListenerInterface {
+	return &listener{dsn: dsn}
+}
+
+func (l *listener) Listen(ctx context.Context, channel string, connected func(), handle func(payload string)) {
+	for {
+		err := l.listen(ctx, channel, connected, handle)
+		if ctx.Err() != nil {
+			return
+		}
+
+		log.ErrorCtx(ctx, log.LogInfo{
+			"channel": channel,
+			"error":   err.Error(),
+		}, "[PGNOTIFY][Listen] lost listener connection, reconnecting")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+func (l *listener) listen(ctx context.Context, channel string, connected func(), handle func(payload string)) error {
+	conn, err := pgx.Connect(ctx, l.dsn)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+
+	connected()
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		handle(notification.Payload)
+	}
+}
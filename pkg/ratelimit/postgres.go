@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+)
+
+const (
+	// The refill is computed from the database clock, so instances with skewed clocks agree
+	queryTake = `
+	INSERT INTO rate_limit_buckets AS b (key, tokens, capacity, rate, allowed, updated_at)
+	VALUES ($1, $2 - 1, $2, $3, TRUE, NOW())
+	ON CONFLICT (key) DO UPDATE SET
+		allowed = LEAST($2, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $3) >= 1,
+		tokens = LEAST($2, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $3)
+			- CASE WHEN LEAST($2, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $3) >= 1 THEN 1 ELSE 0 END,
+		capacity = $2,
+		rate = $3,
+		updated_at = NOW()
+	RETURNING tokens, allowed`
+	querySweep = `
+	DELETE FROM rate_limit_buckets
+	WHERE updated_at + make_interval(secs => (capacity - tokens) / rate) < NOW()`
+)
+
+// PostgresStore shares buckets between instances through the rate_limit_buckets table
+type PostgresStore struct {
+	db    *sql.DB
+	takes atomic.Int64
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	var (
+		tokens  float64
+		allowed bool
+	)
+
+	err := s.db.QueryRowContext(ctx, queryTake, key, float64(limit.Requests), limit.rate()).Scan(&tokens, &allowed)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if s.takes.Add(1)%sweepEvery == 0 {
+		go s.sweep()
+	}
+
+	return newResult(limit, tokens, allowed), nil
+}
+
+// sweep forgets buckets that are full again, they behave exactly like missing ones
+func (s *PostgresStore) sweep() {
+	if _, err := s.db.ExecContext(context.Background(), querySweep); err != nil {
+		log.Warn(log.LogInfo{
+			"error": err.Error(),
+		}, "[RATELIMIT][PostgresStore] failed to sweep idle buckets")
+	}
+}
@@ -0,0 +1,150 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Classes group endpoints that share a limit
+const (
+	ClassAuth   = "auth"
+	ClassUpload = "upload"
+	ClassRead   = "read"
+	ClassWrite  = "write"
+)
+
+// TenantClass is the class of the tenant quota shared by every client of a tenant in class
+func TenantClass(class string) string {
+	return "tenant_" + class
+}
+
+const (
+	StoreMemory   = "memory"
+	StorePostgres = "postgres"
+	StoreRedis    = "redis"
+)
+
+// Limit is a token bucket holding up to Requests tokens and refilling completely over Period,
+// so a client can burst Requests at once and then sustain Requests per Period.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+func (l Limit) unlimited() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// ParseLimit reads limits written as "<requests>/<period>", e.g. "60/1m". "off" disables the limit.
+func ParseLimit(s string) (Limit, error) {
+	if s == "off" {
+		return Limit{}, nil
+	}
+
+	requests, period, found := strings.Cut(s, "/")
+	if !found {
+		return Limit{}, fmt.Errorf("ratelimit: invalid limit %q, expected <requests>/<period>", s)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid request count in %q", s)
+	}
+
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("ratelimit: invalid period in %q", s)
+	}
+
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Result describes the bucket after a request took, or failed to take, a token
+type Result struct {
+	Allowed   bool
+	Limit     Limit
+	Remaining int
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next token, set when the request was refused
+	RetryAfter time.Duration
+}
+
+func newResult(limit Limit, tokens float64, allowed bool) Result {
+	rate := limit.rate()
+	res := Result{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     seconds((float64(limit.Requests) - tokens) / rate),
+	}
+
+	if !allowed {
+		res.RetryAfter = seconds((1 - tokens) / rate)
+	}
+
+	return res
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Max(0, s) * float64(time.Second))
+}
+
+// Store keeps the buckets. Take refills the bucket of key for the time elapsed since its last
+// use and removes one token if there is one, atomically across every instance sharing the store.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+var ErrUnknownClass = errors.New("ratelimit: unknown class")
+
+type Limiter struct {
+	store      Store
+	limits     map[string]Limit
+	failClosed bool
+}
+
+// New returns a limiter taking tokens from store. When the store fails, requests are let through
+// unless failClosed is set, see FailsClosed.
+func New(store Store, limits map[string]Limit, failClosed bool) *Limiter {
+	return &Limiter{
+		store:      store,
+		limits:     limits,
+		failClosed: failClosed,
+	}
+}
+
+// FailsClosed reports whether requests are refused while the store fails rather than let through
+// without limits
+func (l *Limiter) FailsClosed() bool {
+	return l.failClosed
+}
+
+// Allow takes a token from the bucket of key in class. ok is false when the class is unlimited,
+// in which case there is nothing to report to the client.
+func (l *Limiter) Allow(ctx context.Context, class, key string) (res Result, ok bool, err error) {
+	limit, found := l.limits[class]
+	if !found {
+		return Result{}, false, ErrUnknownClass
+	}
+
+	if limit.unlimited() {
+		return Result{}, false, nil
+	}
+
+	res, err = l.store.Take(ctx, class+":"+key, limit)
+	if err != nil {
+		return Result{}, false, err
+	}
+
+	return res, true, nil
+}
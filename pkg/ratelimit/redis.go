@@ -0,0 +1,248 @@
+package ratelimit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// takeScript refills and takes from a hash holding the tokens and the last refill time in
+// milliseconds. It uses the server clock, so instances with skewed clocks agree.
+const takeScript = `
+local capacity = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'updated')
+local tokens = tonumber(state[1]) or capacity
+local updated = tonumber(state[2]) or now
+tokens = math.min(capacity, tokens + math.max(0, now - updated) / 1000 * rate)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'updated', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil((capacity - tokens) / rate * 1000) + 1000)
+return {tostring(tokens), allowed}`
+
+const (
+	redisDialTimeout = 3 * time.Second
+	redisIOTimeout   = 2 * time.Second
+	redisMaxIdle     = 8
+)
+
+var errRedisProtocol = errors.New("ratelimit: malformed redis reply")
+
+// RedisStore shares buckets between instances through any server speaking the Redis
+// protocol (Redis, Valkey, KeyDB, Dragonfly). It only needs EVAL, so it carries a small
+// client instead of a full driver.
+type RedisStore struct {
+	addr     string
+	username string
+	password string
+	db       int
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+// NewRedisStore accepts redis://[[user]:password@]host:port[/db]
+func NewRedisStore(rawURL string) (*RedisStore, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "redis" || u.Host == "" {
+		return nil, fmt.Errorf("ratelimit: invalid redis url %q", rawURL)
+	}
+
+	s := &RedisStore{addr: u.Host}
+	if u.Port() == "" {
+		s.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+
+	if u.User != nil {
+		s.username = u.User.Username()
+		s.password, _ = u.User.Password()
+	}
+
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if s.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("ratelimit: invalid redis database %q", db)
+		}
+	}
+
+	return s, nil
+}
+
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	conn, err := s.get(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+
+	reply, err := conn.do(ctx, "EVAL", takeScript, "1", key,
+		strconv.Itoa(limit.Requests), strconv.FormatFloat(limit.rate(), 'g', -1, 64))
+	if err != nil {
+		conn.Close()
+		return Result{}, err
+	}
+	s.put(conn)
+
+	values, ok := reply.([]any)
+	if !ok || len(values) != 2 {
+		return Result{}, errRedisProtocol
+	}
+
+	raw, _ := values[0].(string)
+	tokens, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return Result{}, errRedisProtocol
+	}
+
+	allowed, _ := values[1].(int64)
+
+	return newResult(limit, tokens, allowed == 1), nil
+}
+
+func (s *RedisStore) get(ctx context.Context) (*redisConn, error) {
+	s.mu.Lock()
+	if n := len(s.idle); n > 0 {
+		conn := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+		return conn, nil
+	}
+	s.mu.Unlock()
+
+	dialer := net.Dialer{Timeout: redisDialTimeout}
+	nc, err := dialer.DialContext(ctx, "tcp", s.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &redisConn{Conn: nc, r: bufio.NewReader(nc)}
+
+	if s.password != "" {
+		args := []string{"AUTH", s.password}
+		if s.username != "" {
+			args = []string{"AUTH", s.username, s.password}
+		}
+
+		if _, err := conn.do(ctx, args...); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if s.db != 0 {
+		if _, err := conn.do(ctx, "SELECT", strconv.Itoa(s.db)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return conn, nil
+}
+
+func (s *RedisStore) put(conn *redisConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.idle) >= redisMaxIdle {
+		conn.Close()
+		return
+	}
+
+	s.idle = append(s.idle, conn)
+}
+
+type redisConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *redisConn) do(ctx context.Context, args ...string) (any, error) {
+	deadline := time.Now().Add(redisIOTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	if err := c.SetDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if _, err := c.Write([]byte(b.String())); err != nil {
+		return nil, err
+	}
+
+	return c.read()
+}
+
+// read decodes one RESP2 reply: bulk strings become string, integers int64 and arrays []any
+func (c *redisConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, errRedisProtocol
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("ratelimit: redis: %s", line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, errRedisProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, errRedisProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+
+		return values, nil
+	default:
+		return nil, errRedisProtocol
+	}
+}
@@ -0,0 +1,69 @@
+package reqctx
+
+import (
+	"context"
+	"slices"
+)
+
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	clientIPKey
+	principalKey
+)
+
+// Principal identifies who is performing a request. TenantID is the manager owning the data
+// being accessed; UserID is the authenticated account (equal to TenantID for manager tokens).
+// APIKeyID is set when the manager authenticated with an API key instead of a token, Scopes
+// then lists what the key was granted.
+type Principal struct {
+	TenantID int
+	UserID   string
+	Kind     string
+	APIKeyID int
+	Scopes   []string
+}
+
+// HasScope reports whether the principal may act within scope. Tokens carry every scope.
+func (p Principal) HasScope(scope string) bool {
+	return p.APIKeyID == 0 || slices.Contains(p.Scopes, scope)
+}
+
+const (
+	PrincipalManager = "manager"
+	PrincipalUser    = "user"
+)
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPKey, ip)
+}
+
+func ClientIP(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPKey).(string)
+	return ip
+}
+
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+func GetPrincipal(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey).(Principal)
+	return principal, ok
+}
+
+// TenantID returns the tenant of the authenticated principal, or 0 for anonymous requests.
+func TenantID(ctx context.Context) int {
+	principal, _ := GetPrincipal(ctx)
+	return principal.TenantID
+}
@@ -1,14 +1,20 @@
 package s3
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"mime/multipart"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
 	"github.com/aws/aws-sdk-go/aws/credentials"
 	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/env"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
@@ -16,13 +22,33 @@ import (
 
 type S3Interface interface {
 	Upload(file *multipart.FileHeader) (string, error)
+	// Download reads an object uploaded to the bucket, given the URI Upload returned
+	Download(ctx context.Context, uri string) (*Object, error)
+	// Delete removes an object uploaded to the bucket; deleting a missing object succeeds
+	Delete(ctx context.Context, uri string) error
 }
 
 type S3Struct struct {
 	session  *session.Session
 	uploader *s3manager.Uploader
+	client   *s3.S3
 }
 
+type Object struct {
+	Key         string
+	ContentType string
+	Body        []byte
+}
+
+// maxDownloadSize bounds what Download reads into memory, uploads are far smaller
+const maxDownloadSize = 10 << 20
+
+var (
+	// ErrForeignURI is returned for URIs outside the bucket, which are never fetched or deleted
+	ErrForeignURI = errors.New("uri does not point into the bucket")
+	ErrNotFound   = errors.New("object not found")
+)
+
 var S3 = getS3()
 
 func getS3() S3Interface {
@@ -40,6 +66,7 @@ func getS3() S3Interface {
 	return &S3Struct{
 		session:  session,
 		uploader: uploader,
+		client:   s3.New(session),
 	}
 }
 
@@ -97,3 +124,95 @@ func (s *S3Struct) Upload(file *multipart.FileHeader) (string, error) {
 	// Return public URL
 	return result.Location, nil
 }
+
+func (s *S3Struct) Download(ctx context.Context, uri string) (*Object, error) {
+	key, ok := objectKey(uri)
+	if !ok {
+		return nil, ErrForeignURI
+	}
+
+	output, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(env.AppEnv.AWSS3BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var awsErr awserr.Error
+		if errors.As(err, &awsErr) && awsErr.Code() == s3.ErrCodeNoSuchKey {
+			return nil, ErrNotFound
+		}
+
+		log.Error(log.LogInfo{
+			"error": err.Error(),
+		}, "[S3][Download] failed to get object")
+		return nil, err
+	}
+	defer output.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(output.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(body) > maxDownloadSize {
+		return nil, fmt.Errorf("object %s is larger than %d bytes", key, maxDownloadSize)
+	}
+
+	return &Object{
+		Key:         key,
+		ContentType: aws.StringValue(output.ContentType),
+		Body:        body,
+	}, nil
+}
+
+func (s *S3Struct) Delete(ctx context.Context, uri string) error {
+	key, ok := objectKey(uri)
+	if !ok {
+		return ErrForeignURI
+	}
+
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(env.AppEnv.AWSS3BucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		log.Error(log.LogInfo{
+			"error": err.Error(),
+		}, "[S3][Delete] failed to delete object")
+		return err
+	}
+
+	return nil
+}
+
+// objectKey returns the key of a URI in the bucket, in the virtual-hosted or path style
+// Upload returns. Image URIs are client supplied; anything else is rejected.
+func objectKey(uri string) (string, bool) {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Scheme != "https" {
+		return "", false
+	}
+
+	bucket := env.AppEnv.AWSS3BucketName
+	region := env.AppEnv.AWSRegion
+	host := strings.ToLower(parsed.Hostname())
+	path := strings.TrimPrefix(parsed.Path, "/")
+
+	var key string
+	switch host {
+	case bucket + ".s3." + region + ".amazonaws.com", bucket + ".s3.amazonaws.com":
+		key = path
+	case "s3." + region + ".amazonaws.com", "s3.amazonaws.com":
+		var found bool
+		if key, found = strings.CutPrefix(path, bucket+"/"); !found {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+
+	if key == "" {
+		return "", false
+	}
+
+	return key, true
+}
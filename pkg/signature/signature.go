@@ -0,0 +1,84 @@
+// Package signature signs webhook requests following the Standard Webhooks specification
+// (https://www.standardwebhooks.com), so receivers can verify them with any of its libraries.
+package signature
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderID        = "Webhook-Id"
+	HeaderTimestamp = "Webhook-Timestamp"
+	HeaderSignature = "Webhook-Signature"
+
+	// SecretPrefix starts every secret, the rest is the base64 encoded HMAC key
+	SecretPrefix = "whsec_"
+	secretSize   = 24
+	version      = "v1"
+)
+
+var ErrInvalidSecret = errors.New("webhook secret is not a whsec_ prefixed base64 key")
+
+// NewSecret returns a random signing secret
+func NewSecret() (string, error) {
+	key := make([]byte, secretSize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return SecretPrefix + base64.StdEncoding.EncodeToString(key), nil
+}
+
+// Sign returns the Webhook-Signature header value of a message: the HMAC-SHA256 of
+// "id.timestamp.body" keyed with the secret
+func Sign(secret, id string, timestamp time.Time, body []byte) (string, error) {
+	mac, err := sum(secret, id, timestamp, body)
+	if err != nil {
+		return "", err
+	}
+
+	return version + "," + base64.StdEncoding.EncodeToString(mac), nil
+}
+
+// Verify reports whether header, a space separated list of signatures, holds one matching the
+// message. Receivers should also reject timestamps too far from their clock to stop replays.
+func Verify(secret, id string, timestamp time.Time, body []byte, header string) bool {
+	expected, err := sum(secret, id, timestamp, body)
+	if err != nil {
+		return false
+	}
+
+	for _, signature := range strings.Fields(header) {
+		sigVersion, encoded, found := strings.Cut(signature, ",")
+		if !found || sigVersion != version {
+			continue
+		}
+
+		mac, err := base64.StdEncoding.DecodeString(encoded)
+		if err == nil && hmac.Equal(mac, expected) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sum(secret, id string, timestamp time.Time, body []byte) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(secret, SecretPrefix))
+	if err != nil || len(key) == 0 {
+		return nil, ErrInvalidSecret
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id + "." + strconv.FormatInt(timestamp.Unix(), 10) + "."))
+	mac.Write(body)
+
+	return mac.Sum(nil), nil
+}
@@ -4,13 +4,14 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strings"
 
-	"github.com/bytedance/sonic"
 	"github.com/go-playground/locales/en"
 	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	enTranslations "github.com/go-playground/validator/v10/translations/en"
 	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/log"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/patch"
 )
 
 type ValidatorInterface interface {
@@ -43,6 +44,11 @@ func getValidator() ValidatorInterface {
 		}, "[VALIDATOR][getValidator] Failed to register default translations")
 	}
 
+	// Merge patch members are validated by what they hold, see patch.Field.ValidationValue
+	validator.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		return field.Interface().(interface{ ValidationValue() any }).ValidationValue()
+	}, patch.Field[string]{}, patch.Field[bool]{}, patch.Field[[]string]{})
+
 	return &ValidatorStruct{
 		validator: validator,
 		trans:     trans,
@@ -60,71 +66,17 @@ func (v *ValidatorStruct) Validate(data interface{}) ValidationErrors {
 				dataType = dataType.Elem()
 			}
 
-			body := ValidationError{
-				Fields: make(map[string]FieldError),
-			}
-			query := ValidationError{
-				Fields: make(map[string]FieldError),
-			}
-			param := ValidationError{
-				Fields: make(map[string]FieldError),
-			}
-			other := ValidationError{
-				Fields: make(map[string]FieldError),
-			}
-
+			res := make(ValidationErrors, 0, len(valErrs))
 			for _, err := range valErrs {
 				field, _ := dataType.FieldByName(err.StructField())
 				tag, fieldName := getTagAndFieldName(field)
 
-				if tag == "json" {
-					body.Fields = map[string]FieldError{
-						fieldName: {
-							Tag:     err.Tag(),
-							Message: err.Translate(v.trans),
-						},
-					}
-					continue
-				}
-
-				if tag == "param" {
-					param.Fields = map[string]FieldError{
-						fieldName: {
-							Tag:     err.Tag(),
-							Message: err.Translate(v.trans),
-						},
-					}
-					continue
-				}
-
-				if tag == "query" {
-					query.Fields = map[string]FieldError{
-						fieldName: {
-							Tag:     err.Tag(),
-							Message: err.Translate(v.trans),
-						},
-					}
-					continue
-				}
-
-				other.Fields = map[string]FieldError{
-					fieldName: {
-						Tag:     err.Tag(),
-						Message: err.Translate(v.trans),
-					},
-				}
-			}
-
-			body.Message = fmt.Sprintf("%d validation error(s) in body", len(body.Fields))
-			param.Message = fmt.Sprintf("%d validation error(s) in param", len(param.Fields))
-			query.Message = fmt.Sprintf("%d validation error(s) in query", len(query.Fields))
-			other.Message = fmt.Sprintf("%d validation error(s) in others", len(other.Fields))
-
-			res := ValidationErrors{
-				"body":   body,
-				"param":  param,
-				"query":  query,
-				"others": other,
+				res = append(res, FieldError{
+					In:      locations[tag],
+					Field:   fieldName,
+					Tag:     err.Tag(),
+					Message: err.Translate(v.trans),
+				})
 			}
 
 			return res
@@ -138,25 +90,26 @@ func (v *ValidatorStruct) Validate(data interface{}) ValidationErrors {
 	return nil
 }
 
+// locations maps the struct tag a field was bound from to where the client sent it
+var locations = map[string]string{
+	"json":  "body",
+	"query": "query",
+	"param": "param",
+	"":      "others",
+}
+
 type FieldError struct {
+	In      string `json:"in"`
+	Field   string `json:"field"`
 	Tag     string `json:"tag"`
 	Message string `json:"message"`
 }
 
-type ValidationError struct {
-	Message string                `json:"message"`
-	Fields  map[string]FieldError `json:"fields"`
-}
-
-type ValidationErrors map[string]ValidationError
+// ValidationErrors holds one entry per invalid field, in the order they were checked
+type ValidationErrors []FieldError
 
 func (v ValidationErrors) Error() string {
-	j, err := sonic.Marshal(v)
-	if err != nil {
-		return ""
-	}
-
-	return string(j)
+	return fmt.Sprintf("%d validation error(s)", len(v))
 }
 
 func (v ValidationErrors) Serialize() any {
@@ -168,7 +121,7 @@ func getTagAndFieldName(field reflect.StructField) (string, string) {
 	for _, tag := range checkTags {
 		fieldName, ok := field.Tag.Lookup(tag)
 		if ok {
-			return tag, fieldName
+			return tag, strings.Split(fieldName, ",")[0]
 		}
 	}
 
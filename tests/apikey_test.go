@@ -0,0 +1,310 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	apiKeyRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/apikey/repository"
+	apiKeySvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/apikey/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	errorhandler "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/error_handler"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/ratelimit"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+func TestAPIKeyAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryAPIKeyRepository()
+	service := apiKeySvc.NewAPIKeyService(repo, validator.Validator)
+
+	created, err := service.Create(ctx, 1, dto.CreateAPIKeyRequest{Name: "ci", Scopes: []string{"read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only a hash of the key is stored, found by the prefix kept in clear
+	stored := repo.keys[created.ID]
+	secret := created.Key[strings.LastIndex(created.Key, "_")+1:]
+	if stored.KeyHash == created.Key || strings.Contains(stored.KeyHash, secret) || !strings.HasPrefix(created.Key, created.Prefix+"_") {
+		t.Fatalf("stored %+v for key %s, want only its prefix and hash", stored, created.Key)
+	}
+
+	key, err := service.Authenticate(ctx, created.Key)
+	if err != nil || key.ID != created.ID || key.ManagerID != 1 {
+		t.Fatalf("authenticate = %+v, %v, want key %d of manager 1", key, err, created.ID)
+	}
+	if stored.LastUsedAt == nil {
+		t.Error("last_used_at was not set")
+	}
+
+	// Same prefix, other secret
+	forged := created.Key[:len(created.Key)-len(secret)] + strings.Repeat("A", len(secret))
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{name: "other secret", key: forged},
+		{name: "unknown prefix", key: apiKeySvc.KeyTag + "000000000000_" + secret},
+		{name: "no tag", key: strings.TrimPrefix(created.Key, apiKeySvc.KeyTag)},
+		{name: "prefix only", key: created.Prefix + "_"},
+		{name: "empty", key: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := service.Authenticate(ctx, tt.key); !errors.Is(err, domain.ErrInvalidAPIKey) {
+				t.Errorf("authenticate = %v, want ErrInvalidAPIKey", err)
+			}
+		})
+	}
+}
+
+func TestAPIKeyRejectedOnceExpiredOrRevoked(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryAPIKeyRepository()
+	service := apiKeySvc.NewAPIKeyService(repo, validator.Validator)
+
+	expired, err := service.Create(ctx, 1, dto.CreateAPIKeyRequest{Name: "expired", Scopes: []string{"read"}, ExpiresInDays: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := service.Create(ctx, 1, dto.CreateAPIKeyRequest{Name: "revoked", Scopes: []string{"read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{expired.Key, revoked.Key} {
+		if _, err := service.Authenticate(ctx, key); err != nil {
+			t.Fatalf("authenticate before = %v", err)
+		}
+	}
+
+	yesterday := time.Now().Add(-24 * time.Hour)
+	repo.keys[expired.ID].ExpiresAt = &yesterday
+
+	// Only the owner can revoke
+	if err := service.Revoke(ctx, 2, revoked.ID); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("revoke by another manager = %v, want ErrAPIKeyNotFound", err)
+	}
+	if err := service.Revoke(ctx, 1, revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Revoke(ctx, 1, revoked.ID); !errors.Is(err, domain.ErrAPIKeyNotFound) {
+		t.Errorf("revoke twice = %v, want ErrAPIKeyNotFound", err)
+	}
+
+	for _, key := range []string{expired.Key, revoked.Key} {
+		if _, err := service.Authenticate(ctx, key); !errors.Is(err, domain.ErrInvalidAPIKey) {
+			t.Errorf("authenticate after = %v, want ErrInvalidAPIKey", err)
+		}
+	}
+}
+
+func TestRequireAdminScopes(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryAPIKeyRepository()
+	service := apiKeySvc.NewAPIKeyService(repo, validator.Validator)
+
+	reader, err := service.Create(ctx, 1, dto.CreateAPIKeyRequest{Name: "reader", Scopes: []string{"read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer, err := service.Create(ctx, 1, dto.CreateAPIKeyRequest{Name: "writer", Scopes: []string{"employee:write", "department:write"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, err := service.Create(ctx, 1, dto.CreateAPIKeyRequest{Name: "revoked", Scopes: []string{"read"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Revoke(ctx, 1, revoked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{
+		ratelimit.ClassRead:                         {},
+		ratelimit.ClassWrite:                        {},
+		ratelimit.TenantClass(ratelimit.ClassRead):  {},
+		ratelimit.TenantClass(ratelimit.ClassWrite): {},
+	}, false)
+	middleware := middlewares.NewMiddleware(jwt.Jwt, jwt.JwtManager, jwt.JwtChallenge, service, limiter, nil, false)
+
+	// Handlers answer with the principal the key authenticated as
+	principal := func(ctx *fiber.Ctx) error {
+		p, _ := reqctx.GetPrincipal(ctx.UserContext())
+		return ctx.SendString(fmt.Sprintf("tenant %d key %d", p.TenantID, p.APIKeyID))
+	}
+	app := fiber.New(fiber.Config{ErrorHandler: errorhandler.ErrorHandler})
+	app.Get("/v1/employee", middleware.RequireAdmin(enums.ScopeRead), principal)
+	app.Post("/v1/employee", middleware.RequireAdmin(enums.ScopeEmployeeWrite), principal)
+	app.Post("/v1/department", middleware.RequireAdmin(enums.ScopeDepartmentWrite, enums.ScopeEmployeeWrite), principal)
+	app.Post("/v1/webhooks", middleware.RequireAdmin(), principal)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		key    string
+		status int
+		body   string
+	}{
+		{name: "read with read", method: http.MethodGet, path: "/v1/employee", key: reader.Key, status: http.StatusOK, body: fmt.Sprintf("tenant 1 key %d", reader.ID)},
+		{name: "write with read", method: http.MethodPost, path: "/v1/employee", key: reader.Key, status: http.StatusForbidden},
+		{name: "write with write", method: http.MethodPost, path: "/v1/employee", key: writer.Key, status: http.StatusOK, body: fmt.Sprintf("tenant 1 key %d", writer.ID)},
+		{name: "read with write", method: http.MethodGet, path: "/v1/employee", key: writer.Key, status: http.StatusForbidden},
+		{name: "one of the scopes", method: http.MethodPost, path: "/v1/department", key: writer.Key, status: http.StatusOK, body: fmt.Sprintf("tenant 1 key %d", writer.ID)},
+		{name: "endpoint without scopes", method: http.MethodPost, path: "/v1/webhooks", key: writer.Key, status: http.StatusForbidden},
+		{name: "revoked key", method: http.MethodGet, path: "/v1/employee", key: revoked.Key, status: http.StatusUnauthorized},
+		{name: "unknown key", method: http.MethodGet, path: "/v1/employee", key: apiKeySvc.KeyTag + "000000000000_secret", status: http.StatusUnauthorized},
+		{name: "no key", method: http.MethodGet, path: "/v1/employee", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			if tt.key != "" {
+				req.Header.Set(middlewares.HeaderAPIKey, tt.key)
+			}
+
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+
+			body, err := io.ReadAll(res.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if res.StatusCode != tt.status || (tt.body != "" && string(body) != tt.body) {
+				t.Errorf("status = %d, body = %s, want %d %s", res.StatusCode, body, tt.status, tt.body)
+			}
+		})
+	}
+}
+
+func TestAPIKeyRepositoryTouchesLastUsedOncePerMinute(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	managerID, _ := createTestDepartment(t, db)
+	repo := apiKeyRepo.NewAPIKeyRepository(db)
+
+	prefix := fmt.Sprintf("%012x", time.Now().UnixNano()&0xffffffffffff)
+	created, err := repo.Create(ctx, entity.APIKey{ManagerID: managerID, Name: "ci", Prefix: prefix, KeyHash: strings.Repeat("a", 64), Scopes: "read"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := repo.GetActiveByPrefix(ctx, prefix)
+	if err != nil || key.ID != created.ID || key.ManagerEmail == "" || key.LastUsedAt != nil {
+		t.Fatalf("get = %+v, %v, want key %d with its manager's email, never used", key, err, created.ID)
+	}
+
+	if err := repo.TouchLastUsed(ctx, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	touched, err := repo.GetActiveByPrefix(ctx, prefix)
+	if err != nil || touched.LastUsedAt == nil {
+		t.Fatalf("get after use = %+v, %v, want last_used_at set", touched, err)
+	}
+
+	// Used again within the minute, the write is skipped
+	if err := repo.TouchLastUsed(ctx, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	again, err := repo.GetActiveByPrefix(ctx, prefix)
+	if err != nil || !again.LastUsedAt.Equal(*touched.LastUsedAt) {
+		t.Errorf("get after another use = %+v, %v, want last_used_at unchanged", again, err)
+	}
+
+	if _, err := db.Exec("UPDATE api_keys SET expires_at = NOW() - INTERVAL '1 second' WHERE id = $1", key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.GetActiveByPrefix(ctx, prefix); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("get expired = %v, want sql.ErrNoRows", err)
+	}
+
+	if _, err := db.Exec("UPDATE api_keys SET expires_at = NULL WHERE id = $1", key.ID); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, err := repo.Revoke(ctx, managerID, key.ID); err != nil || !revoked {
+		t.Fatalf("revoke = %v, %v, want it revoked", revoked, err)
+	}
+	if _, err := repo.GetActiveByPrefix(ctx, prefix); !errors.Is(err, sql.ErrNoRows) {
+		t.Errorf("get revoked = %v, want sql.ErrNoRows", err)
+	}
+}
+
+// memoryAPIKeyRepository keeps keys like the Postgres repository: revoked and expired keys are
+// not found and last_used_at is written at most once a minute
+type memoryAPIKeyRepository struct {
+	contracts.APIKeyRepository
+	keys   map[int]*entity.APIKey
+	nextID int
+}
+
+func newMemoryAPIKeyRepository() *memoryAPIKeyRepository {
+	return &memoryAPIKeyRepository{keys: map[int]*entity.APIKey{}}
+}
+
+func (r *memoryAPIKeyRepository) Create(_ context.Context, key entity.APIKey) (entity.APIKey, error) {
+	r.nextID++
+	key.ID = r.nextID
+	key.CreatedAt = time.Now()
+
+	r.keys[key.ID] = &key
+	return key, nil
+}
+
+func (r *memoryAPIKeyRepository) Revoke(_ context.Context, managerID, id int) (bool, error) {
+	key, ok := r.keys[id]
+	if !ok || key.ManagerID != managerID || key.RevokedAt != nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	key.RevokedAt = &now
+	return true, nil
+}
+
+func (r *memoryAPIKeyRepository) GetActiveByPrefix(_ context.Context, prefix string) (*entity.APIKey, error) {
+	for _, key := range r.keys {
+		if key.Prefix != prefix || key.RevokedAt != nil || (key.ExpiresAt != nil && !key.ExpiresAt.After(time.Now())) {
+			continue
+		}
+
+		found := *key
+		found.ManagerEmail = fmt.Sprintf("manager%d@example.com", key.ManagerID)
+		return &found, nil
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryAPIKeyRepository) TouchLastUsed(_ context.Context, id int) error {
+	key := r.keys[id]
+	if key.LastUsedAt == nil || key.LastUsedAt.Before(time.Now().Add(-time.Minute)) {
+		now := time.Now()
+		key.LastUsedAt = &now
+	}
+
+	return nil
+}
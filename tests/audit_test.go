@@ -0,0 +1,192 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/enums"
+	auditRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/audit/repository"
+	auditSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/audit/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+func TestAuditChainBreaksAtTamperedEvent(t *testing.T) {
+	tests := []struct {
+		name string
+		// tamper changes the chain and returns the event Verify must stop at
+		tamper func(events []entity.AuditEvent) ([]entity.AuditEvent, int64)
+	}{
+		{name: "edited field", tamper: func(events []entity.AuditEvent) ([]entity.AuditEvent, int64) {
+			events[2].EntityID = "mallory"
+			return events, events[2].ID
+		}},
+		{name: "edited hash", tamper: func(events []entity.AuditEvent) ([]entity.AuditEvent, int64) {
+			events[2].Hash = entity.AuditGenesisHash
+			return events, events[2].ID
+		}},
+		// Removing tenant 2's event breaks tenant 1's next one, which linked to it
+		{name: "removed event of the other tenant", tamper: func(events []entity.AuditEvent) ([]entity.AuditEvent, int64) {
+			next := events[2].ID
+			return slices.Delete(events, 1, 2), next
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memoryAuditRepository{}
+			audit := auditSvc.NewAuditService(repo, validator.Validator)
+			recordAlternating(t, audit, 1, 2)
+
+			if res := verifyAudit(t, audit, 1); !res.Valid || res.Checked != 2 {
+				t.Fatalf("verify tenant 1 = %+v, want 2 valid events", res)
+			}
+			if res := verifyAudit(t, audit, 2); !res.Valid || res.Checked != 2 {
+				t.Fatalf("verify tenant 2 = %+v, want 2 valid events", res)
+			}
+
+			var brokenAt int64
+			repo.events, brokenAt = tt.tamper(repo.events)
+
+			res := verifyAudit(t, audit, 1)
+			if res.Valid || res.BrokenAt == nil || *res.BrokenAt != brokenAt {
+				t.Errorf("verify after tampering = %+v, want broken at %d", res, brokenAt)
+			}
+		})
+	}
+}
+
+func TestAuditChainBreaksAtTamperedRow(t *testing.T) {
+	db := openTestDB(t)
+	first, _ := createTestDepartment(t, db)
+	second, _ := createTestDepartment(t, db)
+
+	repo := auditRepo.NewAuditRepository(db)
+	audit := auditSvc.NewAuditService(repo, validator.Validator)
+	recordAlternating(t, audit, first, second)
+
+	for _, tenantID := range []int{first, second} {
+		if res := verifyAudit(t, audit, tenantID); !res.Valid || res.Checked != 2 {
+			t.Fatalf("verify tenant %d = %+v, want 2 valid events", tenantID, res)
+		}
+	}
+
+	events, err := repo.Find(context.Background(), dto.AuditFilter{TenantID: first, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Find returns the newest first
+	tampered := events[0]
+
+	// The table is append-only, only a session acting as a replica gets past the triggers
+	rewrite := func(entityID string) {
+		tx, err := db.Beginx()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer func() { _ = tx.Rollback() }()
+
+		if _, err := tx.Exec("SET LOCAL session_replication_role = replica"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Exec("UPDATE audit_events SET entity_id = $1 WHERE id = $2", entityID, tampered.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rewrite("mallory")
+	t.Cleanup(func() { rewrite(tampered.EntityID) })
+
+	res := verifyAudit(t, audit, first)
+	if res.Valid || res.BrokenAt == nil || *res.BrokenAt != tampered.ID {
+		t.Errorf("verify after tampering = %+v, want broken at %d", res, tampered.ID)
+	}
+
+	// The stored hash is untouched, so the other tenant's events still link to it
+	if res := verifyAudit(t, audit, second); !res.Valid || res.Checked != 2 {
+		t.Errorf("verify the other tenant = %+v, want 2 valid events", res)
+	}
+}
+
+// recordAlternating records two updates for each tenant, interleaved so every event links to
+// one of the other tenant
+func recordAlternating(t *testing.T, audit contracts.AuditService, first, second int) {
+	t.Helper()
+
+	for i := range 2 {
+		for _, tenantID := range []int{first, second} {
+			ctx := reqctx.WithPrincipal(context.Background(), reqctx.Principal{
+				TenantID: tenantID,
+				UserID:   fmt.Sprint(tenantID),
+				Kind:     reqctx.PrincipalManager,
+			})
+
+			err := audit.Record(ctx, dto.AuditRecord{
+				Action:     enums.AuditUpdate,
+				EntityType: dto.AuditEntityDepartment,
+				EntityID:   fmt.Sprint(i),
+				Before:     map[string]any{"name": "Engineering"},
+				After:      map[string]any{"name": fmt.Sprintf("Engineering %d", i)},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func verifyAudit(t *testing.T, audit contracts.AuditService, tenantID int) dto.AuditVerifyRes {
+	t.Helper()
+
+	res, err := audit.Verify(context.Background(), tenantID, dto.AuditVerifyQuery{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+// memoryAuditRepository chains events like the Postgres repository, one chain for every tenant
+type memoryAuditRepository struct {
+	contracts.AuditRepository
+	events []entity.AuditEvent
+	nextID int64
+}
+
+func (r *memoryAuditRepository) Append(_ context.Context, event entity.AuditEvent, seal func(entity.AuditEvent) string) error {
+	event.PrevHash = entity.AuditGenesisHash
+	if len(r.events) > 0 {
+		event.PrevHash = r.events[len(r.events)-1].Hash
+	}
+
+	r.nextID++
+	event.ID = r.nextID
+	event.Hash = seal(event)
+
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *memoryAuditRepository) ListLinks(_ context.Context, tenantID int, afterID int64, limit int) ([]entity.AuditLink, error) {
+	links := []entity.AuditLink{}
+	for i, event := range r.events {
+		if event.TenantID == nil || *event.TenantID != tenantID || event.ID <= afterID || len(links) == limit {
+			continue
+		}
+
+		link := entity.AuditLink{AuditEvent: event}
+		if i > 0 {
+			link.PredecessorHash = &r.events[i-1].Hash
+		}
+		links = append(links, link)
+	}
+
+	return links, nil
+}
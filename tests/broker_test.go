@@ -0,0 +1,173 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	natstest "github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/broker"
+	"github.com/segmentio/kafka-go"
+)
+
+func TestNATSPublisher(t *testing.T) {
+	ctx := context.Background()
+	srv := runJetStream(t)
+
+	conn, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	js, err := jetstream.New(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stream, err := js.CreateStream(ctx, jetstream.StreamConfig{
+		Name:       "EVENTS",
+		Subjects:   []string{"gogomanager.>"},
+		Duplicates: time.Minute,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publisher, err := broker.NewNATSPublisher(broker.NATSConfig{URL: srv.ClientURL(), SubjectPrefix: "gogomanager"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer publisher.Close()
+
+	msg := broker.Message{
+		Subject: "employee.created",
+		Key:     "employee:1",
+		ID:      "evt_1",
+		Body:    []byte(`{"id":"1"}`),
+	}
+
+	// The relay publishes again whatever it isn't sure was stored, the stream keeps one copy
+	for range 2 {
+		if err := publisher.Publish(ctx, msg); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info, err := stream.Info(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.State.Msgs != 1 {
+		t.Errorf("stream holds %d messages, want 1", info.State.Msgs)
+	}
+
+	stored, err := stream.GetMsg(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Subject != "gogomanager.employee.created" {
+		t.Errorf("subject = %s, want gogomanager.employee.created", stored.Subject)
+	}
+	if key := stored.Header.Get(broker.HeaderKey); key != msg.Key {
+		t.Errorf("key header = %q, want %q", key, msg.Key)
+	}
+	if id := stored.Header.Get(jetstream.MsgIDHeader); id != msg.ID {
+		t.Errorf("message id = %q, want %q", id, msg.ID)
+	}
+	if string(stored.Data) != string(msg.Body) {
+		t.Errorf("body = %s, want %s", stored.Data, msg.Body)
+	}
+
+	// Nothing acknowledges a subject no stream captures, the publish fails and is retried later
+	msg.Subject, msg.ID = "nowhere", "evt_2"
+	other, err := broker.NewNATSPublisher(broker.NATSConfig{URL: srv.ClientURL(), SubjectPrefix: "elsewhere"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	if err := other.Publish(ctx, msg); err == nil {
+		t.Error("publish to a subject outside every stream succeeded")
+	}
+}
+
+func TestKafkaPublisher(t *testing.T) {
+	writer := &fakeKafkaWriter{}
+	publisher := broker.NewKafkaWriterPublisher(writer)
+
+	err := publisher.Publish(context.Background(), broker.Message{
+		Subject: "department.updated",
+		Key:     "department:3",
+		ID:      "evt_3",
+		Body:    []byte(`{"id":"3"}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(writer.written) != 1 {
+		t.Fatalf("wrote %d messages, want 1", len(writer.written))
+	}
+
+	written := writer.written[0]
+	if string(written.Key) != "department:3" || string(written.Value) != `{"id":"3"}` {
+		t.Errorf("message = %s %s, want keyed by aggregate with the body as value", written.Key, written.Value)
+	}
+
+	headers := map[string]string{}
+	for _, header := range written.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+	if headers["id"] != "evt_3" || headers["type"] != "department.updated" {
+		t.Errorf("headers = %v, want the id and the type", headers)
+	}
+
+	writer.err = kafka.NotEnoughReplicas
+	err = publisher.Publish(context.Background(), broker.Message{Subject: "department.updated", ID: "evt_4"})
+	if !errors.Is(err, kafka.NotEnoughReplicas) {
+		t.Errorf("err = %v, want the writer's error", err)
+	}
+
+	if err := publisher.Close(); err != nil || !writer.closed {
+		t.Errorf("writer closed = %t, err = %v", writer.closed, err)
+	}
+}
+
+func runJetStream(t *testing.T) *server.Server {
+	t.Helper()
+
+	opts := natstest.DefaultTestOptions
+	opts.Port = -1
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+
+	srv := natstest.RunServer(&opts)
+	t.Cleanup(srv.Shutdown)
+
+	return srv
+}
+
+type fakeKafkaWriter struct {
+	written []kafka.Message
+	err     error
+	closed  bool
+}
+
+func (w *fakeKafkaWriter) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	if w.err != nil {
+		return w.err
+	}
+
+	w.written = append(w.written, msgs...)
+	return nil
+}
+
+func (w *fakeKafkaWriter) Close() error {
+	w.closed = true
+	return nil
+}
@@ -0,0 +1,46 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	deptSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/department/service"
+	outboxSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/outbox/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/uuid"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+func TestDepartmentsOfAnotherManagerAreNotFound(t *testing.T) {
+	// The repository writes nothing, an update or delete reaching it panics
+	departments := &memoryDepartmentRepository{departments: []*entity.Department{
+		{ID: 10, Name: "Engineering", ManagerID: 1, Version: 1},
+	}}
+	service := deptSvc.NewDepartmentService(
+		departments,
+		validator.Validator,
+		discardAudit{},
+		outboxSvc.NewOutboxEventService(newMemoryOutboxRepository(), uuid.UUID),
+		passthroughTransactor{},
+	)
+	ctx := context.Background()
+
+	if _, err := service.FindOwned(ctx, 2, 10); !errors.Is(err, domain.ErrDepartmentNotFound) {
+		t.Errorf("find by another manager = %v, want ErrDepartmentNotFound", err)
+	}
+
+	if _, err := service.Update(ctx, 2, 10, "Sales and Marketing", ""); !errors.Is(err, domain.ErrDepartmentNotFound) {
+		t.Errorf("update by another manager = %v, want ErrDepartmentNotFound", err)
+	}
+
+	if err := service.Delete(ctx, 2, 10, ""); !errors.Is(err, domain.ErrDepartmentNotFound) {
+		t.Errorf("delete by another manager = %v, want ErrDepartmentNotFound", err)
+	}
+
+	department, err := service.FindOwned(ctx, 1, 10)
+	if err != nil || department.Name != "Engineering" {
+		t.Errorf("find by the owner = %+v, %v, want Engineering", department, err)
+	}
+}
@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	employeeSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/employee/service"
+	outboxSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/outbox/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/patch"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/uuid"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+func TestEmployeesOfAnotherManagerAreNotFound(t *testing.T) {
+	employees := &memoryEmployeeRepository{employees: []entity.Employee{
+		{ID: 1, IdentityNumber: "3175091201900001", Name: "Jane Doe", Gender: "female", DepartmentID: 10, Version: 1},
+	}}
+	departments := &memoryDepartmentRepository{departments: []*entity.Department{
+		{ID: 10, Name: "Engineering", ManagerID: 1},
+		{ID: 20, Name: "Marketing", ManagerID: 2},
+	}}
+	service := employeeSvc.NewEmployeeService(
+		employees,
+		departments,
+		validator.Validator,
+		discardAudit{},
+		outboxSvc.NewOutboxEventService(newMemoryOutboxRepository(), uuid.UUID),
+		passthroughTransactor{},
+	)
+	ctx := context.Background()
+
+	// Manager 2 can neither read, change nor delete manager 1's employee
+	if _, err := service.FindByIdentityNumber(ctx, 2, "3175091201900001"); !errors.Is(err, domain.ErrEmployeeNotFound) {
+		t.Errorf("find by another manager = %v, want ErrEmployeeNotFound", err)
+	}
+
+	_, err := service.Update(ctx, 2, dto.EmployeeUpdateReq{Name: patch.Value("Mallory")}, "3175091201900001", "")
+	if !errors.Is(err, domain.ErrEmployeeNotFound) {
+		t.Errorf("update by another manager = %v, want ErrEmployeeNotFound", err)
+	}
+
+	if err := service.Delete(ctx, 2, "3175091201900001", ""); !errors.Is(err, domain.ErrEmployeeNotFound) {
+		t.Errorf("delete by another manager = %v, want ErrEmployeeNotFound", err)
+	}
+
+	if employees.employees[0].Name != "Jane Doe" || employees.employees[0].Version != 1 {
+		t.Fatalf("employee = %+v, want it untouched", employees.employees[0])
+	}
+
+	// Manager 1 still can
+	employee, err := service.FindByIdentityNumber(ctx, 1, "3175091201900001")
+	if err != nil || employee.Name != "Jane Doe" {
+		t.Fatalf("find by the owner = %+v, %v, want Jane Doe", employee, err)
+	}
+
+	if err := service.Delete(ctx, 1, "3175091201900001", ""); err != nil {
+		t.Fatalf("delete by the owner = %v", err)
+	}
+	if len(employees.employees) != 0 {
+		t.Errorf("employees = %+v, want none left", employees.employees)
+	}
+}
+
+func TestEmployeesOnlyMoveToDepartmentsOfTheirManager(t *testing.T) {
+	employees := &memoryEmployeeRepository{employees: []entity.Employee{
+		{ID: 1, IdentityNumber: "3175091201900001", Name: "Jane Doe", Gender: "female", DepartmentID: 10, Version: 1},
+	}}
+	departments := &memoryDepartmentRepository{departments: []*entity.Department{
+		{ID: 10, Name: "Engineering", ManagerID: 1},
+		{ID: 11, Name: "Finance", ManagerID: 1},
+		{ID: 20, Name: "Marketing", ManagerID: 2},
+	}}
+	service := employeeSvc.NewEmployeeService(
+		employees,
+		departments,
+		validator.Validator,
+		discardAudit{},
+		outboxSvc.NewOutboxEventService(newMemoryOutboxRepository(), uuid.UUID),
+		passthroughTransactor{},
+	)
+	ctx := context.Background()
+
+	for _, departmentID := range []string{"20", "99"} {
+		_, err := service.Update(ctx, 1, dto.EmployeeUpdateReq{DepartmentID: patch.Value(departmentID)}, "3175091201900001", "")
+		if !errors.Is(err, domain.ErrDepartmentNotFound) {
+			t.Errorf("move to department %s = %v, want ErrDepartmentNotFound", departmentID, err)
+		}
+	}
+	if employees.employees[0].DepartmentID != 10 || employees.employees[0].Version != 1 {
+		t.Fatalf("employee = %+v, want it left in department 10", employees.employees[0])
+	}
+
+	moved, err := service.Update(ctx, 1, dto.EmployeeUpdateReq{DepartmentID: patch.Value("11")}, "3175091201900001", "")
+	if err != nil || moved.DepartmentID != "11" {
+		t.Fatalf("move to department 11 = %+v, %v, want it moved", moved, err)
+	}
+}
+
+func TestEmployeeMergePatchClearsImageWithNull(t *testing.T) {
+	employees := &memoryEmployeeRepository{employees: []entity.Employee{
+		{ID: 1, IdentityNumber: "3175091201900001", Name: "Jane Doe", EmployeeImageURI: "https://example.com/jane.png", Gender: "female", DepartmentID: 10, Version: 1},
+	}}
+	departments := &memoryDepartmentRepository{departments: []*entity.Department{
+		{ID: 10, Name: "Engineering", ManagerID: 1},
+	}}
+	service := employeeSvc.NewEmployeeService(
+		employees,
+		departments,
+		validator.Validator,
+		discardAudit{},
+		outboxSvc.NewOutboxEventService(newMemoryOutboxRepository(), uuid.UUID),
+		passthroughTransactor{},
+	)
+	ctx := context.Background()
+
+	// Left out, the image is kept
+	updated, err := service.Update(ctx, 1, dto.EmployeeUpdateReq{Name: patch.Value("Jane Roe")}, "3175091201900001", "")
+	if err != nil || updated.Name != "Jane Roe" || updated.EmployeeImageURI != "https://example.com/jane.png" {
+		t.Fatalf("update the name = %+v, %v, want the image kept", updated, err)
+	}
+
+	updated, err = service.Update(ctx, 1, dto.EmployeeUpdateReq{EmployeeImageURI: patch.Null[string]()}, "3175091201900001", "")
+	if err != nil || updated.EmployeeImageURI != "" || updated.Name != "Jane Roe" {
+		t.Fatalf("null image = %+v, %v, want the image cleared and the rest kept", updated, err)
+	}
+	if employees.employees[0].EmployeeImageURI != "" {
+		t.Errorf("stored image = %q, want it cleared", employees.employees[0].EmployeeImageURI)
+	}
+
+	// A required member can't be cleared
+	if _, err := service.Update(ctx, 1, dto.EmployeeUpdateReq{Name: patch.Null[string]()}, "3175091201900001", ""); err == nil {
+		t.Error("null name was accepted")
+	}
+	if employees.employees[0].Name != "Jane Roe" {
+		t.Errorf("stored name = %q, want it kept", employees.employees[0].Name)
+	}
+}
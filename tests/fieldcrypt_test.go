@@ -0,0 +1,255 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	employeeRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/employee/repository"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/fieldcrypt"
+)
+
+// testKeys are the key testFieldCrypt uses and the one it is rotated to in the rotation tests
+var testKeys = map[string][]byte{
+	"test":    bytes.Repeat([]byte{1}, fieldcrypt.KeySize),
+	"rotated": bytes.Repeat([]byte{3}, fieldcrypt.KeySize),
+}
+
+// newTestKeyring loads the named test keys, the first one active. The blind index key is testFieldCrypt's.
+func newTestKeyring(t *testing.T, ids ...string) *fieldcrypt.FieldCryptStruct {
+	t.Helper()
+
+	keys := map[string][]byte{}
+	for _, id := range ids {
+		keys[id] = testKeys[id]
+	}
+
+	crypt, err := fieldcrypt.New(fieldcrypt.Config{
+		Keys:          keys,
+		ActiveKeyID:   ids[0],
+		BlindIndexKey: bytes.Repeat([]byte{2}, fieldcrypt.KeySize),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return crypt
+}
+
+func TestFieldCryptBindsCiphertextToField(t *testing.T) {
+	crypt := newTestKeyring(t, "rotated", "test")
+
+	dataKey, err := crypt.NewDataKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dataKey.KeyID != "rotated" {
+		t.Fatalf("data key wrapped with %s, want the active key", dataKey.KeyID)
+	}
+
+	ciphertext, err := dataKey.Encrypt("employees.name", "Jane Doe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(ciphertext, []byte("Jane Doe")) {
+		t.Fatal("ciphertext holds the plaintext")
+	}
+
+	tampered := bytes.Clone(ciphertext)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name       string
+		keyID      string
+		wrapped    []byte
+		field      string
+		ciphertext []byte
+		want       string
+		wantErr    error
+	}{
+		{name: "same field", keyID: "rotated", wrapped: dataKey.Wrapped, field: "employees.name", ciphertext: ciphertext, want: "Jane Doe"},
+		{name: "other field", keyID: "rotated", wrapped: dataKey.Wrapped, field: "employees.identity_number", ciphertext: ciphertext, wantErr: fieldcrypt.ErrDecrypt},
+		{name: "tampered ciphertext", keyID: "rotated", wrapped: dataKey.Wrapped, field: "employees.name", ciphertext: tampered, wantErr: fieldcrypt.ErrDecrypt},
+		{name: "truncated ciphertext", keyID: "rotated", wrapped: dataKey.Wrapped, field: "employees.name", ciphertext: ciphertext[:4], wantErr: fieldcrypt.ErrDecrypt},
+		// The key id is bound into the wrapped data key, it can't be relabelled with another key of the keyring
+		{name: "data key under another key id", keyID: "test", wrapped: dataKey.Wrapped, field: "employees.name", ciphertext: ciphertext, wantErr: fieldcrypt.ErrDecrypt},
+		{name: "unknown key id", keyID: "retired", wrapped: dataKey.Wrapped, field: "employees.name", ciphertext: ciphertext, wantErr: fieldcrypt.ErrUnknownKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened, err := crypt.OpenDataKey(tt.keyID, tt.wrapped)
+			if err == nil {
+				var plain string
+				plain, err = opened.Decrypt(tt.field, tt.ciphertext)
+				if err == nil && plain != tt.want {
+					t.Errorf("decrypted %q, want %q", plain, tt.want)
+				}
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("open = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFieldCryptBlindIndexStable(t *testing.T) {
+	before := newTestKeyring(t, "test")
+	after := newTestKeyring(t, "rotated", "test")
+
+	index := before.BlindIndex("employees.identity_number", "3175091201900001")
+
+	tests := []struct {
+		name  string
+		crypt fieldcrypt.FieldCryptInterface
+		field string
+		value string
+		same  bool
+	}{
+		{name: "same value", crypt: before, field: "employees.identity_number", value: "3175091201900001", same: true},
+		// Rotating the keyring leaves the blind index key alone, lookups keep working
+		{name: "after rotation", crypt: after, field: "employees.identity_number", value: "3175091201900001", same: true},
+		{name: "other value", crypt: before, field: "employees.identity_number", value: "3175091201900002"},
+		{name: "other field", crypt: before, field: "employees.name", value: "3175091201900001"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.crypt.BlindIndex(tt.field, tt.value)
+			if (got == index) != tt.same {
+				t.Errorf("index %s of %s = %s, same = %v, want %v", tt.field, tt.value, got, got == index, tt.same)
+			}
+		})
+	}
+}
+
+func TestFieldCryptReencryptUnderRotatedKey(t *testing.T) {
+	sealed, err := fieldcrypt.Seal(newTestKeyring(t, "test"), "webhooks.secret", "whsec_123")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both keys loaded, the new one active: old values open, new ones are sealed with the new key
+	rotated := newTestKeyring(t, "rotated", "test")
+	plain, err := fieldcrypt.Open(rotated, "webhooks.secret", sealed)
+	if err != nil || plain != "whsec_123" {
+		t.Fatalf("open after rotation = %q, %v, want whsec_123", plain, err)
+	}
+
+	resealed, err := fieldcrypt.Seal(rotated, "webhooks.secret", plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resealed.KeyID != "rotated" {
+		t.Fatalf("resealed with %s, want rotated", resealed.KeyID)
+	}
+
+	// Once the old key is retired only the resealed value opens
+	retired := newTestKeyring(t, "rotated")
+	if _, err := fieldcrypt.Open(retired, "webhooks.secret", sealed); !errors.Is(err, fieldcrypt.ErrUnknownKey) {
+		t.Errorf("open with the key retired = %v, want ErrUnknownKey", err)
+	}
+	if plain, err := fieldcrypt.Open(retired, "webhooks.secret", resealed); err != nil || plain != "whsec_123" {
+		t.Errorf("open resealed = %q, %v, want whsec_123", plain, err)
+	}
+}
+
+func TestEmployeeReencryptedUnderRotatedKey(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	_, departmentID := createTestDepartment(t, db)
+
+	identityNumber := fmt.Sprint(time.Now().UnixNano())
+	employee := entity.Employee{
+		IdentityNumber: identityNumber,
+		Name:           "Jane Doe",
+		Gender:         "female",
+		DepartmentID:   departmentID,
+	}
+
+	id, err := employeeRepo.NewEmployeeRepository(db, newTestKeyring(t, "test")).Create(ctx, employee)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.Exec("DELETE FROM employees WHERE id = $1", id)
+	})
+
+	var stored struct {
+		IdentityNumber     *string `db:"identity_number"`
+		IdentityNumberEnc  []byte  `db:"identity_number_enc"`
+		IdentityNumberBidx string  `db:"identity_number_bidx"`
+		KeyID              string  `db:"key_id"`
+	}
+	query := "SELECT identity_number, identity_number_enc, identity_number_bidx, key_id FROM employees WHERE id = $1"
+	if err := db.Get(&stored, query, id); err != nil {
+		t.Fatal(err)
+	}
+	if stored.IdentityNumber != nil || bytes.Contains(stored.IdentityNumberEnc, []byte(identityNumber)) || stored.KeyID != "test" {
+		t.Fatalf("stored %+v, want the identity number sealed with the test key", stored)
+	}
+	index := stored.IdentityNumberBidx
+
+	// Rotate: every row not under the new key is sealed again, rows of earlier runs included
+	rotated := employeeRepo.NewEmployeeRepository(db, newTestKeyring(t, "rotated", "test"))
+	reencryptAll(t, rotated)
+	t.Cleanup(func() {
+		reencryptAll(t, employeeRepo.NewEmployeeRepository(db, newTestKeyring(t, "test", "rotated")))
+	})
+
+	if err := db.Get(&stored, query, id); err != nil {
+		t.Fatal(err)
+	}
+	if stored.KeyID != "rotated" || stored.IdentityNumberBidx != index {
+		t.Fatalf("stored %+v, want it sealed with the rotated key under the same blind index", stored)
+	}
+
+	// The old key can go, the row is found by its blind index and opens with the new key alone
+	found, err := employeeRepo.NewEmployeeRepository(db, newTestKeyring(t, "rotated")).FindByIdentityNumber(ctx, identityNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found.ID != id || found.IdentityNumber != identityNumber || found.Name != "Jane Doe" {
+		t.Errorf("found %+v, want employee %d as created", found, id)
+	}
+}
+
+func reencryptAll(t *testing.T, repo contracts.EmployeeRepository) {
+	t.Helper()
+
+	for {
+		n, err := repo.Reencrypt(context.Background(), 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n == 0 {
+			return
+		}
+	}
+}
+
+// createTestDepartment stores a manager with a department, both deleted when the test ends
+func createTestDepartment(t *testing.T, db *sqlx.DB) (managerID, departmentID int) {
+	t.Helper()
+
+	email := fmt.Sprintf("test-%d@example.com", time.Now().UnixNano())
+	if err := db.Get(&managerID, "INSERT INTO managers (email, password) VALUES ($1, 'x') RETURNING id", email); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_, _ = db.Exec("DELETE FROM managers WHERE id = $1", managerID)
+	})
+
+	if err := db.Get(&departmentID, "INSERT INTO departments (name, manager_id) VALUES ('Engineering', $1) RETURNING id", managerID); err != nil {
+		t.Fatal(err)
+	}
+
+	return managerID, departmentID
+}
@@ -0,0 +1,199 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql/testutil"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/graphql/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/metrics"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+)
+
+func TestGraphQLRejectsExpensiveQueries(t *testing.T) {
+	departments := &emptyDepartmentService{}
+	graphQL := service.NewGraphQLService(nil, departments, &memoryEmployeeService{}, nil, false)
+
+	// Departments and employees refer to each other, so a query can nest them without end
+	deep := "name"
+	for range 7 {
+		deep = fmt.Sprintf("employees { department { %s } }", deep)
+	}
+
+	tests := []struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		want      *domain.RequestError
+	}{
+		{
+			name:  "introspection",
+			query: testutil.IntrospectionQuery,
+		},
+		{
+			name:  "full pages of departments and their employees",
+			query: `{ departments(limit: 100) { departmentId name employees(limit: 100) { identityNumber name gender departmentId } } }`,
+		},
+		{
+			name:  "too deep",
+			query: "{ departments { " + deep + " } }",
+			want:  domain.ErrGraphQLTooDeep,
+		},
+		{
+			name:  "pages nested in pages",
+			query: `{ departments(limit: 100) { employees(limit: 100) { department { employees(limit: 100) { name } } } } }`,
+			want:  domain.ErrGraphQLTooComplex,
+		},
+		{
+			name:      "page size from a variable",
+			query:     `query($limit: Int) { departments(limit: $limit) { employees(limit: $limit) { department { employees(limit: $limit) { name } } } } }`,
+			variables: map[string]interface{}{"limit": float64(100)},
+			want:      domain.ErrGraphQLTooComplex,
+		},
+		{
+			name: "fragments spread over and over",
+			query: `{ departments(limit: 100) { ...a } }
+				fragment a on Department { employees(limit: 100) { ...b ...b } }
+				fragment b on Employee { department { ...c ...c } }
+				fragment c on Department { employees(limit: 100) { name } }`,
+			want: domain.ErrGraphQLTooComplex,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			departments.calls = 0
+			res := graphQL.Execute(context.Background(), dto.GraphQLRequest{Query: tt.query, Variables: tt.variables})
+
+			if tt.want == nil {
+				if len(res.Errors) > 0 {
+					t.Fatalf("errors = %v, want none", res.Errors[0].Message)
+				}
+				return
+			}
+
+			if len(res.Errors) != 1 || res.Errors[0].Extensions == nil || res.Errors[0].Extensions.Code != tt.want.Code {
+				t.Fatalf("errors = %+v, want %s", res.Errors, tt.want.Code)
+			}
+			if res.Data != nil || departments.calls > 0 {
+				t.Errorf("the query was resolved before being rejected")
+			}
+			if !strings.Contains(res.Errors[0].Message, tt.want.Error()) {
+				t.Errorf("message = %q, want %q", res.Errors[0].Message, tt.want.Error())
+			}
+		})
+	}
+}
+
+func TestGraphQLScopedToTenant(t *testing.T) {
+	departments := &memoryDepartmentRepository{departments: []*entity.Department{
+		{ID: 10, Name: "Engineering", ManagerID: 1},
+		{ID: 20, Name: "Engineering", ManagerID: 2},
+	}}
+	employees := &memoryEmployeeService{departments: departments, employees: []*dto.EmployeeDataRes{
+		{IdentityNumber: "own-1", Name: "Own", Gender: "female", DepartmentID: "20"},
+		{IdentityNumber: "other-1", Name: "Other", Gender: "male", DepartmentID: "10"},
+	}}
+	departmentService := &memoryDepartmentService{departments: departments}
+	graphQL := service.NewGraphQLService(nil, departmentService, employees, metrics.Metrics, false)
+
+	// Manager 2 asks, everything of manager 1 is out of reach
+	ctx := reqctx.WithPrincipal(context.Background(), reqctx.Principal{TenantID: 2})
+	execute := func(query string) *dto.GraphQLResponse {
+		return graphQL.Execute(ctx, dto.GraphQLRequest{Query: query})
+	}
+
+	t.Run("reads", func(t *testing.T) {
+		res := execute(`{
+			employee(identityNumber: "other-1") { name }
+			department(departmentId: "10") { name }
+			employees(identityNumber: "other-1") { name }
+			departments { departmentId employees { identityNumber department { departmentId } } }
+		}`)
+		if len(res.Errors) > 0 {
+			t.Fatalf("errors = %v, want none", res.Errors[0].Message)
+		}
+
+		got, err := json.Marshal(res.Data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := `{"department":null,"departments":[{"departmentId":"20","employees":[{"department":{"departmentId":"20"},"identityNumber":"own-1"}]}],"employee":null,"employees":[]}`
+		if string(got) != want {
+			t.Errorf("data = %s, want %s", got, want)
+		}
+	})
+
+	mutations := []struct {
+		name  string
+		query string
+		want  *domain.RequestError
+	}{
+		{
+			name:  "update department of another tenant",
+			query: `mutation { updateDepartment(departmentId: "10", name: "Sales") { name } }`,
+			want:  domain.ErrDepartmentNotFound,
+		},
+		{
+			name:  "delete department of another tenant",
+			query: `mutation { deleteDepartment(departmentId: "10") }`,
+			want:  domain.ErrDepartmentNotFound,
+		},
+		{
+			name: "create employee in a department of another tenant",
+			query: `mutation { createEmployee(input: {
+				identityNumber: "new-1", name: "New", employeeImageUri: "https://example.com/a.png", gender: "male", departmentId: "10"
+			}) { name } }`,
+			want: domain.ErrDepartmentNotFound,
+		},
+		{
+			name:  "update employee of another tenant",
+			query: `mutation { updateEmployee(identityNumber: "other-1", input: {name: "Renamed"}) { name } }`,
+			want:  domain.ErrEmployeeNotFound,
+		},
+		{
+			name:  "move employee to a department of another tenant",
+			query: `mutation { updateEmployee(identityNumber: "own-1", input: {departmentId: "10"}) { name } }`,
+			want:  domain.ErrDepartmentNotFound,
+		},
+		{
+			name:  "delete employee of another tenant",
+			query: `mutation { deleteEmployee(identityNumber: "other-1") }`,
+			want:  domain.ErrEmployeeNotFound,
+		},
+	}
+
+	for _, tt := range mutations {
+		t.Run(tt.name, func(t *testing.T) {
+			res := execute(tt.query)
+			if len(res.Errors) != 1 || res.Errors[0].Extensions == nil || res.Errors[0].Extensions.Code != tt.want.Code {
+				t.Fatalf("errors = %+v, want %s", res.Errors, tt.want.Code)
+			}
+		})
+	}
+
+	if len(employees.created) > 0 || len(employees.updated) > 0 || len(employees.deleted) > 0 {
+		t.Errorf("created %v, updated %v and deleted %v, want nothing changed", employees.created, employees.updated, employees.deleted)
+	}
+	if len(departmentService.updated) > 0 || len(departmentService.deleted) > 0 {
+		t.Errorf("updated departments %v and deleted %v, want nothing changed", departmentService.updated, departmentService.deleted)
+	}
+}
+
+// emptyDepartmentService has no departments, it counts how often it was asked for them
+type emptyDepartmentService struct {
+	contracts.DepartmentService
+	calls int
+}
+
+func (s *emptyDepartmentService) FindByManager(context.Context, int, string, int, int) ([]*dto.DepartmentRes, error) {
+	s.calls++
+	return []*dto.DepartmentRes{}, nil
+}
@@ -0,0 +1,226 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	deptRpc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/department/rpc"
+	employeeRpc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/employee/rpc"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/server"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/metrics"
+	pb "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/pb/gogomanager/v1"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPCRateLimitedAndRecovered(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{
+		ratelimit.ClassRead:                         {Requests: 2, Period: time.Minute},
+		ratelimit.ClassWrite:                        {Requests: 1, Period: time.Minute},
+		ratelimit.TenantClass(ratelimit.ClassRead):  {},
+		ratelimit.TenantClass(ratelimit.ClassWrite): {},
+	}, false)
+	middleware := middlewares.NewMiddleware(jwt.Jwt, jwt.JwtManager, jwt.JwtChallenge, nil, limiter, nil, false)
+	client := pb.NewEmployeeServiceClient(startGRPCServer(t, middleware, func(registrar grpc.ServiceRegistrar) {
+		pb.RegisterEmployeeServiceServer(registrar, &panickingEmployeeServer{})
+	}))
+
+	ctx := context.Background()
+	call := func(tenant int, identityNumber string) (metadata.MD, codes.Code) {
+		token, err := jwt.JwtManager.CreateManager(tenant, "manager@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var header metadata.MD
+		_, err = client.GetEmployee(
+			metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token),
+			&pb.GetEmployeeRequest{IdentityNumber: identityNumber},
+			grpc.Header(&header),
+		)
+
+		return header, status.Code(err)
+	}
+
+	header, code := call(1, "1")
+	if code != codes.OK {
+		t.Fatalf("first call: code = %s, want OK", code)
+	}
+	if remaining := header.Get("ratelimit-remaining"); len(remaining) != 1 || remaining[0] != "1" {
+		t.Errorf("ratelimit-remaining = %v, want 1", remaining)
+	}
+
+	if _, code := call(1, "panic"); code != codes.Internal {
+		t.Errorf("panicking handler: code = %s, want Internal", code)
+	}
+	if _, code := call(1, "1"); code != codes.ResourceExhausted {
+		t.Errorf("call beyond the limit: code = %s, want ResourceExhausted", code)
+	}
+	if _, code := call(2, "1"); code != codes.OK {
+		t.Errorf("another manager: code = %s, want OK", code)
+	}
+
+	token, err := jwt.JwtManager.CreateManager(2, "manager@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticated := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+token)
+	if _, err := client.DeleteEmployee(authenticated, &pb.DeleteEmployeeRequest{}); status.Code(err) != codes.OK {
+		t.Errorf("first write: err = %v, want none", err)
+	}
+	if _, err := client.DeleteEmployee(authenticated, &pb.DeleteEmployeeRequest{}); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("write beyond the limit: err = %v, want ResourceExhausted", err)
+	}
+}
+
+// startGRPCServer serves what register registers through the interceptors of the gRPC server,
+// the returned connection dials it
+func TestGRPCScopedToTenant(t *testing.T) {
+	departments := &memoryDepartmentRepository{departments: []*entity.Department{
+		{ID: 10, Name: "Engineering", ManagerID: 1},
+		{ID: 20, Name: "Engineering", ManagerID: 2},
+	}}
+	employees := &memoryEmployeeService{departments: departments, employees: []*dto.EmployeeDataRes{
+		{IdentityNumber: "own-1", Name: "Own", Gender: "female", DepartmentID: "20"},
+		{IdentityNumber: "other-1", Name: "Other", Gender: "male", DepartmentID: "10"},
+	}}
+	departmentService := &memoryDepartmentService{departments: departments}
+
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{
+		ratelimit.ClassRead:                         {},
+		ratelimit.ClassWrite:                        {},
+		ratelimit.TenantClass(ratelimit.ClassRead):  {},
+		ratelimit.TenantClass(ratelimit.ClassWrite): {},
+	}, false)
+	middleware := middlewares.NewMiddleware(jwt.Jwt, jwt.JwtManager, jwt.JwtChallenge, nil, limiter, nil, false)
+	conn := startGRPCServer(t, middleware, func(registrar grpc.ServiceRegistrar) {
+		employeeRpc.Register(registrar, employees, departmentService, metrics.Metrics, false)
+		deptRpc.Register(registrar, departmentService, metrics.Metrics, false)
+	})
+	employeeClient := pb.NewEmployeeServiceClient(conn)
+	departmentClient := pb.NewDepartmentServiceClient(conn)
+
+	// Manager 2 calls, everything of manager 1 is out of reach
+	token, err := jwt.JwtManager.CreateManager(2, "manager@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+
+	if _, err := employeeClient.GetEmployee(ctx, &pb.GetEmployeeRequest{IdentityNumber: "other-1"}); status.Code(err) != codes.NotFound {
+		t.Errorf("get employee of another tenant: err = %v, want NotFound", err)
+	}
+	if _, err := employeeClient.UpdateEmployee(ctx, &pb.UpdateEmployeeRequest{IdentityNumber: "other-1"}); status.Code(err) != codes.NotFound {
+		t.Errorf("update employee of another tenant: err = %v, want NotFound", err)
+	}
+	if _, err := employeeClient.DeleteEmployee(ctx, &pb.DeleteEmployeeRequest{IdentityNumber: "other-1"}); status.Code(err) != codes.NotFound {
+		t.Errorf("delete employee of another tenant: err = %v, want NotFound", err)
+	}
+	moved := "10"
+	if _, err := employeeClient.UpdateEmployee(ctx, &pb.UpdateEmployeeRequest{IdentityNumber: "own-1", DepartmentId: &moved}); status.Code(err) != codes.NotFound {
+		t.Errorf("move employee to a department of another tenant: err = %v, want NotFound", err)
+	}
+	if len(employees.updated) > 0 || len(employees.deleted) > 0 {
+		t.Errorf("updated %v and deleted %v, want nothing changed", employees.updated, employees.deleted)
+	}
+
+	stream, err := employeeClient.StreamEmployees(ctx, &pb.StreamEmployeesRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var streamed []string
+	for {
+		employee, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		streamed = append(streamed, employee.GetIdentityNumber())
+	}
+	if !slices.Equal(streamed, []string{"own-1"}) {
+		t.Errorf("streamed %v, want own-1 alone", streamed)
+	}
+
+	list, err := employeeClient.ListEmployees(ctx, &pb.ListEmployeesRequest{Filter: &pb.EmployeeFilter{IdentityNumber: "other-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.GetEmployees()) > 0 {
+		t.Errorf("filter matched %d employees of another tenant", len(list.GetEmployees()))
+	}
+
+	if _, err := departmentClient.GetDepartment(ctx, &pb.GetDepartmentRequest{DepartmentId: "10"}); status.Code(err) != codes.NotFound {
+		t.Errorf("get department of another tenant: err = %v, want NotFound", err)
+	}
+	if _, err := departmentClient.DeleteDepartment(ctx, &pb.DeleteDepartmentRequest{DepartmentId: "10"}); status.Code(err) != codes.NotFound || len(departmentService.deleted) > 0 {
+		t.Errorf("delete department of another tenant: err = %v, deleted %v", err, departmentService.deleted)
+	}
+
+	listed, err := departmentClient.ListDepartments(ctx, &pb.ListDepartmentsRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(listed.GetDepartments()) != 1 || listed.GetDepartments()[0].GetDepartmentId() != "20" {
+		t.Errorf("listed %v, want department 20 alone", listed.GetDepartments())
+	}
+}
+
+func startGRPCServer(t *testing.T, middleware *middlewares.Middleware, register func(grpc.ServiceRegistrar)) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+	srv := server.NewGrpcServer(middleware)
+	register(srv)
+
+	grpcSrv := srv.(interface {
+		Serve(net.Listener) error
+		Stop()
+	})
+	go grpcSrv.Serve(listener)
+	t.Cleanup(grpcSrv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return conn
+}
+
+// panickingEmployeeServer panics getting the employee "panic", other calls succeed
+type panickingEmployeeServer struct {
+	pb.UnimplementedEmployeeServiceServer
+}
+
+func (s *panickingEmployeeServer) GetEmployee(_ context.Context, req *pb.GetEmployeeRequest) (*pb.Employee, error) {
+	if req.IdentityNumber == "panic" {
+		panic("employee store unavailable")
+	}
+
+	return &pb.Employee{IdentityNumber: req.IdentityNumber}, nil
+}
+
+func (s *panickingEmployeeServer) DeleteEmployee(context.Context, *pb.DeleteEmployeeRequest) (*pb.DeleteEmployeeResponse, error) {
+	return &pb.DeleteEmployeeResponse{}, nil
+}
@@ -0,0 +1,209 @@
+package tests
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/hasher"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Cheap parameters keep the tests fast, the algorithms don't care
+var (
+	testArgon2id = hasher.Config{Algorithm: hasher.AlgorithmArgon2id, Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1, BcryptCost: bcrypt.MinCost}
+	testBcrypt   = hasher.Config{Algorithm: hasher.AlgorithmBcrypt, Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1, BcryptCost: bcrypt.MinCost}
+)
+
+func newTestHasher(t *testing.T, config hasher.Config) *hasher.HasherStruct {
+	t.Helper()
+
+	h, err := hasher.New(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return h
+}
+
+func TestHasherRoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		config hasher.Config
+		prefix string
+	}{
+		{name: "argon2id", config: testArgon2id, prefix: "$argon2id$v=19$m=64,t=1,p=1$"},
+		{name: "bcrypt", config: testBcrypt, prefix: "$2a$04$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHasher(t, tt.config)
+
+			hashed, err := h.Hash("correct horse battery staple")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(hashed, tt.prefix) {
+				t.Errorf("hash = %s, want it to start with %s", hashed, tt.prefix)
+			}
+
+			if ok, needsRehash := h.Verify("correct horse battery staple", hashed); !ok || needsRehash {
+				t.Errorf("verify the password = %v, %v, want a match needing no rehash", ok, needsRehash)
+			}
+			if ok, _ := h.Verify("correct horse battery stapler", hashed); ok {
+				t.Error("verify another password matched")
+			}
+
+			// Every hash is salted on its own
+			again, err := h.Hash("correct horse battery staple")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again == hashed {
+				t.Error("hashing twice gave the same hash")
+			}
+		})
+	}
+}
+
+func TestHasherVerifiesLegacyBcrypt(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := newTestHasher(t, testArgon2id)
+
+	tests := []struct {
+		name        string
+		password    string
+		hashed      string
+		ok          bool
+		needsRehash bool
+	}{
+		{name: "matching password", password: "password123", hashed: string(legacy), ok: true, needsRehash: true},
+		{name: "wrong password", password: "password124", hashed: string(legacy), ok: false},
+		{name: "$2y$ prefix", password: "password123", hashed: "$2y$" + strings.TrimPrefix(string(legacy), "$2a$"), ok: true, needsRehash: true},
+		{name: "unknown algorithm", password: "password123", hashed: "$scrypt$whatever", ok: false},
+		{name: "plaintext", password: "password123", hashed: "password123", ok: false},
+		{name: "truncated argon2id", password: "password123", hashed: "$argon2id$v=19$m=64,t=1,p=1$c2FsdA", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, needsRehash := h.Verify(tt.password, tt.hashed)
+			if ok != tt.ok || needsRehash != tt.needsRehash {
+				t.Errorf("verify = %v, %v, want %v, %v", ok, needsRehash, tt.ok, tt.needsRehash)
+			}
+		})
+	}
+}
+
+func TestHasherRefusesPasswordsBcryptWouldTruncate(t *testing.T) {
+	h := newTestHasher(t, testBcrypt)
+	long := strings.Repeat("a", 72)
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  error
+	}{
+		{name: "72 bytes", password: long},
+		{name: "73 bytes", password: long + "b", wantErr: domain.ErrPasswordTooLong},
+		// Length is in bytes, not runes: 24 three-byte runes and one more
+		{name: "multibyte", password: strings.Repeat("€", 25), wantErr: domain.ErrPasswordTooLong},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := h.Hash(tt.password)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("hash = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// A bcrypt hash of the first 72 bytes must not match the longer password either
+	hashed, err := h.Hash(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := h.Verify(long+"b", hashed); ok {
+		t.Error("a 73 byte password matched the hash of its first 72 bytes")
+	}
+
+	// argon2id has no such limit
+	argon := newTestHasher(t, testArgon2id)
+	hashed, err = argon.Hash(long + "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := argon.Verify(long+"b", hashed); !ok {
+		t.Error("argon2id did not match a 73 byte password")
+	}
+	if ok, _ := argon.Verify(long, hashed); ok {
+		t.Error("argon2id matched the first 72 bytes of the password")
+	}
+}
+
+func TestHasherNeedsRehashAfterParameterChange(t *testing.T) {
+	with := func(config hasher.Config, change func(*hasher.Config)) hasher.Config {
+		change(&config)
+		return config
+	}
+
+	tests := []struct {
+		name        string
+		hashedWith  hasher.Config
+		verifyWith  hasher.Config
+		needsRehash bool
+	}{
+		{name: "same argon2id parameters", hashedWith: testArgon2id, verifyWith: testArgon2id},
+		{name: "more memory", hashedWith: testArgon2id, verifyWith: with(testArgon2id, func(c *hasher.Config) { c.Argon2Memory = 128 }), needsRehash: true},
+		{name: "more iterations", hashedWith: testArgon2id, verifyWith: with(testArgon2id, func(c *hasher.Config) { c.Argon2Iterations = 2 }), needsRehash: true},
+		{name: "other parallelism", hashedWith: testArgon2id, verifyWith: with(testArgon2id, func(c *hasher.Config) { c.Argon2Parallelism = 2 }), needsRehash: true},
+		{name: "less memory", hashedWith: with(testArgon2id, func(c *hasher.Config) { c.Argon2Memory = 128 }), verifyWith: testArgon2id},
+		{name: "argon2id to bcrypt", hashedWith: testArgon2id, verifyWith: testBcrypt, needsRehash: true},
+		{name: "same bcrypt cost", hashedWith: testBcrypt, verifyWith: testBcrypt},
+		{name: "higher bcrypt cost", hashedWith: testBcrypt, verifyWith: with(testBcrypt, func(c *hasher.Config) { c.BcryptCost = bcrypt.MinCost + 1 }), needsRehash: true},
+		{name: "bcrypt to argon2id", hashedWith: testBcrypt, verifyWith: testArgon2id, needsRehash: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hashed, err := newTestHasher(t, tt.hashedWith).Hash("password123")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ok, needsRehash := newTestHasher(t, tt.verifyWith).Verify("password123", hashed)
+			if !ok || needsRehash != tt.needsRehash {
+				t.Errorf("verify = %v, %v, want true, %v", ok, needsRehash, tt.needsRehash)
+			}
+		})
+	}
+}
+
+func TestHasherCompareDummyNeverMatches(t *testing.T) {
+	tests := []struct {
+		name   string
+		config hasher.Config
+	}{
+		{name: "argon2id", config: testArgon2id},
+		{name: "bcrypt", config: testBcrypt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHasher(t, tt.config)
+
+			for _, password := range []string{"", "password123", "gogomanager-dummy-password", strings.Repeat("a", 100)} {
+				if h.CompareDummy(password) {
+					t.Errorf("CompareDummy(%q) matched", password)
+				}
+			}
+		})
+	}
+}
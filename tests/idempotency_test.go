@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	idempotencyRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/idempotency/repository"
+	idempotencySvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/idempotency/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	errorhandler "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/error_handler"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+)
+
+func TestIdempotentReplaysStoredResponse(t *testing.T) {
+	send, calls := idempotentApp(t)
+
+	first := send(1, "key-1", `{"name":"Jane Doe"}`)
+	if first.status != http.StatusCreated || first.replayed {
+		t.Fatalf("first request = %+v, want a fresh 201", first)
+	}
+
+	again := send(1, "key-1", `{"name":"Jane Doe"}`)
+	if again.status != http.StatusCreated || !again.replayed || again.body != first.body || again.contentType != first.contentType {
+		t.Errorf("retry = %+v, want %+v replayed", again, first)
+	}
+
+	if *calls != 1 {
+		t.Errorf("handler ran %d times, want once", *calls)
+	}
+
+	if res := send(1, "", `{"name":"Jane Doe"}`); res.status != http.StatusCreated || res.replayed || *calls != 2 {
+		t.Errorf("request without a key = %+v after %d calls, want a fresh 201", res, *calls)
+	}
+}
+
+func TestIdempotentRejectsKeyReusedForAnotherRequest(t *testing.T) {
+	send, calls := idempotentApp(t)
+
+	if res := send(1, "key-1", `{"name":"Jane Doe"}`); res.status != http.StatusCreated {
+		t.Fatalf("first request = %+v, want 201", res)
+	}
+
+	res := send(1, "key-1", `{"name":"John Doe"}`)
+	if res.status != http.StatusUnprocessableEntity || !strings.Contains(res.body, domain.ErrIdempotencyKeyReused.Code) {
+		t.Errorf("other payload under the same key = %+v, want 422 %s", res, domain.ErrIdempotencyKeyReused.Code)
+	}
+	if *calls != 1 {
+		t.Errorf("handler ran %d times, want once", *calls)
+	}
+}
+
+func TestIdempotentKeysArePerTenant(t *testing.T) {
+	send, calls := idempotentApp(t)
+
+	first := send(1, "key-1", `{"name":"Jane Doe"}`)
+	if first.status != http.StatusCreated {
+		t.Fatalf("first tenant = %+v, want 201", first)
+	}
+
+	// The same key and body from another tenant is another request, neither replayed nor refused
+	second := send(2, "key-1", `{"name":"Jane Doe"}`)
+	if second.status != http.StatusCreated || second.replayed || second.body == first.body {
+		t.Errorf("second tenant = %+v, want a fresh 201", second)
+	}
+	if *calls != 2 {
+		t.Errorf("handler ran %d times, want twice", *calls)
+	}
+
+	// Each tenant gets its own response replayed
+	if res := send(2, "key-1", `{"name":"Jane Doe"}`); !res.replayed || res.body != second.body {
+		t.Errorf("second tenant retrying = %+v, want %+v replayed", res, second)
+	}
+	if res := send(1, "key-1", `{"name":"Jane Doe"}`); !res.replayed || res.body != first.body {
+		t.Errorf("first tenant retrying = %+v, want %+v replayed", res, first)
+	}
+}
+
+func TestIdempotencyRepositoryKeysArePerTenant(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	first, _ := createTestDepartment(t, db)
+	second, _ := createTestDepartment(t, db)
+	repo := idempotencyRepo.NewIdempotencyRepository(db)
+
+	key := entity.IdempotencyKey{Key: "key-1", Fingerprint: strings.Repeat("a", 64), ExpiresAt: time.Now().Add(time.Hour)}
+	staleBefore := time.Now().Add(-time.Minute)
+
+	for _, tenantID := range []int{first, second} {
+		key.TenantID = tenantID
+		existing, err := repo.Reserve(ctx, key, staleBefore)
+		if err != nil || existing != nil {
+			t.Fatalf("reserve for tenant %d = %+v, %v, want it reserved", tenantID, existing, err)
+		}
+	}
+
+	if err := repo.Complete(ctx, first, "key-1", http.StatusCreated, fiber.MIMEApplicationJSON, []byte(`{"id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	key.TenantID = first
+	existing, err := repo.Reserve(ctx, key, staleBefore)
+	if err != nil || existing == nil || existing.StatusCode == nil || string(existing.Body) != `{"id":1}` {
+		t.Fatalf("reserve again for the first tenant = %+v, %v, want the stored response", existing, err)
+	}
+
+	// The second tenant's reservation is still its own, in progress
+	key.TenantID = second
+	existing, err = repo.Reserve(ctx, key, staleBefore)
+	if err != nil || existing == nil || existing.StatusCode != nil {
+		t.Errorf("reserve again for the second tenant = %+v, %v, want its reservation in progress", existing, err)
+	}
+}
+
+type idempotentRes struct {
+	status      int
+	replayed    bool
+	contentType string
+	body        string
+}
+
+// idempotentApp serves an idempotent create route counting its calls. The returned function sends
+// the body as the given tenant, with an Idempotency-Key header unless key is empty.
+func idempotentApp(t *testing.T) (func(tenantID int, key, body string) idempotentRes, *int) {
+	t.Helper()
+
+	service := idempotencySvc.NewIdempotencyService(newMemoryIdempotencyRepository(), idempotencySvc.Config{TTL: time.Hour})
+	middleware := middlewares.NewMiddleware(nil, nil, nil, nil, nil, service, false)
+
+	calls := 0
+	app := fiber.New(fiber.Config{ErrorHandler: errorhandler.ErrorHandler})
+	app.Use(func(ctx *fiber.Ctx) error {
+		tenantID, _ := strconv.Atoi(ctx.Get("X-Tenant-ID"))
+		ctx.SetUserContext(reqctx.WithPrincipal(ctx.UserContext(), reqctx.Principal{TenantID: tenantID}))
+		return ctx.Next()
+	})
+	app.Post("/v1/employee", middleware.Idempotent(), func(ctx *fiber.Ctx) error {
+		calls++
+		return ctx.Status(http.StatusCreated).JSON(fiber.Map{"call": calls})
+	})
+
+	send := func(tenantID int, key, body string) idempotentRes {
+		req := httptest.NewRequest(http.MethodPost, "/v1/employee", strings.NewReader(body))
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		req.Header.Set("X-Tenant-ID", strconv.Itoa(tenantID))
+		if key != "" {
+			req.Header.Set(middlewares.HeaderIdempotencyKey, key)
+		}
+
+		res, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+
+		read, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return idempotentRes{
+			status:      res.StatusCode,
+			replayed:    res.Header.Get(middlewares.HeaderIdempotentReplayed) == "true",
+			contentType: res.Header.Get(fiber.HeaderContentType),
+			body:        string(read),
+		}
+	}
+
+	return send, &calls
+}
+
+// memoryIdempotencyRepository keeps keys like the Postgres repository, per tenant and never expiring
+type memoryIdempotencyRepository struct {
+	contracts.IdempotencyRepository
+	keys map[string]*entity.IdempotencyKey
+}
+
+func newMemoryIdempotencyRepository() *memoryIdempotencyRepository {
+	return &memoryIdempotencyRepository{keys: map[string]*entity.IdempotencyKey{}}
+}
+
+func (r *memoryIdempotencyRepository) Reserve(_ context.Context, key entity.IdempotencyKey, _ time.Time) (*entity.IdempotencyKey, error) {
+	id := strconv.Itoa(key.TenantID) + ":" + key.Key
+	if existing, ok := r.keys[id]; ok {
+		copied := *existing
+		return &copied, nil
+	}
+
+	r.keys[id] = &key
+	return nil, nil
+}
+
+func (r *memoryIdempotencyRepository) Complete(_ context.Context, tenantID int, key string, statusCode int, contentType string, body []byte) error {
+	stored := r.keys[strconv.Itoa(tenantID)+":"+key]
+	stored.StatusCode = &statusCode
+	stored.ContentType = &contentType
+	stored.Body = body
+
+	return nil
+}
+
+func (r *memoryIdempotencyRepository) Release(_ context.Context, tenantID int, key string) error {
+	delete(r.keys, strconv.Itoa(tenantID)+":"+key)
+	return nil
+}
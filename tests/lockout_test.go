@@ -0,0 +1,124 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	lockoutSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/lockout/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/server"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+func TestLockoutPerClientBehindProxy(t *testing.T) {
+	tests := []struct {
+		name    string
+		trusted string
+		// separate tells whether clients behind the proxy are counted apart
+		separate bool
+	}{
+		// Requests made with app.Test come from 0.0.0.0
+		{name: "trusted proxy", trusted: "10.0.0.1, 0.0.0.0/32", separate: true},
+		{name: "untrusted proxy", trusted: "10.0.0.1", separate: false},
+		{name: "no proxy", trusted: "", separate: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lockout := lockoutSvc.NewLockoutService(newMemoryLoginAttempts(), validator.Validator, lockoutSvc.Policy{
+				MaxAttempts:   100,
+				IPMaxAttempts: 2,
+			})
+
+			s := server.NewHttpServer(server.ProxyConfig{TrustedProxies: tt.trusted, Header: "X-Real-IP"})
+			app := s.GetApp()
+			app.Use(middlewares.RequestID())
+			app.Post("/login/:email", func(ctx *fiber.Ctx) error {
+				account := lockoutSvc.Account(lockoutSvc.KindManager, ctx.Params("email"))
+				if err := lockout.Check(ctx.UserContext(), account); err != nil {
+					return err
+				}
+
+				return lockout.Fail(ctx.UserContext(), account)
+			})
+
+			login := func(clientIP, email string) int {
+				req := httptest.NewRequest(http.MethodPost, "/login/"+email, nil)
+				req.Header.Set("X-Real-IP", clientIP)
+
+				res, err := app.Test(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				return res.StatusCode
+			}
+
+			// Two bad passwords on different accounts lock the client IP
+			login("203.0.113.1", "a@example.com")
+			login("203.0.113.1", "b@example.com")
+			if status := login("203.0.113.1", "c@example.com"); status != http.StatusTooManyRequests {
+				t.Fatalf("locked client: status = %d, want 429", status)
+			}
+
+			status := login("203.0.113.2", "d@example.com")
+			if tt.separate && status != http.StatusUnauthorized {
+				t.Errorf("another client behind the proxy: status = %d, want 401", status)
+			}
+			if !tt.separate && status != http.StatusTooManyRequests {
+				t.Errorf("forged client IP: status = %d, want 429", status)
+			}
+		})
+	}
+}
+
+// memoryLoginAttempts keeps the failures and locks of LoginAttemptRepository in memory
+type memoryLoginAttempts struct {
+	mu       sync.Mutex
+	failures map[string]int
+	locked   map[string]time.Time
+}
+
+func newMemoryLoginAttempts() *memoryLoginAttempts {
+	return &memoryLoginAttempts{
+		failures: map[string]int{},
+		locked:   map[string]time.Time{},
+	}
+}
+
+func (r *memoryLoginAttempts) RecordFailure(_ context.Context, scope, subject string, _ time.Duration) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failures[scope+":"+subject]++
+	return r.failures[scope+":"+subject], nil
+}
+
+func (r *memoryLoginAttempts) Lock(_ context.Context, scope, subject string, duration time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.locked[scope+":"+subject] = time.Now().Add(duration)
+	return nil
+}
+
+func (r *memoryLoginAttempts) LockedFor(_ context.Context, scope, subject string) (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return max(time.Until(r.locked[scope+":"+subject]), 0), nil
+}
+
+func (r *memoryLoginAttempts) Reset(_ context.Context, scope, subject string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.failures, scope+":"+subject)
+	delete(r.locked, scope+":"+subject)
+	return nil
+}
@@ -0,0 +1,356 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	lockoutSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/lockout/service"
+	mfaRepo "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/mfa/repository"
+	mfaSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/mfa/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/totp"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+func TestTOTPMatchesRFC6238(t *testing.T) {
+	// The SHA-1 seed of RFC 6238 appendix B, "12345678901234567890", in base32. The RFC lists
+	// 8 digit codes, 6 digit ones are their last 6 digits.
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	tests := []struct {
+		unix int64
+		code string
+		step int64
+	}{
+		{unix: 59, code: "287082", step: 1},
+		{unix: 1111111109, code: "081804", step: 37037036},
+		{unix: 1111111111, code: "050471", step: 37037037},
+		{unix: 1234567890, code: "005924", step: 41152263},
+		{unix: 2000000000, code: "279037", step: 66666666},
+		{unix: 20000000000, code: "353130", step: 666666666},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.unix), func(t *testing.T) {
+			at := time.Unix(tt.unix, 0)
+
+			step, ok := totp.TOTP.Validate(secret, tt.code, at)
+			if !ok || step != tt.step {
+				t.Errorf("validate = %d, %v, want step %d", step, ok, tt.step)
+			}
+
+			// One period of drift either way is absorbed, two are not
+			if step, ok := totp.TOTP.Validate(secret, tt.code, at.Add(totp.Period)); !ok || step != tt.step {
+				t.Errorf("validate a period later = %d, %v, want step %d", step, ok, tt.step)
+			}
+			if _, ok := totp.TOTP.Validate(secret, tt.code, at.Add(2*totp.Period)); ok {
+				t.Error("validate two periods later matched")
+			}
+			// Before the epoch there is no earlier step to drift from
+			if _, ok := totp.TOTP.Validate(secret, tt.code, at.Add(-2*totp.Period)); ok && tt.step > 1 {
+				t.Error("validate two periods early matched")
+			}
+		})
+	}
+
+	for _, code := range []string{"", "28708", "2870820", "94287082", "28708a"} {
+		if _, ok := totp.TOTP.Validate(secret, code, time.Unix(59, 0)); ok {
+			t.Errorf("validate %q matched", code)
+		}
+	}
+}
+
+func TestMFARejectsReusedStep(t *testing.T) {
+	ctx := context.Background()
+	codes := &stepTOTP{TOTPInterface: totp.TOTP}
+	mfa, _ := newTestMFAService(codes)
+	subject := dto.MFASubject{Kind: lockoutSvc.KindManager, ID: "1", Email: "jane@example.com"}
+
+	if _, err := mfa.Enroll(ctx, subject); err != nil {
+		t.Fatal(err)
+	}
+
+	codes.step = 100
+	if _, err := mfa.Confirm(ctx, subject, dto.MFACodeRequest{Code: stepCode}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		step    int64
+		wantErr error
+	}{
+		{name: "step used to confirm", step: 100, wantErr: domain.ErrInvalidMFACode},
+		{name: "next step", step: 101},
+		{name: "same step again", step: 101, wantErr: domain.ErrInvalidMFACode},
+		{name: "earlier step", step: 100, wantErr: domain.ErrInvalidMFACode},
+		{name: "later step", step: 103},
+	}
+
+	for _, tt := range tests {
+		codes.step = tt.step
+
+		_, err := mfa.Verify(ctx, subject, dto.MFACodeRequest{Code: stepCode})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: verify = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMFARecoveryCodesWorkOnce(t *testing.T) {
+	ctx := context.Background()
+	codes := &stepTOTP{TOTPInterface: totp.TOTP, step: 100}
+	mfa, _ := newTestMFAService(codes)
+	subject := dto.MFASubject{Kind: lockoutSvc.KindManager, ID: "1", Email: "jane@example.com"}
+
+	if _, err := mfa.Enroll(ctx, subject); err != nil {
+		t.Fatal(err)
+	}
+
+	confirmed, err := mfa.Confirm(ctx, subject, dto.MFACodeRequest{Code: stepCode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recovery := confirmed.RecoveryCodes
+	if len(recovery) != mfaSvc.RecoveryCodeCount {
+		t.Fatalf("got %d recovery codes, want %d", len(recovery), mfaSvc.RecoveryCodeCount)
+	}
+
+	tests := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{name: "first use", code: recovery[0]},
+		{name: "second use", code: recovery[0], wantErr: domain.ErrInvalidMFACode},
+		// Codes are typed back the way they were read
+		{name: "upper case without dash", code: strings.ToUpper(strings.ReplaceAll(recovery[1], "-", ""))},
+		{name: "unknown code", code: "aaaaa-bbbbb", wantErr: domain.ErrInvalidMFACode},
+	}
+
+	for _, tt := range tests {
+		_, err := mfa.Verify(ctx, subject, dto.MFACodeRequest{Code: tt.code})
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: verify = %v, want %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	// A recovery code can't mint a fresh set
+	_, err = mfa.RegenerateRecoveryCodes(ctx, subject, dto.MFACodeRequest{Code: recovery[2]})
+	if !errors.Is(err, domain.ErrInvalidMFACode) {
+		t.Errorf("regenerate with a recovery code = %v, want ErrInvalidMFACode", err)
+	}
+}
+
+func TestMFAChallengeTokenAudience(t *testing.T) {
+	ctx := context.Background()
+	codes := &stepTOTP{TOTPInterface: totp.TOTP, step: 100}
+	mfa, tokens := newTestMFAService(codes)
+	subject := dto.MFASubject{Kind: lockoutSvc.KindManager, ID: "1", Email: "jane@example.com"}
+
+	// Without a factor nor the requirement the password is enough
+	if challenge, err := mfa.Challenge(ctx, subject); err != nil || challenge != nil {
+		t.Fatalf("challenge without 2FA = %+v, %v, want none", challenge, err)
+	}
+
+	if _, err := mfa.Enroll(ctx, subject); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mfa.Confirm(ctx, subject, dto.MFACodeRequest{Code: stepCode}); err != nil {
+		t.Fatal(err)
+	}
+
+	challenge, err := mfa.Challenge(ctx, subject)
+	if err != nil || challenge == nil {
+		t.Fatalf("challenge = %+v, %v, want a token", challenge, err)
+	}
+
+	var claims jwt.ClaimsChallenge
+	if err := tokens.DecodeChallenge(challenge.MFAToken, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.Purpose != jwt.ChallengeVerify || claims.SubjectID != "1" || challenge.EnrollmentRequired {
+		t.Errorf("challenge claims = %+v, want verify of manager 1", claims)
+	}
+
+	// The challenge is no access token, nor the other way round
+	if err := tokens.DecodeManager(challenge.MFAToken, &jwt.ClaimsManager{}); err == nil {
+		t.Error("the challenge token was accepted as a manager access token")
+	}
+	if err := tokens.Decode(challenge.MFAToken, &jwt.Claims{}); err == nil {
+		t.Error("the challenge token was accepted as a user access token")
+	}
+
+	codes.step = 101
+	verified, err := mfa.Verify(ctx, subject, dto.MFACodeRequest{Code: stepCode})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tokens.DecodeManager(verified.Token, &jwt.ClaimsManager{}); err != nil {
+		t.Errorf("access token = %v, want a manager token", err)
+	}
+	if err := tokens.DecodeChallenge(verified.Token, &jwt.ClaimsChallenge{}); err == nil {
+		t.Error("the access token was accepted as a challenge")
+	}
+}
+
+func TestMFARepositoryUsesStepsAndCodesOnce(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := mfaRepo.NewMFARepository(db)
+
+	subjectID := fmt.Sprint(time.Now().UnixNano())
+	t.Cleanup(func() {
+		_, _ = db.Exec("DELETE FROM mfa_factors WHERE subject_kind = 'test' AND subject_id = $1", subjectID)
+	})
+
+	if err := repo.SaveFactor(ctx, entity.MFAFactor{SubjectKind: "test", SubjectID: subjectID, Secret: "GEZDGNBVGY3TQOJQ"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.ConfirmFactor(ctx, "test", subjectID, []string{strings.Repeat("a", 64), strings.Repeat("b", 64)}); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		step int64
+		want bool
+	}{
+		{step: 100, want: true},
+		{step: 100, want: false},
+		{step: 99, want: false},
+		{step: 101, want: true},
+	}
+	for _, tt := range steps {
+		used, err := repo.UseStep(ctx, "test", subjectID, tt.step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used != tt.want {
+			t.Errorf("use step %d = %v, want %v", tt.step, used, tt.want)
+		}
+	}
+
+	recovery := []struct {
+		hash string
+		want bool
+	}{
+		{hash: strings.Repeat("a", 64), want: true},
+		{hash: strings.Repeat("a", 64), want: false},
+		{hash: strings.Repeat("c", 64), want: false},
+		{hash: strings.Repeat("b", 64), want: true},
+	}
+	for _, tt := range recovery {
+		used, err := repo.UseRecoveryCode(ctx, "test", subjectID, tt.hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used != tt.want {
+			t.Errorf("use recovery code %s… = %v, want %v", tt.hash[:4], used, tt.want)
+		}
+	}
+}
+
+// stepCode is the only code stepTOTP accepts
+const stepCode = "123456"
+
+// stepTOTP accepts stepCode as a code of whatever step the test sets
+type stepTOTP struct {
+	totp.TOTPInterface
+	step int64
+}
+
+func (s *stepTOTP) Validate(_, code string, _ time.Time) (int64, bool) {
+	return s.step, code == stepCode
+}
+
+func newTestMFAService(codes totp.TOTPInterface) (contracts.MFAService, *jwt.JwtStruct) {
+	tokens := &jwt.JwtStruct{SecretKey: "test", ExpiredTime: time.Minute}
+	lockout := lockoutSvc.NewLockoutService(newMemoryLoginAttempts(), validator.Validator, lockoutSvc.Policy{})
+	mfa := mfaSvc.NewMFAService(newMemoryMFARepository(), lockout, codes, tokens, tokens, tokens, validator.Validator, mfaSvc.Policy{})
+
+	return mfa, tokens
+}
+
+// memoryMFARepository keeps factors like the Postgres repository: the step of a factor only moves
+// forward and a recovery code is used once
+type memoryMFARepository struct {
+	contracts.MFARepository
+	factors  map[string]*entity.MFAFactor
+	recovery map[string]map[string]bool
+}
+
+func newMemoryMFARepository() *memoryMFARepository {
+	return &memoryMFARepository{
+		factors:  map[string]*entity.MFAFactor{},
+		recovery: map[string]map[string]bool{},
+	}
+}
+
+func (r *memoryMFARepository) GetFactor(_ context.Context, kind, subjectID string) (*entity.MFAFactor, error) {
+	factor, ok := r.factors[kind+":"+subjectID]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+
+	copied := *factor
+	return &copied, nil
+}
+
+func (r *memoryMFARepository) SaveFactor(_ context.Context, factor entity.MFAFactor) error {
+	if current, ok := r.factors[factor.SubjectKind+":"+factor.SubjectID]; ok && current.ConfirmedAt != nil {
+		return nil
+	}
+
+	r.factors[factor.SubjectKind+":"+factor.SubjectID] = &factor
+	return nil
+}
+
+func (r *memoryMFARepository) ConfirmFactor(ctx context.Context, kind, subjectID string, codeHashes []string) error {
+	now := time.Now()
+	r.factors[kind+":"+subjectID].ConfirmedAt = &now
+
+	return r.ReplaceRecoveryCodes(ctx, kind, subjectID, codeHashes)
+}
+
+func (r *memoryMFARepository) UseStep(_ context.Context, kind, subjectID string, step int64) (bool, error) {
+	factor := r.factors[kind+":"+subjectID]
+	if factor.LastUsedStep != nil && *factor.LastUsedStep >= step {
+		return false, nil
+	}
+
+	factor.LastUsedStep = &step
+	return true, nil
+}
+
+func (r *memoryMFARepository) UseRecoveryCode(_ context.Context, kind, subjectID, codeHash string) (bool, error) {
+	codes := r.recovery[kind+":"+subjectID]
+	if used, ok := codes[codeHash]; !ok || used {
+		return false, nil
+	}
+
+	codes[codeHash] = true
+	return true, nil
+}
+
+func (r *memoryMFARepository) ReplaceRecoveryCodes(_ context.Context, kind, subjectID string, codeHashes []string) error {
+	codes := map[string]bool{}
+	for _, hash := range codeHashes {
+		codes[hash] = false
+	}
+
+	r.recovery[kind+":"+subjectID] = codes
+	return nil
+}
+
+func (r *memoryMFARepository) GetManagerRequired(context.Context, int) (bool, error) {
+	return false, nil
+}
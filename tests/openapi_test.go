@@ -0,0 +1,27 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/database"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/server"
+)
+
+// TestRoutesAreDocumented mounts every route and fails for those missing from apiSpec. Mounting
+// needs no database, sqlx.Open only connects on first use.
+func TestRoutesAreDocumented(t *testing.T) {
+	db, err := sqlx.Open("pgx", database.DataSourceName())
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	defer db.Close()
+
+	s := server.NewHttpServer(server.ProxyConfig{})
+	s.MountMiddlewares()
+	s.MountRoutes(db)
+
+	for _, route := range server.Undocumented(s.GetApp()) {
+		t.Errorf("%s is not in the OpenAPI document, add it to apiSpec in internal/infra/server/docs.go", route)
+	}
+}
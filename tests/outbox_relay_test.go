@@ -0,0 +1,555 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	employeeSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/employee/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/outbox/repository"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/outbox/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/database"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/broker"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/fieldcrypt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/uuid"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+func TestOutboxRelayRetriesFailedEvents(t *testing.T) {
+	repo := newMemoryOutboxRepository()
+	id := repo.add("employee", "1")
+
+	logged := &fakePublisher{}
+	flaky := &fakePublisher{failures: map[string]int{repo.event(id).EventID: 1}}
+	relay := service.NewOutboxRelay(repo, []contracts.OutboxSink{
+		service.NewBrokerSink("first", logged),
+		service.NewBrokerSink("second", flaky),
+	}, service.RelayConfig{})
+
+	relayed(t, relay, 0)
+
+	event := repo.event(id)
+	if event.Status != entity.OutboxEventPending || event.Attempts != 1 {
+		t.Fatalf("failed event: status = %s after %d attempts, want pending after 1", event.Status, event.Attempts)
+	}
+	if !strings.HasPrefix(event.LastError, "second: ") {
+		t.Errorf("last error = %q, want it to name the failing sink", event.LastError)
+	}
+
+	// Not due before its backoff ran out
+	relayed(t, relay, 0)
+	repo.due(id)
+	relayed(t, relay, 1)
+
+	if status := repo.event(id).Status; status != entity.OutboxEventPublished {
+		t.Fatalf("status = %s, want %s", status, entity.OutboxEventPublished)
+	}
+
+	// The sink that took the event is given it again with the retry, consumers dedupe on its id
+	if ids := logged.ids(); len(ids) != 2 || ids[0] != ids[1] {
+		t.Errorf("first sink got %v, want the event twice", ids)
+	}
+	if ids := flaky.ids(); len(ids) != 2 || ids[0] != ids[1] {
+		t.Errorf("second sink was given %v, want the event twice", ids)
+	}
+
+	relayed(t, relay, 0)
+}
+
+func TestOutboxRelayKeepsAggregateOrder(t *testing.T) {
+	repo := newMemoryOutboxRepository()
+	first := repo.add("employee", "1")
+	second := repo.add("employee", "1")
+	other := repo.add("employee", "2")
+
+	publisher := &fakePublisher{failures: map[string]int{repo.event(first).EventID: 2}}
+	relay := service.NewOutboxRelay(repo, []contracts.OutboxSink{service.NewBrokerSink("broker", publisher)}, service.RelayConfig{})
+
+	// The first event keeps failing, the other aggregate isn't held back by it
+	relayed(t, relay, 1)
+	if status := repo.event(other).Status; status != entity.OutboxEventPublished {
+		t.Fatalf("other aggregate: status = %s, want %s", status, entity.OutboxEventPublished)
+	}
+
+	repo.due(first)
+	relayed(t, relay, 0)
+	repo.due(first)
+
+	if status := repo.event(second).Status; status != entity.OutboxEventPending || repo.event(second).Attempts != 0 {
+		t.Fatalf("event behind a failing one was claimed: status = %s", status)
+	}
+
+	relayed(t, relay, 1)
+	relayed(t, relay, 1)
+
+	var aggregate []string
+	for _, msg := range publisher.messages() {
+		if msg.Key == "employee:1" {
+			aggregate = append(aggregate, msg.ID)
+		}
+	}
+
+	firstID, secondID := repo.event(first).EventID, repo.event(second).EventID
+	if fmt.Sprint(aggregate) != fmt.Sprint([]string{firstID, firstID, firstID, secondID}) {
+		t.Errorf("employee:1 published as %v, want %s until it succeeded and only then %s", aggregate, firstID, secondID)
+	}
+}
+
+// TestOutboxClaimHoldsBackAggregate runs the claim query itself, against the configured database.
+// It leases whatever else is due there too, so point it at a disposable one.
+func TestOutboxClaimHoldsBackAggregate(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := repository.NewOutboxEventRepository(db, testFieldCrypt(t))
+
+	aggregate := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		_, _ = db.Exec("DELETE FROM outbox_events WHERE aggregate_type = 'test' AND aggregate_id LIKE $1", aggregate+"%")
+	})
+
+	for i, aggregateID := range []string{aggregate + "-a", aggregate + "-a", aggregate + "-b"} {
+		err := repo.Append(ctx, entity.OutboxEvent{
+			AggregateType: "test",
+			AggregateID:   aggregateID,
+			EventID:       fmt.Sprintf("%s-%d", aggregate, i),
+			EventType:     "test.event",
+			Payload:       "{}",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	claim := func() []string {
+		t.Helper()
+
+		events, err := repo.Claim(ctx, 1000, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []string
+		for _, event := range events {
+			if strings.HasPrefix(event.EventID, aggregate) {
+				ids = append(ids, event.EventID)
+			}
+		}
+		sort.Strings(ids)
+
+		return ids
+	}
+
+	first, second, other := aggregate+"-0", aggregate+"-1", aggregate+"-2"
+	if claimed := claim(); fmt.Sprint(claimed) != fmt.Sprint([]string{first, other}) {
+		t.Fatalf("claimed %v, want %s and %s", claimed, first, other)
+	}
+
+	var firstID int64
+	if err := db.Get(&firstID, "SELECT id FROM outbox_events WHERE event_id = $1", first); err != nil {
+		t.Fatal(err)
+	}
+
+	// Failed and due again, still the only event of its aggregate handed out
+	if err := repo.MarkFailed(ctx, firstID, "unavailable", 0); err != nil {
+		t.Fatal(err)
+	}
+	if claimed := claim(); fmt.Sprint(claimed) != fmt.Sprint([]string{first}) {
+		t.Fatalf("claimed %v after a failure, want only %s", claimed, first)
+	}
+
+	if err := repo.MarkPublished(ctx, firstID); err != nil {
+		t.Fatal(err)
+	}
+	if claimed := claim(); fmt.Sprint(claimed) != fmt.Sprint([]string{second}) {
+		t.Fatalf("claimed %v once %s was published, want %s", claimed, first, second)
+	}
+}
+
+// TestOutboxEventsSealedAndScrubbed checks, against the configured database, that payloads are
+// only stored encrypted and that scrubbing leaves nothing but the envelope
+func TestOutboxEventsSealedAndScrubbed(t *testing.T) {
+	ctx := context.Background()
+	db := openTestDB(t)
+	repo := repository.NewOutboxEventRepository(db, testFieldCrypt(t))
+
+	aggregate := fmt.Sprintf("test-%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		_, _ = db.Exec("DELETE FROM outbox_events WHERE aggregate_type = 'test' AND aggregate_id = $1", aggregate)
+	})
+
+	payload := `{"id":"` + aggregate + `","type":"test.event","data":{"identityNumber":"3175091201900001"}}`
+	err := repo.Append(ctx, entity.OutboxEvent{
+		AggregateType: "test",
+		AggregateID:   aggregate,
+		EventID:       aggregate,
+		EventType:     "test.event",
+		Payload:       payload,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var stored struct {
+		Payload    *string `db:"payload"`
+		PayloadEnc []byte  `db:"payload_enc"`
+	}
+	query := "SELECT payload, payload_enc FROM outbox_events WHERE event_id = $1"
+	if err := db.Get(&stored, query, aggregate); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Payload != nil || strings.Contains(string(stored.PayloadEnc), "3175091201900001") {
+		t.Fatalf("payload is stored in plaintext")
+	}
+
+	if events, err := repo.ListAfter(ctx, 0, 0, 1000); err != nil {
+		t.Fatal(err)
+	} else if !slices.ContainsFunc(events, func(event entity.OutboxEvent) bool { return event.Payload == payload }) {
+		t.Errorf("listed events don't hold the payload as appended")
+	}
+
+	eventIDs, err := repo.Scrub(ctx, "test", aggregate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(eventIDs) != fmt.Sprint([]string{aggregate}) {
+		t.Fatalf("scrubbed %v, want %s", eventIDs, aggregate)
+	}
+
+	if err := db.Get(&stored, query, aggregate); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Payload == nil || stored.PayloadEnc != nil || strings.Contains(*stored.Payload, "3175091201900001") {
+		t.Fatalf("scrubbed payload = %v, want the envelope alone", stored.Payload)
+	}
+}
+
+func TestOutboxEventsGoToTheOwningManager(t *testing.T) {
+	departments := &memoryDepartmentRepository{departments: []*entity.Department{
+		{ID: 10, Name: "Engineering", ManagerID: 1},
+	}}
+	outbox := newMemoryOutboxRepository()
+	employees := employeeSvc.NewEmployeeService(
+		&memoryEmployeeRepository{},
+		departments,
+		validator.Validator,
+		discardAudit{},
+		service.NewOutboxEventService(outbox, uuid.UUID),
+		passthroughTransactor{},
+	)
+
+	// Manager 2 changes an employee of manager 1, the events are manager 1's
+	ctx := reqctx.WithPrincipal(context.Background(), reqctx.Principal{TenantID: 2})
+	_, err := employees.Create(ctx, dto.EmployeeCreateReq{
+		IdentityNumber:   "3175091201900001",
+		Name:             "Jane Doe",
+		EmployeeImageURI: "https://example.com/jane.png",
+		Gender:           "female",
+		DepartmentID:     "10",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outbox.events) != 1 || outbox.events[0].TenantID != 1 {
+		t.Fatalf("events = %+v, want one of tenant 1", outbox.events)
+	}
+}
+
+func testFieldCrypt(t *testing.T) fieldcrypt.FieldCryptInterface {
+	t.Helper()
+
+	return newTestKeyring(t, "test")
+}
+
+func openTestDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+
+	db, err := sqlx.Open("pgx", database.DataSourceName())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Skipf("postgres is not reachable: %v", err)
+	}
+
+	migrator, err := database.NewMigrator()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer migrator.Close()
+
+	if err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func relayed(t *testing.T, relay contracts.OutboxRelay, want int) {
+	t.Helper()
+
+	published, err := relay.Relay(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if published != want {
+		t.Fatalf("published %d events, want %d", published, want)
+	}
+}
+
+// memoryOutboxRepository claims like the Postgres repository: only the oldest unpublished
+// event of each aggregate, once it is due
+type memoryOutboxRepository struct {
+	mu     sync.Mutex
+	events []*entity.OutboxEvent
+}
+
+func newMemoryOutboxRepository() *memoryOutboxRepository {
+	return &memoryOutboxRepository{}
+}
+
+func (r *memoryOutboxRepository) add(aggregateType, aggregateID string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := int64(len(r.events) + 1)
+	r.events = append(r.events, &entity.OutboxEvent{
+		ID:            id,
+		TenantID:      1,
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventID:       fmt.Sprintf("evt_%d", id),
+		EventType:     aggregateType + ".updated",
+		Payload:       fmt.Sprintf(`{"id":"%s"}`, aggregateID),
+		Status:        entity.OutboxEventPending,
+		NextAttemptAt: time.Now(),
+		CreatedAt:     time.Now(),
+	})
+
+	return id
+}
+
+func (r *memoryOutboxRepository) event(id int64) entity.OutboxEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return *r.events[id-1]
+}
+
+func (r *memoryOutboxRepository) due(id int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.events[id-1].NextAttemptAt = time.Now()
+}
+
+func (r *memoryOutboxRepository) Append(_ context.Context, event entity.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.ID = int64(len(r.events) + 1)
+	event.Status = entity.OutboxEventPending
+	r.events = append(r.events, &event)
+
+	return nil
+}
+
+func (r *memoryOutboxRepository) Claim(_ context.Context, limit int, lease time.Duration) ([]entity.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	claimed := []entity.OutboxEvent{}
+	held := map[string]bool{}
+	for _, event := range r.events {
+		aggregate := event.AggregateType + ":" + event.AggregateID
+		if event.Status == entity.OutboxEventPublished {
+			continue
+		}
+
+		oldest := !held[aggregate]
+		held[aggregate] = true
+
+		if !oldest || event.NextAttemptAt.After(time.Now()) || len(claimed) == limit {
+			continue
+		}
+
+		event.Status = entity.OutboxEventPublishing
+		event.Attempts++
+		event.NextAttemptAt = time.Now().Add(lease)
+		claimed = append(claimed, *event)
+	}
+
+	return claimed, nil
+}
+
+func (r *memoryOutboxRepository) MarkPublished(_ context.Context, id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	event := r.events[id-1]
+	event.Status = entity.OutboxEventPublished
+	event.PublishedAt = &now
+	event.LastError = ""
+
+	return nil
+}
+
+func (r *memoryOutboxRepository) MarkFailed(_ context.Context, id int64, lastError string, retryIn time.Duration) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event := r.events[id-1]
+	event.Status = entity.OutboxEventPending
+	event.LastError = lastError
+	event.NextAttemptAt = time.Now().Add(retryIn)
+
+	return nil
+}
+
+func (r *memoryOutboxRepository) Prune(context.Context, time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (r *memoryOutboxRepository) ListAfter(context.Context, int, int64, int) ([]entity.OutboxEvent, error) {
+	return []entity.OutboxEvent{}, nil
+}
+
+func (r *memoryOutboxRepository) LastID(context.Context, int) (int64, error) {
+	return 0, nil
+}
+
+func (r *memoryOutboxRepository) Scrub(context.Context, string, string) ([]string, error) {
+	return []string{}, nil
+}
+
+func (r *memoryOutboxRepository) Reencrypt(context.Context, int) (int, error) {
+	return 0, nil
+}
+
+func (r *memoryOutboxRepository) Decrypt(context.Context, int) (int, error) {
+	return 0, nil
+}
+
+// fakePublisher records what it is given, failing each message as often as failures says
+type fakePublisher struct {
+	mu        sync.Mutex
+	failures  map[string]int
+	published []broker.Message
+}
+
+func (p *fakePublisher) Publish(_ context.Context, msg broker.Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.published = append(p.published, msg)
+	if p.failures[msg.ID] > 0 {
+		p.failures[msg.ID]--
+		return errors.New("broker unavailable")
+	}
+
+	return nil
+}
+
+func (p *fakePublisher) Close() error {
+	return nil
+}
+
+func (p *fakePublisher) messages() []broker.Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]broker.Message{}, p.published...)
+}
+
+func (p *fakePublisher) ids() []string {
+	var ids []string
+	for _, msg := range p.messages() {
+		ids = append(ids, msg.ID)
+	}
+
+	return ids
+}
+
+// memoryEmployeeRepository stores the employees it creates, updates, deletes and erases, the rest panics
+type memoryEmployeeRepository struct {
+	contracts.EmployeeRepository
+	employees []entity.Employee
+}
+
+func (r *memoryEmployeeRepository) Create(_ context.Context, data entity.Employee) (int, error) {
+	data.ID = len(r.employees) + 1
+	r.employees = append(r.employees, data)
+	return data.ID, nil
+}
+
+func (r *memoryEmployeeRepository) FindByIdentityNumber(_ context.Context, identityNumber string) (*entity.Employee, error) {
+	for _, employee := range r.employees {
+		if employee.IdentityNumber == identityNumber {
+			return &employee, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryEmployeeRepository) Update(_ context.Context, data entity.Employee) (int, error) {
+	for i, employee := range r.employees {
+		if employee.ID == data.ID && employee.Version == data.Version {
+			data.Version++
+			r.employees[i] = data
+			return data.Version, nil
+		}
+	}
+
+	return 0, sql.ErrNoRows
+}
+
+func (r *memoryEmployeeRepository) Delete(_ context.Context, id, version int) error {
+	for i, employee := range r.employees {
+		if employee.ID == id && employee.Version == version {
+			r.employees = slices.Delete(r.employees, i, i+1)
+			return nil
+		}
+	}
+
+	return sql.ErrNoRows
+}
+
+func (r *memoryEmployeeRepository) Erase(_ context.Context, id int) error {
+	for i, employee := range r.employees {
+		if employee.ID == id {
+			r.employees[i] = entity.Employee{ID: id, DepartmentID: employee.DepartmentID}
+			return nil
+		}
+	}
+
+	return sql.ErrNoRows
+}
+
+// discardAudit records nothing
+type discardAudit struct {
+	contracts.AuditService
+}
+
+func (discardAudit) Record(context.Context, dto.AuditRecord) error {
+	return nil
+}
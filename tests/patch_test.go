@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/patch"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+func TestPatchFieldStates(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		present  bool
+		null     bool
+		value    string
+		ok       bool
+		applied  string
+	}{
+		{name: "absent", document: `{}`, applied: "Jane Doe"},
+		{name: "null", document: `{"name":null}`, present: true, null: true},
+		{name: "null with spaces", document: `{"name": null }`, present: true, null: true},
+		{name: "value", document: `{"name":"John Doe"}`, present: true, value: "John Doe", ok: true, applied: "John Doe"},
+		// An empty string is a value, not a null
+		{name: "empty value", document: `{"name":""}`, present: true, ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc struct {
+				Name patch.Field[string] `json:"name"`
+			}
+			if err := json.Unmarshal([]byte(tt.document), &doc); err != nil {
+				t.Fatal(err)
+			}
+
+			if doc.Name.Present() != tt.present || doc.Name.IsNull() != tt.null {
+				t.Errorf("present, null = %v, %v, want %v, %v", doc.Name.Present(), doc.Name.IsNull(), tt.present, tt.null)
+			}
+			if value, ok := doc.Name.Get(); value != tt.value || ok != tt.ok {
+				t.Errorf("get = %q, %v, want %q, %v", value, ok, tt.value, tt.ok)
+			}
+			if applied := doc.Name.Apply("Jane Doe"); applied != tt.applied {
+				t.Errorf("apply = %q, want %q", applied, tt.applied)
+			}
+		})
+	}
+
+	// The constructors build the same states as the documents
+	if field := patch.Null[string](); !field.Present() || !field.IsNull() {
+		t.Error("Null is not a present null")
+	}
+	if value, ok := patch.Value("John Doe").Get(); value != "John Doe" || !ok {
+		t.Errorf("Value get = %q, %v, want John Doe, true", value, ok)
+	}
+	if field := (patch.Field[string]{}); field.Present() || field.IsNull() {
+		t.Error("the zero field is not absent")
+	}
+}
+
+func TestEmployeeMergePatchValidation(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		wantErr  bool
+	}{
+		{name: "empty patch", document: `{}`},
+		{name: "name", document: `{"name":"John Doe"}`},
+		{name: "null name", document: `{"name":null}`, wantErr: true},
+		{name: "null identity number", document: `{"identityNumber":null}`, wantErr: true},
+		{name: "null gender", document: `{"gender":null}`, wantErr: true},
+		{name: "null department", document: `{"departmentId":null}`, wantErr: true},
+		{name: "name too short", document: `{"name":"Jo"}`, wantErr: true},
+		// The image is the only optional member, null clears it
+		{name: "null image", document: `{"employeeImageUri":null}`},
+		{name: "image", document: `{"employeeImageUri":"https://example.com/jane.png"}`},
+		{name: "image not a url", document: `{"employeeImageUri":"jane.png"}`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var req dto.EmployeeUpdateReq
+			if err := json.Unmarshal([]byte(tt.document), &req); err != nil {
+				t.Fatal(err)
+			}
+
+			err := validator.Validator.Validate(&req)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validate = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
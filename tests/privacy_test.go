@@ -0,0 +1,66 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	outboxSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/outbox/service"
+	privacySvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/privacy/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/uuid"
+)
+
+func TestErasureEventCarriesNoPII(t *testing.T) {
+	employee := entity.Employee{
+		ID:             3,
+		IdentityNumber: "3175091201900001",
+		Name:           "Jane Doe",
+		Gender:         "female",
+		DepartmentID:   10,
+	}
+	employees := &memoryEmployeeRepository{employees: []entity.Employee{employee}}
+	departments := &memoryDepartmentRepository{departments: []*entity.Department{
+		{ID: 10, Name: "Engineering", ManagerID: 1},
+	}}
+	outbox := newMemoryOutboxRepository()
+	privacy := privacySvc.NewPrivacyService(
+		employees,
+		departments,
+		discardAudit{},
+		nil,
+		passthroughTransactor{},
+		outbox,
+		newMemoryWebhookRepository(),
+		outboxSvc.NewOutboxEventService(outbox, uuid.UUID),
+	)
+
+	if err := privacy.Erase(context.Background(), 1, employee.IdentityNumber); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(outbox.events) != 1 {
+		t.Fatalf("recorded %d events, want the erasure alone", len(outbox.events))
+	}
+	erased := outbox.events[0]
+	if erased.EventType != "employee.erased" || erased.AggregateID != "3" || erased.TenantID != 1 {
+		t.Errorf("event = %s of %s for tenant %d, want employee.erased of 3 for tenant 1", erased.EventType, erased.AggregateID, erased.TenantID)
+	}
+
+	for _, pii := range []string{employee.IdentityNumber, employee.Name, employee.Gender} {
+		if strings.Contains(erased.Payload, pii) {
+			t.Errorf("payload %s carries %q", erased.Payload, pii)
+		}
+	}
+
+	var payload struct {
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(erased.Payload), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if len(payload.Data) != 2 || payload.Data["employeeId"] != "3" || payload.Data["departmentId"] != "10" {
+		t.Errorf("data = %v, want the employee and department ids alone", payload.Data)
+	}
+}
@@ -0,0 +1,80 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/infra/server"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/ratelimit"
+)
+
+func TestAuthRateLimitPerClientBehindProxy(t *testing.T) {
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{
+		ratelimit.ClassAuth: {Requests: 1, Period: time.Minute},
+	}, false)
+	login := authApp(t, limiter)
+
+	if status := login("203.0.113.1"); status != http.StatusOK {
+		t.Fatalf("first login: status = %d, want 200", status)
+	}
+	if status := login("203.0.113.1"); status != http.StatusTooManyRequests {
+		t.Errorf("second login of the client: status = %d, want 429", status)
+	}
+	if status := login("203.0.113.2"); status != http.StatusOK {
+		t.Errorf("login of another client behind the proxy: status = %d, want 200", status)
+	}
+}
+
+func TestRateLimitStoreFailure(t *testing.T) {
+	limits := map[string]ratelimit.Limit{
+		ratelimit.ClassAuth: {Requests: 1, Period: time.Minute},
+	}
+
+	login := authApp(t, ratelimit.New(failingStore{}, limits, false))
+	if status := login("203.0.113.1"); status != http.StatusOK {
+		t.Errorf("failing open: status = %d, want 200", status)
+	}
+
+	login = authApp(t, ratelimit.New(failingStore{}, limits, true))
+	if status := login("203.0.113.1"); status != http.StatusServiceUnavailable {
+		t.Errorf("failing closed: status = %d, want 503", status)
+	}
+}
+
+// authApp serves a login route limited by limiter behind a trusted proxy, the returned function
+// logs in as the client with the given IP
+func authApp(t *testing.T, limiter *ratelimit.Limiter) func(clientIP string) int {
+	t.Helper()
+
+	s := server.NewHttpServer(server.ProxyConfig{TrustedProxies: "0.0.0.0", Header: "X-Real-IP"})
+	app := s.GetApp()
+	app.Use(middlewares.RequestID())
+	app.Use(middlewares.RateLimit(limiter))
+	app.Post("/v1/auth", func(ctx *fiber.Ctx) error {
+		return ctx.SendStatus(http.StatusOK)
+	})
+
+	return func(clientIP string) int {
+		req := httptest.NewRequest(http.MethodPost, "/v1/auth", nil)
+		req.Header.Set("X-Real-IP", clientIP)
+
+		res, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res.StatusCode
+	}
+}
+
+type failingStore struct{}
+
+func (failingStore) Take(context.Context, string, ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("store unavailable")
+}
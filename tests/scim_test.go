@@ -0,0 +1,347 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/contracts"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/scim/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/reqctx"
+)
+
+func TestSCIMScopedToTenant(t *testing.T) {
+	departments := &memoryDepartmentRepository{departments: []*entity.Department{
+		{ID: 10, Name: "Engineering", ManagerID: 1},
+		{ID: 20, Name: "Engineering", ManagerID: 2},
+	}}
+	employees := &memoryEmployeeService{departments: departments, employees: []*dto.EmployeeDataRes{
+		{IdentityNumber: "own-1", Name: "Own", Gender: "female", DepartmentID: "10"},
+		{IdentityNumber: "other-1", Name: "Other", Gender: "male", DepartmentID: "20"},
+	}}
+	scim := service.NewSCIMService(employees, &memoryDepartmentService{}, departments, passthroughTransactor{})
+	ctx := reqctx.WithPrincipal(context.Background(), reqctx.Principal{TenantID: 1})
+
+	if _, err := scim.GetUser(ctx, "other-1"); !errors.Is(err, domain.ErrEmployeeNotFound) {
+		t.Errorf("get user of another tenant: err = %v, want not found", err)
+	}
+	if _, err := scim.PatchUser(ctx, "other-1", dto.SCIMPatchRequest{}, ""); !errors.Is(err, domain.ErrEmployeeNotFound) {
+		t.Errorf("patch user of another tenant: err = %v, want not found", err)
+	}
+	if err := scim.DeleteUser(ctx, "other-1", ""); !errors.Is(err, domain.ErrEmployeeNotFound) || employees.deleted != nil {
+		t.Errorf("delete user of another tenant: err = %v, deleted %v", err, employees.deleted)
+	}
+
+	users, err := scim.ListUsers(ctx, dto.SCIMQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users.TotalResults != 1 || len(users.Resources) != 1 || users.Resources[0].ID != "own-1" {
+		t.Errorf("listed %d of %d users, want own-1 alone", len(users.Resources), users.TotalResults)
+	}
+
+	users, err = scim.ListUsers(ctx, dto.SCIMQuery{Filter: `userName eq "other-1"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if users.TotalResults != 0 {
+		t.Errorf("filter matched %d users of another tenant", users.TotalResults)
+	}
+
+	_, err = scim.CreateUser(ctx, dto.SCIMUser{
+		UserName:    "new-1",
+		DisplayName: "New",
+		Employee:    &dto.SCIMEmployeeExtension{Gender: "male", DepartmentID: "20"},
+	})
+	if !errors.Is(err, domain.ErrSCIMUnknownGroup) {
+		t.Errorf("create user in a group of another tenant: err = %v, want unknown group", err)
+	}
+
+	if _, err := scim.GetGroup(ctx, "20", ""); !errors.Is(err, domain.ErrDepartmentNotFound) {
+		t.Errorf("get group of another tenant: err = %v, want not found", err)
+	}
+	if err := scim.DeleteGroup(ctx, "20", ""); !errors.Is(err, domain.ErrDepartmentNotFound) {
+		t.Errorf("delete group of another tenant: err = %v, want not found", err)
+	}
+
+	groups, err := scim.ListGroups(ctx, dto.SCIMQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups.TotalResults != 1 || len(groups.Resources) != 1 || groups.Resources[0].ID != "10" {
+		t.Errorf("listed %d of %d groups, want 10 alone", len(groups.Resources), groups.TotalResults)
+	}
+
+	groups, err = scim.ListGroups(ctx, dto.SCIMQuery{Filter: `displayName eq "Engineering"`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if groups.TotalResults != 1 || groups.Resources[0].ID != "10" {
+		t.Errorf("filter matched %d groups, want 10 alone", groups.TotalResults)
+	}
+
+	members, _ := json.Marshal([]dto.SCIMValue{{Value: "other-1"}})
+	_, err = scim.PatchGroup(ctx, "10", dto.SCIMPatchRequest{Operations: []dto.SCIMPatchOperation{
+		{Op: "add", Path: "members", Value: members},
+	}}, "")
+	if !errors.Is(err, domain.ErrSCIMUnknownMember) {
+		t.Errorf("add member of another tenant: err = %v, want unknown member", err)
+	}
+}
+
+func TestSCIMListTotalCountsEveryPage(t *testing.T) {
+	departments := &memoryDepartmentRepository{departments: []*entity.Department{{ID: 10, Name: "Engineering", ManagerID: 1}}}
+	employees := &memoryEmployeeService{departments: departments}
+	for i := range 5 {
+		employees.employees = append(employees.employees, &dto.EmployeeDataRes{
+			IdentityNumber: "own-" + strconv.Itoa(i),
+			DepartmentID:   "10",
+		})
+	}
+	scim := service.NewSCIMService(employees, &memoryDepartmentService{}, departments, passthroughTransactor{})
+	ctx := reqctx.WithPrincipal(context.Background(), reqctx.Principal{TenantID: 1})
+
+	count := 2
+	users, err := scim.ListUsers(ctx, dto.SCIMQuery{StartIndex: 3, Count: &count})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if users.TotalResults != 5 || users.StartIndex != 3 || users.ItemsPerPage != 2 || users.Resources[0].ID != "own-2" {
+		t.Errorf("page = %d items from %d of %d, first %s; want 2 from 3 of 5, first own-2",
+			users.ItemsPerPage, users.StartIndex, users.TotalResults, users.Resources[0].ID)
+	}
+
+	departments.departments = append(departments.departments,
+		&entity.Department{ID: 11, Name: "engineering", ManagerID: 1},
+		&entity.Department{ID: 12, Name: "Engineering", ManagerID: 2},
+		&entity.Department{ID: 13, Name: "ENGINEERING", ManagerID: 1},
+	)
+	count = 1
+	groups, err := scim.ListGroups(ctx, dto.SCIMQuery{Filter: `displayName eq "Engineering"`, StartIndex: 2, Count: &count, ExcludedAttributes: "members"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if groups.TotalResults != 3 || groups.ItemsPerPage != 1 || groups.Resources[0].ID != "11" {
+		t.Errorf("filtered page = %d items of %d, want 1 of 3, the second being 11", groups.ItemsPerPage, groups.TotalResults)
+	}
+}
+
+// memoryEmployeeService implements what SCIM, gRPC and GraphQL use of employees, the rest panics
+type memoryEmployeeService struct {
+	contracts.EmployeeService
+	departments *memoryDepartmentRepository
+	employees   []*dto.EmployeeDataRes
+	created     []string
+	updated     []string
+	deleted     []string
+}
+
+func (s *memoryEmployeeService) Create(_ context.Context, data dto.EmployeeCreateReq) (*dto.EmployeeDataRes, error) {
+	s.created = append(s.created, data.IdentityNumber)
+	return &dto.EmployeeDataRes{IdentityNumber: data.IdentityNumber, DepartmentID: data.DepartmentID}, nil
+}
+
+func (s *memoryEmployeeService) FindByDepartmentIDs(_ context.Context, managerID int, departmentIDs []int, _, _ int) ([]*dto.EmployeeDataRes, error) {
+	found := []*dto.EmployeeDataRes{}
+	for _, employee := range s.employees {
+		for _, id := range departmentIDs {
+			if employee.DepartmentID == strconv.Itoa(id) && s.departments.owns(managerID, employee.DepartmentID) {
+				found = append(found, employee)
+			}
+		}
+	}
+
+	return found, nil
+}
+
+func (s *memoryEmployeeService) FindByIdentityNumber(_ context.Context, managerID int, identityNumber string) (*dto.EmployeeDataRes, error) {
+	for _, employee := range s.employees {
+		if employee.IdentityNumber == identityNumber && (managerID == 0 || s.departments.owns(managerID, employee.DepartmentID)) {
+			return employee, nil
+		}
+	}
+
+	return nil, domain.ErrEmployeeNotFound
+}
+
+func (s *memoryEmployeeService) FindByManager(_ context.Context, managerID, limit, offset int) ([]*dto.EmployeeDataRes, int, error) {
+	var owned []*dto.EmployeeDataRes
+	for _, employee := range s.employees {
+		for _, department := range s.departments.owned(managerID) {
+			if employee.DepartmentID == strconv.Itoa(department.ID) {
+				owned = append(owned, employee)
+			}
+		}
+	}
+
+	start := min(offset, len(owned))
+	return owned[start:min(start+limit, len(owned))], len(owned), nil
+}
+
+func (s *memoryEmployeeService) Find(_ context.Context, managerID int, identityNumber, _, _ string, departmentID, limit, offset int) ([]*dto.EmployeeDataRes, error) {
+	found := []*dto.EmployeeDataRes{}
+	for _, employee := range s.employees {
+		switch {
+		case identityNumber != "" && employee.IdentityNumber != identityNumber:
+		case departmentID != 0 && employee.DepartmentID != strconv.Itoa(departmentID):
+		case managerID != 0 && !s.departments.owns(managerID, employee.DepartmentID):
+		default:
+			found = append(found, employee)
+		}
+	}
+
+	start := min(offset, len(found))
+	return found[start:min(start+limit, len(found))], nil
+}
+
+func (s *memoryEmployeeService) Update(ctx context.Context, managerID int, _ dto.EmployeeUpdateReq, identityNumber, _ string) (*dto.EmployeeDataRes, error) {
+	employee, err := s.FindByIdentityNumber(ctx, managerID, identityNumber)
+	if err != nil {
+		return nil, err
+	}
+
+	s.updated = append(s.updated, identityNumber)
+	return employee, nil
+}
+
+func (s *memoryEmployeeService) Delete(ctx context.Context, managerID int, identityNumber, _ string) error {
+	if _, err := s.FindByIdentityNumber(ctx, managerID, identityNumber); err != nil {
+		return err
+	}
+
+	s.deleted = append(s.deleted, identityNumber)
+	return nil
+}
+
+// memoryDepartmentService implements what the gRPC servers and GraphQL use of departments over
+// departments, SCIM goes to the repository
+type memoryDepartmentService struct {
+	contracts.DepartmentService
+	departments *memoryDepartmentRepository
+	updated     []int
+	deleted     []int
+}
+
+func (s *memoryDepartmentService) FindByIDs(_ context.Context, managerID int, ids []int) ([]*dto.DepartmentRes, error) {
+	res := []*dto.DepartmentRes{}
+	for _, department := range s.departments.owned(managerID) {
+		if slices.Contains(ids, department.ID) {
+			res = append(res, toDepartmentRes(department))
+		}
+	}
+
+	return res, nil
+}
+
+func (s *memoryDepartmentService) Update(ctx context.Context, managerID, id int, name, _ string) (*dto.DepartmentRes, error) {
+	if _, err := s.FindOwned(ctx, managerID, id); err != nil {
+		return nil, err
+	}
+
+	s.updated = append(s.updated, id)
+	return &dto.DepartmentRes{ID: strconv.Itoa(id), Name: name}, nil
+}
+
+func (s *memoryDepartmentService) FindOwned(_ context.Context, managerID, id int) (*dto.DepartmentRes, error) {
+	for _, department := range s.departments.owned(managerID) {
+		if department.ID == id {
+			return toDepartmentRes(department), nil
+		}
+	}
+
+	return nil, domain.ErrDepartmentNotFound
+}
+
+func (s *memoryDepartmentService) FindByManager(_ context.Context, managerID int, _ string, limit, offset int) ([]*dto.DepartmentRes, error) {
+	owned := s.departments.owned(managerID)
+	start := min(offset, len(owned))
+
+	res := []*dto.DepartmentRes{}
+	for _, department := range owned[start:min(start+limit, len(owned))] {
+		res = append(res, toDepartmentRes(department))
+	}
+
+	return res, nil
+}
+
+func (s *memoryDepartmentService) Delete(ctx context.Context, managerID, id int, _ string) error {
+	if _, err := s.FindOwned(ctx, managerID, id); err != nil {
+		return err
+	}
+
+	s.deleted = append(s.deleted, id)
+	return nil
+}
+
+func toDepartmentRes(department *entity.Department) *dto.DepartmentRes {
+	return &dto.DepartmentRes{ID: strconv.Itoa(department.ID), Name: department.Name, Version: department.Version}
+}
+
+type memoryDepartmentRepository struct {
+	contracts.DepartmentRepository
+	departments []*entity.Department
+}
+
+func (r *memoryDepartmentRepository) FindByID(_ context.Context, id int) (*entity.Department, error) {
+	for _, department := range r.departments {
+		if department.ID == id {
+			return department, nil
+		}
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (r *memoryDepartmentRepository) FindByManagerAndName(_ context.Context, managerID int, name string, limit, offset int) ([]*entity.Department, error) {
+	named := r.named(managerID, name)
+	start := min(offset, len(named))
+	return named[start:min(start+limit, len(named))], nil
+}
+
+func (r *memoryDepartmentRepository) CountByManagerAndName(_ context.Context, managerID int, name string) (int, error) {
+	return len(r.named(managerID, name)), nil
+}
+
+func (r *memoryDepartmentRepository) FindByManager(_ context.Context, managerID, limit, offset int) ([]*entity.Department, error) {
+	owned := r.owned(managerID)
+	start := min(offset, len(owned))
+	return owned[start:min(start+limit, len(owned))], nil
+}
+
+func (r *memoryDepartmentRepository) CountByManager(_ context.Context, managerID int) (int, error) {
+	return len(r.owned(managerID)), nil
+}
+
+func (r *memoryDepartmentRepository) named(managerID int, name string) []*entity.Department {
+	return slices.DeleteFunc(r.owned(managerID), func(department *entity.Department) bool {
+		return !strings.EqualFold(department.Name, name)
+	})
+}
+
+func (r *memoryDepartmentRepository) owns(managerID int, departmentID string) bool {
+	return slices.ContainsFunc(r.owned(managerID), func(department *entity.Department) bool {
+		return strconv.Itoa(department.ID) == departmentID
+	})
+}
+
+func (r *memoryDepartmentRepository) owned(managerID int) []*entity.Department {
+	return slices.DeleteFunc(slices.Clone(r.departments), func(department *entity.Department) bool {
+		return department.ManagerID != managerID
+	})
+}
+
+type passthroughTransactor struct{}
+
+func (passthroughTransactor) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
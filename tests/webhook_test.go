@@ -0,0 +1,555 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/dto"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/domain/entity"
+	webhookCtr "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/webhook/controller"
+	webhookSvc "github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/app/webhook/service"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/internal/middlewares"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/egress"
+	errorhandler "github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/helpers/http/error_handler"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/jwt"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/ratelimit"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/signature"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/uuid"
+	"github.com/projectsprintdev-mikroserpis01/gogomanager-api/pkg/validator"
+)
+
+const webhookTenant = 1
+
+// httptest servers listen on loopback, which the dispatcher refuses by default
+var localGuard = egress.Guard{AllowPrivate: true}
+
+func TestSignatureRoundTrip(t *testing.T) {
+	secret, err := signature.NewSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _ := signature.NewSecret()
+
+	now := time.Now()
+	body := []byte(`{"type":"employee.created"}`)
+
+	header, err := signature.Sign(secret, "evt_1", now, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !signature.Verify(secret, "evt_1", now, body, header) {
+		t.Error("signature doesn't verify with its own secret")
+	}
+
+	// Receivers rotating their secret see both signatures in one header
+	rotated, _ := signature.Sign(other, "evt_1", now, body)
+	if !signature.Verify(secret, "evt_1", now, body, rotated+" "+header) {
+		t.Error("signature isn't found among several")
+	}
+
+	for name, ok := range map[string]bool{
+		"other secret":    signature.Verify(other, "evt_1", now, body, header),
+		"other id":        signature.Verify(secret, "evt_2", now, body, header),
+		"other timestamp": signature.Verify(secret, "evt_1", now.Add(time.Second), body, header),
+		"tampered body":   signature.Verify(secret, "evt_1", now, []byte(`{"type":"employee.deleted"}`), header),
+		"other version":   signature.Verify(secret, "evt_1", now, body, strings.Replace(header, "v1,", "v2,", 1)),
+	} {
+		if ok {
+			t.Errorf("signature verifies with %s", name)
+		}
+	}
+
+	if _, err := signature.Sign("whsec_", "evt_1", now, body); !errors.Is(err, signature.ErrInvalidSecret) {
+		t.Errorf("empty secret signed, err = %v", err)
+	}
+}
+
+func TestWebhookDeliveredOn2xx(t *testing.T) {
+	repo := newMemoryWebhookRepository()
+
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+
+		timestamp, _ := strconv.ParseInt(r.Header.Get(signature.HeaderTimestamp), 10, 64)
+		body, _ := io.ReadAll(r.Body)
+
+		if !signature.Verify(repo.endpoint.Secret, r.Header.Get(signature.HeaderID), time.Unix(timestamp, 0), body, r.Header.Get(signature.HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	repo.setURL(receiver.URL)
+	id := repo.add(entity.WebhookDeliveryPending)
+
+	dispatch(t, repo, 1)
+
+	delivery := repo.delivery(id)
+	if delivery.Status != entity.WebhookDeliveryDelivered {
+		t.Fatalf("status = %s, want %s (last error %q)", delivery.Status, entity.WebhookDeliveryDelivered, delivery.LastError)
+	}
+	if received.Load() != 1 {
+		t.Errorf("receiver got %d requests, want 1", received.Load())
+	}
+	if attempts := repo.attemptsOf(id); len(attempts) != 1 || *attempts[0].StatusCode != http.StatusNoContent {
+		t.Errorf("attempts = %+v, want a single 204", attempts)
+	}
+
+	// Delivered is final, nothing is claimed again
+	dispatch(t, repo, 0)
+}
+
+func TestWebhookRetriedWithBackoff(t *testing.T) {
+	repo := newMemoryWebhookRepository()
+
+	var followed atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/final", func(w http.ResponseWriter, _ *http.Request) {
+		followed.Add(1)
+	})
+
+	var calls atomic.Int32
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			http.Error(w, "boom", http.StatusInternalServerError)
+		case 2:
+			http.Redirect(w, r, "/final", http.StatusFound)
+		default:
+			w.WriteHeader(http.StatusOK)
+		}
+	})
+
+	receiver := httptest.NewServer(mux)
+	defer receiver.Close()
+
+	repo.setURL(receiver.URL)
+	id := repo.add(entity.WebhookDeliveryPending)
+
+	for attempt, wantStatus := range []int{http.StatusInternalServerError, http.StatusFound} {
+		dispatch(t, repo, 1)
+
+		delivery := repo.delivery(id)
+		if delivery.Status != entity.WebhookDeliveryPending {
+			t.Fatalf("attempt %d: status = %s, want %s", attempt+1, delivery.Status, entity.WebhookDeliveryPending)
+		}
+		if *delivery.LastStatusCode != wantStatus {
+			t.Errorf("attempt %d: last status = %d, want %d", attempt+1, *delivery.LastStatusCode, wantStatus)
+		}
+
+		// Not due before the backoff ran out
+		dispatch(t, repo, 0)
+		repo.due(id)
+	}
+
+	if followed.Load() != 0 {
+		t.Error("redirect was followed")
+	}
+
+	retries := repo.retries
+	if len(retries) != 2 || retries[0] != 30*time.Second || retries[1] != time.Minute {
+		t.Errorf("retried in %v, want [30s 1m0s]", retries)
+	}
+
+	dispatch(t, repo, 1)
+	if delivery := repo.delivery(id); delivery.Status != entity.WebhookDeliveryDelivered || delivery.Attempts != 3 {
+		t.Errorf("status = %s after %d attempts, want delivered after 3", delivery.Status, delivery.Attempts)
+	}
+}
+
+func TestWebhookDeadAfterMaxAttempts(t *testing.T) {
+	repo := newMemoryWebhookRepository()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	repo.setURL(receiver.URL)
+	id := repo.add(entity.WebhookDeliveryPending)
+
+	for attempt := 1; attempt <= webhookSvc.MaxAttempts; attempt++ {
+		dispatch(t, repo, 1)
+		repo.due(id)
+
+		want := entity.WebhookDeliveryPending
+		if attempt == webhookSvc.MaxAttempts {
+			want = entity.WebhookDeliveryDead
+		}
+
+		if status := repo.delivery(id).Status; status != want {
+			t.Fatalf("attempt %d: status = %s, want %s", attempt, status, want)
+		}
+	}
+
+	for i, retry := range repo.retries {
+		if retry > webhookSvc.MaxBackoff || (i > 0 && retry < repo.retries[i-1]) {
+			t.Errorf("retries %v don't grow up to %s", repo.retries, webhookSvc.MaxBackoff)
+			break
+		}
+	}
+
+	// Dead deliveries are only sent again on request
+	dispatch(t, repo, 0)
+}
+
+func TestWebhookRedeliverEndpoint(t *testing.T) {
+	repo := newMemoryWebhookRepository()
+
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	repo.setURL(receiver.URL)
+	dead := repo.add(entity.WebhookDeliveryDead)
+	delivering := repo.add(entity.WebhookDeliveryDelivering)
+
+	app := fiber.New(fiber.Config{ErrorHandler: errorhandler.ErrorHandler})
+	limiter := ratelimit.New(ratelimit.NewMemoryStore(), map[string]ratelimit.Limit{
+		ratelimit.ClassRead:                         {},
+		ratelimit.ClassWrite:                        {},
+		ratelimit.TenantClass(ratelimit.ClassRead):  {},
+		ratelimit.TenantClass(ratelimit.ClassWrite): {},
+	}, false)
+	middleware := middlewares.NewMiddleware(jwt.Jwt, jwt.JwtManager, jwt.JwtChallenge, nil, limiter, nil, false)
+	service := webhookSvc.NewWebhookService(repo, validator.Validator, uuid.UUID, localGuard)
+	webhookCtr.InitNewController(app, service, middleware)
+
+	redeliver := func(tenant int, deliveryID int64) int {
+		token, err := jwt.JwtManager.CreateManager(tenant, "manager@example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		path := "/v1/webhooks/" + strconv.Itoa(repo.endpoint.ID) + "/deliveries/" + strconv.FormatInt(deliveryID, 10) + "/redeliver"
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		res, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return res.StatusCode
+	}
+
+	if status := redeliver(webhookTenant+1, dead); status != http.StatusNotFound {
+		t.Errorf("another tenant redelivering: status = %d, want 404", status)
+	}
+	if status := redeliver(webhookTenant, dead+100); status != http.StatusNotFound {
+		t.Errorf("unknown delivery: status = %d, want 404", status)
+	}
+	if status := redeliver(webhookTenant, delivering); status != http.StatusConflict {
+		t.Errorf("delivery being sent: status = %d, want 409", status)
+	}
+	if status := redeliver(webhookTenant, dead); status != http.StatusAccepted {
+		t.Fatalf("status = %d, want 202", status)
+	}
+
+	if delivery := repo.delivery(dead); delivery.Status != entity.WebhookDeliveryPending || delivery.Attempts != 0 {
+		t.Fatalf("redelivered: status = %s with %d attempts, want pending with 0", delivery.Status, delivery.Attempts)
+	}
+
+	dispatch(t, repo, 1)
+	if status := repo.delivery(dead).Status; status != entity.WebhookDeliveryDelivered {
+		t.Errorf("status = %s, want %s", status, entity.WebhookDeliveryDelivered)
+	}
+}
+
+func TestWebhookRefusesInternalAddresses(t *testing.T) {
+	repo := newMemoryWebhookRepository()
+
+	var received atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		received.Add(1)
+	}))
+	defer receiver.Close()
+
+	service := webhookSvc.NewWebhookService(repo, validator.Validator, uuid.UUID, egress.Guard{})
+	for _, url := range []string{receiver.URL, "http://localhost/hook", "http://169.254.169.254/latest", "http://10.0.0.1", "http://[::1]:8080"} {
+		_, err := service.Create(context.Background(), webhookTenant, dto.CreateWebhookRequest{URL: url})
+		if !errors.Is(err, domain.ErrInvalidWebhookURL) {
+			t.Errorf("%s registered, err = %v", url, err)
+		}
+	}
+
+	// A host that resolved publicly at registration may point inside by the time it is sent to
+	repo.setURL(receiver.URL)
+	id := repo.add(entity.WebhookDeliveryPending)
+
+	dispatcher := webhookSvc.NewWebhookDispatcher(repo, webhookSvc.DispatcherConfig{})
+	if _, err := dispatcher.Dispatch(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if received.Load() != 0 {
+		t.Error("delivery reached a loopback address")
+	}
+	if delivery := repo.delivery(id); !strings.Contains(delivery.LastError, egress.ErrForbiddenAddress.Error()) {
+		t.Errorf("last error = %q, want the address refused", delivery.LastError)
+	}
+}
+
+func dispatch(t *testing.T, repo *memoryWebhookRepository, want int) {
+	t.Helper()
+
+	dispatcher := webhookSvc.NewWebhookDispatcher(repo, webhookSvc.DispatcherConfig{Guard: localGuard})
+	claimed, err := dispatcher.Dispatch(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claimed != want {
+		t.Fatalf("claimed %d deliveries, want %d", claimed, want)
+	}
+}
+
+// memoryWebhookRepository keeps one endpoint of webhookTenant and its deliveries, following the
+// state changes of the Postgres repository
+type memoryWebhookRepository struct {
+	mu         sync.Mutex
+	endpoint   entity.WebhookEndpoint
+	deliveries map[int64]*entity.WebhookDelivery
+	attempts   []entity.WebhookAttempt
+	retries    []time.Duration
+	nextID     int64
+}
+
+func newMemoryWebhookRepository() *memoryWebhookRepository {
+	secret, _ := signature.NewSecret()
+
+	return &memoryWebhookRepository{
+		endpoint: entity.WebhookEndpoint{
+			ID:       7,
+			TenantID: webhookTenant,
+			Secret:   secret,
+			Active:   true,
+		},
+		deliveries: map[int64]*entity.WebhookDelivery{},
+	}
+}
+
+func (r *memoryWebhookRepository) setURL(url string) {
+	r.endpoint.URL = url
+}
+
+func (r *memoryWebhookRepository) add(status string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// A delivery being sent is leased, it isn't due again before the lease runs out
+	next := time.Now()
+	if status == entity.WebhookDeliveryDelivering {
+		next = next.Add(webhookSvc.Lease)
+	}
+
+	r.nextID++
+	r.deliveries[r.nextID] = &entity.WebhookDelivery{
+		ID:            r.nextID,
+		EndpointID:    r.endpoint.ID,
+		EventID:       "evt_" + strconv.FormatInt(r.nextID, 10),
+		EventType:     "employee.created",
+		Payload:       `{"identityNumber":"12345"}`,
+		Status:        status,
+		NextAttemptAt: next,
+		CreatedAt:     time.Now(),
+	}
+
+	return r.nextID
+}
+
+// due makes the delivery's next attempt due now, as if its backoff ran out
+func (r *memoryWebhookRepository) due(id int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.deliveries[id].NextAttemptAt = time.Now()
+}
+
+func (r *memoryWebhookRepository) delivery(id int64) entity.WebhookDelivery {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return *r.deliveries[id]
+}
+
+func (r *memoryWebhookRepository) attemptsOf(id int64) []entity.WebhookAttempt {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var attempts []entity.WebhookAttempt
+	for _, attempt := range r.attempts {
+		if attempt.DeliveryID == id {
+			attempts = append(attempts, attempt)
+		}
+	}
+
+	return attempts
+}
+
+func (r *memoryWebhookRepository) Create(context.Context, entity.WebhookEndpoint) (entity.WebhookEndpoint, error) {
+	return r.endpoint, nil
+}
+
+func (r *memoryWebhookRepository) ListByTenant(_ context.Context, tenantID int) ([]entity.WebhookEndpoint, error) {
+	if tenantID != r.endpoint.TenantID {
+		return []entity.WebhookEndpoint{}, nil
+	}
+
+	return []entity.WebhookEndpoint{r.endpoint}, nil
+}
+
+func (r *memoryWebhookRepository) FindByID(_ context.Context, tenantID, id int) (*entity.WebhookEndpoint, error) {
+	if tenantID != r.endpoint.TenantID || id != r.endpoint.ID {
+		return nil, sql.ErrNoRows
+	}
+
+	endpoint := r.endpoint
+	return &endpoint, nil
+}
+
+func (r *memoryWebhookRepository) Update(_ context.Context, endpoint entity.WebhookEndpoint) (entity.WebhookEndpoint, error) {
+	r.endpoint = endpoint
+	return endpoint, nil
+}
+
+func (r *memoryWebhookRepository) Delete(context.Context, int, int) (bool, error) {
+	return false, nil
+}
+
+func (r *memoryWebhookRepository) Enqueue(context.Context, int, entity.WebhookDelivery) (int, error) {
+	return 0, nil
+}
+
+func (r *memoryWebhookRepository) EnqueueTo(context.Context, int, entity.WebhookDelivery) (entity.WebhookDelivery, error) {
+	return entity.WebhookDelivery{}, nil
+}
+
+func (r *memoryWebhookRepository) Claim(_ context.Context, limit int, lease time.Duration) ([]entity.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	claimed := []entity.WebhookDelivery{}
+	for _, delivery := range r.deliveries {
+		due := delivery.Status == entity.WebhookDeliveryPending || delivery.Status == entity.WebhookDeliveryDelivering
+		if !r.endpoint.Active || !due || delivery.NextAttemptAt.After(time.Now()) || len(claimed) == limit {
+			continue
+		}
+
+		delivery.Status = entity.WebhookDeliveryDelivering
+		delivery.Attempts++
+		delivery.NextAttemptAt = time.Now().Add(lease)
+
+		sent := *delivery
+		sent.URL, sent.Secret = r.endpoint.URL, r.endpoint.Secret
+		claimed = append(claimed, sent)
+	}
+
+	return claimed, nil
+}
+
+func (r *memoryWebhookRepository) MarkDelivered(_ context.Context, attempt entity.WebhookAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	delivery := r.deliveries[attempt.DeliveryID]
+	delivery.Status = entity.WebhookDeliveryDelivered
+	delivery.DeliveredAt = &now
+	delivery.LastStatusCode = attempt.StatusCode
+	delivery.LastError = ""
+	r.attempts = append(r.attempts, attempt)
+
+	return nil
+}
+
+func (r *memoryWebhookRepository) MarkFailed(_ context.Context, attempt entity.WebhookAttempt, retryIn time.Duration, giveUp bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery := r.deliveries[attempt.DeliveryID]
+	delivery.Status = entity.WebhookDeliveryPending
+	delivery.NextAttemptAt = time.Now().Add(retryIn)
+	if giveUp {
+		delivery.Status = entity.WebhookDeliveryDead
+	} else {
+		r.retries = append(r.retries, retryIn)
+	}
+	delivery.LastStatusCode = attempt.StatusCode
+	delivery.LastError = attempt.Error
+	r.attempts = append(r.attempts, attempt)
+
+	return nil
+}
+
+func (r *memoryWebhookRepository) ListDeliveries(context.Context, int, dto.WebhookDeliveryQuery) ([]entity.WebhookDelivery, error) {
+	return []entity.WebhookDelivery{}, nil
+}
+
+func (r *memoryWebhookRepository) FindDelivery(_ context.Context, endpointID int, id int64) (*entity.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery, ok := r.deliveries[id]
+	if !ok || delivery.EndpointID != endpointID {
+		return nil, sql.ErrNoRows
+	}
+
+	found := *delivery
+	return &found, nil
+}
+
+func (r *memoryWebhookRepository) ListAttempts(_ context.Context, deliveryID int64) ([]entity.WebhookAttempt, error) {
+	return r.attemptsOf(deliveryID), nil
+}
+
+func (r *memoryWebhookRepository) Redeliver(_ context.Context, endpointID int, id int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delivery, ok := r.deliveries[id]
+	if !ok || delivery.EndpointID != endpointID || delivery.Status == entity.WebhookDeliveryDelivering {
+		return false, nil
+	}
+
+	delivery.Status = entity.WebhookDeliveryPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = time.Now()
+	delivery.DeliveredAt = nil
+
+	return true, nil
+}
+
+func (r *memoryWebhookRepository) RedeliverDead(context.Context, int) (int, error) {
+	return 0, nil
+}
+
+func (r *memoryWebhookRepository) ScrubEvents(context.Context, []string) (int, error) {
+	return 0, nil
+}
+
+func (r *memoryWebhookRepository) Reencrypt(context.Context, int) (int, error) {
+	return 0, nil
+}
+
+func (r *memoryWebhookRepository) Decrypt(context.Context, int) (int, error) {
+	return 0, nil
+}